@@ -2,8 +2,8 @@ package main
 
 import (
 	"bufio"
-	"crypto/rand"
-	"encoding/hex"
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"os"
@@ -11,38 +11,42 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
+	fyneapp "fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"fyne.io/systray"
 
+	"go-local-server/internal/api"
+	"go-local-server/internal/app"
+	"go-local-server/internal/backup"
+	"go-local-server/internal/certs"
 	"go-local-server/internal/config"
 	"go-local-server/internal/dns"
+	"go-local-server/internal/events"
+	"go-local-server/internal/livereload"
+	"go-local-server/internal/mail"
+	"go-local-server/internal/packages"
+	"go-local-server/internal/platform"
 	"go-local-server/internal/projects"
+	"go-local-server/internal/registry"
+	"go-local-server/internal/resolver"
 	"go-local-server/internal/services"
 	"go-local-server/pkg/apache"
 )
 
-func generateRandomHex(bytesLen int) string {
-	b := make([]byte, bytesLen)
-	if _, err := rand.Read(b); err != nil {
-		// fallback: not cryptographically strong but avoids empty password
-		return fmt.Sprintf("%d", time.Now().UnixNano())
-	}
-	return hex.EncodeToString(b)
-}
-
 func (a *App) refreshUIState() {
 	// Apply latest service statuses immediately so the UI doesn't flash
 	// incorrect button states when switching views.
-	a.serviceManager.RefreshStatuses()
+	a.core.Services().RefreshStatuses(context.Background())
 	a.updateServiceCards()
 	a.updateQuickActionButtons()
 	if a.busy {
@@ -52,74 +56,11 @@ func (a *App) refreshUIState() {
 	}
 }
 
-func findDockerComposeFile() (string, error) {
-	// Try current working directory
-	if cwd, err := os.Getwd(); err == nil {
-		candidate := filepath.Join(cwd, "docker-compose.yml")
-		if st, err2 := os.Stat(candidate); err2 == nil && !st.IsDir() {
-			return candidate, nil
-		}
-	}
-
-	// Try executable location (../docker-compose.yml)
-	exe, err := os.Executable()
-	if err == nil {
-		exeDir := filepath.Dir(exe)
-		candidate := filepath.Clean(filepath.Join(exeDir, "..", "docker-compose.yml"))
-		if st, err2 := os.Stat(candidate); err2 == nil && !st.IsDir() {
-			return candidate, nil
-		}
-		
-		// Try Mac app bundle Resources directory (GoLocalServer.app/Contents/Resources)
-		resourcesDir := filepath.Clean(filepath.Join(exeDir, "..", "Resources"))
-		candidate = filepath.Join(resourcesDir, "docker-compose.yml")
-		if st, err2 := os.Stat(candidate); err2 == nil && !st.IsDir() {
-			return candidate, nil
-		}
-		
-		// Try one more level up (for nested app bundles)
-		candidate = filepath.Clean(filepath.Join(exeDir, "..", "..", "Resources", "docker-compose.yml"))
-		if st, err2 := os.Stat(candidate); err2 == nil && !st.IsDir() {
-			return candidate, nil
-		}
-	}
-
-	return "", fmt.Errorf("docker-compose.yml not found")
-}
-
-func findDockerBinary() string {
-	// Common Docker locations on macOS
-	locations := []string{
-		"/usr/local/bin/docker",
-		"/opt/homebrew/bin/docker",
-		"/usr/bin/docker",
-		"/Applications/Docker.app/Contents/Resources/bin/docker",
-		"/Applications/Docker.app/Contents/MacOS/docker",
-		"/usr/local/docker/bin/docker",
-	}
-	
-	// Add user's home directory paths
-	if home, err := os.UserHomeDir(); err == nil {
-		locations = append(locations, 
-			filepath.Join(home, ".docker", "bin", "docker"),
-			filepath.Join(home, "bin", "docker"),
-		)
-	}
-	
-	for _, loc := range locations {
-		if st, err := os.Stat(loc); err == nil && !st.IsDir() {
-			return loc
-		}
-	}
-	
-	// Fallback to "docker" and hope PATH works
-	return "docker"
-}
-
 func (a *App) dockerCompose(args ...string) *exec.Cmd {
-	dockerPath := findDockerBinary()
+	dockerPath := services.FindDockerBinary()
 	cmd := exec.Command(dockerPath, append([]string{"compose", "-f", a.composeFile}, args...)...)
 	cmd.Dir = filepath.Dir(a.composeFile)
+	cmd.Env = services.ComposeEnv()
 	return cmd
 }
 
@@ -162,10 +103,11 @@ func (a *App) runDockerComposeCommandWithLogs(title string, args ...string) {
 	dlg.Show()
 
 	go func() {
-		dockerPath := findDockerBinary()
+		dockerPath := services.FindDockerBinary()
 		composeArgs := append([]string{"compose", "-f", "docker-compose.yml"}, args...)
 		cmd := exec.Command(dockerPath, composeArgs...)
 		cmd.Dir = dockerDir
+		cmd.Env = services.ComposeEnv()
 
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -239,101 +181,17 @@ func (a *App) runDockerComposeCommandWithLogs(title string, args ...string) {
 }
 
 func (a *App) runDockerComposeWithLogs() {
-	a.runDockerComposeCommandWithLogs("Docker Compose Up", "up", "-d", "--build")
+	a.runDockerComposeCommandWithLogs("Docker Compose Up", "up", "-d", "--build", "--remove-orphans")
 }
 
 // copyDockerResources copies docker files to user directory for Docker mounting
+// and regenerates docker-compose.yml from the current settings.
 func (a *App) copyDockerResources() (string, error) {
-	dockerDir := filepath.Join(config.ConfigDir, "docker")
-	
-	// Create directory
-	if err := os.MkdirAll(dockerDir, 0755); err != nil {
-		return "", err
-	}
-	
-	// Find source resources
-	exe, _ := os.Executable()
-	exeDir := filepath.Dir(exe)
-	var resourcesDir string
-	
-	// Try app bundle Resources
-	candidate := filepath.Clean(filepath.Join(exeDir, "..", "Resources"))
-	if _, err := os.Stat(filepath.Join(candidate, "docker-compose.yml")); err == nil {
-		resourcesDir = candidate
-	} else {
-		// Fallback to current working directory
-		if cwd, err := os.Getwd(); err == nil {
-			if _, err := os.Stat(filepath.Join(cwd, "docker-compose.yml")); err == nil {
-				resourcesDir = cwd
-			}
-		}
-	}
-	
-	if resourcesDir == "" {
-		return "", fmt.Errorf("docker-compose.yml not found")
-	}
-	
-	// Copy docker-compose.yml
-	srcCompose := filepath.Join(resourcesDir, "docker-compose.yml")
-	dstCompose := filepath.Join(dockerDir, "docker-compose.yml")
-	if err := copyFile(srcCompose, dstCompose); err != nil {
-		return "", err
-	}
-	
-	// Copy apache directory
-	srcApache := filepath.Join(resourcesDir, "apache")
-	dstApache := filepath.Join(dockerDir, "apache")
-	if err := copyDir(srcApache, dstApache); err != nil {
-		return "", err
-	}
-
-	// Copy php directory (php.ini)
-	srcPHP := filepath.Join(resourcesDir, "php")
-	dstPHP := filepath.Join(dockerDir, "php")
-	if st, err := os.Stat(srcPHP); err == nil && st.IsDir() {
-		if err := copyDir(srcPHP, dstPHP); err != nil {
-			return "", err
-		}
-	}
-	
-	return dockerDir, nil
-}
-
-// copyFile copies a single file
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(dst, data, 0644)
-}
-
-// copyDir recursively copies a directory
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		rel, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-		
-		dstPath := filepath.Join(dst, rel)
-		
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-		
-		return copyFile(path, dstPath)
-	})
+	return services.CopyDockerResources(a.config)
 }
 
 func (a *App) reloadProjects() {
-	gen := apache.NewGenerator(a.config)
-	gen.GenerateAllVhosts()
-	a.serviceManager.ReloadNginx()
+	a.core.ReloadVhosts()
 	a.refreshProjectCards()
 	a.updateStatus("Projects reloaded")
 }
@@ -341,13 +199,18 @@ func (a *App) reloadProjects() {
 type App struct {
 	fyneApp        fyne.App
 	mainWindow     fyne.Window
-	serviceManager services.ServiceManagerInterface
 	projectManager *projects.Manager
+	core           *app.Service
 	dnsServer      *dns.Server
+	apiServer      *api.Server
+	mailServer     *mail.Server
+	liveReload     *livereload.Manager
+	events         *events.Bus
 	config         *config.AppConfig
 	usingDocker    bool
 	composeFile    string
 	stopRefreshCh  chan struct{}
+	backendCh      chan struct{} // tells refreshLoop the service manager changed
 	busy           bool
 	busyPrevText   map[*widget.Button]string
 	quickStartBtn  *widget.Button
@@ -360,14 +223,16 @@ type App struct {
 	currentView      string
 	statusLabel      *widget.Label
 
-	nginxCard  *serviceCard
-	phpCard    *serviceCard
-	mysqlCard  *serviceCard
+	// cards of the services shown in the current view; the event loop
+	// reads them too, so they are guarded by cardsMu
+	cardsMu      sync.Mutex
+	serviceCards map[services.ServiceID]*serviceCard
 
 	projectsContainer *fyne.Container
 }
 
 type serviceCard struct {
+	id         services.ServiceID
 	name       string
 	desc       string
 	statusText *canvas.Text
@@ -390,25 +255,32 @@ func main() {
 	fmt.Println("[DEBUG] Config loaded")
 
 	a := &App{
-		fyneApp:        app.NewWithID("com.golocalserver.app"),
+		fyneApp:        fyneapp.NewWithID("com.golocalserver.app"),
 		config:         cfg,
-		serviceManager: nil, // Will be set below
 		projectManager: projects.NewManager(cfg),
-		dnsServer:      dns.NewServer(cfg),
+		liveReload:     livereload.NewManager(livereload.DefaultPort),
+		events:         events.New(),
 		stopRefreshCh:  make(chan struct{}),
+		backendCh:      make(chan struct{}, 1),
+	}
+	a.liveReload.SetEvents(a.events)
+	a.dnsServer = dns.NewServer(cfg, a.projectManager)
+	if cfg.LiveReloadProtocol {
+		a.liveReload.SetProtocolPort(livereload.ProtocolPort)
 	}
 
-	composeFile, err := findDockerComposeFile()
-	if err == nil {
-		a.composeFile = composeFile
-		fmt.Printf("[DEBUG] Using docker compose file: %s\n", composeFile)
+	if dockerDir, err := a.copyDockerResources(); err == nil {
+		a.composeFile = filepath.Join(dockerDir, "docker-compose.yml")
+		fmt.Printf("[DEBUG] Using docker compose file: %s\n", a.composeFile)
 	} else {
-		fmt.Printf("[DEBUG] docker compose file not found: %v\n", err)
+		fmt.Printf("[DEBUG] docker compose file not generated: %v\n", err)
 	}
 
-	// Docker-only mode
-	a.serviceManager = services.NewDockerServiceManager(cfg)
-	a.usingDocker = true
+	a.usingDocker = !cfg.Native()
+	a.core = app.New(cfg, a.projectManager, a.newServiceManager())
+	a.core.SetEvents(a.events)
+	a.apiServer = api.NewServer(cfg, a.core)
+	a.mailServer = mail.NewServer(cfg, a.core.Mail())
 	fmt.Println("[DEBUG] App struct created")
 
 	// go a.setupTray()
@@ -419,11 +291,13 @@ func main() {
 	fmt.Println("[DEBUG] Main window setup complete")
 	
 	a.generateConfigs()
-	// DNS server disabled temporarily to prevent crashes
-	// a.startDNSServer()
+	a.startDNSServer()
+	a.startAPIServer()
+	a.startMailServer()
 	
 	// Load existing projects
 	a.loadProjectsOnStartup()
+	a.syncLiveReload()
 	a.showDashboard()
 	fmt.Println("[DEBUG] Dashboard shown")
 
@@ -431,7 +305,7 @@ func main() {
 	a.mainWindow.Show()
 	
 	// Always show Docker helper if Docker is not available
-	if !services.CheckDockerAvailable() {
+	if !cfg.Native() && !services.CheckDockerAvailable() {
 		go a.showDockerWarning()
 	}
 	
@@ -442,7 +316,7 @@ func (a *App) showDockerWarning() {
 	// Wait a moment for window to be fully shown
 	time.Sleep(500 * time.Millisecond)
 
-	dockerInstalled := findDockerBinary() != "docker"
+	dockerInstalled := services.FindDockerBinary() != "docker"
 	dockerRunning := services.CheckDockerAvailable()
 	composeOK := a.composeFile != ""
 	if composeOK {
@@ -476,11 +350,19 @@ func (a *App) showDockerWarning() {
 	)
 
 	openDockerBtn := widget.NewButtonWithIcon("Open Docker Desktop", theme.ComputerIcon(), func() {
-		exec.Command("open", "-a", "Docker").Run()
+		if err := services.StartDockerDesktop(); err != nil {
+			a.showError("Docker", err)
+		}
 	})
+	if platform.NativeDockerd() {
+		// Docker comes from a system dockerd; there is no app to open.
+		info.SetText("Docker is not running or not installed.\n\n" +
+			"A native dockerd was found. Start it (e.g. sudo systemctl start docker) and then run Docker Compose.")
+		openDockerBtn.Hide()
+	}
 
 	downloadDockerBtn := widget.NewButtonWithIcon("Download Docker", theme.DownloadIcon(), func() {
-		exec.Command("open", "https://www.docker.com/products/docker-desktop").Run()
+		platform.OpenURL("https://docs.docker.com/get-docker/")
 	})
 
 	runComposeBtn := widget.NewButtonWithIcon("Run Docker Compose", theme.MediaPlayIcon(), func() {
@@ -496,18 +378,18 @@ func (a *App) showDockerWarning() {
 			a.showError("Docker Compose", fmt.Errorf("docker-compose.yml not found"))
 			return
 		}
-		dialog.ShowConfirm("Reset Stack", "This will stop containers and remove volumes (MySQL data will be deleted). Continue?", func(ok bool) {
+		dialog.ShowConfirm("Reset Stack", "This will snapshot the databases, then stop containers and remove volumes (all database data will be deleted). Continue?", func(ok bool) {
 			if !ok {
 				return
 			}
-			a.runDockerComposeCommandWithLogs("Docker Compose Down (volumes)", "down", "-v")
+			a.resetStack()
 		}, a.mainWindow)
 	})
 	resetStackBtn.Importance = widget.DangerImportance
 
 	refreshBtn := widget.NewButtonWithIcon("Re-check Docker", theme.ViewRefreshIcon(), func() {
 		if services.CheckDockerAvailable() {
-			a.serviceManager = services.NewDockerServiceManager(a.config)
+			a.setServiceManager(a.newServiceManager())
 			a.updateStatus("Docker detected - switched to Docker services")
 			return
 		}
@@ -547,7 +429,7 @@ func (a *App) setupMainWindow() {
 	creditsPrefix.TextSize = 10
 	creditsLink := widget.NewHyperlink("K1Dev", nil)
 	creditsLink.OnTapped = func() {
-		exec.Command("open", "https://github.com/K1Dev-Core").Run()
+		platform.OpenURL("https://github.com/K1Dev-Core")
 	}
 	creditsRow := container.NewHBox(creditsPrefix, creditsLink)
 
@@ -573,6 +455,65 @@ func (a *App) setupMainWindow() {
 	))
 
 	go a.refreshLoop()
+	go a.eventLoop()
+}
+
+// newServiceManager returns a manager for the configured service backend
+// that publishes to the app's event bus.
+func (a *App) newServiceManager() services.ServiceManagerInterface {
+	return services.NewServiceManager(a.config, a.events)
+}
+
+// switchServiceBackend stops the stack of the previous backend, so the new
+// one can take its ports, and hands over to a manager for the current
+// settings. Nothing is started on the new backend.
+func (a *App) switchServiceBackend() {
+	old := a.core.Services()
+	a.withLoading("Switching service backend", func() error {
+		err := old.StopAll(context.Background())
+		a.usingDocker = !a.config.Native()
+		a.setServiceManager(a.newServiceManager())
+		a.showDockerButtons()
+		a.refreshUIState()
+		if err != nil {
+			return fmt.Errorf("switched backend, but stopping the old services failed: %w", err)
+		}
+		a.updateStatus("Switched to the " + a.config.ServiceBackend + " service backend")
+		return nil
+	})
+}
+
+// setServiceManager hands the app over to sm and has refreshLoop watch it
+// instead of the previous one.
+func (a *App) setServiceManager(sm services.ServiceManagerInterface) {
+	a.core.SetServiceManager(sm)
+	select {
+	case a.backendCh <- struct{}{}:
+	default:
+	}
+}
+
+// showDockerButtons shows the Docker Compose buttons only with the Docker
+// backend.
+func (a *App) showDockerButtons() {
+	for _, btn := range []*widget.Button{a.dockerUpBtn, a.restartBtn} {
+		if a.config.Native() {
+			btn.Hide()
+		} else {
+			btn.Show()
+		}
+	}
+}
+
+// serviceName is the display name the service manager uses, which names the
+// web server after the one in use.
+func (a *App) serviceName(id services.ServiceID) string {
+	for _, svc := range a.core.Services().Services() {
+		if svc.ID == id {
+			return svc.Name
+		}
+	}
+	return services.ServiceName(id)
 }
 
 func (a *App) shutdown() {
@@ -587,6 +528,20 @@ func (a *App) shutdown() {
 	if a.dnsServer != nil {
 		a.dnsServer.Stop()
 	}
+	if a.mailServer != nil {
+		a.mailServer.Stop()
+	}
+	if a.liveReload != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_ = a.liveReload.Stop(ctx)
+		cancel()
+	}
+	if a.apiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_ = a.apiServer.Stop(ctx)
+		cancel()
+	}
+	a.events.Close()
 
 	if a.fyneApp != nil {
 		a.fyneApp.Quit()
@@ -676,18 +631,12 @@ func (a *App) setBusy(b bool) {
 		disableBtn(a.quickStopBtn)
 		disableBtn(a.dockerUpBtn)
 		disableBtn(a.restartBtn)
-		if a.nginxCard != nil {
-			disableBtn(a.nginxCard.startBtn)
-			disableBtn(a.nginxCard.stopBtn)
-		}
-		if a.phpCard != nil {
-			disableBtn(a.phpCard.startBtn)
-			disableBtn(a.phpCard.stopBtn)
-		}
-		if a.mysqlCard != nil {
-			disableBtn(a.mysqlCard.startBtn)
-			disableBtn(a.mysqlCard.stopBtn)
+		a.cardsMu.Lock()
+		for _, card := range a.serviceCards {
+			disableBtn(card.startBtn)
+			disableBtn(card.stopBtn)
 		}
+		a.cardsMu.Unlock()
 		return
 	}
 
@@ -709,18 +658,12 @@ func (a *App) setBusy(b bool) {
 	enableBtn(a.quickStopBtn)
 	enableBtn(a.dockerUpBtn)
 	enableBtn(a.restartBtn)
-	if a.nginxCard != nil {
-		enableBtn(a.nginxCard.startBtn)
-		enableBtn(a.nginxCard.stopBtn)
-	}
-	if a.phpCard != nil {
-		enableBtn(a.phpCard.startBtn)
-		enableBtn(a.phpCard.stopBtn)
-	}
-	if a.mysqlCard != nil {
-		enableBtn(a.mysqlCard.startBtn)
-		enableBtn(a.mysqlCard.stopBtn)
+	a.cardsMu.Lock()
+	for _, card := range a.serviceCards {
+		enableBtn(card.startBtn)
+		enableBtn(card.stopBtn)
 	}
+	a.cardsMu.Unlock()
 }
 
 func (a *App) createSidebar() {
@@ -739,6 +682,7 @@ func (a *App) createSidebar() {
 		{"Dashboard", theme.HomeIcon(), func() { a.showDashboard() }},
 		{"Services", theme.SettingsIcon(), func() { a.showServices() }},
 		{"Projects", theme.FolderIcon(), func() { a.showProjects() }},
+		{"Mail", theme.MailComposeIcon(), func() { a.showMail() }},
 		{"Settings", theme.DocumentCreateIcon(), func() { a.showSettings() }},
 	}
 
@@ -755,7 +699,7 @@ func (a *App) createSidebar() {
 
 	startAllBtn := widget.NewButtonWithIcon("Start All", theme.MediaPlayIcon(), func() {
 		a.withLoading("Starting services", func() error {
-			if err := a.serviceManager.StartAll(); err != nil {
+			if err := a.core.Services().StartAll(context.Background()); err != nil {
 				return err
 			}
 			a.updateStatus("All services started")
@@ -783,14 +727,14 @@ func (a *App) createSidebar() {
 		// Use a clean reset flow to avoid "container name already in use" when compose
 		// is run from different locations.
 		a.runDockerComposeCommandWithLogs("Docker Compose Down", "down")
-		a.runDockerComposeCommandWithLogs("Docker Compose Up", "up", "-d", "--build")
+		a.runDockerComposeCommandWithLogs("Docker Compose Up", "up", "-d", "--build", "--remove-orphans")
 		a.updateStatus("Docker containers restarted")
 	})
 	a.restartBtn = restartContainersBtn
 
 	stopAllBtn := widget.NewButtonWithIcon("Stop All", theme.MediaStopIcon(), func() {
 		a.withLoading("Stopping services", func() error {
-			if err := a.serviceManager.StopAll(); err != nil {
+			if err := a.core.Services().StopAll(context.Background()); err != nil {
 				return err
 			}
 			a.updateStatus("All services stopped")
@@ -815,6 +759,10 @@ func (a *App) createSidebar() {
 		a.showContainerLogsDialog()
 	})
 
+	snapshotsBtn := widget.NewButtonWithIcon("DB Snapshots", theme.StorageIcon(), func() {
+		a.showSnapshotsDialog()
+	})
+
 	a.sidebar = container.NewVBox(
 		container.NewPadded(container.NewVBox(title, subtitle)),
 		widget.NewSeparator(),
@@ -829,9 +777,11 @@ func (a *App) createSidebar() {
 		toolsLabel,
 		healthBtn,
 		logsBtn,
+		snapshotsBtn,
 		widget.NewSeparator(),
 		portInfo,
 	)
+	a.showDockerButtons()
 }
 
 func (a *App) showDashboard() {
@@ -848,14 +798,11 @@ func (a *App) showDashboard() {
 	statusTitle.TextSize = 18
 	statusTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	a.nginxCard = a.createServiceCard("Apache", "Apache Web Server", "Stopped")
-	a.phpCard = a.createServiceCard("PHP-FPM", "PHP Processor", "Stopped")
-	a.mysqlCard = a.createServiceCard("MySQL", fmt.Sprintf("Port %d", a.config.MySQLPort), "Stopped")
-
+	a.resetServiceCards()
 	cards := container.NewGridWithColumns(3,
-		a.nginxCard.container,
-		a.phpCard.container,
-		a.mysqlCard.container,
+		a.createServiceCard(services.Apache, "Apache Web Server", "Stopped").container,
+		a.createServiceCard(services.PHP, "PHP Processor", "Stopped").container,
+		a.createServiceCard(services.MySQL, fmt.Sprintf("Port %d", a.config.MySQLPort), "Stopped").container,
 	)
 
 	projectsTitle := canvas.NewText("Recent Projects", color.White)
@@ -891,22 +838,22 @@ func (a *App) showServices() {
 	desc := canvas.NewText("Manage your local development services", color.NRGBA{150, 150, 150, 255})
 	desc.TextSize = 14
 
-	a.nginxCard = a.createDetailedServiceCard("Apache", "Apache Web Server", "docker")
-	a.phpCard = a.createDetailedServiceCard("PHP-FPM", "PHP Processor", "docker")
-	a.mysqlCard = a.createDetailedServiceCard("MySQL", fmt.Sprintf("Database Server (Port %d)", a.config.MySQLPort), "docker")
-
+	a.resetServiceCards()
+	backend := a.config.ServiceBackend
 	servicesList := container.NewVBox(
-		a.nginxCard.container,
+		a.createDetailedServiceCard(services.Apache, a.serviceName(services.Apache)+" Web Server", backend).container,
 		widget.NewSeparator(),
-		a.phpCard.container,
+		a.createDetailedServiceCard(services.PHP, "PHP Processor", backend).container,
 		widget.NewSeparator(),
-		a.mysqlCard.container,
+		a.createDetailedServiceCard(services.MySQL, fmt.Sprintf("Database Server (Port %d)", a.config.MySQLPort), backend).container,
 	)
 
 	content := container.NewVBox(
 		container.NewPadded(container.NewVBox(title, desc)),
 		widget.NewSeparator(),
 		servicesList,
+		widget.NewSeparator(),
+		a.optionalServicesList(),
 	)
 
 	scroll := container.NewScroll(content)
@@ -915,6 +862,52 @@ func (a *App) showServices() {
 	a.refreshUIState()
 }
 
+// optionalServicesList shows a card per registry service enabled in the
+// settings, with its web UI and the variables projects connect with.
+func (a *App) optionalServicesList() fyne.CanvasObject {
+	title := canvas.NewText("Optional Services", color.White)
+	title.TextSize = 18
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	list := container.NewVBox(container.NewPadded(title))
+	enabled := registry.Enabled(a.config)
+	if len(enabled) == 0 {
+		hint := canvas.NewText("Redis, Memcached, MinIO, Meilisearch and Mailpit can be enabled in Settings", color.NRGBA{120, 120, 120, 255})
+		hint.TextSize = 12
+		return container.NewVBox(list, container.NewPadded(hint))
+	}
+
+	for _, d := range enabled {
+		d := d
+		card := a.createServiceCard(services.ServiceID(d.ID), d.Description, "Stopped")
+
+		var env []string
+		for _, e := range d.Connection {
+			env = append(env, e.Name+"="+e.Value)
+		}
+		envText := canvas.NewText(strings.Join(env, "  "), color.NRGBA{100, 100, 100, 255})
+		envText.TextSize = 10
+
+		copyEnvBtn := widget.NewButtonWithIcon("Copy .env", theme.ContentCopyIcon(), func() {
+			a.mainWindow.Clipboard().SetContent(strings.Join(env, "\n") + "\n")
+			a.updateStatus(fmt.Sprintf("Copied %s connection settings", d.Title))
+		})
+		buttons := container.NewHBox(copyEnvBtn)
+		if url := d.WebURL(); url != "" {
+			buttons.Add(widget.NewButtonWithIcon("Open", theme.ComputerIcon(), func() {
+				platform.OpenURL(url)
+			}))
+		}
+
+		list.Add(container.NewVBox(
+			card.container,
+			container.NewPadded(container.NewVBox(envText, buttons)),
+		))
+		list.Add(widget.NewSeparator())
+	}
+	return list
+}
+
 func (a *App) showProjects() {
 	a.currentView = "projects"
 
@@ -962,6 +955,206 @@ func (a *App) showProjects() {
 	a.refreshUIState()
 }
 
+func (a *App) showMail() {
+	a.currentView = "mail"
+
+	title := canvas.NewText("Mail", color.White)
+	title.TextSize = 28
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	status := "Mail catcher is off (set its port in Settings)"
+	if a.mailServer.IsRunning() {
+		status = fmt.Sprintf("Mail from PHP's mail() and from SMTP on port %d lands here", a.config.MailPort)
+	}
+	desc := canvas.NewText(status, color.NRGBA{150, 150, 150, 255})
+	desc.TextSize = 14
+
+	store := a.core.Mail()
+	mailboxes := []string{"All recipients"}
+	if boxes, err := store.Mailboxes(); err == nil {
+		for _, b := range boxes {
+			mailboxes = append(mailboxes, fmt.Sprintf("%s (%d)", b.Address, b.Count))
+		}
+	}
+
+	var (
+		messages []*mail.Message
+		selected *mail.Message
+		to       string
+	)
+	detail := container.NewMax(container.NewCenter(widget.NewLabel("Select a message")))
+
+	list := widget.NewList(
+		func() int { return len(messages) },
+		func() fyne.CanvasObject {
+			subject := widget.NewLabel("")
+			subject.TextStyle = fyne.TextStyle{Bold: true}
+			subject.Truncation = fyne.TextTruncateEllipsis
+			from := widget.NewLabel("")
+			from.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(subject, from)
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			m := messages[i]
+			box := o.(*fyne.Container)
+			subject := m.Subject
+			if subject == "" {
+				subject = "(no subject)"
+			}
+			box.Objects[0].(*widget.Label).SetText(subject)
+			box.Objects[1].(*widget.Label).SetText(fmt.Sprintf("%s  ·  %s", m.From, m.Received.Format("Jan 2 15:04")))
+		},
+	)
+	load := func() {
+		var err error
+		if messages, err = store.List(to); err != nil {
+			a.showError("Mail", err)
+		}
+		list.UnselectAll()
+		list.Refresh()
+		selected = nil
+		detail.Objects = []fyne.CanvasObject{container.NewCenter(widget.NewLabel("Select a message"))}
+		detail.Refresh()
+	}
+	list.OnSelected = func(i widget.ListItemID) {
+		m, err := store.Get(messages[i].ID)
+		if err != nil {
+			a.showError("Mail", err)
+			return
+		}
+		selected = m
+		detail.Objects = []fyne.CanvasObject{a.mailDetail(m)}
+		detail.Refresh()
+	}
+
+	recipient := widget.NewSelect(mailboxes, func(v string) {
+		to = ""
+		if v != mailboxes[0] {
+			to, _, _ = strings.Cut(v, " (")
+		}
+		load()
+	})
+	recipient.SetSelected(mailboxes[0])
+
+	toolbar := container.NewHBox(
+		recipient,
+		widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() { a.showMail() }),
+		widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
+			if selected == nil {
+				return
+			}
+			if err := store.Delete(selected.ID); err != nil {
+				a.showError("Mail", err)
+			}
+			load()
+		}),
+		widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), func() {
+			dialog.ShowConfirm("Clear Mail", "Delete every message shown?", func(ok bool) {
+				if !ok {
+					return
+				}
+				if _, err := store.Clear(to); err != nil {
+					a.showError("Mail", err)
+				}
+				a.showMail()
+			}, a.mainWindow)
+		}),
+	)
+
+	split := container.NewHSplit(list, container.NewPadded(detail))
+	split.Offset = 0.35
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewPadded(container.NewVBox(title, desc)),
+			container.NewPadded(toolbar),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		split,
+	)
+	a.contentArea.Objects = []fyne.CanvasObject{content}
+	a.contentArea.Refresh()
+	a.refreshUIState()
+}
+
+// mailDetail shows one caught message: its headers, the text body and
+// buttons for the HTML body, the source and the attachments.
+func (a *App) mailDetail(m *mail.Message) fyne.CanvasObject {
+	header := widget.NewForm(
+		widget.NewFormItem("From", widget.NewLabel(m.From)),
+		widget.NewFormItem("To", widget.NewLabel(strings.Join(m.Recipients, ", "))),
+		widget.NewFormItem("Subject", widget.NewLabel(m.Subject)),
+		widget.NewFormItem("Received", widget.NewLabel(m.Received.Format("2006-01-02 15:04:05"))),
+	)
+
+	body := widget.NewMultiLineEntry()
+	body.Wrapping = fyne.TextWrapWord
+	switch {
+	case m.Text != "":
+		body.SetText(m.Text)
+	case m.HTML != "":
+		body.SetText("This message only has an HTML body. Use Open HTML to view it.")
+	}
+	body.Disable()
+
+	actions := container.NewHBox()
+	if m.HTML != "" {
+		actions.Add(widget.NewButtonWithIcon("Open HTML", theme.ComputerIcon(), func() {
+			path := filepath.Join(os.TempDir(), "golocal-mail-"+m.ID+".html")
+			if err := os.WriteFile(path, []byte(m.HTML), 0600); err != nil {
+				a.showError("Mail", err)
+				return
+			}
+			platform.OpenURL("file://" + filepath.ToSlash(path))
+		}))
+	}
+	actions.Add(widget.NewButtonWithIcon("Source", theme.DocumentIcon(), func() {
+		a.openLog(filepath.Join(a.core.Mail().Dir(), m.ID+".eml"))
+	}))
+	for _, att := range m.Attachments {
+		att := att
+		name := att.Filename
+		if name == "" {
+			name = fmt.Sprintf("part-%d", att.Index)
+		}
+		actions.Add(widget.NewButtonWithIcon(name, theme.DownloadIcon(), func() {
+			a.saveMailAttachment(m.ID, att.Index, name)
+		}))
+	}
+
+	return container.NewBorder(
+		container.NewVBox(header, actions, widget.NewSeparator()),
+		nil, nil, nil,
+		container.NewScroll(body),
+	)
+}
+
+func (a *App) saveMailAttachment(id string, index int, name string) {
+	_, data, err := a.core.Mail().Attachment(id, index)
+	if err != nil {
+		a.showError("Mail", err)
+		return
+	}
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil {
+			a.showError("Mail", err)
+			return
+		}
+		if w == nil {
+			return
+		}
+		defer w.Close()
+		if _, err := w.Write(data); err != nil {
+			a.showError("Mail", err)
+			return
+		}
+		a.updateStatus("Saved attachment to " + w.URI().Path())
+	}, a.mainWindow)
+	save.SetFileName(filepath.Base(name))
+	save.Show()
+}
+
 func (a *App) showSettings() {
 	a.currentView = "settings"
 
@@ -973,41 +1166,221 @@ func (a *App) showSettings() {
 	portTitle.TextSize = 16
 	portTitle.TextStyle = fyne.TextStyle{Bold: true}
 
+	backendTitle := canvas.NewText("Service Backend", color.White)
+	backendTitle.TextSize = 16
+	backendTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	backendInfo := canvas.NewText("native runs the web server, PHP-FPM and MySQL below as local processes, for machines without Docker", color.NRGBA{120, 120, 120, 255})
+	backendInfo.TextSize = 11
+
 	dbTitle := canvas.NewText("Database (Docker)", color.White)
 	dbTitle.TextSize = 16
 	dbTitle.TextStyle = fyne.TextStyle{Bold: true}
 
+	servicesTitle := canvas.NewText("Optional Services", color.White)
+	servicesTitle.TextSize = 16
+	servicesTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	servicesInfo := canvas.NewText("Enabled services join the stack; PHP sees their connection settings as environment variables", color.NRGBA{120, 120, 120, 255})
+	servicesInfo.TextSize = 11
+
 	editorTitle := canvas.NewText("Editor Settings", color.White)
 	editorTitle.TextSize = 16
 	editorTitle.TextStyle = fyne.TextStyle{Bold: true}
 
-	dbInfo := canvas.NewText("Host: mysql  |  Port (inside Docker): 3306", color.NRGBA{120, 120, 120, 255})
+	dbInfo := canvas.NewText("Host: mysql  |  Port (inside Docker): 3306  |  Root password applies to a new MySQL volume", color.NRGBA{120, 120, 120, 255})
 	dbInfo.TextSize = 11
 
+	apiTitle := canvas.NewText("Control API", color.White)
+	apiTitle.TextSize = 16
+	apiTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	apiInfo := canvas.NewText(a.apiServer.Addr(), color.NRGBA{120, 120, 120, 255})
+	apiInfo.TextSize = 11
+
+	copyTokenBtn := widget.NewButtonWithIcon("Copy Token", theme.ContentCopyIcon(), func() {
+		token, err := api.LoadOrCreateToken()
+		if err != nil {
+			a.showError("API Token", err)
+			return
+		}
+		a.mainWindow.Clipboard().SetContent(token)
+		a.updateStatus("Copied API token")
+	})
+
+	apiPort := widget.NewEntry()
+	apiPort.SetText(strconv.Itoa(a.config.APIPort))
+
+	httpsTitle := canvas.NewText("HTTPS", color.White)
+	httpsTitle.TextSize = 16
+	httpsTitle.TextStyle = fyne.TextStyle{Bold: true}
+
+	httpsInfo := canvas.NewText("Sites are signed by a local CA. Import its certificate into your system or browser trust store.", color.NRGBA{120, 120, 120, 255})
+	httpsInfo.TextSize = 11
+
+	exportCABtn := widget.NewButtonWithIcon("Export CA Certificate", theme.DocumentSaveIcon(), func() {
+		a.exportCACert()
+	})
+
 	httpPort := widget.NewEntry()
 	httpPort.SetText(strconv.Itoa(a.config.HTTPPort))
+	httpsPort := widget.NewEntry()
+	httpsPort.SetText(strconv.Itoa(a.config.HTTPSPort))
 	mysqlPort := widget.NewEntry()
 	mysqlPort.SetText(strconv.Itoa(a.config.MySQLPort))
+	pmaPort := widget.NewEntry()
+	pmaPort.SetText(strconv.Itoa(a.config.PhpMyAdminPort))
+	pmaEnabled := widget.NewCheck("Run phpMyAdmin", nil)
+	pmaEnabled.SetChecked(a.config.ServiceEnabled("phpmyadmin"))
+	optionalChecks := make([]*widget.Check, len(registry.Builtins))
+	optionalItems := make([]*widget.FormItem, len(registry.Builtins))
+	for i, d := range registry.Builtins {
+		optionalChecks[i] = widget.NewCheck(d.Description, nil)
+		optionalChecks[i].SetChecked(a.config.ServiceEnabled(d.ID))
+		optionalItems[i] = widget.NewFormItem(d.Title, optionalChecks[i])
+	}
+	rootPassword := widget.NewPasswordEntry()
+	rootPassword.SetText(a.config.MySQLRootPassword)
+	phpImage := widget.NewEntry()
+	phpImage.SetText(a.config.PHPImage)
+	mysqlImage := widget.NewEntry()
+	mysqlImage.SetText(a.config.MySQLImage)
 	domain := widget.NewEntry()
 	domain.SetText(a.config.Domain)
+	dnsPort := widget.NewEntry()
+	dnsPort.SetText(strconv.Itoa(a.config.DNSPort))
+	dnsUpstreams := widget.NewEntry()
+	dnsUpstreams.SetPlaceHolder("System resolvers")
+	dnsUpstreams.SetText(strings.Join(a.config.DNSUpstreams, ", "))
+	resolverBtn := widget.NewButtonWithIcon("Install System Resolver", theme.SettingsIcon(), func() {
+		a.installResolver()
+	})
+	dnsAnswer := widget.NewSelectEntry([]string{dns.AnswerLoopback, dns.AnswerLAN})
+	dnsAnswer.SetPlaceHolder(dns.AnswerLoopback)
+	dnsAnswer.SetText(a.config.DNSAnswer)
+	mailPort := widget.NewEntry()
+	mailPort.SetText(strconv.Itoa(a.config.MailPort))
 
 	editorSelector := widget.NewSelect([]string{"VSCode", "Cursor", "Windsurf"}, nil)
 	editorSelector.SetSelected(a.config.PreferredEditor)
 	if editorSelector.Selected == "" {
 		editorSelector.SetSelected("VSCode")
 	}
+	lrProtocol := widget.NewCheck(fmt.Sprintf("Accept LiveReload browser extensions (port %d)", livereload.ProtocolPort), nil)
+	lrProtocol.SetChecked(a.config.LiveReloadProtocol)
+
+	backendSelect := widget.NewSelect([]string{config.BackendDocker, config.BackendNative}, nil)
+	backendSelect.SetSelected(a.config.ServiceBackend)
+	if backendSelect.Selected == "" {
+		backendSelect.SetSelected(config.BackendDocker)
+	}
+	webServerSelect := widget.NewSelect([]string{config.WebServerApache, config.WebServerNginx}, nil)
+	webServerSelect.SetSelected(a.config.WebServer)
+	if webServerSelect.Selected == "" {
+		webServerSelect.SetSelected(config.WebServerApache)
+	}
+	apachePath := widget.NewEntry()
+	apachePath.SetText(a.config.ApachePath)
+	nginxPath := widget.NewEntry()
+	nginxPath.SetText(a.config.NginxPath)
+	phpPath := widget.NewEntry()
+	phpPath.SetText(a.config.PHPPath)
+	mysqlPath := widget.NewEntry()
+	mysqlPath.SetText(a.config.MySQLPath)
+	installStackBtn := widget.NewButtonWithIcon("Install Native Stack", theme.DownloadIcon(), func() {
+		a.installNativeStack(func() {
+			apachePath.SetText(a.config.ApachePath)
+			nginxPath.SetText(a.config.NginxPath)
+			phpPath.SetText(a.config.PHPPath)
+			mysqlPath.SetText(a.config.MySQLPath)
+		})
+	})
 
 	saveBtn := widget.NewButtonWithIcon("Save Settings", theme.DocumentSaveIcon(), func() {
 		if p, err := strconv.Atoi(httpPort.Text); err == nil {
 			a.config.HTTPPort = p
 		}
+		if p, err := strconv.Atoi(httpsPort.Text); err == nil {
+			a.config.HTTPSPort = p
+		}
 		if p, err := strconv.Atoi(mysqlPort.Text); err == nil {
 			a.config.MySQLPort = p
 		}
+		if p, err := strconv.Atoi(apiPort.Text); err == nil {
+			a.config.APIPort = p
+		}
+		if p, err := strconv.Atoi(pmaPort.Text); err == nil {
+			a.config.PhpMyAdminPort = p
+		}
+		a.config.OptionalServices = []string{}
+		if pmaEnabled.Checked {
+			a.config.OptionalServices = append(a.config.OptionalServices, "phpmyadmin")
+		}
+		for i, d := range registry.Builtins {
+			if optionalChecks[i].Checked {
+				a.config.OptionalServices = append(a.config.OptionalServices, d.ID)
+			}
+		}
+		if rootPassword.Text != "" {
+			a.config.MySQLRootPassword = rootPassword.Text
+		}
+		if img := strings.TrimSpace(phpImage.Text); img != "" {
+			a.config.PHPImage = img
+		}
+		if img := strings.TrimSpace(mysqlImage.Text); img != "" {
+			a.config.MySQLImage = img
+		}
+		oldDNSPort, oldDNSAnswer := a.config.DNSPort, a.config.DNSAnswer
+		a.config.DNSAnswer = strings.TrimSpace(dnsAnswer.Text)
+		if p, err := strconv.Atoi(dnsPort.Text); err == nil {
+			a.config.DNSPort = p
+		}
+		a.config.DNSUpstreams = nil
+		for _, u := range strings.Split(dnsUpstreams.Text, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				a.config.DNSUpstreams = append(a.config.DNSUpstreams, u)
+			}
+		}
+		oldMailPort := a.config.MailPort
+		if p, err := strconv.Atoi(mailPort.Text); err == nil {
+			a.config.MailPort = p
+		}
+		oldBackend, oldWebServer := a.config.ServiceBackend, a.config.WebServer
+		a.config.ServiceBackend = backendSelect.Selected
+		a.config.WebServer = webServerSelect.Selected
+		if p := strings.TrimSpace(apachePath.Text); p != "" {
+			a.config.ApachePath = p
+		}
+		if p := strings.TrimSpace(nginxPath.Text); p != "" {
+			a.config.NginxPath = p
+		}
+		if p := strings.TrimSpace(phpPath.Text); p != "" {
+			a.config.PHPPath = p
+		}
+		if p := strings.TrimSpace(mysqlPath.Text); p != "" {
+			a.config.MySQLPath = p
+		}
 		a.config.Domain = domain.Text
 		a.config.PreferredEditor = editorSelector.Selected
+		oldLRProtocol := a.config.LiveReloadProtocol
+		a.config.LiveReloadProtocol = lrProtocol.Checked
 		a.config.Save()
 		a.updateStatus("Settings saved")
+		if a.config.LiveReloadProtocol != oldLRProtocol {
+			go a.restartLiveReload()
+		}
+		if a.config.DNSPort != oldDNSPort || a.config.DNSAnswer != oldDNSAnswer || !a.dnsServer.IsRunning() {
+			a.dnsServer.Stop()
+			a.startDNSServer()
+		}
+		if a.config.MailPort != oldMailPort || !a.mailServer.IsRunning() {
+			a.mailServer.Stop()
+			a.startMailServer()
+		}
+		if a.config.ServiceBackend != oldBackend || a.config.WebServer != oldWebServer {
+			go a.switchServiceBackend()
+		}
+		go a.applyStackSettings()
 	})
 	saveBtn.Importance = widget.HighImportance
 
@@ -1017,17 +1390,57 @@ func (a *App) showSettings() {
 		container.NewPadded(portTitle),
 		container.NewPadded(widget.NewForm(
 			widget.NewFormItem("HTTP Port", httpPort),
+			widget.NewFormItem("HTTPS Port (0 = off)", httpsPort),
 			widget.NewFormItem("MySQL Port", mysqlPort),
 			widget.NewFormItem("Domain", domain),
+			widget.NewFormItem("DNS Port", dnsPort),
+			widget.NewFormItem("Upstream DNS", dnsUpstreams),
+			widget.NewFormItem("Answer With (loopback, lan or IP)", dnsAnswer),
+			widget.NewFormItem("Mail Catcher SMTP Port (0 = off)", mailPort),
 		)),
+		container.NewPadded(container.NewHBox(resolverBtn)),
 		widget.NewSeparator(),
 		container.NewPadded(editorTitle),
 		container.NewPadded(widget.NewForm(
 			widget.NewFormItem("Preferred Editor", editorSelector),
+			widget.NewFormItem("", lrProtocol),
+		)),
+		widget.NewSeparator(),
+		container.NewPadded(backendTitle),
+		container.NewPadded(backendInfo),
+		container.NewPadded(widget.NewForm(
+			widget.NewFormItem("Backend", backendSelect),
+			widget.NewFormItem("Web Server (native)", webServerSelect),
+			widget.NewFormItem("Apache (httpd)", apachePath),
+			widget.NewFormItem("Nginx", nginxPath),
+			widget.NewFormItem("PHP-FPM", phpPath),
+			widget.NewFormItem("MySQL (mysqld)", mysqlPath),
 		)),
+		container.NewPadded(container.NewHBox(installStackBtn)),
 		widget.NewSeparator(),
 		container.NewPadded(dbTitle),
 		container.NewPadded(dbInfo),
+		container.NewPadded(widget.NewForm(
+			widget.NewFormItem("Root Password", rootPassword),
+			widget.NewFormItem("MySQL Image", mysqlImage),
+			widget.NewFormItem("PHP-FPM Image", phpImage),
+			widget.NewFormItem("phpMyAdmin Port", pmaPort),
+			widget.NewFormItem("", pmaEnabled),
+		)),
+		widget.NewSeparator(),
+		container.NewPadded(servicesTitle),
+		container.NewPadded(servicesInfo),
+		container.NewPadded(widget.NewForm(optionalItems...)),
+		widget.NewSeparator(),
+		container.NewPadded(httpsTitle),
+		container.NewPadded(httpsInfo),
+		container.NewPadded(container.NewHBox(exportCABtn)),
+		widget.NewSeparator(),
+		container.NewPadded(apiTitle),
+		container.NewPadded(widget.NewForm(
+			widget.NewFormItem("TCP Port (0 = off)", apiPort),
+		)),
+		container.NewPadded(container.NewHBox(apiInfo, copyTokenBtn)),
 		widget.NewSeparator(),
 		container.NewPadded(saveBtn),
 	)
@@ -1038,24 +1451,190 @@ func (a *App) showSettings() {
 	a.refreshUIState()
 }
 
-func (a *App) createServiceCard(name, desc, status string) *serviceCard {
-	card := &serviceCard{name: name, desc: desc}
-
-	card.indicator = canvas.NewCircle(color.NRGBA{100, 100, 100, 255})
-	card.indicator.Resize(fyne.NewSize(12, 12))
-
-	card.statusText = canvas.NewText(status, color.NRGBA{150, 150, 150, 255})
-	card.statusText.TextSize = 12
+// exportCACert saves a copy of the local CA certificate so it can be added to
+// the system or browser trust store.
+func (a *App) exportCACert() {
+	ca, err := certs.Default()
+	if err != nil {
+		a.showError("HTTPS", err)
+		return
+	}
+	data, err := os.ReadFile(ca.CertPath())
+	if err != nil {
+		a.showError("HTTPS", err)
+		return
+	}
 
-	card.startBtn = widget.NewButtonWithIcon("Start", theme.MediaPlayIcon(), func() {
-		a.startService(name)
-	})
-	card.startBtn.Importance = widget.SuccessImportance
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil {
+			a.showError("HTTPS", err)
+			return
+		}
+		if w == nil {
+			return
+		}
+		defer w.Close()
+		if _, err := w.Write(data); err != nil {
+			a.showError("HTTPS", err)
+			return
+		}
+		a.updateStatus("Exported CA certificate to " + w.URI().Path())
+	}, a.mainWindow)
+	save.SetFileName("GoLocalServer-CA.pem")
+	save.Show()
+}
 
-	card.stopBtn = widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), func() {
-		a.stopService(name)
-	})
-	card.stopBtn.Importance = widget.DangerImportance
+// applyStackSettings regenerates docker-compose.yml after a settings change
+// and offers to recreate the containers that no longer match it. The native
+// backend regenerates its configs when services start.
+func (a *App) applyStackSettings() {
+	if a.config.Native() {
+		return
+	}
+	if _, err := a.copyDockerResources(); err != nil {
+		a.showError("Docker Compose", fmt.Errorf("failed to generate docker-compose.yml: %v", err))
+		return
+	}
+	drift, err := a.core.Services().ComposeDrift(context.Background())
+	if err != nil || len(drift) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%s no longer match the saved settings.\nRecreate the containers now?", strings.Join(drift, ", "))
+	dialog.ShowConfirm("Apply Settings", msg, func(ok bool) {
+		if ok {
+			a.runDockerComposeWithLogs()
+		}
+	}, a.mainWindow)
+}
+
+// installResolver shows the changes needed to point the system resolver at
+// the DNS server and applies them once confirmed. Applying prompts for an
+// administrator password.
+func (a *App) installResolver() {
+	list, _ := a.projectManager.List()
+	plan, err := resolver.Install(a.config, resolver.Detect(), list)
+	if err != nil {
+		a.showError("System Resolver", err)
+		return
+	}
+	if plan.Empty() {
+		dialog.ShowInformation("System Resolver", fmt.Sprintf("*.%s already resolves through GoLocalServer (%s).", a.config.Domain, plan.Method), a.mainWindow)
+		return
+	}
+
+	diff := widget.NewLabel(plan.Diff())
+	diff.TextStyle = fyne.TextStyle{Monospace: true}
+	scroll := container.NewScroll(diff)
+	scroll.SetMinSize(fyne.NewSize(560, 240))
+	dialog.ShowCustomConfirm("System Resolver ("+string(plan.Method)+")", "Apply", "Cancel", scroll, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			if err := plan.Apply(); err != nil {
+				a.showError("System Resolver", err)
+				return
+			}
+			a.updateStatus("System resolver installed (" + string(plan.Method) + ")")
+		}()
+	}, a.mainWindow)
+}
+
+// installNativeStack installs the web server, PHP and MySQL of the saved
+// settings with the system's package manager, showing each one's progress,
+// and points the binary paths at them. updated runs once the paths are
+// saved. Installing prompts for an administrator password.
+func (a *App) installNativeStack(updated func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pm, err := packages.Detect(ctx, nil)
+	if err != nil {
+		cancel()
+		a.showError("Install Native Stack", err)
+		return
+	}
+
+	bar := widget.NewProgressBar()
+	status := widget.NewLabel("Checking what is installed...")
+	status.Wrapping = fyne.TextTruncate
+	cancelBtn := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		status.SetText("Cancelling...")
+		cancel()
+	})
+	content := container.NewVBox(
+		widget.NewLabel("Installing with "+pm.Name()),
+		bar,
+		status,
+		container.NewCenter(cancelBtn),
+	)
+	d := dialog.NewCustomWithoutButtons("Install Native Stack", content, a.mainWindow)
+	d.Resize(fyne.NewSize(460, 200))
+	d.Show()
+	a.setBusy(true)
+
+	go func() {
+		results := packages.InstallStack(ctx, pm, a.config, func(dep packages.Dependency, percent int, message string) {
+			if percent >= 0 {
+				bar.SetValue(float64(percent) / 100)
+			}
+			status.SetText(dep.Title + ": " + message)
+		})
+		cancel()
+		a.config.Save()
+		a.setBusy(false)
+		d.Hide()
+		updated()
+
+		lines := make([]string, 0, len(results))
+		for _, res := range results {
+			switch {
+			case res.Err != nil:
+				lines = append(lines, fmt.Sprintf("%s: failed: %v", res.Dependency.Title, res.Err))
+			case res.Installed:
+				lines = append(lines, fmt.Sprintf("%s: installed", res.Dependency.Title))
+			default:
+				lines = append(lines, fmt.Sprintf("%s: already installed", res.Dependency.Title))
+			}
+		}
+		if packages.Errors(results) != nil {
+			a.updateStatus("Native stack installation failed")
+		} else {
+			a.updateStatus("Native stack installed")
+		}
+		dialog.ShowInformation("Install Native Stack", strings.Join(lines, "\n"), a.mainWindow)
+	}()
+}
+
+// resetServiceCards forgets the cards of the previous view.
+func (a *App) resetServiceCards() {
+	a.cardsMu.Lock()
+	a.serviceCards = map[services.ServiceID]*serviceCard{}
+	a.cardsMu.Unlock()
+}
+
+// createServiceCard builds the card of a service and registers it for
+// status updates.
+func (a *App) createServiceCard(id services.ServiceID, desc, status string) *serviceCard {
+	name := a.serviceName(id)
+	card := &serviceCard{id: id, name: name, desc: desc}
+	a.cardsMu.Lock()
+	a.serviceCards[id] = card
+	a.cardsMu.Unlock()
+
+	card.indicator = canvas.NewCircle(color.NRGBA{100, 100, 100, 255})
+	card.indicator.Resize(fyne.NewSize(12, 12))
+
+	card.statusText = canvas.NewText(status, color.NRGBA{150, 150, 150, 255})
+	card.statusText.TextSize = 12
+
+	card.startBtn = widget.NewButtonWithIcon("Start", theme.MediaPlayIcon(), func() {
+		a.startService(id)
+	})
+	card.startBtn.Importance = widget.SuccessImportance
+
+	card.stopBtn = widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), func() {
+		a.stopService(id)
+	})
+	card.stopBtn.Importance = widget.DangerImportance
 	card.stopBtn.Hide()
 
 	title := canvas.NewText(name, color.White)
@@ -1084,14 +1663,20 @@ func (a *App) createServiceCard(name, desc, status string) *serviceCard {
 	return card
 }
 
-func (a *App) createDetailedServiceCard(name, desc, path string) *serviceCard {
-	card := a.createServiceCard(name, desc, "Stopped")
+func (a *App) createDetailedServiceCard(id services.ServiceID, desc, path string) *serviceCard {
+	card := a.createServiceCard(id, desc, "Stopped")
 
 	pathText := canvas.NewText(path, color.NRGBA{100, 100, 100, 255})
 	pathText.TextSize = 10
 
 	logBtn := widget.NewButtonWithIcon("View Logs", theme.DocumentIcon(), func() {
-		a.openLog(filepath.Join(config.LogDir, fmt.Sprintf("%s.log", name)))
+		logFile := filepath.Join(config.LogDir, string(id)+".log")
+		for _, svc := range a.core.Services().Services() {
+			if svc.ID == id && svc.LogFile != "" {
+				logFile = svc.LogFile
+			}
+		}
+		a.openLog(logFile)
 	})
 
 	card.container = container.NewVBox(
@@ -1110,36 +1695,41 @@ func (a *App) createProjectCard(p *projects.Project) fyne.CanvasObject {
 	title.TextSize = 16
 	title.TextStyle = fyne.TextStyle{Bold: true}
 
-	url := fmt.Sprintf("http://%s", p.Domain)
+	url := a.config.SiteURL(p.Domain)
 	urlText := canvas.NewText(url, color.NRGBA{100, 150, 255, 255})
 	urlText.TextSize = 12
 
-	phpText := canvas.NewText(fmt.Sprintf("PHP %s", p.PHPVersion), color.NRGBA{150, 150, 150, 255})
+	phpText := canvas.NewText(fmt.Sprintf("PHP %s", p.PHP()), color.NRGBA{150, 150, 150, 255})
 	phpText.TextSize = 11
 
 	dbText := canvas.NewText(fmt.Sprintf("DB: %s@%s", p.Database.DBUser, p.Database.DBHost), color.NRGBA{100, 100, 100, 255})
 	dbText.TextSize = 10
 
 	openBtn := widget.NewButtonWithIcon("Open", theme.ComputerIcon(), func() {
-		exec.Command("open", url).Run()
+		platform.OpenURL(url)
 	})
 	openBtn.Importance = widget.HighImportance
 
 	phpmyadminBtn := widget.NewButtonWithIcon("phpMyAdmin", theme.FolderOpenIcon(), func() {
-		exec.Command("open", "http://localhost:8081").Run()
+		platform.OpenURL(a.config.PhpMyAdminURL())
 	})
 	phpmyadminBtn.Importance = widget.SuccessImportance
+	if p.Database.EngineName() == projects.EnginePostgres {
+		phpmyadminBtn.Hide()
+	}
 
 	openFolderBtn := widget.NewButtonWithIcon("Folder", theme.FolderIcon(), func() {
 		if p.Path != "" {
-			exec.Command("open", p.Path).Run()
+			platform.OpenFolder(p.Path)
 		}
 	})
 
-	appName, displayName := a.config.GetEditorInfo()
+	_, displayName := a.config.GetEditorInfo()
 	openEditorBtn := widget.NewButtonWithIcon(displayName, theme.ComputerIcon(), func() {
 		if p.Path != "" {
-			_ = exec.Command("open", "-a", appName, p.Path).Run()
+			if err := platform.OpenInEditor(a.config.Editor(), p.Path); err != nil {
+				a.showError(displayName, err)
+			}
 		}
 	})
 
@@ -1174,6 +1764,12 @@ func (a *App) createProjectCard(p *projects.Project) fyne.CanvasObject {
 		a.showEditProjectDialog(p)
 	})
 
+	liveReloadCheck := widget.NewCheck("Live Reload", nil)
+	liveReloadCheck.SetChecked(p.LiveReload)
+	liveReloadCheck.OnChanged = func(on bool) {
+		go a.setLiveReload(p, on)
+	}
+
 	deleteBtn := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
 		a.deleteProject(p)
 	})
@@ -1183,10 +1779,42 @@ func (a *App) createProjectCard(p *projects.Project) fyne.CanvasObject {
 		a.fixProjectDatabase(p)
 	})
 	fixDBBtn.Importance = widget.MediumImportance
+
+	stripBtn := widget.NewButtonWithIcon("Strip Old LR Script", theme.ContentClearIcon(), func() {
+		a.stripLiveReloadScripts(p)
+	})
 	if p.Database.DBName == "" || p.Database.DBUser == "" {
 		fixDBBtn.Hide()
 	}
 
+	snapshotBtn := widget.NewButtonWithIcon("Snapshot DB", theme.StorageIcon(), func() {
+		a.withLoading("Taking snapshot", func() error {
+			snap, err := a.core.Snapshot(context.Background(), "", p.ID)
+			if err != nil {
+				return err
+			}
+			a.updateStatus(fmt.Sprintf("Saved snapshot %s of '%s'", snap.ID, p.Name))
+			return nil
+		})
+	})
+	if p.Database.DBName == "" || p.Database.DBUser == "" {
+		snapshotBtn.Hide()
+	}
+
+	importBtn := widget.NewButtonWithIcon("Import SQL", theme.UploadIcon(), func() {
+		a.importDatabase(p)
+	})
+	if p.Database.DBName == "" || p.Database.DBUser == "" {
+		importBtn.Hide()
+	}
+
+	taskLogBtn := widget.NewButtonWithIcon("Watch Task Log", theme.DocumentIcon(), func() {
+		a.openLog(livereload.TaskLogPath(p.ID))
+	})
+	if len(p.Watch.Tasks) == 0 {
+		taskLogBtn.Hide()
+	}
+
 	actionsBtn := widget.NewButtonWithIcon("Actions", theme.MenuIcon(), func() {
 		content := container.NewGridWithColumns(2,
 			phpmyadminBtn,
@@ -1194,10 +1822,14 @@ func (a *App) createProjectCard(p *projects.Project) fyne.CanvasObject {
 			copyURLBtn,
 			copyDBBtn,
 			fixDBBtn,
+			snapshotBtn,
+			importBtn,
+			stripBtn,
+			taskLogBtn,
 			deleteBtn,
 		)
 		d := dialog.NewCustom("Project Actions", "Close", container.NewPadded(content), a.mainWindow)
-		d.Resize(fyne.NewSize(520, 220))
+		d.Resize(fyne.NewSize(520, 380))
 		d.Show()
 	})
 
@@ -1207,54 +1839,102 @@ func (a *App) createProjectCard(p *projects.Project) fyne.CanvasObject {
 			container.NewVBox(title, urlText, phpText, dbText),
 			nil,
 			nil,
-			container.NewHBox(openBtn, openFolderBtn, editBtn, actionsBtn),
+			container.NewHBox(openBtn, openFolderBtn, editBtn, actionsBtn, liveReloadCheck),
 		)),
 	)
 }
 
-func (a *App) fixProjectDatabase(p *projects.Project) {
-	if p == nil {
+// setLiveReload saves the project's live reload switch and starts or stops
+// watching it.
+func (a *App) setLiveReload(p *projects.Project, on bool) {
+	res, err := a.core.UpdateProject(p.ID, app.ProjectUpdate{LiveReload: &on})
+	if err != nil {
+		a.showError("Live Reload", err)
 		return
 	}
-	if p.Database.DBName == "" || p.Database.DBUser == "" {
-		a.showError("Fix DB", fmt.Errorf("this project has no database configuration"))
+	*p = *res.Project
+	a.syncLiveReload()
+
+	if on {
+		a.updateStatus(fmt.Sprintf("Live reload on for '%s'", p.Name))
+	} else {
+		a.updateStatus(fmt.Sprintf("Live reload off for '%s'", p.Name))
+	}
+}
+
+// stripLiveReloadScripts removes scripts that older releases wrote into the
+// project's files.
+func (a *App) stripLiveReloadScripts(p *projects.Project) {
+	changed, err := livereload.StripInjected(p.Path, false)
+	if err != nil {
+		a.showError("Live Reload", err)
 		return
 	}
-	if a.serviceManager.GetServices()["mysql"].Status != services.StatusRunning {
-		a.showError("Fix DB", fmt.Errorf("MySQL is not running"))
+	if len(changed) == 0 {
+		a.updateStatus(fmt.Sprintf("No injected live reload scripts in '%s'", p.Name))
 		return
 	}
+	dialog.ShowInformation("Live Reload", fmt.Sprintf("Removed the injected script from:\n%s", strings.Join(changed, "\n")), a.mainWindow)
+}
 
-	a.withLoading("Fixing database", func() error {
-		// Ensure password exists
-		if strings.TrimSpace(p.Database.DBPassword) == "" {
-			p.Database.DBPassword = generateRandomHex(8)
-		}
-		// Ensure docker host defaults
-		p.Database.DBHost = "mysql"
-		p.Database.DBPort = 3306
+// syncLiveReload watches exactly the projects that have live reload on.
+func (a *App) syncLiveReload() {
+	list, err := a.projectManager.List()
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Live reload: %v\n", err)
+		return
+	}
+	if err := a.liveReload.Sync(list); err != nil {
+		fmt.Printf("Live reload: %v\n", err)
+	}
+}
 
-		if err := a.projectManager.Update(p); err != nil {
-			return err
-		}
+// restartLiveReload rebinds the live reload servers after the extension port
+// setting changed.
+func (a *App) restartLiveReload() {
+	port := 0
+	if a.config.LiveReloadProtocol {
+		port = livereload.ProtocolPort
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = a.liveReload.Stop(ctx)
+	a.liveReload.SetProtocolPort(port)
+	a.syncLiveReload()
+}
 
-		if err := a.serviceManager.CreateDatabase(p.Database.DBName, p.Database.DBUser, p.Database.DBPassword); err != nil {
+func (a *App) fixProjectDatabase(p *projects.Project) {
+	if p == nil {
+		return
+	}
+	if p.Database.DBName == "" || p.Database.DBUser == "" {
+		a.showError("Fix DB", fmt.Errorf("this project has no database configuration"))
+		return
+	}
+	a.withLoading("Fixing database", func() error {
+		res, err := a.core.RepairDatabase(p.ID)
+		if err != nil {
 			return err
 		}
+		*p = *res.Project
 
-		creds := fmt.Sprintf("DB Name: %s\nUser: %s\nPassword: %s\nHost: %s\nPort: %d",
-			p.Database.DBName,
-			p.Database.DBUser,
-			p.Database.DBPassword,
-			p.Database.DBHost,
-			p.Database.DBPort,
-		)
-		dialog.ShowInformation("Database Credentials", creds, a.mainWindow)
+		a.showDatabaseCredentials(p.Database)
 		a.updateStatus(fmt.Sprintf("DB fixed for '%s'", p.Name))
 		return nil
 	})
 }
 
+func (a *App) showDatabaseCredentials(db projects.DatabaseConfig) {
+	creds := fmt.Sprintf("DB Name: %s\nUser: %s\nPassword: %s\nHost: %s\nPort: %d",
+		db.DBName,
+		db.DBUser,
+		db.DBPassword,
+		db.DBHost,
+		db.DBPort,
+	)
+	dialog.ShowInformation("Database Credentials", creds, a.mainWindow)
+}
+
 func (a *App) refreshProjectCards() {
 	a.projectsContainer.Objects = nil
 
@@ -1285,18 +1965,10 @@ func (a *App) refreshProjectList() {
 	}
 }
 
-func (a *App) startService(name string) {
+func (a *App) startService(id services.ServiceID) {
+	name := a.serviceName(id)
 	a.withLoading(fmt.Sprintf("Starting %s", name), func() error {
-		var err error
-		switch name {
-		case "Nginx", "Apache":
-			err = a.serviceManager.StartNginx()
-		case "PHP-FPM":
-			err = a.serviceManager.StartPHP()
-		case "MySQL":
-			err = a.serviceManager.StartMySQL()
-		}
-		if err != nil {
+		if err := a.core.Services().Start(context.Background(), id); err != nil {
 			a.updateStatus(fmt.Sprintf("%s failed: %v", name, err))
 			return err
 		}
@@ -1305,18 +1977,10 @@ func (a *App) startService(name string) {
 	})
 }
 
-func (a *App) stopService(name string) {
+func (a *App) stopService(id services.ServiceID) {
+	name := a.serviceName(id)
 	a.withLoading(fmt.Sprintf("Stopping %s", name), func() error {
-		var err error
-		switch name {
-		case "Nginx", "Apache":
-			err = a.serviceManager.StopNginx()
-		case "PHP-FPM":
-			err = a.serviceManager.StopPHP()
-		case "MySQL":
-			err = a.serviceManager.StopMySQL()
-		}
-		if err != nil {
+		if err := a.core.Services().Stop(context.Background(), id); err != nil {
 			a.updateStatus(fmt.Sprintf("%s stop failed: %v", name, err))
 			return err
 		}
@@ -1326,40 +1990,111 @@ func (a *App) stopService(name string) {
 }
 
 func (a *App) refreshLoop() {
-	// Use a slower ticker (3 seconds) to reduce CPU usage and make UI feel smoother
-	ticker := time.NewTicker(3 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Status changes are pushed from the Docker event stream; the ticker is a
+	// slow safety net, and drops to 3 seconds if the stream is unavailable.
+	// Either way the refresh only records statuses: the changes reach the
+	// UI through eventLoop.
+	const pollInterval, safetyInterval = 3 * time.Second, 30 * time.Second
+
+	// Each watch has its own context, so a new service manager's watch can
+	// replace the old one's; an error from a replaced watch is dropped.
+	watchDone := make(chan error, 1)
+	cancelWatch := func() {}
+	watch := func() {
+		cancelWatch()
+		watchCtx, cancel := context.WithCancel(ctx)
+		cancelWatch = cancel
+		sm := a.core.Services()
+		go func() {
+			err := sm.WatchStatus(watchCtx, a.refreshServiceStatus)
+			if watchCtx.Err() == nil {
+				watchDone <- err
+			}
+		}()
+	}
+	watching, lastWatch := true, time.Now()
+	watch()
+	go a.refreshServiceStatus()
+
+	ticker := time.NewTicker(safetyInterval)
 	defer ticker.Stop()
 	
 	for {
 		select {
+		case err := <-watchDone:
+			fmt.Printf("docker events unavailable, polling instead: %v\n", err)
+			watching = false
+			ticker.Reset(pollInterval)
 		case <-ticker.C:
 			// Use goroutine to prevent blocking UI thread
-			go func() {
-				a.serviceManager.RefreshStatuses()
-				a.updateServiceCards()
-				a.updateQuickActionButtons()
-			}()
+			go a.refreshServiceStatus()
+			if !watching && time.Since(lastWatch) > safetyInterval {
+				watching, lastWatch = true, time.Now()
+				ticker.Reset(safetyInterval)
+				watch()
+			}
+		case <-a.backendCh:
+			watching, lastWatch = true, time.Now()
+			ticker.Reset(safetyInterval)
+			watch()
+			go a.refreshServiceStatus()
 		case <-a.stopRefreshCh:
 			return
 		}
 	}
 }
 
-func (a *App) updateQuickActionButtons() {
-	svcs := a.serviceManager.GetServices()
-	ng := svcs["nginx"]
-	php := svcs["php-fpm"]
-	my := svcs["mysql"]
+func (a *App) refreshServiceStatus() {
+	a.core.Services().RefreshStatuses(context.Background())
+}
 
-	allRunning := ng != nil && php != nil && my != nil &&
-		ng.Status == services.StatusRunning &&
-		php.Status == services.StatusRunning &&
-		my.Status == services.StatusRunning
+// eventLoop applies the events of the service manager, the project
+// workflows (including those run through the API) and live reload to the
+// UI, one at a time, until the bus is closed on shutdown.
+func (a *App) eventLoop() {
+	sub := a.events.Subscribe()
+	defer sub.Close()
+
+	for e := range sub.C {
+		switch e := e.(type) {
+		case events.ServiceStarted, events.ServiceStopped:
+			a.updateServiceCards()
+			a.updateQuickActionButtons()
+		case events.ServiceFailed:
+			a.updateServiceCards()
+			a.updateQuickActionButtons()
+			a.notify(e.Name+" failed", e.Error)
+		case events.ProjectCreated, events.ProjectUpdated, events.ProjectDeleted:
+			a.syncLiveReload()
+			a.refreshProjectCards()
+		case events.FileChanged:
+			if e.Failed {
+				a.notify("Watch task failed", fmt.Sprintf("A watch task failed in %s; see the browser for details", e.Project))
+			}
+		}
+	}
+}
+
+// notify shows a desktop notification.
+func (a *App) notify(title, content string) {
+	a.fyneApp.SendNotification(fyne.NewNotification(title, content))
+}
+
+func (a *App) updateQuickActionButtons() {
+	statuses := map[services.ServiceID]services.ServiceStatus{}
+	for _, svc := range a.core.Services().Services() {
+		statuses[svc.ID] = svc.Status
+	}
 
-	allStopped := ng != nil && php != nil && my != nil &&
-		ng.Status == services.StatusStopped &&
-		php.Status == services.StatusStopped &&
-		my.Status == services.StatusStopped
+	allRunning, allStopped := true, true
+	for _, id := range services.CoreServices {
+		status, ok := statuses[id]
+		allRunning = allRunning && ok && status == services.StatusRunning
+		allStopped = allStopped && ok && status == services.StatusStopped
+	}
 
 	if a.quickStartBtn != nil {
 		if allRunning {
@@ -1378,62 +2113,31 @@ func (a *App) updateQuickActionButtons() {
 }
 
 func (a *App) updateServiceCards() {
-	nginxSvc := a.serviceManager.GetServices()["nginx"]
-	phpSvc := a.serviceManager.GetServices()["php-fpm"]
-	mysqlSvc := a.serviceManager.GetServices()["mysql"]
-
-	if a.nginxCard != nil {
-		if nginxSvc.Status == services.StatusRunning {
-			a.nginxCard.statusText.Text = fmt.Sprintf("Running (PID:%d)", nginxSvc.PID)
-			a.nginxCard.statusText.Color = color.NRGBA{100, 200, 100, 255}
-			a.nginxCard.indicator.FillColor = color.NRGBA{100, 200, 100, 255}
-			a.nginxCard.startBtn.Hide()
-			a.nginxCard.stopBtn.Show()
-		} else {
-			a.nginxCard.statusText.Text = "Stopped"
-			a.nginxCard.statusText.Color = color.NRGBA{150, 150, 150, 255}
-			a.nginxCard.indicator.FillColor = color.NRGBA{100, 100, 100, 255}
-			a.nginxCard.startBtn.Show()
-			a.nginxCard.stopBtn.Hide()
-		}
-		a.nginxCard.indicator.Refresh()
-		a.nginxCard.statusText.Refresh()
-	}
-
-	if a.phpCard != nil {
-		if phpSvc.Status == services.StatusRunning {
-			a.phpCard.statusText.Text = fmt.Sprintf("Running (PID:%d)", phpSvc.PID)
-			a.phpCard.statusText.Color = color.NRGBA{100, 200, 100, 255}
-			a.phpCard.indicator.FillColor = color.NRGBA{100, 200, 100, 255}
-			a.phpCard.startBtn.Hide()
-			a.phpCard.stopBtn.Show()
-		} else {
-			a.phpCard.statusText.Text = "Stopped"
-			a.phpCard.statusText.Color = color.NRGBA{150, 150, 150, 255}
-			a.phpCard.indicator.FillColor = color.NRGBA{100, 100, 100, 255}
-			a.phpCard.startBtn.Show()
-			a.phpCard.stopBtn.Hide()
-		}
-		a.phpCard.indicator.Refresh()
-		a.phpCard.statusText.Refresh()
-	}
-
-	if a.mysqlCard != nil {
-		if mysqlSvc.Status == services.StatusRunning {
-			a.mysqlCard.statusText.Text = fmt.Sprintf("Running (PID:%d)", mysqlSvc.PID)
-			a.mysqlCard.statusText.Color = color.NRGBA{100, 200, 100, 255}
-			a.mysqlCard.indicator.FillColor = color.NRGBA{100, 200, 100, 255}
-			a.mysqlCard.startBtn.Hide()
-			a.mysqlCard.stopBtn.Show()
+	a.cardsMu.Lock()
+	defer a.cardsMu.Unlock()
+	for _, svc := range a.core.Services().Services() {
+		card := a.serviceCards[svc.ID]
+		if card == nil {
+			continue
+		}
+		if svc.Status == services.StatusRunning {
+			card.statusText.Text = "Running"
+			if svc.PID != 0 {
+				card.statusText.Text = fmt.Sprintf("Running (PID:%d)", svc.PID)
+			}
+			card.statusText.Color = color.NRGBA{100, 200, 100, 255}
+			card.indicator.FillColor = color.NRGBA{100, 200, 100, 255}
+			card.startBtn.Hide()
+			card.stopBtn.Show()
 		} else {
-			a.mysqlCard.statusText.Text = "Stopped"
-			a.mysqlCard.statusText.Color = color.NRGBA{150, 150, 150, 255}
-			a.mysqlCard.indicator.FillColor = color.NRGBA{100, 100, 100, 255}
-			a.mysqlCard.startBtn.Show()
-			a.mysqlCard.stopBtn.Hide()
+			card.statusText.Text = "Stopped"
+			card.statusText.Color = color.NRGBA{150, 150, 150, 255}
+			card.indicator.FillColor = color.NRGBA{100, 100, 100, 255}
+			card.startBtn.Show()
+			card.stopBtn.Hide()
 		}
-		a.mysqlCard.indicator.Refresh()
-		a.mysqlCard.statusText.Refresh()
+		card.indicator.Refresh()
+		card.statusText.Refresh()
 	}
 }
 
@@ -1509,8 +2213,8 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 
 	pathRow := container.NewBorder(nil, nil, nil, browseBtn, pathEntry)
 
-	phpVersion := widget.NewSelect([]string{"8.3", "8.2", "8.1", "8.0", "7.4"}, nil)
-	phpVersion.SetSelected("8.3")
+	phpVersion := widget.NewSelect(projects.PHPVersions, nil)
+	phpVersion.SetSelected(projects.DefaultPHPVersion)
 
 	// DocumentRoot input for custom subfolder
 	docRootEntry := widget.NewEntry()
@@ -1523,8 +2227,15 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 	templateType := widget.NewSelect([]string{"Simple", "MVC"}, nil)
 	templateType.SetSelected("Simple")
 
-	useMySQL := widget.NewCheck("Use MySQL", nil)
-	useMySQL.SetChecked(true)
+	useDatabase := widget.NewCheck("Use a database", nil)
+	useDatabase.SetChecked(true)
+
+	engineTitles := make([]string, len(projects.Engines))
+	for i, e := range projects.Engines {
+		engineTitles[i] = projects.EngineTitle(e)
+	}
+	dbEngine := widget.NewSelect(engineTitles, nil)
+	dbEngine.SetSelected(projects.EngineTitle(projects.EngineMySQL))
 
 	dbName := widget.NewEntry()
 	dbName.SetPlaceHolder("database_name")
@@ -1535,17 +2246,19 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 
 	setDBFieldsEnabled := func(enabled bool) {
 		if enabled {
+			dbEngine.Enable()
 			dbName.Enable()
 			dbUser.Enable()
 			dbPass.Enable()
 		} else {
+			dbEngine.Disable()
 			dbName.Disable()
 			dbUser.Disable()
 			dbPass.Disable()
 		}
 	}
 	setDBFieldsEnabled(true)
-	useMySQL.OnChanged = func(b bool) {
+	useDatabase.OnChanged = func(b bool) {
 		setDBFieldsEnabled(b)
 	}
 
@@ -1556,13 +2269,14 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 	if isEdit {
 		phpVersion.SetSelected(existing.PHPVersion)
 		if existing.Database.DBName != "" || existing.Database.DBUser != "" {
-			useMySQL.SetChecked(true)
+			useDatabase.SetChecked(true)
 			setDBFieldsEnabled(true)
+			dbEngine.SetSelected(projects.EngineTitle(existing.Database.EngineName()))
 			dbName.SetText(existing.Database.DBName)
 			dbUser.SetText(existing.Database.DBUser)
 			dbPass.SetText(existing.Database.DBPassword)
 		} else {
-			useMySQL.SetChecked(false)
+			useDatabase.SetChecked(false)
 			setDBFieldsEnabled(false)
 		}
 	} else if isImport {
@@ -1608,8 +2322,9 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 	// Section 3: Database Card
 	dbSection := container.NewVBox(
 		canvas.NewText("3. Database (Optional)", color.NRGBA{255, 255, 255, 255}),
-		useMySQL,
+		useDatabase,
 		widget.NewForm(
+			widget.NewFormItem("Engine", dbEngine),
 			widget.NewFormItem("DB Name", dbName),
 			widget.NewFormItem("DB User", dbUser),
 			widget.NewFormItem("Password", dbPass),
@@ -1649,116 +2364,68 @@ func (a *App) showProjectDialog(existing *projects.Project, prefillName, prefill
 			return
 		}
 
-		// Use custom subdomain or generate from name
-		subdomain := subdomainEntry.Text
-		if subdomain == "" {
-			subdomain = strings.ToLower(strings.ReplaceAll(nameEntry.Text, " ", "-"))
-		}
-
-		// Clean subdomain - remove special characters
-		subdomain = strings.ToLower(subdomain)
-		subdomain = strings.ReplaceAll(subdomain, " ", "-")
-		subdomain = strings.ReplaceAll(subdomain, "_", "-")
-
-		domain := fmt.Sprintf("%s.%s", subdomain, a.config.Domain)
-
-		finalDBName := strings.TrimSpace(dbName.Text)
-		finalDBUser := strings.TrimSpace(dbUser.Text)
-		finalDBPass := dbPass.Text
-
-		// Keep existing values on edit if left blank
-		if isEdit {
-			if finalDBName == "" {
-				finalDBName = existing.Database.DBName
-			}
-			if finalDBUser == "" {
-				finalDBUser = existing.Database.DBUser
-			}
-			if finalDBPass == "" {
-				finalDBPass = existing.Database.DBPassword
-			}
-		}
-
-		var dbConfig projects.DatabaseConfig
-		if useMySQL.Checked {
-			// Auto-generate defaults if still empty
-			if finalDBName == "" {
-				finalDBName = subdomain
+		var db *projects.DatabaseConfig
+		if useDatabase.Checked {
+			// Blank fields keep their current value on edit, otherwise
+			// they are generated from the subdomain.
+			db = &projects.DatabaseConfig{
+				Engine:     projects.Engines[dbEngine.SelectedIndex()],
+				DBName:     strings.TrimSpace(dbName.Text),
+				DBUser:     strings.TrimSpace(dbUser.Text),
+				DBPassword: dbPass.Text,
 			}
-			if finalDBUser == "" {
-				finalDBUser = subdomain + "_user"
-			}
-			if finalDBPass == "" {
-				finalDBPass = generateRandomHex(8)
-			}
-
-			dbConfig = projects.DatabaseConfig{
-				DBName:     finalDBName,
-				DBUser:     finalDBUser,
-				DBPassword: finalDBPass,
-				DBHost:     "mysql",
-				DBPort:     3306,
-			}
-		} else {
-			// No DB for this project
-			dbConfig = projects.DatabaseConfig{DBHost: "", DBPort: 0}
 		}
 
-		var p *projects.Project
+		var res *app.ProjectResult
 		var err error
 
 		if isEdit {
-			existing.Name = nameEntry.Text
-			existing.Domain = domain
-			existing.PHPVersion = phpVersion.Selected
-			existing.Database = dbConfig
-			existing.DocumentRoot = strings.TrimSpace(docRootEntry.Text)
-			err = a.projectManager.Update(existing)
-			p = existing
+			name := nameEntry.Text
+			subdomain := subdomainEntry.Text
+			php := phpVersion.Selected
+			docRoot := docRootEntry.Text
+			res, err = a.core.UpdateProject(existing.ID, app.ProjectUpdate{
+				Name:           &name,
+				Subdomain:      &subdomain,
+				PHPVersion:     &php,
+				DocumentRoot:   &docRoot,
+				Database:       db,
+				RemoveDatabase: db == nil,
+			})
 		} else {
-			p, err = a.projectManager.CreateWithSubdomain(nameEntry.Text, subdomain, selectedPath, phpVersion.Selected, dbConfig)
-			if p != nil {
-				p.DocumentRoot = strings.TrimSpace(docRootEntry.Text)
-				a.projectManager.Update(p)
+			tmpl := app.TemplateNone
+			if genTemplate.Checked && !isImport {
+				tmpl = app.TemplateSimple
+				if templateType.Selected == "MVC" {
+					tmpl = app.TemplateMVC
+				}
 			}
+			res, err = a.core.CreateProject(app.ProjectOptions{
+				Name:         nameEntry.Text,
+				Subdomain:    subdomainEntry.Text,
+				Path:         selectedPath,
+				PHPVersion:   phpVersion.Selected,
+				DocumentRoot: docRootEntry.Text,
+				Template:     tmpl,
+				Database:     db,
+			})
 		}
 
 		if err != nil {
-			a.showError("Error", err)
-			return
-		}
-
-		if genTemplate.Checked && !isImport {
-			if templateType.Selected == "MVC" {
-				if err := a.projectManager.CopyMVCTemplate(p); err != nil {
-					a.showError("MVC template error", err)
-				}
+			if errors.Is(err, app.ErrInvalid) {
+				a.showError("Validation Error", err)
 			} else {
-				a.projectManager.GeneratePHPIndex(p)
-				a.projectManager.GeneratePHPInfo(p)
+				a.showError("Error", err)
 			}
+			return
 		}
-		a.projectManager.GenerateDBConfig(p)
 
-		if useMySQL.Checked && a.serviceManager.GetServices()["mysql"].Status == services.StatusRunning {
-			if err := a.serviceManager.CreateDatabase(dbConfig.DBName, dbConfig.DBUser, dbConfig.DBPassword); err != nil {
-				a.showError("Database setup failed", err)
-			} else {
-				creds := fmt.Sprintf("DB Name: %s\nUser: %s\nPassword: %s\nHost: %s\nPort: %d",
-					dbConfig.DBName,
-					dbConfig.DBUser,
-					dbConfig.DBPassword,
-					dbConfig.DBHost,
-					dbConfig.DBPort,
-				)
-				dialog.ShowInformation("Database Credentials", creds, a.mainWindow)
-			}
+		p := res.Project
+		if res.DatabaseProvisioned {
+			a.showDatabaseCredentials(p.Database)
 		}
 
-		gen := apache.NewGenerator(a.config)
-		gen.GenerateVhost(p)
-		a.serviceManager.ReloadNginx()
-
+		a.syncLiveReload()
 		a.refreshProjectCards()
 		a.updateStatus(fmt.Sprintf("Saved '%s' at %s", p.Name, p.Domain))
 		dlg.Hide()
@@ -1778,25 +2445,256 @@ func (a *App) deleteProject(p *projects.Project) {
 		if !ok {
 			return
 		}
-		a.projectManager.Delete(p.ID)
-		apache.NewGenerator(a.config).RemoveVhost(p.ID)
-		a.serviceManager.ReloadNginx()
-		a.refreshProjectCards()
-		a.updateStatus(fmt.Sprintf("Deleted '%s'", p.Name))
+		// The project's database is snapshotted first, which can take a while.
+		a.withLoading("Delete Project", func() error {
+			if err := a.core.DeleteProject(p.ID); err != nil {
+				return err
+			}
+			a.syncLiveReload()
+			a.refreshProjectCards()
+			a.updateStatus(fmt.Sprintf("Deleted '%s'", p.Name))
+			return nil
+		})
 	}, a.mainWindow)
 }
 
+// importDatabase asks for a dump file and loads it into the project's
+// database.
+func (a *App) importDatabase(p *projects.Project) {
+	open := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			a.showError("Import SQL", err)
+			return
+		}
+		if r == nil {
+			return
+		}
+		file := r.URI().Path()
+		r.Close()
+		a.runDatabaseImport(p, file)
+	}, a.mainWindow)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".sql", ".gz", ".zip"}))
+	open.Show()
+}
+
+// runDatabaseImport shows the import's progress with a Cancel button.
+func (a *App) runDatabaseImport(p *projects.Project, file string) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bar := widget.NewProgressBar()
+	status := widget.NewLabel("Taking a snapshot of the current database...")
+	cancelBtn := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		status.SetText("Cancelling...")
+		cancel()
+	})
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("%s → %s", filepath.Base(file), p.Database.DBName)),
+		bar,
+		status,
+		container.NewCenter(cancelBtn),
+	)
+	d := dialog.NewCustomWithoutButtons("Import SQL", content, a.mainWindow)
+	d.Resize(fyne.NewSize(460, 200))
+	d.Show()
+	a.setBusy(true)
+
+	go func() {
+		start := time.Now()
+		res, err := a.core.ImportDatabase(ctx, p.ID, file, func(done, total int64) {
+			if total > 0 {
+				bar.SetValue(float64(done) / float64(total))
+			}
+			rate := int64(float64(done) / time.Since(start).Seconds())
+			status.SetText(fmt.Sprintf("%s of %s  (%s/s)", formatBytes(done), formatBytes(total), formatBytes(rate)))
+		})
+		cancelled := ctx.Err() != nil
+		cancel()
+		a.setBusy(false)
+		d.Hide()
+
+		if cancelled {
+			a.updateStatus("Import cancelled")
+			dialog.ShowInformation("Import SQL", "The import was cancelled. Tables loaded so far were kept; the previous contents are in DB Snapshots.", a.mainWindow)
+			return
+		}
+		if err != nil {
+			a.showError("Import SQL", err)
+			return
+		}
+
+		lines := []string{fmt.Sprintf("Imported %s into %s.", formatBytes(res.Bytes), res.Database)}
+		if res.Entry != "" {
+			lines = append(lines, "Used "+res.Entry+" from the archive.")
+		}
+		if res.Source != "" && res.Source != res.Database {
+			lines = append(lines, fmt.Sprintf("Statements for database %s were pointed at %s.", res.Source, res.Database))
+		}
+		if res.Definers > 0 {
+			lines = append(lines, fmt.Sprintf("%d DEFINER clause(s) now use %s.", res.Definers, p.Database.DBUser))
+		}
+		if res.Snapshot != nil {
+			lines = append(lines, "The previous contents are in snapshot "+res.Snapshot.ID+".")
+		}
+		a.updateStatus(fmt.Sprintf("Imported %s into '%s'", filepath.Base(file), p.Name))
+		dialog.ShowInformation("Import SQL", strings.Join(lines, "\n"), a.mainWindow)
+	}()
+}
+
+// resetStack snapshots the databases and then runs docker compose down -v.
+// When no snapshot can be taken it asks before deleting the data anyway.
+func (a *App) resetStack() {
+	a.withLoading("Snapshotting databases", func() error {
+		snaps, err := a.core.SnapshotBeforeReset(context.Background())
+		if err != nil {
+			msg := fmt.Sprintf("The databases could not all be snapshotted: %v\n\nDelete the database data anyway?", err)
+			dialog.ShowConfirm("Reset Stack", msg, func(ok bool) {
+				if ok {
+					a.runDockerComposeCommandWithLogs("Docker Compose Down (volumes)", "down", "-v")
+				}
+			}, a.mainWindow)
+			return nil
+		}
+		if len(snaps) > 0 {
+			ids := make([]string, len(snaps))
+			for i, snap := range snaps {
+				ids[i] = snap.ID
+			}
+			a.updateStatus("Saved snapshots " + strings.Join(ids, ", "))
+		}
+		a.runDockerComposeCommandWithLogs("Docker Compose Down (volumes)", "down", "-v")
+		return nil
+	})
+}
+
+// showSnapshotsDialog lists the database snapshots with one-click restore.
+func (a *App) showSnapshotsDialog() {
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.Objects = nil
+		snaps, err := a.core.Backups().List()
+		if err != nil {
+			list.Add(widget.NewLabel(err.Error()))
+		} else if len(snaps) == 0 {
+			list.Add(widget.NewLabel("No snapshots yet"))
+		}
+		for _, snap := range snaps {
+			snap := snap
+			label := snap.Name
+			if label == "" {
+				label = snap.Reason
+			}
+			if label == "" {
+				label = snap.ID
+			}
+			info := widget.NewLabel(fmt.Sprintf("%s\n%s  ·  %s: %s  ·  %s", label,
+				snap.Created.Format("2006-01-02 15:04"), projects.EngineTitle(snap.Engine), strings.Join(snap.Databases, ", "), formatBytes(snap.Size)))
+
+			restoreBtn := widget.NewButtonWithIcon("Restore", theme.HistoryIcon(), func() {
+				msg := fmt.Sprintf("Replace %s with the data from %s?", strings.Join(snap.Databases, ", "), snap.Created.Format("2006-01-02 15:04"))
+				dialog.ShowConfirm("Restore Snapshot", msg, func(ok bool) {
+					if !ok {
+						return
+					}
+					a.withLoading("Restoring snapshot", func() error {
+						if _, err := a.core.RestoreSnapshot(context.Background(), snap.ID); err != nil {
+							return err
+						}
+						a.updateStatus("Restored snapshot " + snap.ID)
+						return nil
+					})
+				}, a.mainWindow)
+			})
+			deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				if err := a.core.Backups().Delete(snap.ID); err != nil {
+					a.showError("Delete Snapshot", err)
+				}
+				refresh()
+			})
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(restoreBtn, deleteBtn), info))
+		}
+		list.Refresh()
+	}
+
+	name := widget.NewEntry()
+	name.SetPlaceHolder("Name (optional; named snapshots are never pruned)")
+	newBtn := widget.NewButtonWithIcon("Snapshot All", theme.ContentAddIcon(), func() {
+		a.withLoading("Taking snapshot", func() error {
+			snap, err := a.core.Snapshot(context.Background(), name.Text, "")
+			if err != nil {
+				return err
+			}
+			name.SetText("")
+			refresh()
+			a.updateStatus("Saved snapshot " + snap.ID)
+			return nil
+		})
+	})
+
+	info := canvas.NewText(fmt.Sprintf("Kept in %s", backup.Dir()), color.NRGBA{120, 120, 120, 255})
+	info.TextSize = 11
+	content := container.NewBorder(
+		container.NewVBox(container.NewBorder(nil, nil, nil, newBtn, name), info, widget.NewSeparator()),
+		nil, nil, nil,
+		container.NewScroll(list),
+	)
+	refresh()
+	d := dialog.NewCustom("Database Snapshots", "Close", content, a.mainWindow)
+	d.Resize(fyne.NewSize(720, 480))
+	d.Show()
+}
+
+// formatBytes renders a size such as "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func (a *App) generateConfigs() {
 	gen := apache.NewGenerator(a.config)
 	_ = gen.GenerateAllVhosts()
 }
 
 func (a *App) startDNSServer() {
-	go func() {
-		if err := a.dnsServer.Start(); err != nil {
-			fmt.Printf("DNS error: %v\n", err)
+	if err := a.dnsServer.Start(); err != nil {
+		fmt.Printf("DNS error: %v\n", err)
+		a.updateStatus(fmt.Sprintf("DNS server not started: %v", err))
+		return
+	}
+	fmt.Printf("DNS server listening on %s (udp+tcp)\n", a.dnsServer.Addr())
+}
+
+// startMailServer starts the SMTP mail catcher unless it is switched off.
+func (a *App) startMailServer() {
+	if a.config.MailPort <= 0 {
+		return
+	}
+	a.mailServer.OnMessage = func(m *mail.Message) {
+		a.updateStatus(fmt.Sprintf("Mail caught: %s", m.Subject))
+		if a.currentView == "mail" {
+			a.showMail()
 		}
-	}()
+	}
+	if err := a.mailServer.Start(); err != nil {
+		fmt.Printf("Mail error: %v\n", err)
+		a.updateStatus(fmt.Sprintf("Mail catcher not started: %v", err))
+		return
+	}
+	fmt.Printf("Mail catcher listening on %s\n", a.mailServer.Addr())
+}
+
+func (a *App) startAPIServer() {
+	if err := a.apiServer.Start(); err != nil {
+		fmt.Printf("API error: %v\n", err)
+	}
 }
 
 func (a *App) openLog(path string) {
@@ -1857,19 +2755,31 @@ func (a *App) setupTray() {
 		systray.AddSeparator()
 		mQuit := systray.AddMenuItem("Quit", "Exit application")
 
+		// Keep the tooltip in step with the services.
+		go func() {
+			sub := a.events.Subscribe()
+			defer sub.Close()
+			for e := range sub.C {
+				switch e.(type) {
+				case events.ServiceStarted, events.ServiceStopped, events.ServiceFailed:
+					systray.SetTooltip(a.trayTooltip())
+				}
+			}
+		}()
+
 		go func() {
 			for {
 				select {
 				case <-mShow.ClickedCh:
 					a.showMainWindow()
 				case <-mStartAll.ClickedCh:
-					a.serviceManager.StartAll()
+					a.core.Services().StartAll(context.Background())
 					a.updateStatus("All services started")
 				case <-mStopAll.ClickedCh:
-					a.serviceManager.StopAll()
+					a.core.Services().StopAll(context.Background())
 					a.updateStatus("All services stopped")
 				case <-mQuit.ClickedCh:
-					a.serviceManager.StopAll()
+					a.core.Services().StopAll(context.Background())
 					a.dnsServer.Stop()
 					systray.Quit()
 					a.fyneApp.Quit()
@@ -1880,6 +2790,18 @@ func (a *App) setupTray() {
 	}, nil)
 }
 
+// trayTooltip summarises how many services are running.
+func (a *App) trayTooltip() string {
+	svcs := a.core.Services().Services()
+	running := 0
+	for _, svc := range svcs {
+		if svc.Status == services.StatusRunning {
+			running++
+		}
+	}
+	return fmt.Sprintf("Go Local Server - %d of %d services running", running, len(svcs))
+}
+
 func (a *App) showMainWindow() {
 	a.mainWindow.Show()
 	a.mainWindow.RequestFocus()
@@ -1909,7 +2831,7 @@ func (a *App) showHealthCheckDashboard() {
 	title.TextStyle = fyne.TextStyle{Bold: true}
 
 	// Get detailed health status
-	healthStatus := a.serviceManager.GetAllHealthStatus()
+	healthStatus := a.core.Services().Health(context.Background())
 
 	var rows []fyne.CanvasObject
 
@@ -1982,7 +2904,7 @@ func (a *App) showHealthCheckDashboard() {
 	content.Add(refreshBtn)
 
 	// Auto-start Docker button if Docker is not running
-	if !services.CheckDockerAvailable() {
+	if !services.CheckDockerAvailable() && !platform.NativeDockerd() {
 		dockerBtn := widget.NewButton("Start Docker Desktop", func() {
 			if err := services.EnsureDockerRunning(); err != nil {
 				a.showError("Docker Start", err)
@@ -2033,7 +2955,7 @@ func (a *App) showContainerLogsDialog() {
 
 		// Start streaming logs
 		go func() {
-			logChan, err := a.serviceManager.StreamContainerLogs(selectedContainer, false)
+			logChan, err := a.core.Services().Logs(context.Background(), services.ServiceID(selectedContainer), false)
 			if err != nil {
 				logEntry.SetText(fmt.Sprintf("Error: %v", err))
 				return
@@ -2061,7 +2983,7 @@ func (a *App) showContainerLogsDialog() {
 			if followCheck.Checked && stopCh != nil {
 				// Refresh logs in follow mode
 				selectedContainer := containerSelect.Selected
-				logChan, err := a.serviceManager.StreamContainerLogs(selectedContainer, true)
+				logChan, err := a.core.Services().Logs(context.Background(), services.ServiceID(selectedContainer), true)
 				if err == nil {
 					var logs []string
 					for line := range logChan {
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-local-server/internal/backup"
+)
+
+func (c *ctl) backupCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("backup requires list, create, restore, rm or prune")
+	}
+	switch args[0] {
+	case "list", "ls":
+		return c.backupList(args[1:])
+	case "create":
+		return c.backupCreate(args[1:])
+	case "restore":
+		return c.backupRestore(args[1:])
+	case "rm":
+		return c.backupRemove(args[1:])
+	case "prune":
+		return c.backupPrune(args[1:])
+	}
+	return usagef("unknown backup command %q", args[0])
+}
+
+func (c *ctl) backupList(args []string) error {
+	if _, err := parse(c.flagSet("backup list"), args); err != nil {
+		return err
+	}
+	list, err := c.app.Backups().List()
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []*backup.Snapshot{}
+	}
+	c.out.result(list, func(w io.Writer) {
+		if len(list) == 0 {
+			fmt.Fprintln(w, "No snapshots")
+			return
+		}
+		rows := make([][]string, 0, len(list))
+		for _, s := range list {
+			label := s.Name
+			if label == "" {
+				label = s.Reason
+			}
+			rows = append(rows, []string{s.ID, s.Created.Format("2006-01-02 15:04:05"), strings.Join(s.Databases, ", "), formatBytes(s.Size), label})
+		}
+		table(w, []string{"id", "created", "databases", "size", "name"}, rows)
+	})
+	return nil
+}
+
+func (c *ctl) backupCreate(args []string) error {
+	fs := c.flagSet("backup create")
+	name := fs.String("name", "", "name the snapshot; named snapshots are never pruned")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 1 {
+		return usagef("backup create takes at most one project id")
+	}
+	var id string
+	if len(pos) == 1 {
+		if id, err = c.resolveProject(pos[0]); err != nil {
+			return err
+		}
+	}
+	snap, err := c.app.Snapshot(context.Background(), *name, id)
+	if err != nil {
+		return appErr(err)
+	}
+	c.out.result(snap, func(w io.Writer) {
+		fmt.Fprintf(w, "Saved snapshot %s (%s, %s)\n", snap.ID, strings.Join(snap.Databases, ", "), formatBytes(snap.Size))
+	})
+	return nil
+}
+
+func (c *ctl) backupRestore(args []string) error {
+	pos, err := parse(c.flagSet("backup restore"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("backup restore takes exactly one snapshot id or name")
+	}
+	snap, err := c.app.RestoreSnapshot(context.Background(), pos[0])
+	if err != nil {
+		return appErr(err)
+	}
+	c.out.message("Restored %s from snapshot %s", strings.Join(snap.Databases, ", "), snap.ID)
+	return nil
+}
+
+func (c *ctl) backupRemove(args []string) error {
+	pos, err := parse(c.flagSet("backup rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return usagef("backup rm takes one or more snapshot ids or names")
+	}
+	for _, id := range pos {
+		if err := c.app.Backups().Delete(id); err != nil {
+			return err
+		}
+	}
+	c.out.message("Deleted %d snapshot(s)", len(pos))
+	return nil
+}
+
+func (c *ctl) backupPrune(args []string) error {
+	if _, err := parse(c.flagSet("backup prune"), args); err != nil {
+		return err
+	}
+	removed, err := c.app.Backups().Prune()
+	if err != nil {
+		return err
+	}
+	if removed == nil {
+		removed = []string{}
+	}
+	c.out.result(map[string]interface{}{"removed": removed}, func(w io.Writer) {
+		fmt.Fprintf(w, "Pruned %d snapshot(s)\n", len(removed))
+	})
+	return nil
+}
+
+// formatBytes renders a size for tables, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go-local-server/internal/certs"
+)
+
+func (c *ctl) certsCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("certs requires path or export")
+	}
+	switch args[0] {
+	case "path":
+		return c.certsPath(args[1:])
+	case "export":
+		return c.certsExport(args[1:])
+	}
+	return usagef("unknown certs command %q", args[0])
+}
+
+func (c *ctl) certsPath(args []string) error {
+	if _, err := parse(c.flagSet("certs path"), args); err != nil {
+		return err
+	}
+	ca, err := certs.Default()
+	if err != nil {
+		return err
+	}
+	c.out.result(map[string]string{"ca_cert": ca.CertPath()}, func(w io.Writer) {
+		fmt.Fprintln(w, ca.CertPath())
+	})
+	return nil
+}
+
+func (c *ctl) certsExport(args []string) error {
+	pos, err := parse(c.flagSet("certs export"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("certs export takes exactly one destination file")
+	}
+	ca, err := certs.Default()
+	if err != nil {
+		return err
+	}
+	if err := ca.Export(pos[0]); err != nil {
+		return err
+	}
+	c.out.message("Exported the CA certificate to %s; import it into your system or browser trust store", pos[0])
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"go-local-server/internal/app"
+)
+
+type dbCredentials struct {
+	Project  string `json:"project"`
+	Name     string `json:"db_name"`
+	User     string `json:"db_user"`
+	Password string `json:"db_password"`
+	Host     string `json:"db_host"`
+	Port     int    `json:"db_port"`
+}
+
+func (c *ctl) dbCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("db requires create, fix or import")
+	}
+	switch args[0] {
+	case "create":
+		return c.dbAction("db create", args[1:], c.app.ProvisionDatabase)
+	case "fix":
+		// Equivalent of the "Fix DB" project action.
+		return c.dbAction("db fix", args[1:], c.app.RepairDatabase)
+	case "import":
+		return c.dbImport(args[1:])
+	}
+	return usagef("unknown db command %q", args[0])
+}
+
+func (c *ctl) dbAction(name string, args []string, action func(id string) (*app.ProjectResult, error)) error {
+	pos, err := parse(c.flagSet(name), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("%s takes exactly one project id", name)
+	}
+	id, err := c.resolveProject(pos[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := action(id)
+	if err != nil {
+		return appErr(err)
+	}
+
+	db := res.Project.Database
+	creds := dbCredentials{Project: id, Name: db.DBName, User: db.DBUser, Password: db.DBPassword, Host: db.DBHost, Port: db.DBPort}
+	c.out.result(creds, func(w io.Writer) {
+		fmt.Fprintf(w, "DB Name: %s\nUser: %s\nPassword: %s\nHost: %s\nPort: %d\n",
+			creds.Name, creds.User, creds.Password, creds.Host, creds.Port)
+	})
+	return nil
+}
+
+func (c *ctl) dbImport(args []string) error {
+	fs := c.flagSet("db import")
+	quiet := fs.Bool("q", false, "do not print progress")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return usagef("db import takes a project id and a dump file")
+	}
+	id, err := c.resolveProject(pos[0])
+	if err != nil {
+		return err
+	}
+	file, err := filepath.Abs(pos[1])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var progress func(done, total int64)
+	if !*quiet && !c.out.json() {
+		progress = func(done, total int64) {
+			pct := 0.0
+			if total > 0 {
+				pct = float64(done) * 100 / float64(total)
+			}
+			fmt.Fprintf(c.out.errW, "\rImporting %s: %s / %s (%.0f%%)  ", filepath.Base(file), formatBytes(done), formatBytes(total), pct)
+		}
+	}
+	res, err := c.app.ImportDatabase(ctx, id, file, progress)
+	if progress != nil {
+		fmt.Fprintln(c.out.errW)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("import cancelled; tables already loaded were kept")
+		}
+		return appErr(err)
+	}
+
+	c.out.result(res, func(w io.Writer) {
+		fmt.Fprintf(w, "Imported %s into %s\n", formatBytes(res.Bytes), res.Database)
+		if res.Entry != "" {
+			fmt.Fprintf(w, "Archive entry: %s\n", res.Entry)
+		}
+		if res.Source != "" && res.Source != res.Database {
+			fmt.Fprintf(w, "Rewrote database %s to %s\n", res.Source, res.Database)
+		}
+		if res.Definers > 0 {
+			fmt.Fprintf(w, "Rewrote %d DEFINER clause(s)\n", res.Definers)
+		}
+		if res.Snapshot != nil {
+			fmt.Fprintf(w, "Previous contents: snapshot %s\n", res.Snapshot.ID)
+		}
+	})
+	return nil
+}
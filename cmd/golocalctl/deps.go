@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"go-local-server/internal/packages"
+)
+
+func (c *ctl) depsCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("deps requires status or install")
+	}
+	switch args[0] {
+	case "status":
+		return c.depsStatus(args[1:])
+	case "install":
+		return c.depsInstall(args[1:])
+	}
+	return usagef("unknown deps command %q", args[0])
+}
+
+// depsList returns the dependencies named in ids, or the native stack for
+// the current settings when there are none.
+func (c *ctl) depsList(ids []string) ([]packages.Dependency, error) {
+	if len(ids) == 0 {
+		return packages.Stack(c.config), nil
+	}
+	deps := make([]packages.Dependency, 0, len(ids))
+	for _, id := range ids {
+		dep, err := packages.Lookup(id)
+		if err != nil {
+			return nil, usageError(err.Error())
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+func (c *ctl) depsStatus(args []string) error {
+	ids, err := parse(c.flagSet("deps status"), args)
+	if err != nil {
+		return err
+	}
+	deps, err := c.depsList(ids)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pm, err := packages.Detect(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		ID        string `json:"id"`
+		Title     string `json:"title"`
+		Installed bool   `json:"installed"`
+		Error     string `json:"error,omitempty"`
+	}
+	rows := make([]row, 0, len(deps))
+	for _, dep := range deps {
+		installed, err := pm.IsInstalled(ctx, dep)
+		r := row{ID: dep.ID, Title: dep.Title, Installed: installed}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		rows = append(rows, r)
+	}
+
+	c.out.result(rows, func(w io.Writer) {
+		fmt.Fprintf(w, "Package manager: %s\n\n", pm.Name())
+		cells := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			status := "missing"
+			switch {
+			case r.Error != "":
+				status = r.Error
+			case r.Installed:
+				status = "installed"
+			}
+			cells = append(cells, []string{r.ID, r.Title, status})
+		}
+		table(w, []string{"id", "name", "status"}, cells)
+	})
+	return nil
+}
+
+// depsInstall installs the dependencies that are missing. Without IDs it
+// installs the native stack and records where its binaries went in the
+// settings, as the Settings view does.
+func (c *ctl) depsInstall(args []string) error {
+	ids, err := parse(c.flagSet("deps install"), args)
+	if err != nil {
+		return err
+	}
+	deps, err := c.depsList(ids)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	pm, err := packages.Detect(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	progress := func(dep packages.Dependency, percent int, message string) {
+		if percent < 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", dep.ID, message)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s: [%3d%%] %s\n", dep.ID, percent, message)
+	}
+	var results []packages.Result
+	if len(ids) == 0 {
+		results = packages.InstallStack(ctx, pm, c.config, progress)
+		if err := c.config.Save(); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	} else {
+		results = packages.EnsureAll(ctx, pm, deps, progress)
+	}
+
+	type row struct {
+		ID     string `json:"id"`
+		Title  string `json:"title"`
+		Result string `json:"result"`
+		Error  string `json:"error,omitempty"`
+	}
+	rows := make([]row, 0, len(results))
+	for _, res := range results {
+		r := row{ID: res.Dependency.ID, Title: res.Dependency.Title, Result: depResult(res)}
+		if res.Err != nil {
+			r.Error = res.Err.Error()
+		}
+		rows = append(rows, r)
+	}
+	c.out.result(rows, func(w io.Writer) {
+		cells := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			cells = append(cells, []string{r.ID, r.Title, r.Result})
+		}
+		table(w, []string{"id", "name", "result"}, cells)
+	})
+	return packages.Errors(results)
+}
+
+// depResult is a one-word summary of an install result.
+func depResult(res packages.Result) string {
+	switch {
+	case res.Err != nil:
+		return "failed"
+	case res.Installed:
+		return "installed"
+	}
+	return "already installed"
+}
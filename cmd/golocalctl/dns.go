@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/projects"
+)
+
+// projectDNS handles "project dns <id> [list|add|rm]".
+func (c *ctl) projectDNS(args []string) error {
+	if len(args) == 0 {
+		return usagef("project dns requires a project id")
+	}
+	id, err := c.resolveProject(args[0])
+	if err != nil {
+		return err
+	}
+	action, rest := "list", args[1:]
+	if len(rest) > 0 {
+		action, rest = rest[0], rest[1:]
+	}
+
+	switch action {
+	case "list", "ls":
+		return c.dnsList(id, rest)
+	case "add":
+		return c.dnsAdd(id, rest)
+	case "rm", "remove", "delete":
+		return c.dnsRemove(id, rest)
+	}
+	return usagef("unknown project dns command %q", action)
+}
+
+func (c *ctl) dnsList(id string, args []string) error {
+	if _, err := parse(c.flagSet("project dns list"), args); err != nil {
+		return err
+	}
+	p, err := c.projects.Load(id)
+	if err != nil {
+		return err
+	}
+	records := p.DNSRecords
+	if records == nil {
+		records = []projects.DNSRecord{}
+	}
+
+	c.out.result(records, func(w io.Writer) {
+		rows := make([][]string, 0, len(records))
+		for _, r := range records {
+			value := r.Value
+			switch r.Type {
+			case "MX":
+				value = fmt.Sprintf("%d %s", r.Priority, r.Value)
+			case "SRV":
+				value = fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Value)
+			case "TXT":
+				value = strconv.Quote(r.Value)
+			}
+			ttl := "-"
+			if r.TTL > 0 {
+				ttl = strconv.Itoa(int(r.TTL))
+			}
+			rows = append(rows, []string{r.FQDN(p.Domain), r.Type, ttl, value})
+		}
+		table(w, []string{"name", "type", "ttl", "value"}, rows)
+	})
+	return nil
+}
+
+func (c *ctl) dnsAdd(id string, args []string) error {
+	var rec projects.DNSRecord
+	fs := c.flagSet("project dns add")
+	ttl := fs.Uint("ttl", 0, "TTL in seconds (default 300)")
+	priority := fs.Uint("priority", 10, "MX/SRV priority")
+	weight := fs.Uint("weight", 0, "SRV weight")
+	port := fs.Uint("port", 0, "SRV port")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 3 {
+		return usagef("project dns add takes <type> <name> <value>; use @ for the project domain")
+	}
+
+	rec.Type, rec.Name, rec.Value = pos[0], pos[1], pos[2]
+	rec.TTL = uint32(*ttl)
+	switch strings.ToUpper(rec.Type) {
+	case "MX", "SRV":
+		rec.Priority = uint16(*priority)
+		rec.Weight = uint16(*weight)
+		rec.Port = uint16(*port)
+	}
+	if err := rec.Validate(); err != nil {
+		return usageError(err.Error())
+	}
+
+	p, err := c.projects.Load(id)
+	if err != nil {
+		return err
+	}
+	records := append(append([]projects.DNSRecord(nil), p.DNSRecords...), rec)
+	if _, err := c.app.UpdateProject(id, app.ProjectUpdate{DNSRecords: &records}); err != nil {
+		return appErr(err)
+	}
+	c.out.message("Added %s %s", rec.Type, rec.FQDN(p.Domain))
+	return nil
+}
+
+func (c *ctl) dnsRemove(id string, args []string) error {
+	pos, err := parse(c.flagSet("project dns rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return usagef("project dns rm takes <type> <name>")
+	}
+
+	p, err := c.projects.Load(id)
+	if err != nil {
+		return err
+	}
+	want := projects.DNSRecord{Name: pos[1]}
+	var kept []projects.DNSRecord
+	for _, r := range p.DNSRecords {
+		if strings.EqualFold(r.Type, pos[0]) && r.FQDN(p.Domain) == want.FQDN(p.Domain) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	if len(kept) == len(p.DNSRecords) {
+		return fmt.Errorf("no %s record named %s", strings.ToUpper(pos[0]), want.FQDN(p.Domain))
+	}
+	if _, err := c.app.UpdateProject(id, app.ProjectUpdate{DNSRecords: &kept}); err != nil {
+		return appErr(err)
+	}
+	c.out.message("Removed %s %s", strings.ToUpper(pos[0]), want.FQDN(p.Domain))
+	return nil
+}
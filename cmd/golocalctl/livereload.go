@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"go-local-server/internal/livereload"
+)
+
+func (c *ctl) liveReloadCmd(args []string) error {
+	if len(args) == 0 || args[0] != "strip" {
+		return usagef("livereload requires strip")
+	}
+
+	fs := c.flagSet("livereload strip")
+	dryRun := fs.Bool("dry-run", false, "list the files without changing them")
+	pos, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("livereload strip takes exactly one project id")
+	}
+	id, err := c.resolveProject(pos[0])
+	if err != nil {
+		return err
+	}
+	p, err := c.projects.Load(id)
+	if err != nil {
+		return err
+	}
+
+	changed, err := livereload.StripInjected(p.Path, *dryRun)
+	if err != nil {
+		return err
+	}
+	if changed == nil {
+		changed = []string{}
+	}
+	c.out.result(map[string]interface{}{"files": changed, "dry_run": *dryRun}, func(w io.Writer) {
+		if len(changed) == 0 {
+			fmt.Fprintf(w, "No injected live reload scripts in %s\n", p.Path)
+			return
+		}
+		verb := "Removed the live reload script from"
+		if *dryRun {
+			verb = "Would remove the live reload script from"
+		}
+		fmt.Fprintf(w, "%s:\n", verb)
+		for _, f := range changed {
+			fmt.Fprintf(w, "  %s\n", f)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"go-local-server/internal/services"
+)
+
+func (c *ctl) logsCmd(args []string) error {
+	fs := c.flagSet("logs")
+	follow := fs.Bool("f", false, "follow log output")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("logs takes exactly one service (apache, php, mysql, phpmyadmin or an optional service)")
+	}
+	// Besides the named services, any compose service (php83, mariadb) works.
+	id, err := services.ParseServiceID(pos[0])
+	if err != nil {
+		id = services.ServiceID(pos[0])
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	lines, err := c.services.Logs(ctx, id, *follow)
+	if err != nil {
+		return serviceErr(err)
+	}
+
+	// Logs are streamed line by line; in JSON mode each line is its own
+	// object so followers can consume them incrementally.
+	enc := json.NewEncoder(c.out.w)
+	for line := range lines {
+		if c.out.json() {
+			_ = enc.Encode(map[string]string{"container": pos[0], "line": line})
+			continue
+		}
+		fmt.Fprintln(c.out.w, line)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go-local-server/internal/mail"
+)
+
+func (c *ctl) mailCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("mail requires list, show, rm or clear")
+	}
+	switch args[0] {
+	case "list", "ls":
+		return c.mailList(args[1:])
+	case "show":
+		return c.mailShow(args[1:])
+	case "rm":
+		return c.mailRemove(args[1:])
+	case "clear":
+		return c.mailClear(args[1:])
+	}
+	return usagef("unknown mail command %q", args[0])
+}
+
+func (c *ctl) mailList(args []string) error {
+	fs := c.flagSet("mail list")
+	to := fs.String("to", "", "only messages sent to this address")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	list, err := c.app.Mail().List(*to)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []*mail.Message{}
+	}
+	c.out.result(list, func(w io.Writer) {
+		if len(list) == 0 {
+			fmt.Fprintln(w, "No mail caught")
+			return
+		}
+		rows := make([][]string, 0, len(list))
+		for _, m := range list {
+			rows = append(rows, []string{m.ID, m.Received.Format("2006-01-02 15:04:05"), strings.Join(m.Recipients, ", "), m.Subject})
+		}
+		table(w, []string{"id", "received", "to", "subject"}, rows)
+	})
+	return nil
+}
+
+func (c *ctl) mailShow(args []string) error {
+	fs := c.flagSet("mail show")
+	raw := fs.Bool("raw", false, "print the MIME source")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("mail show takes exactly one message id")
+	}
+	if *raw {
+		data, err := c.app.Mail().Raw(pos[0])
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	m, err := c.app.Mail().Get(pos[0])
+	if err != nil {
+		return err
+	}
+	c.out.result(m, func(w io.Writer) {
+		fmt.Fprintf(w, "From:     %s\n", m.From)
+		fmt.Fprintf(w, "To:       %s\n", strings.Join(m.Recipients, ", "))
+		fmt.Fprintf(w, "Subject:  %s\n", m.Subject)
+		fmt.Fprintf(w, "Received: %s\n", m.Received.Format("2006-01-02 15:04:05"))
+		for _, a := range m.Attachments {
+			fmt.Fprintf(w, "Attached: [%d] %s (%s, %d bytes)\n", a.Index, a.Filename, a.ContentType, a.Size)
+		}
+		fmt.Fprintln(w)
+		switch {
+		case m.Text != "":
+			fmt.Fprintln(w, strings.TrimRight(m.Text, "\r\n"))
+		case m.HTML != "":
+			fmt.Fprintln(w, "(HTML only; use -o json or -raw to see it)")
+		}
+	})
+	return nil
+}
+
+func (c *ctl) mailRemove(args []string) error {
+	pos, err := parse(c.flagSet("mail rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return usagef("mail rm takes one or more message ids")
+	}
+	for _, id := range pos {
+		if err := c.app.Mail().Delete(id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	c.out.message("Deleted %d message(s)", len(pos))
+	return nil
+}
+
+func (c *ctl) mailClear(args []string) error {
+	fs := c.flagSet("mail clear")
+	to := fs.String("to", "", "only delete messages sent to this address")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	n, err := c.app.Mail().Clear(*to)
+	if err != nil {
+		return err
+	}
+	c.out.message("Deleted %d message(s)", n)
+	return nil
+}
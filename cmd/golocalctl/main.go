@@ -0,0 +1,204 @@
+// Command golocalctl is a headless companion to the GoLocalServer app. It
+// drives the same Docker stack, project definitions and Apache vhosts as the
+// GUI so everything can be scripted or used over SSH.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/services"
+)
+
+const usage = `Usage: golocalctl [-o text|json] <command> [arguments]
+
+Commands:
+  services up [--build] [service]   Start the stack or a single service
+  services down [service]           Stop the stack or a single service
+  services restart [service]        Restart a service, or recreate all containers (down + up --build)
+  services reset --yes              Snapshot the databases, stop containers and delete volumes
+  services status                   Show service status
+  services available                List the optional services and their connection variables
+  services enable|disable <name>... Switch optional services (redis, minio, ...) on or off
+  project list                      List projects
+  project add --name N --path P     Create or import a project
+  project edit <id> [flags]         Change an existing project
+  project rm <id>                   Delete a project and its vhost
+  project dns <id> [add|rm]         List or change a project's extra DNS records
+  db create <project>               Create the project's database and user
+  db fix <project>                  Reset DB host/password and re-provision
+  db import <project> <file>        Load a .sql, .sql.gz or .zip dump (Ctrl-C cancels)
+  backup list                       List database snapshots
+  backup create [-name N] [project] Snapshot every database, or one project's
+  backup restore <id|name>          Load a snapshot back into its database
+  backup rm <id|name>... | prune    Delete snapshots, or apply the retention settings
+  logs [-f] <service>               Print service logs
+  certs path                        Print the local CA certificate path
+  certs export <file>               Copy the local CA certificate for trusting
+  livereload strip <project>        Remove scripts older releases wrote into project files
+  mail list [-to address]           List caught mail, newest first
+  mail show <id> [-raw]             Print a message (or its MIME source)
+  mail rm <id>... | mail clear      Delete caught mail
+  deps status [id...]               Show which native-backend dependencies are installed
+  deps install [id...]              Install the native stack (or the given dependencies) with the package manager
+  resolver status                   Show how the system resolves the local domain
+  resolver install [--dry-run]      Point the system resolver at the DNS server
+  resolver uninstall [--dry-run]    Undo resolver install (--method hosts for /etc/hosts)
+
+Run "golocalctl <command> -h" for command flags.
+`
+
+// usageError marks errors caused by bad invocation; they exit with status 2.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+type ctl struct {
+	config   *config.AppConfig
+	projects *projects.Manager
+	services services.ServiceManagerInterface
+	app      *app.Service
+	out      *printer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	out := &printer{format: "text", w: os.Stdout, errW: os.Stderr}
+
+	global := flag.NewFlagSet("golocalctl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	global.Var(out, "o", "output format: text or json")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return 2
+	}
+
+	config.EnsureDirs()
+	cfg := config.DefaultConfig()
+	if err := cfg.Load(); err != nil && !os.IsNotExist(err) {
+		out.fail(fmt.Errorf("load config: %w", err))
+		return 1
+	}
+
+	c := &ctl{
+		config:   cfg,
+		projects: projects.NewManager(cfg),
+		services: services.NewServiceManager(cfg, nil),
+		out:      out,
+	}
+	c.app = app.New(cfg, c.projects, c.services)
+
+	rest := global.Args()
+	var err error
+	switch rest[0] {
+	case "services", "service", "svc":
+		err = c.servicesCmd(rest[1:])
+	case "project", "projects":
+		err = c.projectCmd(rest[1:])
+	case "db":
+		err = c.dbCmd(rest[1:])
+	case "backup", "backups":
+		err = c.backupCmd(rest[1:])
+	case "logs":
+		err = c.logsCmd(rest[1:])
+	case "certs", "cert":
+		err = c.certsCmd(rest[1:])
+	case "deps":
+		err = c.depsCmd(rest[1:])
+	case "resolver":
+		err = c.resolverCmd(rest[1:])
+	case "livereload":
+		err = c.liveReloadCmd(rest[1:])
+	case "mail":
+		err = c.mailCmd(rest[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		err = usagef("unknown command %q", rest[0])
+	}
+
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	out.fail(err)
+	var ue usageError
+	if errors.As(err, &ue) {
+		return 2
+	}
+	return 1
+}
+
+// flagSet returns a FlagSet for a subcommand that also accepts -o so the
+// output format can be given after the subcommand name.
+func (c *ctl) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Var(c.out, "o", "output format: text or json")
+	return fs
+}
+
+// parse parses args, allowing flags to follow positional arguments
+// (e.g. "project rm blog -o json").
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usagef("%v", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// listFlag collects the values of a repeatable flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer renders command results either as human-readable text or JSON.
+// It implements flag.Value so it can be bound directly to -o.
+type printer struct {
+	format string
+	w      io.Writer
+	errW   io.Writer
+}
+
+func (p *printer) String() string {
+	return p.format
+}
+
+func (p *printer) Set(v string) error {
+	switch v {
+	case "text", "json":
+		p.format = v
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (want text or json)", v)
+}
+
+func (p *printer) json() bool {
+	return p.format == "json"
+}
+
+// result prints v as JSON, or calls text to render it for humans.
+func (p *printer) result(v interface{}, text func(w io.Writer)) {
+	if p.json() {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(v)
+		return
+	}
+	text(p.w)
+}
+
+// message prints a one-line confirmation. In JSON mode it is wrapped in an
+// object so output stays machine-readable.
+func (p *printer) message(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	p.result(map[string]string{"message": msg}, func(w io.Writer) {
+		fmt.Fprintln(w, msg)
+	})
+}
+
+func (p *printer) fail(err error) {
+	if p.json() {
+		enc := json.NewEncoder(p.errW)
+		_ = enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	fmt.Fprintf(p.errW, "golocalctl: %v\n", err)
+}
+
+// table writes rows as aligned columns with an upper-cased header.
+func table(w io.Writer, header []string, rows [][]string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, r := range rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	tw.Flush()
+}
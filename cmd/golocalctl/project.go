@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/projects"
+)
+
+func (c *ctl) projectCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("project requires list, add, edit, rm or dns")
+	}
+	switch args[0] {
+	case "list", "ls":
+		return c.projectList(args[1:])
+	case "add", "import":
+		return c.projectAdd(args[1:])
+	case "edit":
+		return c.projectEdit(args[1:])
+	case "rm", "remove", "delete":
+		return c.projectRemove(args[1:])
+	case "dns":
+		return c.projectDNS(args[1:])
+	}
+	return usagef("unknown project command %q", args[0])
+}
+
+func (c *ctl) projectList(args []string) error {
+	if _, err := parse(c.flagSet("project list"), args); err != nil {
+		return err
+	}
+	list, err := c.projects.List()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if list == nil {
+		list = []*projects.Project{}
+	}
+
+	c.out.result(list, func(w io.Writer) {
+		rows := make([][]string, 0, len(list))
+		for _, p := range list {
+			db := "-"
+			if p.Database.DBName != "" {
+				db = p.Database.DBName
+				if e := p.Database.EngineName(); e != projects.EngineMySQL {
+					db += " (" + e + ")"
+				}
+			}
+			rows = append(rows, []string{p.ID, c.config.SiteURL(p.Domain), p.PHP(), db, p.Path})
+		}
+		table(w, []string{"id", "url", "php", "database", "path"}, rows)
+	})
+	return nil
+}
+
+// projectFlags are shared by "project add" and "project edit".
+type projectFlags struct {
+	name, subdomain, path, php, docRoot string
+	noDB, liveReload                    bool
+	dbEngine, dbName, dbUser, dbPass    string
+}
+
+func (pf *projectFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&pf.name, "name", "", "project name")
+	fs.StringVar(&pf.subdomain, "subdomain", "", "subdomain (defaults to the name, lower-cased)")
+	fs.StringVar(&pf.php, "php", "", "PHP version ("+strings.Join(projects.PHPVersions, ", ")+")")
+	fs.StringVar(&pf.docRoot, "docroot", "", "DocumentRoot relative to the project path")
+	fs.BoolVar(&pf.noDB, "no-db", false, "do not configure a database")
+	fs.BoolVar(&pf.liveReload, "livereload", false, "reload the browser when project files change (while the app runs)")
+	fs.StringVar(&pf.dbEngine, "db-engine", "", "database engine ("+strings.Join(projects.Engines, ", ")+"; default mysql)")
+	fs.StringVar(&pf.dbName, "db-name", "", "database name")
+	fs.StringVar(&pf.dbUser, "db-user", "", "database user")
+	fs.StringVar(&pf.dbPass, "db-pass", "", "database password (generated when empty)")
+}
+
+func (pf *projectFlags) database() *projects.DatabaseConfig {
+	return &projects.DatabaseConfig{Engine: pf.dbEngine, DBName: pf.dbName, DBUser: pf.dbUser, DBPassword: pf.dbPass}
+}
+
+func (c *ctl) projectAdd(args []string) error {
+	var pf projectFlags
+	fs := c.flagSet("project add")
+	pf.register(fs)
+	fs.StringVar(&pf.path, "path", "", "project folder")
+	tmplName := fs.String("template", "none", "generate a starter template: none, simple or mvc")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if pf.path != "" {
+		if abs, err := filepath.Abs(pf.path); err == nil {
+			pf.path = abs
+		}
+		if pf.name == "" {
+			pf.name = filepath.Base(pf.path)
+		}
+	}
+	tmpl, err := app.ParseTemplate(*tmplName)
+	if err != nil {
+		return appErr(err)
+	}
+
+	opts := app.ProjectOptions{
+		Name:         pf.name,
+		Subdomain:    pf.subdomain,
+		Path:         pf.path,
+		PHPVersion:   pf.php,
+		DocumentRoot: pf.docRoot,
+		Template:     tmpl,
+		LiveReload:   pf.liveReload,
+	}
+	if !pf.noDB {
+		opts.Database = pf.database()
+	}
+
+	res, err := c.app.CreateProject(opts)
+	if err != nil {
+		return appErr(err)
+	}
+	c.printProject(res)
+	return nil
+}
+
+func (c *ctl) projectEdit(args []string) error {
+	var pf projectFlags
+	fs := c.flagSet("project edit")
+	pf.register(fs)
+	include := fs.String("watch-include", "", "comma-separated globs that trigger live reload (replaces the default extensions; \"\" restores them)")
+	exclude := fs.String("watch-exclude", "", "comma-separated globs never watched, on top of .gitignore")
+	debounce := fs.Int("debounce", 0, "live reload debounce in ms (0 = 800)")
+	poll := fs.Bool("poll", false, "scan for changes instead of using file system events (network folders)")
+	pollInterval := fs.Int("poll-interval", 0, "ms between scans with -poll (0 = 1000)")
+	var tasks listFlag
+	fs.Var(&tasks, "task", "glob=command run before reloading, e.g. 'resources/scss/**=npm run build:css' (repeatable; replaces the tasks)")
+	noTasks := fs.Bool("no-tasks", false, "remove the watch tasks")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usagef("project edit takes exactly one project id")
+	}
+
+	id, err := c.resolveProject(pos[0])
+	if err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	upd := app.ProjectUpdate{RemoveDatabase: pf.noDB}
+	if set["name"] {
+		upd.Name = &pf.name
+	}
+	if set["subdomain"] {
+		upd.Subdomain = &pf.subdomain
+	}
+	if set["php"] {
+		upd.PHPVersion = &pf.php
+	}
+	if set["docroot"] {
+		upd.DocumentRoot = &pf.docRoot
+	}
+	if set["livereload"] {
+		upd.LiveReload = &pf.liveReload
+	}
+	if set["db-engine"] || set["db-name"] || set["db-user"] || set["db-pass"] {
+		upd.Database = pf.database()
+	}
+	if set["watch-include"] || set["watch-exclude"] || set["debounce"] || set["poll"] || set["poll-interval"] || set["task"] || *noTasks {
+		p, err := c.projects.Load(id)
+		if err != nil {
+			return err
+		}
+		watch := p.Watch
+		if set["watch-include"] {
+			watch.Include = splitList(*include)
+		}
+		if set["watch-exclude"] {
+			watch.Exclude = splitList(*exclude)
+		}
+		if set["debounce"] {
+			watch.DebounceMillis = *debounce
+		}
+		if set["poll"] {
+			watch.Poll = *poll
+		}
+		if set["poll-interval"] {
+			watch.PollMillis = *pollInterval
+		}
+		if *noTasks {
+			watch.Tasks = nil
+		}
+		if set["task"] {
+			watch.Tasks = nil
+			for _, t := range tasks {
+				glob, command, ok := strings.Cut(t, "=")
+				if !ok {
+					return usagef("-task takes glob=command, got %q", t)
+				}
+				watch.Tasks = append(watch.Tasks, projects.WatchTask{Glob: strings.TrimSpace(glob), Command: command})
+			}
+		}
+		upd.Watch = &watch
+	}
+
+	res, err := c.app.UpdateProject(id, upd)
+	if err != nil {
+		return appErr(err)
+	}
+	c.printProject(res)
+	return nil
+}
+
+func (c *ctl) projectRemove(args []string) error {
+	pos, err := parse(c.flagSet("project rm"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return usagef("project rm requires a project id")
+	}
+
+	for _, name := range pos {
+		id, err := c.resolveProject(name)
+		if err != nil {
+			return err
+		}
+		if err := c.app.DeleteProject(id); err != nil {
+			return appErr(err)
+		}
+	}
+
+	c.out.message("Deleted %s", strings.Join(pos, ", "))
+	return nil
+}
+
+func (c *ctl) printProject(res *app.ProjectResult) {
+	p := res.Project
+	c.out.result(p, func(w io.Writer) {
+		fmt.Fprintf(w, "Saved '%s' at %s\n", p.Name, c.config.SiteURL(p.Domain))
+		if p.Database.DBName != "" && !res.DatabaseProvisioned {
+			fmt.Fprintf(w, "%s is not running; run \"golocalctl db create %s\" once it is up\n", projects.EngineTitle(p.Database.EngineName()), p.ID)
+		}
+	})
+}
+
+// resolveProject accepts a project id or one of its domains.
+func (c *ctl) resolveProject(idOrDomain string) (string, error) {
+	if _, err := c.projects.Load(idOrDomain); err == nil {
+		return idOrDomain, nil
+	}
+	if p, err := c.projects.GetByDomain(idOrDomain); err == nil {
+		return p.ID, nil
+	}
+	return "", fmt.Errorf("project %q not found", idOrDomain)
+}
+
+// appErr turns validation failures from the app layer into usage errors.
+func appErr(err error) error {
+	if errors.Is(err, app.ErrInvalid) {
+		return usageError(err.Error())
+	}
+	return err
+}
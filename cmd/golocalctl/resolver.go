@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go-local-server/internal/resolver"
+)
+
+func (c *ctl) resolverCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("resolver requires status, install or uninstall")
+	}
+	switch args[0] {
+	case "status":
+		return c.resolverStatus(args[1:])
+	case "install":
+		return c.resolverChange("install", args[1:])
+	case "uninstall", "remove":
+		return c.resolverChange("uninstall", args[1:])
+	}
+	return usagef("unknown resolver command %q", args[0])
+}
+
+func (c *ctl) resolverStatus(args []string) error {
+	if _, err := parse(c.flagSet("resolver status"), args); err != nil {
+		return err
+	}
+	list, err := c.projects.List()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	type row struct {
+		Method    resolver.Method `json:"method"`
+		Status    string          `json:"status"`
+		Preferred bool            `json:"preferred"`
+	}
+	var rows []row
+	for _, m := range resolver.Methods() {
+		status, err := resolver.Status(c.config, m, list)
+		if err != nil {
+			status = err.Error()
+		}
+		rows = append(rows, row{m, status, m == resolver.Detect()})
+	}
+
+	c.out.result(rows, func(w io.Writer) {
+		fmt.Fprintf(w, "Domain *.%s -> 127.0.0.1:%d\n\n", c.config.Domain, c.config.DNSPort)
+		cells := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			name := string(r.Method)
+			if r.Preferred {
+				name += " (preferred)"
+			}
+			cells = append(cells, []string{name, r.Status})
+		}
+		table(w, []string{"method", "status"}, cells)
+	})
+	return nil
+}
+
+// resolverChange runs "resolver install" and "resolver uninstall".
+func (c *ctl) resolverChange(action string, args []string) error {
+	fs := c.flagSet("resolver " + action)
+	method := fs.String("method", "", "macos, systemd-resolved, networkmanager or hosts (default: detected)")
+	dryRun := fs.Bool("dry-run", false, "print the changes as a diff without applying them")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	m, err := resolver.ParseMethod(*method)
+	if errors.Is(err, resolver.ErrUnsupported) {
+		return usageError(err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	var plan *resolver.Plan
+	if action == "install" {
+		list, lerr := c.projects.List()
+		if lerr != nil && !os.IsNotExist(lerr) {
+			return lerr
+		}
+		plan, err = resolver.Install(c.config, m, list)
+	} else {
+		plan, err = resolver.Uninstall(c.config, m)
+	}
+	if err != nil {
+		return err
+	}
+
+	if plan.Empty() {
+		c.out.message("Nothing to change (%s)", m)
+		return nil
+	}
+	if *dryRun {
+		c.out.result(map[string]interface{}{"method": m, "diff": plan.Diff()}, func(w io.Writer) {
+			fmt.Fprint(w, plan.Diff())
+		})
+		return nil
+	}
+	if err := plan.Apply(); err != nil {
+		return err
+	}
+	c.out.message("Resolver %sed (%s)", action, m)
+	return nil
+}
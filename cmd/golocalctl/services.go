@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/registry"
+	"go-local-server/internal/services"
+)
+
+type serviceView struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	PID    int    `json:"pid"`
+}
+
+func (c *ctl) servicesCmd(args []string) error {
+	if len(args) == 0 {
+		return usagef("services requires up, down, restart, reset, status, available, enable or disable")
+	}
+	switch args[0] {
+	case "up", "start":
+		return c.servicesUp(args[1:])
+	case "down", "stop":
+		return c.servicesDown(args[1:])
+	case "restart":
+		return c.servicesRestart(args[1:])
+	case "reset":
+		return c.servicesReset(args[1:])
+	case "status", "ps":
+		return c.servicesStatus(args[1:])
+	case "available":
+		return c.servicesAvailable(args[1:])
+	case "enable":
+		return c.servicesEnable(args[1:], true)
+	case "disable":
+		return c.servicesEnable(args[1:], false)
+	}
+	return usagef("unknown services command %q", args[0])
+}
+
+func (c *ctl) servicesUp(args []string) error {
+	fs := c.flagSet("services up")
+	build := fs.Bool("build", false, "copy Docker resources and rebuild images (same as Docker Up in the app)")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *build {
+		if len(pos) > 0 {
+			return usagef("--build applies to the whole stack")
+		}
+		dsm, err := c.copiedStack()
+		if err != nil {
+			return err
+		}
+		if err := dsm.RunCompose(c.composeOutput(), "up", "-d", "--build", "--remove-orphans"); err != nil {
+			return err
+		}
+		_ = c.app.ReloadVhosts()
+		c.out.message("Stack built and started")
+		return nil
+	}
+
+	if len(pos) == 0 {
+		if err := c.services.StartAll(context.Background()); err != nil {
+			return err
+		}
+		c.out.message("All services started")
+		return nil
+	}
+
+	for _, name := range pos {
+		if err := c.startService(name); err != nil {
+			return err
+		}
+	}
+	c.out.message("Started %v", pos)
+	return nil
+}
+
+func (c *ctl) servicesDown(args []string) error {
+	pos, err := parse(c.flagSet("services down"), args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) == 0 {
+		if err := c.services.StopAll(context.Background()); err != nil {
+			return err
+		}
+		c.out.message("All services stopped")
+		return nil
+	}
+
+	for _, name := range pos {
+		if err := c.stopService(name); err != nil {
+			return err
+		}
+	}
+	c.out.message("Stopped %v", pos)
+	return nil
+}
+
+func (c *ctl) servicesRestart(args []string) error {
+	pos, err := parse(c.flagSet("services restart"), args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 {
+		for _, name := range pos {
+			id, err := services.ParseServiceID(name)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			if err := c.services.Restart(context.Background(), id); err != nil {
+				return err
+			}
+		}
+		c.out.message("Restarted %v", pos)
+		return nil
+	}
+
+	if c.config.Native() {
+		ctx := context.Background()
+		if err := c.services.StopAll(ctx); err != nil {
+			return err
+		}
+		if err := c.services.StartAll(ctx); err != nil {
+			return err
+		}
+		c.out.message("All services restarted")
+		return nil
+	}
+	dsm, err := c.copiedStack()
+	if err != nil {
+		return err
+	}
+	w := c.composeOutput()
+	if err := dsm.RunCompose(w, "down"); err != nil {
+		return err
+	}
+	if err := dsm.RunCompose(w, "up", "-d", "--build", "--remove-orphans"); err != nil {
+		return err
+	}
+	c.out.message("Docker containers restarted")
+	return nil
+}
+
+func (c *ctl) servicesReset(args []string) error {
+	fs := c.flagSet("services reset")
+	yes := fs.Bool("yes", false, "confirm that database data will be deleted")
+	noSnapshot := fs.Bool("no-snapshot", false, "do not snapshot the databases first")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if !*yes {
+		return usagef("reset removes all volumes (database data will be deleted); pass --yes to continue")
+	}
+	dsm, err := c.copiedStack()
+	if err != nil {
+		return err
+	}
+	if !*noSnapshot {
+		snaps, err := c.app.SnapshotBeforeReset(context.Background())
+		for _, snap := range snaps {
+			fmt.Fprintf(c.composeOutput(), "Saved snapshot %s (%s: %s)\n", snap.ID, snap.Engine, strings.Join(snap.Databases, ", "))
+		}
+		switch {
+		case errors.Is(err, app.ErrMySQLNotRunning), errors.Is(err, app.ErrDatabaseNotRunning):
+			return fmt.Errorf("%v, so its databases cannot be snapshotted; start it or pass --no-snapshot", err)
+		case err != nil:
+			return fmt.Errorf("snapshot before reset: %w (pass --no-snapshot to reset anyway)", err)
+		}
+	}
+	if err := dsm.RunCompose(c.composeOutput(), "down", "-v"); err != nil {
+		return err
+	}
+	c.out.message("Stack reset")
+	return nil
+}
+
+func (c *ctl) servicesStatus(args []string) error {
+	fs := c.flagSet("services status")
+	health := fs.Bool("health", false, "include the health of every container")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	c.services.RefreshStatuses(ctx)
+	svcs := c.services.Services()
+
+	views := make([]serviceView, 0, len(svcs))
+	for _, s := range svcs {
+		views = append(views, serviceView{Key: string(s.ID), Name: s.Name, Status: s.Status.String(), PID: s.PID})
+	}
+
+	var healthStatus map[string]map[string]string
+	if *health {
+		healthStatus = c.services.Health(ctx)
+	}
+
+	// Settings may have changed since the stack was last brought up.
+	drift, _ := c.services.ComposeDrift(ctx)
+
+	result := map[string]interface{}{"services": views}
+	if healthStatus != nil {
+		result["health"] = healthStatus
+	}
+	if len(drift) > 0 {
+		result["drift"] = drift
+	}
+
+	c.out.result(result, func(w io.Writer) {
+		rows := make([][]string, 0, len(views))
+		for _, v := range views {
+			rows = append(rows, []string{v.Name, v.Status, strconv.Itoa(v.PID)})
+		}
+		table(w, []string{"service", "status", "pid"}, rows)
+
+		if healthStatus != nil {
+			fmt.Fprintln(w)
+			names := make([]string, 0, len(healthStatus))
+			for n := range healthStatus {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			rows = rows[:0]
+			for _, n := range names {
+				rows = append(rows, []string{n, healthStatus[n]["status"], healthStatus[n]["health"]})
+			}
+			table(w, []string{"container", "status", "health"}, rows)
+		}
+
+		if len(drift) > 0 {
+			fmt.Fprintf(w, "\n%s out of date with the settings; run \"golocalctl services up --build\" to apply\n", strings.Join(drift, ", "))
+		}
+	})
+	return nil
+}
+
+type optionalServiceView struct {
+	ID          string            `json:"id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Enabled     bool              `json:"enabled"`
+	Image       string            `json:"image"`
+	Ports       map[string]int    `json:"ports,omitempty"` // host ports by name
+	URL         string            `json:"url,omitempty"`
+	Connection  map[string]string `json:"connection"`
+}
+
+func (c *ctl) servicesAvailable(args []string) error {
+	if _, err := parse(c.flagSet("services available"), args); err != nil {
+		return err
+	}
+
+	descs := make([]registry.Descriptor, len(registry.Builtins))
+	views := make([]optionalServiceView, len(registry.Builtins))
+	for i, d := range registry.Builtins {
+		d = d.Resolve(c.config)
+		v := optionalServiceView{
+			ID:          d.ID,
+			Title:       d.Title,
+			Description: d.Description,
+			Enabled:     c.config.ServiceEnabled(d.ID),
+			Image:       d.Image,
+			Ports:       map[string]int{},
+			URL:         d.WebURL(),
+			Connection:  map[string]string{},
+		}
+		for _, p := range d.Published() {
+			v.Ports[p.Name] = p.Host
+		}
+		for _, e := range d.Connection {
+			v.Connection[e.Name] = e.Value
+		}
+		descs[i], views[i] = d, v
+	}
+
+	c.out.result(views, func(w io.Writer) {
+		rows := make([][]string, 0, len(views))
+		for i, v := range views {
+			enabled := "no"
+			if v.Enabled {
+				enabled = "yes"
+			}
+			var ports []string
+			for _, p := range descs[i].Published() {
+				ports = append(ports, strconv.Itoa(p.Host))
+			}
+			var env []string
+			for _, e := range descs[i].Connection {
+				env = append(env, e.Name)
+			}
+			rows = append(rows, []string{v.ID, enabled, strings.Join(ports, ","), strings.Join(env, " ")})
+		}
+		table(w, []string{"service", "enabled", "ports", "connection"}, rows)
+	})
+	return nil
+}
+
+// servicesEnable switches registry services on or off and regenerates the
+// compose file; the containers change on the next "services up".
+func (c *ctl) servicesEnable(args []string, on bool) error {
+	cmd := "services disable"
+	if on {
+		cmd = "services enable"
+	}
+	pos, err := parse(c.flagSet(cmd), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return usagef("%s requires at least one of %s", cmd, strings.Join(registry.IDs(), ", "))
+	}
+
+	for _, name := range pos {
+		if err := registry.SetEnabled(c.config, name, on); err != nil {
+			return usageError(err.Error())
+		}
+	}
+	if err := c.config.Save(); err != nil {
+		return err
+	}
+	if _, err := services.CopyDockerResources(c.config); err != nil {
+		return fmt.Errorf("copy Docker resources: %w", err)
+	}
+
+	verb := "Disabled"
+	if on {
+		verb = "Enabled"
+	}
+	if drift, _ := c.services.ComposeDrift(context.Background()); len(drift) > 0 {
+		c.out.message("%s %v; run \"golocalctl services up --build\" to apply", verb, pos)
+		return nil
+	}
+	c.out.message("%s %v", verb, pos)
+	return nil
+}
+
+func (c *ctl) startService(name string) error {
+	id, err := services.ParseServiceID(name)
+	if err != nil {
+		return serviceErr(err)
+	}
+	return c.services.Start(context.Background(), id)
+}
+
+func (c *ctl) stopService(name string) error {
+	id, err := services.ParseServiceID(name)
+	if err != nil {
+		return serviceErr(err)
+	}
+	return c.services.Stop(context.Background(), id)
+}
+
+// serviceErr turns unknown service names into usage errors.
+func serviceErr(err error) error {
+	if errors.Is(err, services.ErrUnknownService) {
+		return usageError(err.Error())
+	}
+	return err
+}
+
+// copiedStack refreshes the app's private copy of the Docker resources and
+// returns a manager bound to it, mirroring what the GUI does for compose runs.
+// It fails with the native backend, which has no compose stack.
+func (c *ctl) copiedStack() (*services.DockerServiceManager, error) {
+	if c.config.Native() {
+		return nil, errors.New("this needs the Docker backend; the service backend is set to native")
+	}
+	if _, err := services.CopyDockerResources(c.config); err != nil {
+		return nil, fmt.Errorf("copy Docker resources: %w", err)
+	}
+	dsm := services.NewDockerServiceManager(c.config)
+	c.services = dsm
+	c.app.SetServiceManager(dsm)
+	return dsm, nil
+}
+
+// composeOutput is where docker compose progress goes. In JSON mode it is kept
+// off stdout so the result stays parseable.
+func (c *ctl) composeOutput() io.Writer {
+	if c.out.json() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
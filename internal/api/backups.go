@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-local-server/internal/backup"
+)
+
+// backupRequest is the body of POST /v1/backups.
+type backupRequest struct {
+	Name    string `json:"name"`    // optional; named snapshots are never pruned
+	Project string `json:"project"` // optional; only this project's database
+}
+
+// handleBackups serves:
+//
+//	GET  /v1/backups   snapshots, newest first
+//	POST /v1/backups   take a snapshot
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.app.Backups().List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if list == nil {
+			list = []*backup.Snapshot{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var req backupRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+				return
+			}
+		}
+		snap, err := s.app.Snapshot(r.Context(), req.Name, req.Project)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, snap)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleBackup serves:
+//
+//	GET    /v1/backups/{id|name}
+//	DELETE /v1/backups/{id|name}
+//	POST   /v1/backups/{id|name}/restore
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/v1/backups/")
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			snap, err := s.app.Backups().Get(parts[0])
+			if err != nil {
+				writeAppError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, snap)
+		case http.MethodDelete:
+			if err := s.app.Backups().Delete(parts[0]); err != nil {
+				writeAppError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case len(parts) == 2 && parts[1] == "restore":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		snap, err := s.app.RestoreSnapshot(r.Context(), parts[0])
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, snap)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleEvents streams the app's events as server-sent events, named by
+// kind:
+//
+//	GET /v1/events[?kind=service,project.created]
+//
+// kind filters by exact kind or by the part before the dot.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	bus := s.app.Events()
+	if bus == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("events are not available"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	var kinds []string
+	if q := r.URL.Query().Get("kind"); q != "" {
+		kinds = strings.Split(q, ",")
+	}
+
+	sub := bus.Subscribe()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	// Comments keep idle connections from being dropped by proxies and
+	// show a dead client on the next write.
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !matchKind(kinds, e.Kind()) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind(), data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// matchKind reports whether kind passes the filter; an empty filter passes
+// everything.
+func matchKind(filter []string, kind string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		f = strings.TrimSpace(f)
+		if f == kind || strings.HasPrefix(kind, f+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"go-local-server/internal/mail"
+)
+
+// handleMail serves:
+//
+//	GET    /v1/mail?to=address   message summaries, newest first
+//	DELETE /v1/mail?to=address   delete them (every message without to)
+func (s *Server) handleMail(w http.ResponseWriter, r *http.Request) {
+	to := r.URL.Query().Get("to")
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.app.Mail().List(to)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if list == nil {
+			list = []*mail.Message{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodDelete:
+		n, err := s.app.Mail().Clear(to)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"deleted": n})
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// handleMailboxes serves GET /v1/mailboxes, the recipients with mail.
+func (s *Server) handleMailboxes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	boxes, err := s.app.Mail().Mailboxes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, boxes)
+}
+
+// handleMessage serves:
+//
+//	GET    /v1/mail/{id}                    headers, bodies and attachment list
+//	DELETE /v1/mail/{id}
+//	GET    /v1/mail/{id}/raw                the MIME source
+//	GET    /v1/mail/{id}/attachments/{n}    one decoded attachment
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/v1/mail/")
+	store := s.app.Mail()
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			m, err := store.Get(parts[0])
+			if err != nil {
+				writeAppError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, m)
+		case http.MethodDelete:
+			if err := store.Delete(parts[0]); err != nil {
+				writeAppError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case len(parts) == 2 && parts[1] == "raw":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		raw, err := store.Raw(parts[0])
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "message/rfc822")
+		w.Write(raw)
+	case len(parts) == 3 && parts[1] == "attachments":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			writeError(w, http.StatusNotFound, mail.ErrNoAttachment)
+			return
+		}
+		a, data, err := store.Attachment(parts[0], n)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		// Served as a download so HTML attachments never run in a browser
+		// pointed at the API.
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		name := a.Filename
+		if name == "" {
+			name = "attachment-" + parts[2]
+		}
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		w.Write(data)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/backup"
+	"go-local-server/internal/mail"
+	"go-local-server/internal/projects"
+)
+
+// projectRequest is the body of POST /v1/projects and PATCH /v1/projects/{id}.
+// On PATCH only the fields that are present are changed.
+type projectRequest struct {
+	Name         *string                  `json:"name"`
+	Subdomain    *string                  `json:"subdomain"`
+	Path         string                   `json:"path"`
+	PHPVersion   *string                  `json:"php_version"`
+	DocumentRoot *string                  `json:"document_root"`
+	Template     string                   `json:"template"` // none, simple or mvc (create only)
+	LiveReload   *bool                    `json:"live_reload"`
+	Database     *projects.DatabaseConfig `json:"database"`
+	NoDatabase   bool                     `json:"no_database"`
+	DNSRecords   *[]projects.DNSRecord    `json:"dns_records"` // PATCH only; replaces the list
+	Watch        *projects.WatchRules     `json:"watch"`       // PATCH only; replaces the rules
+}
+
+type projectResponse struct {
+	*projects.Project
+	DatabaseProvisioned bool `json:"database_provisioned"`
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.app.Projects().List()
+		if err != nil && !os.IsNotExist(err) {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if list == nil {
+			list = []*projects.Project{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		s.createProject(w, r)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/v1/projects/")
+	if len(parts) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	id := parts[0]
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			p, err := s.app.Projects().Load(id)
+			if err != nil {
+				writeAppError(w, fmt.Errorf("%w: %s", app.ErrNotFound, id))
+				return
+			}
+			writeJSON(w, http.StatusOK, p)
+		case http.MethodPatch, http.MethodPut:
+			s.updateProject(w, r, id)
+		case http.MethodDelete:
+			if err := s.app.DeleteProject(id); err != nil {
+				writeAppError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+		}
+		return
+	}
+
+	if len(parts) == 3 && parts[1] == "database" && parts[2] == "import" && r.Method == http.MethodPost {
+		s.importDatabase(w, r, id)
+		return
+	}
+	if parts[1] == "database" && r.Method == http.MethodPost {
+		var res *app.ProjectResult
+		var err error
+		switch {
+		case len(parts) == 2:
+			res, err = s.app.ProvisionDatabase(id)
+		case len(parts) == 3 && parts[2] == "fix":
+			res, err = s.app.RepairDatabase(id)
+		default:
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+			return
+		}
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res.Project.Database)
+		return
+	}
+	writeError(w, http.StatusNotFound, errors.New("not found"))
+}
+
+// importDatabase loads a dump file on this machine into the project's
+// database. The request returns once the import has finished; closing the
+// connection cancels it.
+func (s *Server) importDatabase(w http.ResponseWriter, r *http.Request, id string) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, errors.New("path is required"))
+		return
+	}
+	res, err := s.app.ImportDatabase(r.Context(), id, req.Path, nil)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request) {
+	var req projectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+
+	tmpl, err := app.ParseTemplate(req.Template)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	opts := app.ProjectOptions{
+		Name:         deref(req.Name),
+		Subdomain:    deref(req.Subdomain),
+		Path:         req.Path,
+		PHPVersion:   deref(req.PHPVersion),
+		DocumentRoot: deref(req.DocumentRoot),
+		Template:     tmpl,
+		LiveReload:   req.LiveReload != nil && *req.LiveReload,
+		Database:     req.Database,
+	}
+	if opts.Database == nil && !req.NoDatabase {
+		opts.Database = &projects.DatabaseConfig{}
+	}
+	if req.NoDatabase {
+		opts.Database = nil
+	}
+
+	res, err := s.app.CreateProject(opts)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, projectResponse{res.Project, res.DatabaseProvisioned})
+}
+
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request, id string) {
+	var req projectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %w", err))
+		return
+	}
+
+	res, err := s.app.UpdateProject(id, app.ProjectUpdate{
+		Name:           req.Name,
+		Subdomain:      req.Subdomain,
+		PHPVersion:     req.PHPVersion,
+		DocumentRoot:   req.DocumentRoot,
+		LiveReload:     req.LiveReload,
+		Database:       req.Database,
+		RemoveDatabase: req.NoDatabase,
+		DNSRecords:     req.DNSRecords,
+		Watch:          req.Watch,
+	})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projectResponse{res.Project, res.DatabaseProvisioned})
+}
+
+// writeAppError maps app errors onto HTTP status codes.
+func writeAppError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, app.ErrNotFound), errors.Is(err, mail.ErrNotFound), errors.Is(err, mail.ErrNoAttachment),
+		errors.Is(err, backup.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, app.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, app.ErrNoDatabase), errors.Is(err, backup.ErrNothingToBackUp):
+		status = http.StatusConflict
+	case errors.Is(err, app.ErrMySQLNotRunning), errors.Is(err, app.ErrDatabaseNotRunning):
+		status = http.StatusServiceUnavailable
+	}
+	writeError(w, status, err)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,198 @@
+// Package api exposes a small versioned JSON API for the running app so that
+// editor plugins, shell prompts and scripts can drive the stack. It listens on
+// a Unix socket next to the config file and, optionally, on 127.0.0.1.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/config"
+	"go-local-server/internal/services"
+)
+
+// ErrAlreadyRunning is returned by Start when another instance of the app is
+// serving on the socket.
+var ErrAlreadyRunning = errors.New("api: another instance is already listening on the socket")
+
+type Server struct {
+	config *config.AppConfig
+	app    *app.Service
+
+	mu      sync.Mutex
+	token   string
+	servers []*http.Server
+	cancel  context.CancelFunc // ends the event streams on Stop
+}
+
+func NewServer(cfg *config.AppConfig, core *app.Service) *Server {
+	return &Server{config: cfg, app: core}
+}
+
+func (s *Server) serviceManager() services.ServiceManagerInterface {
+	return s.app.Services()
+}
+
+// Token returns the bearer token clients must send. It is only valid after
+// Start has been called.
+func (s *Server) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Addr describes where the API is listening, for display in Settings.
+func (s *Server) Addr() string {
+	if s.config.APIPort > 0 {
+		return fmt.Sprintf("unix:%s, http://127.0.0.1:%d", config.APISocket, s.config.APIPort)
+	}
+	return "unix:" + config.APISocket
+}
+
+// Start binds the Unix socket (and TCP port when configured) and serves in the
+// background. Bind errors are returned synchronously.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.servers) > 0 {
+		return nil
+	}
+
+	token, err := LoadOrCreateToken()
+	if err != nil {
+		return err
+	}
+	s.token = token
+
+	var listeners []net.Listener
+
+	// A stale socket from a previous crash would make Listen fail, but one
+	// that answers belongs to a running instance.
+	if conn, err := net.DialTimeout("unix", config.APISocket, time.Second); err == nil {
+		conn.Close()
+		return ErrAlreadyRunning
+	}
+	_ = os.Remove(config.APISocket)
+	ul, err := net.Listen("unix", config.APISocket)
+	if err != nil {
+		return fmt.Errorf("api: listen on %s: %w", config.APISocket, err)
+	}
+	_ = os.Chmod(config.APISocket, 0600)
+	listeners = append(listeners, ul)
+
+	if s.config.APIPort > 0 {
+		tl, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", s.config.APIPort))
+		if err != nil {
+			ul.Close()
+			return fmt.Errorf("api: listen on port %d: %w", s.config.APIPort, err)
+		}
+		listeners = append(listeners, tl)
+	}
+
+	handler := s.Handler()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, l := range listeners {
+		srv := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+		s.servers = append(s.servers, srv)
+		go func(l net.Listener) {
+			if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Printf("API server error: %v\n", err)
+			}
+		}(l)
+	}
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	servers := s.servers
+	s.servers = nil
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	// Without servers the socket, if any, is another instance's.
+	if len(servers) > 0 {
+		_ = os.Remove(config.APISocket)
+	}
+	return firstErr
+}
+
+// Handler returns the API routes wrapped in token authentication.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/health", s.handleHealth)
+	mux.Handle("/v1/projects", s.authenticated(s.handleProjects))
+	mux.Handle("/v1/projects/", s.authenticated(s.handleProject))
+	mux.Handle("/v1/services", s.authenticated(s.handleServices))
+	mux.Handle("/v1/services/", s.authenticated(s.handleService))
+	mux.Handle("/v1/mail", s.authenticated(s.handleMail))
+	mux.Handle("/v1/mail/", s.authenticated(s.handleMessage))
+	mux.Handle("/v1/mailboxes", s.authenticated(s.handleMailboxes))
+	mux.Handle("/v1/backups", s.authenticated(s.handleBackups))
+	mux.Handle("/v1/backups/", s.authenticated(s.handleBackup))
+	mux.Handle("/v1/events", s.authenticated(s.handleEvents))
+	return mux
+}
+
+func (s *Server) authenticated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := s.Token()
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+// pathParts splits the request path below prefix, e.g.
+// "/v1/services/mysql/start" with prefix "/v1/services/" gives [mysql start].
+func pathParts(r *http.Request, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
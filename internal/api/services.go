@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/services"
+)
+
+type serviceView struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	PID    int    `json:"pid"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "ok",
+		"version": config.AppVersion,
+	})
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.serviceViews(r.Context()))
+}
+
+// handleService serves:
+//
+//	GET  /v1/services/health
+//	POST /v1/services/reload
+//	POST /v1/services/{name}/start   (name may be "all")
+//	POST /v1/services/{name}/stop
+//	POST /v1/services/{name}/restart
+func (s *Server) handleService(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r, "/v1/services/")
+	sm := s.serviceManager()
+	ctx := r.Context()
+
+	switch {
+	case len(parts) == 1 && parts[0] == "health":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		writeJSON(w, http.StatusOK, sm.Health(ctx))
+		return
+	case len(parts) == 1 && parts[0] == "reload":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		if err := sm.Reload(ctx); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.serviceViews(ctx))
+		return
+	case len(parts) != 2:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	name, action := parts[0], parts[1]
+	if name == "all" {
+		var err error
+		switch action {
+		case "start":
+			err = sm.StartAll(ctx)
+		case "stop":
+			err = sm.StopAll(ctx)
+		default:
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+			return
+		}
+		s.serviceResult(w, r, err)
+		return
+	}
+
+	id, err := services.ParseServiceID(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	switch action {
+	case "start":
+		err = sm.Start(ctx, id)
+	case "stop":
+		err = sm.Stop(ctx, id)
+	case "restart":
+		err = sm.Restart(ctx, id)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	s.serviceResult(w, r, err)
+}
+
+// serviceResult answers a start/stop/restart with the new service list.
+func (s *Server) serviceResult(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.serviceViews(r.Context()))
+}
+
+func (s *Server) serviceViews(ctx context.Context) []serviceView {
+	sm := s.serviceManager()
+	sm.RefreshStatuses(ctx)
+
+	svcs := sm.Services()
+	views := make([]serviceView, 0, len(svcs))
+	for _, svc := range svcs {
+		views = append(views, serviceView{Key: string(svc.ID), Name: svc.Name, Status: svc.Status.String(), PID: svc.PID})
+	}
+	return views
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/config"
+)
+
+// LoadOrCreateToken returns the API token stored at config.APITokenFile,
+// generating and persisting a new one (mode 0600) on first use.
+func LoadOrCreateToken() (string, error) {
+	data, err := os.ReadFile(config.APITokenFile)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate api token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	if err := os.MkdirAll(filepath.Dir(config.APITokenFile), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(config.APITokenFile, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,172 @@
+// Package app holds the project and database workflows shared by the GUI,
+// golocalctl and the control API. Each operation runs the full sequence
+// (persist, template, database, vhost, reload) and undoes the steps it has
+// already taken when a later one fails.
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/backup"
+	"go-local-server/internal/config"
+	"go-local-server/internal/events"
+	"go-local-server/internal/mail"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/services"
+	"go-local-server/pkg/apache"
+)
+
+var (
+	// ErrNotFound is returned when a project id does not exist.
+	ErrNotFound = errors.New("project not found")
+	// ErrInvalid wraps validation failures in caller-supplied options.
+	ErrInvalid = errors.New("invalid request")
+	// ErrMySQLNotRunning is returned by database actions that need MySQL.
+	ErrMySQLNotRunning = errors.New("MySQL is not running")
+	// ErrDatabaseNotRunning is returned by database actions that need
+	// another engine; it is wrapped with the engine's name.
+	ErrDatabaseNotRunning = errors.New("database server is not running")
+	// ErrNoDatabase is returned by database actions on projects without one.
+	ErrNoDatabase = errors.New("this project has no database configuration")
+)
+
+type Service struct {
+	config   *config.AppConfig
+	projects *projects.Manager
+	vhosts   *apache.Generator
+	mail     *mail.Store
+	backups  *backup.Manager
+
+	mu       sync.Mutex
+	services services.ServiceManagerInterface
+	events   *events.Bus
+}
+
+func New(cfg *config.AppConfig, pm *projects.Manager, sm services.ServiceManagerInterface) *Service {
+	s := &Service{
+		config:   cfg,
+		projects: pm,
+		vhosts:   apache.NewGenerator(cfg),
+		mail:     mail.NewStore(mail.DefaultDir()),
+		services: sm,
+	}
+	s.backups = backup.NewManager(cfg, s.databaseEngine)
+	return s
+}
+
+// Services returns the current service manager.
+func (s *Service) Services() services.ServiceManagerInterface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.services
+}
+
+// SetServiceManager swaps the service manager, e.g. after Docker is detected.
+func (s *Service) SetServiceManager(sm services.ServiceManagerInterface) {
+	s.mu.Lock()
+	s.services = sm
+	s.mu.Unlock()
+}
+
+// SetEvents makes the project workflows publish to bus. The service
+// manager publishes on its own, so it needs the bus as well.
+func (s *Service) SetEvents(bus *events.Bus) {
+	s.mu.Lock()
+	s.events = bus
+	s.mu.Unlock()
+}
+
+// Events returns the bus set with SetEvents, or nil.
+func (s *Service) Events() *events.Bus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+// Projects exposes the underlying project store for read-only listing.
+func (s *Service) Projects() *projects.Manager {
+	return s.projects
+}
+
+// Mail returns the store of caught mail.
+func (s *Service) Mail() *mail.Store {
+	return s.mail
+}
+
+// ReloadVhosts regenerates every vhost and gracefully reloads Apache.
+func (s *Service) ReloadVhosts() error {
+	if err := s.vhosts.GenerateAllVhosts(); err != nil {
+		return err
+	}
+	return s.Services().Reload(context.Background())
+}
+
+// databaseRunning checks the service of a database engine live: nil when it
+// is up, ErrMySQLNotRunning or ErrDatabaseNotRunning when it is not.
+func (s *Service) databaseRunning(ctx context.Context, engine string) error {
+	engine, err := projects.ParseEngine(engine)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	p, err := s.Services().Provisioner(engine)
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.Running(ctx):
+		return nil
+	case engine == projects.EngineMySQL:
+		return ErrMySQLNotRunning
+	}
+	return fmt.Errorf("%w: %s", ErrDatabaseNotRunning, projects.EngineTitle(engine))
+}
+
+func (s *Service) load(id string) (*projects.Project, error) {
+	p, err := s.projects.Load(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return p, nil
+}
+
+// CleanSubdomain derives a DNS-safe subdomain, falling back to name.
+func CleanSubdomain(subdomain, name string) string {
+	subdomain = strings.TrimSpace(subdomain)
+	if subdomain == "" {
+		subdomain = name
+	}
+	subdomain = strings.ToLower(subdomain)
+	subdomain = strings.ReplaceAll(subdomain, " ", "-")
+	subdomain = strings.ReplaceAll(subdomain, "_", "-")
+	return subdomain
+}
+
+// GeneratePassword returns a random hex string of bytesLen random bytes.
+func GeneratePassword(bytesLen int) string {
+	b := make([]byte, bytesLen)
+	if _, err := rand.Read(b); err != nil {
+		// fallback: not cryptographically strong but avoids empty password
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// rollback collects undo steps and runs them in reverse order.
+type rollback []func()
+
+func (r *rollback) add(fn func()) {
+	*r = append(*r, fn)
+}
+
+func (r rollback) run() {
+	for i := len(r) - 1; i >= 0; i-- {
+		r[i]()
+	}
+}
@@ -0,0 +1,450 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-local-server/internal/app"
+	"go-local-server/internal/config"
+	"go-local-server/internal/events"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/services"
+	"go-local-server/internal/services/servicestest"
+)
+
+// TestMain points the app's folders at a scratch directory, so projects,
+// vhosts and certificates are not written to the real ones.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "golocal-app-")
+	if err != nil {
+		panic(err)
+	}
+	config.ConfigDir = filepath.Join(dir, "config")
+	config.DataDir = filepath.Join(dir, "data")
+	config.StateDir = filepath.Join(dir, "state")
+	config.ConfigFile = filepath.Join(config.ConfigDir, "config.json")
+	config.ProjectsDir = filepath.Join(config.ConfigDir, "projects")
+	config.LogDir = filepath.Join(config.StateDir, "logs")
+	config.CADir = filepath.Join(config.DataDir, "ca")
+	config.CertsDir = filepath.Join(config.DataDir, "certs")
+	config.EnsureDirs()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type testEnv struct {
+	app    *app.Service
+	fake   *servicestest.Manager
+	events *events.Subscription
+	path   string // an empty project folder
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	if err := os.RemoveAll(config.ProjectsDir); err != nil {
+		t.Fatal(err)
+	}
+	config.EnsureDirs()
+
+	cfg := config.DefaultConfig()
+	bus := events.New()
+	t.Cleanup(bus.Close)
+	fake := servicestest.New()
+	fake.Events = bus
+
+	core := app.New(cfg, projects.NewManager(cfg), fake)
+	core.SetEvents(bus)
+	return &testEnv{app: core, fake: fake, events: bus.Subscribe(), path: t.TempDir()}
+}
+
+// calls returns the fake's calls, with the SQL of exec calls cut down to
+// its first two words (e.g. "exec mysql CREATE DATABASE").
+func (e *testEnv) calls() []string {
+	var out []string
+	for _, c := range e.fake.Calls() {
+		if strings.HasPrefix(c, "exec ") {
+			if i := strings.Index(c, " -e "); i >= 0 {
+				words := strings.Fields(c[i+4:])
+				if len(words) > 2 {
+					words = words[:2]
+				}
+				c = c[:strings.Index(c[5:], " ")+5] + " " + strings.Join(words, " ")
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (e *testEnv) wantCalls(t *testing.T, want ...string) {
+	t.Helper()
+	if got := e.calls(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+// kinds drains the events published so far.
+func (e *testEnv) kinds() []string {
+	var kinds []string
+	for {
+		select {
+		case ev := <-e.events.C:
+			kinds = append(kinds, ev.Kind())
+		default:
+			return kinds
+		}
+	}
+}
+
+func vhostExists(id string) bool {
+	_, err := os.Stat(filepath.Join(config.DataDir, "apache", "sites", id+".conf"))
+	return err == nil
+}
+
+// provisioning is what CreateDatabase runs on MySQL.
+var provisioning = []string{
+	"exec mysql CREATE DATABASE",
+	"exec mysql SELECT VERSION();",
+	"exec mysql CREATE USER",
+	"exec mysql ALTER USER",
+	"exec mysql GRANT ALL",
+}
+
+func TestCreateProjectWithMySQLStopped(t *testing.T) {
+	env := newTestEnv(t)
+	res, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Blog",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DatabaseProvisioned {
+		t.Error("database provisioned with MySQL stopped")
+	}
+	if res.Project.Database.DBName != "blog" || res.Project.Database.DBHost != "mysql" {
+		t.Errorf("database config %+v", res.Project.Database)
+	}
+	env.wantCalls(t, "status mysql", "reload")
+	if !vhostExists("blog") {
+		t.Error("no vhost written")
+	}
+	if got := strings.Join(env.kinds(), ","); got != "vhost.reloaded,project.created" {
+		t.Errorf("events %s", got)
+	}
+}
+
+func TestCreateProjectProvisionsDatabase(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	if err := env.fake.Start(ctx, services.MySQL); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Shop",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{DBPassword: "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DatabaseProvisioned {
+		t.Error("database not provisioned with MySQL running")
+	}
+	want := append([]string{"start mysql", "status mysql", "createdb mysql shop shop_user"}, provisioning...)
+	env.wantCalls(t, append(want, "reload")...)
+	if got := strings.Join(env.kinds(), ","); got != "service.started,vhost.reloaded,project.created" {
+		t.Errorf("events %s", got)
+	}
+}
+
+func TestCreateProjectRollsBackWhenReloadFails(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.Fail("reload", "", errors.New("apache config test failed"))
+
+	_, err := env.app.CreateProject(app.ProjectOptions{Name: "Broken", Path: env.path})
+	if err == nil || !strings.Contains(err.Error(), "apache config test failed") {
+		t.Fatalf("err = %v", err)
+	}
+	// The failed reload, then the one after the vhost is removed again.
+	env.wantCalls(t, "reload", "reload")
+	if _, err := env.app.Projects().Load("broken"); err == nil {
+		t.Error("project kept after a failed create")
+	}
+	if vhostExists("broken") {
+		t.Error("vhost kept after a failed create")
+	}
+	if _, err := os.Stat(filepath.Join(env.path, "db_config.php")); !os.IsNotExist(err) {
+		t.Error("db_config.php kept after a failed create")
+	}
+	if kinds := env.kinds(); len(kinds) != 0 {
+		t.Errorf("events %v after a failed create", kinds)
+	}
+}
+
+func TestCreateProjectRollsBackWhenProvisioningFails(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.fake.Start(ctx, services.MySQL)
+	env.fake.Fail("exec", services.MySQL, errors.New("access denied"))
+
+	_, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Denied",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "database setup failed") {
+		t.Fatalf("err = %v", err)
+	}
+	for _, c := range env.calls() {
+		if c == "reload" {
+			t.Error("reloaded after provisioning failed")
+		}
+	}
+	if _, err := env.app.Projects().Load("denied"); err == nil {
+		t.Error("project kept after a failed create")
+	}
+}
+
+func TestDatabaseActionsFollowMySQLStatus(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	if _, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Blog",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.app.ProvisionDatabase("blog"); !errors.Is(err, app.ErrMySQLNotRunning) {
+		t.Errorf("stopped: err = %v, want ErrMySQLNotRunning", err)
+	}
+
+	if err := env.fake.Start(ctx, services.MySQL); err != nil {
+		t.Fatal(err)
+	}
+	res, err := env.app.ProvisionDatabase("blog")
+	if err != nil || !res.DatabaseProvisioned {
+		t.Fatalf("running: %v, %v", res, err)
+	}
+
+	if err := env.fake.Stop(ctx, services.MySQL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.app.RepairDatabase("blog"); !errors.Is(err, app.ErrMySQLNotRunning) {
+		t.Errorf("stopped again: err = %v, want ErrMySQLNotRunning", err)
+	}
+
+	want := []string{"status mysql", "reload", "status mysql", "start mysql", "status mysql", "createdb mysql blog blog_user"}
+	want = append(want, provisioning...)
+	env.wantCalls(t, append(want, "stop mysql", "status mysql")...)
+
+	if _, err := env.app.ProvisionDatabase("missing"); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("unknown project: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.app.CreateProject(app.ProjectOptions{Name: "Old", Path: env.path}); err != nil {
+		t.Fatal(err)
+	}
+	env.kinds()
+
+	if err := env.app.DeleteProject("old"); err != nil {
+		t.Fatal(err)
+	}
+	env.wantCalls(t, "reload", "reload")
+	if vhostExists("old") {
+		t.Error("vhost kept")
+	}
+	if got := strings.Join(env.kinds(), ","); got != "vhost.reloaded,project.deleted" {
+		t.Errorf("events %s", got)
+	}
+	if err := env.app.DeleteProject("old"); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("second delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteProjectRestoresOnReloadFailure(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.app.CreateProject(app.ProjectOptions{Name: "Keep", Path: env.path}); err != nil {
+		t.Fatal(err)
+	}
+	env.fake.Fail("reload", "", errors.New("apache is down"))
+
+	if err := env.app.DeleteProject("keep"); err == nil {
+		t.Fatal("delete succeeded with a failing reload")
+	}
+	if _, err := env.app.Projects().Load("keep"); err != nil {
+		t.Error("project not restored")
+	}
+	if !vhostExists("keep") {
+		t.Error("vhost not restored")
+	}
+}
+
+func TestUpdateProjectRestoresOnReloadFailure(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.app.CreateProject(app.ProjectOptions{Name: "Blog", Path: env.path}); err != nil {
+		t.Fatal(err)
+	}
+	env.fake.Fail("reload", "", errors.New("apache is down"))
+
+	sub := "news"
+	if _, err := env.app.UpdateProject("blog", app.ProjectUpdate{Subdomain: &sub}); err == nil {
+		t.Fatal("update succeeded with a failing reload")
+	}
+	// Creating, the failed reload, the one after the new vhost is removed
+	// and the one after the previous vhost is back.
+	env.wantCalls(t, "reload", "reload", "reload", "reload")
+	p, err := env.app.Projects().Load("blog")
+	if err != nil || p.Domain != "blog.localhost" {
+		t.Errorf("project not restored: %+v, %v", p, err)
+	}
+	vhost, err := os.ReadFile(filepath.Join(config.DataDir, "apache", "sites", "blog.conf"))
+	if err != nil || !strings.Contains(string(vhost), "ServerName blog.localhost") || strings.Contains(string(vhost), "news.") {
+		t.Errorf("vhost not restored (%v):\n%s", err, vhost)
+	}
+}
+
+func TestReloadVhosts(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.app.ReloadVhosts(); err != nil {
+		t.Fatal(err)
+	}
+	env.fake.Fail("reload", "", errors.New("boom"))
+	if err := env.app.ReloadVhosts(); err == nil {
+		t.Error("reload error not returned")
+	}
+	env.wantCalls(t, "reload", "reload")
+}
+
+func TestSetServiceManager(t *testing.T) {
+	env := newTestEnv(t)
+	other := servicestest.New()
+	env.app.SetServiceManager(other)
+	if err := env.app.ReloadVhosts(); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.fake.Calls()) != 0 || strings.Join(other.Calls(), ",") != "reload" {
+		t.Errorf("old manager %v, new manager %v", env.fake.Calls(), other.Calls())
+	}
+}
+
+func readDBConfig(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "db_config.php"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestUpdateProjectRewritesDBConfig(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Blog",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDBConfig(t, env.path); !strings.Contains(got, "'mysql:host=mysql;port=3306;dbname=blog;charset=utf8mb4'") {
+		t.Fatalf("MySQL db_config.php:\n%s", got)
+	}
+
+	if _, err := env.app.UpdateProject("blog", app.ProjectUpdate{
+		Database: &projects.DatabaseConfig{Engine: projects.EnginePostgres},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got := readDBConfig(t, env.path)
+	for _, want := range []string{
+		"'driver'   => 'pgsql'",
+		"'dsn'      => 'pgsql:host=postgres;port=5432;dbname=blog'",
+		"'host'     => 'postgres'",
+		"'port'     => 5432",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PostgreSQL db_config.php lacks %s:\n%s", want, got)
+		}
+	}
+
+	// A failed update puts the previous file back.
+	env.fake.Fail("reload", "", errors.New("boom"))
+	if _, err := env.app.UpdateProject("blog", app.ProjectUpdate{
+		Database: &projects.DatabaseConfig{Engine: projects.EngineMariaDB},
+	}); err == nil {
+		t.Fatal("update succeeded with a failing reload")
+	}
+	if after := readDBConfig(t, env.path); after != got {
+		t.Errorf("db_config.php after a failed update:\n%s", after)
+	}
+}
+
+func TestHandWrittenDBConfigIsKept(t *testing.T) {
+	env := newTestEnv(t)
+	own := "<?php return ['dsn' => 'sqlite::memory:'];\n"
+	if err := os.WriteFile(filepath.Join(env.path, "db_config.php"), []byte(own), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Own",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDBConfig(t, env.path); got != own {
+		t.Errorf("hand-written db_config.php replaced:\n%s", got)
+	}
+}
+
+func TestNativeBackendOnlyRunsMySQL(t *testing.T) {
+	env := newTestEnv(t)
+	cfg := config.DefaultConfig()
+	cfg.ServiceBackend = config.BackendNative
+	env.app = app.New(cfg, projects.NewManager(cfg), env.fake)
+
+	for _, engine := range []string{projects.EngineMariaDB, projects.EnginePostgres} {
+		_, err := env.app.CreateProject(app.ProjectOptions{
+			Name:     "Blog",
+			Path:     env.path,
+			Database: &projects.DatabaseConfig{Engine: engine},
+		})
+		if !errors.Is(err, app.ErrInvalid) {
+			t.Errorf("create with %s: err = %v, want ErrInvalid", engine, err)
+		}
+	}
+
+	res, err := env.app.CreateProject(app.ProjectOptions{
+		Name:     "Blog",
+		Path:     env.path,
+		Database: &projects.DatabaseConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db := res.Project.Database; db.DBHost != "127.0.0.1" || db.DBPort != cfg.MySQLPort {
+		t.Errorf("MySQL at %s:%d", db.DBHost, db.DBPort)
+	}
+	_, err = env.app.UpdateProject("blog", app.ProjectUpdate{
+		Database: &projects.DatabaseConfig{Engine: projects.EnginePostgres},
+	})
+	if !errors.Is(err, app.ErrInvalid) {
+		t.Errorf("update to PostgreSQL: err = %v, want ErrInvalid", err)
+	}
+	if p, _ := env.app.Projects().Load("blog"); p.Database.EngineName() != projects.EngineMySQL {
+		t.Errorf("engine changed to %s", p.Database.EngineName())
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-local-server/internal/backup"
+	"go-local-server/internal/projects"
+)
+
+// databaseEngine gives the backup manager the current service manager's
+// provisioners, which SetServiceManager may swap.
+func (s *Service) databaseEngine(engine string) (backup.Engine, error) {
+	p, err := s.Services().Provisioner(engine)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Backups exposes the snapshot store for listing and deleting.
+func (s *Service) Backups() *backup.Manager {
+	return s.backups
+}
+
+// Snapshot dumps the project's database, or every MySQL database when
+// projectID is empty. A named snapshot is kept until it is deleted.
+func (s *Service) Snapshot(ctx context.Context, name, projectID string) (*backup.Snapshot, error) {
+	opts := backup.Options{Name: strings.TrimSpace(name), Engine: projects.EngineMySQL}
+	if opts.Name != "" {
+		if _, err := s.backups.Get(opts.Name); err == nil {
+			return nil, fmt.Errorf("%w: a snapshot named %q already exists", ErrInvalid, opts.Name)
+		}
+	}
+	if projectID != "" {
+		p, err := s.load(projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasDatabase(p) {
+			return nil, ErrNoDatabase
+		}
+		opts.Project = p.ID
+		opts.Engine = p.Database.EngineName()
+		opts.Databases = []string{p.Database.DBName}
+	}
+	if err := s.databaseRunning(ctx, opts.Engine); err != nil {
+		return nil, err
+	}
+	return s.backups.Create(ctx, opts)
+}
+
+// RestoreSnapshot loads a snapshot back into its engine. Users and grants
+// are not part of a snapshot, so the users of the projects whose databases
+// are restored are provisioned first; on PostgreSQL that also makes them
+// the owners of the restored tables.
+func (s *Service) RestoreSnapshot(ctx context.Context, idOrName string) (*backup.Snapshot, error) {
+	snap, err := s.backups.Get(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.databaseRunning(ctx, snap.Engine); err != nil {
+		return nil, err
+	}
+
+	list, _ := s.projects.List()
+	var errs []error
+	for _, p := range list {
+		if !hasDatabase(p) || p.Database.EngineName() != snap.Engine || !containsString(snap.Databases, p.Database.DBName) {
+			continue
+		}
+		if err := s.Services().CreateDatabase(ctx, p.Database); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("provisioning database users failed: %w", errors.Join(errs...))
+	}
+	return s.backups.Restore(ctx, snap.ID, nil)
+}
+
+// SnapshotBeforeReset is taken before Reset Stack deletes the database
+// volumes: one snapshot per engine in use. Engines without databases are
+// skipped; an engine that is down makes it fail, as its data cannot be
+// read.
+func (s *Service) SnapshotBeforeReset(ctx context.Context) ([]*backup.Snapshot, error) {
+	engines := []string{projects.EngineMySQL}
+	list, _ := s.projects.List()
+	for _, e := range projects.ActiveEngines(list) {
+		if e != projects.EngineMySQL {
+			engines = append(engines, e)
+		}
+	}
+
+	var snaps []*backup.Snapshot
+	for _, e := range engines {
+		if err := s.databaseRunning(ctx, e); err != nil {
+			return snaps, err
+		}
+		snap, err := s.backups.Create(ctx, backup.Options{Engine: e, Reason: "before Reset Stack"})
+		if errors.Is(err, backup.ErrNothingToBackUp) {
+			continue
+		}
+		if err != nil {
+			return snaps, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// snapshotProject takes the automatic snapshot of a project's database
+// before a destructive action. It does nothing when the project has no
+// database or its engine is down.
+func (s *Service) snapshotProject(ctx context.Context, p *projects.Project, reason string) (*backup.Snapshot, error) {
+	if !hasDatabase(p) || s.databaseRunning(ctx, p.Database.EngineName()) != nil {
+		return nil, nil
+	}
+	snap, err := s.backups.Create(ctx, backup.Options{
+		Reason:    reason,
+		Project:   p.ID,
+		Engine:    p.Database.EngineName(),
+		Databases: []string{p.Database.DBName},
+	})
+	if errors.Is(err, backup.ErrNothingToBackUp) {
+		return nil, nil
+	}
+	return snap, err
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/backup"
+	"go-local-server/internal/events"
+	"go-local-server/internal/projects"
+)
+
+// ProvisionDatabase creates the project's database and user with the
+// credentials already stored on the project.
+func (s *Service) ProvisionDatabase(id string) (*ProjectResult, error) {
+	ctx := context.Background()
+	p, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDatabase(p) {
+		return nil, ErrNoDatabase
+	}
+	if err := s.databaseRunning(ctx, p.Database.EngineName()); err != nil {
+		return nil, err
+	}
+
+	if err := s.Services().CreateDatabase(ctx, p.Database); err != nil {
+		return nil, err
+	}
+	return &ProjectResult{Project: p, DatabaseProvisioned: true}, nil
+}
+
+// RepairDatabase is the "Fix DB" action: it generates a password if missing,
+// points the project at its engine's Docker host and re-provisions the
+// database and user. If provisioning fails the previous settings are restored.
+func (s *Service) RepairDatabase(id string) (res *ProjectResult, err error) {
+	ctx := context.Background()
+	p, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDatabase(p) {
+		return nil, ErrNoDatabase
+	}
+	engine := p.Database.EngineName()
+	if err := s.databaseRunning(ctx, engine); err != nil {
+		return nil, err
+	}
+
+	prev := *p
+	if strings.TrimSpace(p.Database.DBPassword) == "" {
+		p.Database.DBPassword = GeneratePassword(8)
+	}
+	p.Database.DBHost, p.Database.DBPort = s.databaseAddress(engine)
+
+	if err := s.projects.Update(p); err != nil {
+		return nil, err
+	}
+
+	if err := s.Services().CreateDatabase(ctx, p.Database); err != nil {
+		_ = s.projects.Save(&prev)
+		return nil, err
+	}
+	// The host and port may have moved with the service backend.
+	if err := s.projects.GenerateDBConfig(p); err != nil {
+		return nil, fmt.Errorf("db_config.php: %w", err)
+	}
+	s.Events().Publish(events.ProjectUpdated{Project: p.ID, Name: p.Name, Domain: p.Domain})
+	return &ProjectResult{Project: p, DatabaseProvisioned: true}, nil
+}
+
+// ImportResult is returned by ImportDatabase.
+type ImportResult struct {
+	*backup.ImportResult
+	// Snapshot holds the database as it was before the import.
+	Snapshot *backup.Snapshot `json:"snapshot,omitempty"`
+}
+
+// ImportDatabase loads a .sql, .sql.gz or .zip dump into the project's
+// database. The database and user are provisioned first and the current
+// contents are snapshotted. On MySQL and MariaDB, statements naming another
+// database are pointed at the project's, and definers become the project
+// user so that views and routines keep working. progress may be nil.
+func (s *Service) ImportDatabase(ctx context.Context, id, file string, progress func(done, total int64)) (*ImportResult, error) {
+	p, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDatabase(p) {
+		return nil, ErrNoDatabase
+	}
+	if info, err := os.Stat(file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	} else if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a folder", ErrInvalid, file)
+	}
+	db := p.Database
+	if err := s.databaseRunning(ctx, db.EngineName()); err != nil {
+		return nil, err
+	}
+
+	if err := s.Services().CreateDatabase(ctx, db); err != nil {
+		return nil, err
+	}
+	snap, err := s.snapshotProject(ctx, p, "before importing "+filepath.Base(file)+" into "+p.Name)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot before import: %w", err)
+	}
+
+	opts := backup.ImportOptions{
+		Engine:   db.EngineName(),
+		Database: db.DBName,
+		Progress: progress,
+	}
+	if db.EngineName() != projects.EnginePostgres {
+		opts.Definer = backup.Account(db.DBUser, "%")
+	}
+	res, err := s.backups.Import(ctx, file, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportResult{ImportResult: res, Snapshot: snap}, nil
+}
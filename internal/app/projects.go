@@ -0,0 +1,375 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/events"
+	"go-local-server/internal/projects"
+)
+
+// Template selects the starter files generated for a new project.
+type Template string
+
+const (
+	TemplateNone   Template = ""
+	TemplateSimple Template = "simple"
+	TemplateMVC    Template = "mvc"
+)
+
+// ParseTemplate accepts the names used by the CLI, API and dialog.
+func ParseTemplate(s string) (Template, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none":
+		return TemplateNone, nil
+	case "simple":
+		return TemplateSimple, nil
+	case "mvc":
+		return TemplateMVC, nil
+	}
+	return TemplateNone, fmt.Errorf("%w: unknown template %q", ErrInvalid, s)
+}
+
+// ProjectOptions describes a project to create or import.
+type ProjectOptions struct {
+	Name         string
+	Subdomain    string // defaults to Name
+	Path         string
+	PHPVersion   string // one of projects.PHPVersions; defaults to projects.DefaultPHPVersion
+	DocumentRoot string
+	Template     Template
+	LiveReload   bool
+
+	// Database is nil for projects without a database. Empty fields are
+	// filled with defaults derived from the subdomain.
+	Database *projects.DatabaseConfig
+}
+
+// ProjectUpdate lists the fields to change on an existing project; nil
+// fields are left alone.
+type ProjectUpdate struct {
+	Name         *string
+	Subdomain    *string
+	PHPVersion   *string
+	DocumentRoot *string
+	LiveReload   *bool
+
+	// Database enables or updates the database. Empty fields keep their
+	// current value (or get defaults if the project had no database).
+	Database       *projects.DatabaseConfig
+	RemoveDatabase bool
+
+	// DNSRecords replaces the project's extra DNS records.
+	DNSRecords *[]projects.DNSRecord
+
+	// Watch replaces the project's live reload watch rules.
+	Watch *projects.WatchRules
+}
+
+// ProjectResult is returned by project operations.
+type ProjectResult struct {
+	Project *projects.Project
+	// DatabaseProvisioned reports whether the database and user were
+	// (re)created. It is false when the project has no database or MySQL
+	// is not running; "db create" / Fix DB can be used later.
+	DatabaseProvisioned bool
+}
+
+// CreateProject persists a new project, generates its starter template and
+// db_config.php, provisions its database when MySQL is running, writes the
+// vhost and reloads Apache. On failure everything done so far is undone,
+// except template files, which are never removed from the user's folder.
+func (s *Service) CreateProject(opts ProjectOptions) (res *ProjectResult, err error) {
+	opts.Name = strings.TrimSpace(opts.Name)
+	if opts.Name == "" || opts.Path == "" {
+		return nil, fmt.Errorf("%w: name and path are required", ErrInvalid)
+	}
+	if st, statErr := os.Stat(opts.Path); statErr != nil || !st.IsDir() {
+		return nil, fmt.Errorf("%w: project path does not exist: %s", ErrInvalid, opts.Path)
+	}
+	if _, loadErr := s.projects.Load(projects.IDFromName(opts.Name)); loadErr == nil {
+		return nil, fmt.Errorf("%w: a project named %q already exists", ErrInvalid, opts.Name)
+	}
+	if opts.PHPVersion == "" {
+		opts.PHPVersion = projects.DefaultPHPVersion
+	}
+	if !projects.ValidPHPVersion(opts.PHPVersion) {
+		return nil, fmt.Errorf("%w: unsupported PHP version %q (use one of %s)", ErrInvalid, opts.PHPVersion, strings.Join(projects.PHPVersions, ", "))
+	}
+
+	var undo rollback
+	defer func() {
+		if err != nil {
+			undo.run()
+		}
+	}()
+
+	subdomain := CleanSubdomain(opts.Subdomain, opts.Name)
+	dbConfig, err := s.databaseConfig(opts.Database, subdomain, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.projects.CreateWithSubdomain(opts.Name, subdomain, opts.Path, opts.PHPVersion, dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	undo.add(func() { _ = s.projects.Delete(p.ID) })
+
+	if opts.Database == nil {
+		// CreateWithSubdomain fills in defaults; a project without a
+		// database should not carry any.
+		p.Database = projects.DatabaseConfig{}
+	}
+	p.DocumentRoot = strings.TrimSpace(opts.DocumentRoot)
+	p.LiveReload = opts.LiveReload
+	if err := s.projects.Save(p); err != nil {
+		return nil, err
+	}
+
+	switch opts.Template {
+	case TemplateMVC:
+		if err := s.projects.CopyMVCTemplate(p); err != nil {
+			return nil, fmt.Errorf("mvc template: %w", err)
+		}
+	case TemplateSimple:
+		if err := s.projects.GeneratePHPIndex(p); err != nil {
+			return nil, fmt.Errorf("php template: %w", err)
+		}
+		if err := s.projects.GeneratePHPInfo(p); err != nil {
+			return nil, fmt.Errorf("php template: %w", err)
+		}
+	}
+
+	if res, err = s.apply(p, &undo); err != nil {
+		return nil, err
+	}
+	s.Events().Publish(events.ProjectCreated{Project: p.ID, Name: p.Name, Domain: p.Domain})
+	return res, nil
+}
+
+// UpdateProject applies upd to the project, rewrites its vhost, provisions
+// the database when MySQL is running and reloads Apache. On failure the
+// previous definition and vhost are restored.
+func (s *Service) UpdateProject(id string, upd ProjectUpdate) (res *ProjectResult, err error) {
+	p, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	prev := *p
+
+	if upd.Name != nil {
+		name := strings.TrimSpace(*upd.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalid)
+		}
+		p.Name = name
+	}
+	if upd.Subdomain != nil {
+		p.Domain = fmt.Sprintf("%s.%s", CleanSubdomain(*upd.Subdomain, p.Name), s.config.Domain)
+	}
+	if upd.PHPVersion != nil && *upd.PHPVersion != "" {
+		if !projects.ValidPHPVersion(*upd.PHPVersion) {
+			return nil, fmt.Errorf("%w: unsupported PHP version %q (use one of %s)", ErrInvalid, *upd.PHPVersion, strings.Join(projects.PHPVersions, ", "))
+		}
+		p.PHPVersion = *upd.PHPVersion
+	}
+	if upd.DocumentRoot != nil {
+		p.DocumentRoot = strings.TrimSpace(*upd.DocumentRoot)
+	}
+	if upd.LiveReload != nil {
+		p.LiveReload = *upd.LiveReload
+	}
+	if upd.DNSRecords != nil {
+		records := append([]projects.DNSRecord(nil), *upd.DNSRecords...)
+		if err := projects.ValidateDNSRecords(records); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		p.DNSRecords = records
+	}
+	if upd.Watch != nil {
+		watch := *upd.Watch
+		if err := watch.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		p.Watch = watch
+	}
+	if upd.RemoveDatabase {
+		p.Database = projects.DatabaseConfig{}
+	} else if upd.Database != nil {
+		subdomain := strings.TrimSuffix(p.Domain, "."+s.config.Domain)
+		db, err := s.databaseConfig(upd.Database, subdomain, &prev.Database)
+		if err != nil {
+			return nil, err
+		}
+		p.Database = db
+	}
+
+	var undo rollback
+	defer func() {
+		if err != nil {
+			undo.run()
+		}
+	}()
+
+	if err := s.projects.Update(p); err != nil {
+		return nil, err
+	}
+	undo.add(func() {
+		_ = s.projects.Save(&prev)
+		_ = s.vhosts.GenerateVhost(&prev)
+		_ = s.Services().Reload(context.Background())
+	})
+
+	if res, err = s.apply(p, &undo); err != nil {
+		return nil, err
+	}
+	s.Events().Publish(events.ProjectUpdated{Project: p.ID, Name: p.Name, Domain: p.Domain})
+	return res, nil
+}
+
+// DeleteProject removes the project definition and its vhost and reloads
+// Apache. Project files and the database are left untouched, but the
+// database is snapshotted first in case it is dropped later.
+func (s *Service) DeleteProject(id string) (err error) {
+	p, err := s.load(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.snapshotProject(context.Background(), p, "before deleting project "+p.Name); err != nil {
+		return fmt.Errorf("snapshot before delete: %w", err)
+	}
+
+	var undo rollback
+	defer func() {
+		if err != nil {
+			undo.run()
+		}
+	}()
+
+	s.vhosts.RemoveVhost(p.ID)
+	undo.add(func() { _ = s.vhosts.GenerateVhost(p) })
+
+	if err := s.projects.Delete(p.ID); err != nil {
+		return err
+	}
+	undo.add(func() { _ = s.projects.Save(p) })
+
+	if err := s.Services().Reload(context.Background()); err != nil {
+		return err
+	}
+	s.Events().Publish(events.ProjectDeleted{Project: p.ID, Name: p.Name})
+	return nil
+}
+
+// apply runs the steps shared by create and update: db_config.php, database
+// provisioning, vhost and reload.
+func (s *Service) apply(p *projects.Project, undo *rollback) (*ProjectResult, error) {
+	ctx := context.Background()
+	dbConfigPath := filepath.Join(p.Path, "db_config.php")
+	prevDBConfig, readErr := os.ReadFile(dbConfigPath)
+	if err := s.projects.GenerateDBConfig(p); err != nil {
+		return nil, fmt.Errorf("db_config.php: %w", err)
+	}
+	switch {
+	case os.IsNotExist(readErr):
+		undo.add(func() { _ = os.Remove(dbConfigPath) })
+	case readErr == nil:
+		undo.add(func() { _ = os.WriteFile(dbConfigPath, prevDBConfig, 0644) })
+	}
+
+	// An engine no project used before is only started by the reload
+	// below; its database is provisioned later with "db create" / Fix DB.
+	res := &ProjectResult{Project: p}
+	if hasDatabase(p) && s.databaseRunning(ctx, p.Database.EngineName()) == nil {
+		if err := s.Services().CreateDatabase(ctx, p.Database); err != nil {
+			return nil, fmt.Errorf("database setup failed: %w", err)
+		}
+		res.DatabaseProvisioned = true
+	}
+
+	if err := s.vhosts.GenerateVhost(p); err != nil {
+		return nil, fmt.Errorf("vhost: %w", err)
+	}
+	undo.add(func() {
+		s.vhosts.RemoveVhost(p.ID)
+		_ = s.Services().Reload(ctx)
+	})
+
+	if err := s.Services().Reload(ctx); err != nil {
+		return nil, fmt.Errorf("reload apache: %w", err)
+	}
+	return res, nil
+}
+
+func hasDatabase(p *projects.Project) bool {
+	return p.Database.DBName != "" && p.Database.DBUser != ""
+}
+
+// databaseConfig fills in blanks in requested, first from existing and then
+// from defaults derived from subdomain. A nil request means no database.
+// Host and port always follow the engine and the service backend.
+func (s *Service) databaseConfig(requested *projects.DatabaseConfig, subdomain string, existing *projects.DatabaseConfig) (projects.DatabaseConfig, error) {
+	if requested == nil {
+		return projects.DatabaseConfig{}, nil
+	}
+
+	engine := requested.Engine
+	if engine == "" && existing != nil {
+		engine = existing.Engine
+	}
+	engine, err := projects.ParseEngine(engine)
+	if err != nil {
+		return projects.DatabaseConfig{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if s.config.Native() && engine != projects.EngineMySQL {
+		return projects.DatabaseConfig{}, fmt.Errorf("%w: %s needs the Docker backend; the native backend only runs MySQL", ErrInvalid, projects.EngineTitle(engine))
+	}
+	name := strings.TrimSpace(requested.DBName)
+	user := strings.TrimSpace(requested.DBUser)
+	pass := requested.DBPassword
+	if existing != nil {
+		if name == "" {
+			name = existing.DBName
+		}
+		if user == "" {
+			user = existing.DBUser
+		}
+		if pass == "" {
+			pass = existing.DBPassword
+		}
+	}
+	if name == "" {
+		name = subdomain
+	}
+	if user == "" {
+		user = subdomain + "_user"
+	}
+	if pass == "" {
+		pass = GeneratePassword(8)
+	}
+
+	host, port := s.databaseAddress(engine)
+	return projects.DatabaseConfig{
+		Engine:     engine,
+		DBName:     name,
+		DBUser:     user,
+		DBPassword: pass,
+		DBHost:     host,
+		DBPort:     port,
+	}, nil
+}
+
+// databaseAddress is where PHP reaches a database engine: its container on
+// the compose network, or MySQL's port when PHP-FPM runs natively (where
+// databaseConfig allows no other engine).
+func (s *Service) databaseAddress(engine string) (host string, port int) {
+	if s.config.Native() {
+		return "127.0.0.1", s.config.MySQLPort
+	}
+	return engine, projects.EnginePort(engine)
+}
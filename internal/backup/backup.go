@@ -0,0 +1,379 @@
+// Package backup dumps the databases of the Docker database services into
+// gzip-compressed snapshots and loads them back. Dumps are streamed through
+// the engines' clients in their containers, so nothing is staged inside a
+// container.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+)
+
+const metaFile = "snapshot.json"
+
+// ErrNotFound is returned for unknown snapshot ids and names.
+var ErrNotFound = errors.New("snapshot not found")
+
+// ErrNothingToBackUp is returned by Create when none of the requested
+// databases exist.
+var ErrNothingToBackUp = errors.New("no databases to back up")
+
+// Engine is the database server snapshots are taken from and loaded into.
+// It is implemented by services.DatabaseProvisioner.
+type Engine interface {
+	Databases(ctx context.Context) ([]string, error)
+	Dump(ctx context.Context, database string, w io.Writer) error
+	Restore(ctx context.Context, database string, r io.Reader) error
+}
+
+// Engines returns the Engine for one of projects.Engines.
+type Engines func(engine string) (Engine, error)
+
+// Snapshot is one backup of databases on one engine: a folder holding
+// <database>.sql.gz per database and snapshot.json.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Engine    string    `json:"engine"`            // one of projects.Engines
+	Name      string    `json:"name,omitempty"`    // named snapshots are never pruned
+	Reason    string    `json:"reason,omitempty"`  // why an automatic snapshot was taken
+	Project   string    `json:"project,omitempty"` // set when only that project's database was dumped
+	Created   time.Time `json:"created"`
+	Databases []string  `json:"databases"`
+	Size      int64     `json:"size"` // compressed bytes
+}
+
+// Automatic reports whether the app took the snapshot by itself.
+func (s *Snapshot) Automatic() bool {
+	return s.Reason != ""
+}
+
+// Options selects what Create dumps.
+type Options struct {
+	Name      string
+	Reason    string
+	Project   string
+	Engine    string   // empty = MySQL
+	Databases []string // empty = every database except the system ones
+}
+
+type Manager struct {
+	config  *config.AppConfig
+	engines Engines
+	dir     string
+
+	mu sync.Mutex
+}
+
+func NewManager(cfg *config.AppConfig, engines Engines) *Manager {
+	return &Manager{config: cfg, engines: engines, dir: Dir()}
+}
+
+// Dir is where snapshots are kept.
+func Dir() string {
+	return filepath.Join(config.ConfigDir, "backups")
+}
+
+// engine resolves an engine name, defaulting to MySQL.
+func (m *Manager) engine(name string) (string, Engine, error) {
+	name, err := projects.ParseEngine(name)
+	if err != nil {
+		return "", nil, err
+	}
+	e, err := m.engines(name)
+	return name, e, err
+}
+
+// Create dumps the selected databases into a new snapshot and prunes old
+// ones. Requested databases that do not exist are skipped.
+func (m *Manager) Create(ctx context.Context, opts Options) (*Snapshot, error) {
+	engineName, engine, err := m.engine(opts.Engine)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := engine.Databases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbs := existing
+	if len(opts.Databases) > 0 {
+		dbs = nil
+		for _, db := range opts.Databases {
+			if containsString(existing, db) {
+				dbs = append(dbs, db)
+			}
+		}
+	}
+	if len(dbs) == 0 {
+		return nil, ErrNothingToBackUp
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := strings.TrimSpace(opts.Name)
+	if name != "" {
+		if _, err := m.find(name); err == nil {
+			return nil, fmt.Errorf("a snapshot named %q already exists", name)
+		}
+	}
+	now := time.Now()
+	snap := &Snapshot{
+		ID:        now.Format("20060102-150405") + "-" + randomHex(2),
+		Engine:    engineName,
+		Name:      name,
+		Reason:    opts.Reason,
+		Project:   opts.Project,
+		Created:   now,
+		Databases: dbs,
+	}
+	dir := filepath.Join(m.dir, snap.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	for _, db := range dbs {
+		n, err := dump(ctx, engine, db, filepath.Join(dir, dumpFile(db)))
+		if err != nil {
+			return nil, err
+		}
+		snap.Size += n
+	}
+
+	// snapshot.json is written last, so List never sees a partial snapshot.
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, metaFile), data, 0600); err != nil {
+		return nil, err
+	}
+	ok = true
+
+	if _, err := m.prune(); err != nil {
+		fmt.Printf("Backup prune: %v\n", err)
+	}
+	return snap, nil
+}
+
+// dump streams one database's dump into a gzip file and returns its size.
+func dump(ctx context.Context, engine Engine, db, path string) (int64, error) {
+	tmp := path + ".part"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	gz := gzip.NewWriter(f)
+	err = engine.Dump(ctx, db, gz)
+	if cerr := gz.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Restore loads the snapshot's dumps, or only the listed databases, back
+// into the engine they were taken from. The dumps replace the objects they
+// contain.
+func (m *Manager) Restore(ctx context.Context, idOrName string, databases []string) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap, err := m.find(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	_, engine, err := m.engine(snap.Engine)
+	if err != nil {
+		return nil, err
+	}
+	dbs := snap.Databases
+	if len(databases) > 0 {
+		for _, db := range databases {
+			if !containsString(snap.Databases, db) {
+				return nil, fmt.Errorf("snapshot %s does not contain database %q", snap.ID, db)
+			}
+		}
+		dbs = databases
+	}
+
+	for _, db := range dbs {
+		if err := load(ctx, engine, db, filepath.Join(m.dir, snap.ID, dumpFile(db))); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", db, err)
+		}
+	}
+	return snap, nil
+}
+
+func load(ctx context.Context, engine Engine, db, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return engine.Restore(ctx, db, gz)
+}
+
+// List returns the complete snapshots, newest first.
+func (m *Manager) List() ([]*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list()
+}
+
+// Get finds a snapshot by id or name.
+func (m *Manager) Get(idOrName string) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.find(idOrName)
+}
+
+// Delete removes a snapshot.
+func (m *Manager) Delete(idOrName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap, err := m.find(idOrName)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(m.dir, snap.ID))
+}
+
+// Prune applies the retention settings and returns the ids it removed.
+func (m *Manager) Prune() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.prune()
+}
+
+// prune removes unnamed snapshots beyond the newest BackupKeep and those
+// older than BackupKeepDays. Named snapshots are kept until deleted.
+func (m *Manager) prune() ([]string, error) {
+	all, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	kept := 0
+	for _, s := range all {
+		if s.Name != "" {
+			continue
+		}
+		tooMany := m.config.BackupKeep > 0 && kept >= m.config.BackupKeep
+		tooOld := m.config.BackupKeepDays > 0 && time.Since(s.Created) > time.Duration(m.config.BackupKeepDays)*24*time.Hour
+		if !tooMany && !tooOld {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(m.dir, s.ID)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, s.ID)
+	}
+	return removed, nil
+}
+
+func (m *Manager) list() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []*Snapshot
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.dir, e.Name(), metaFile))
+		if err != nil {
+			continue
+		}
+		var s Snapshot
+		if err := json.Unmarshal(data, &s); err != nil || s.ID != e.Name() {
+			continue
+		}
+		if s.Engine == "" {
+			// Snapshots from before other engines were supported.
+			s.Engine = projects.EngineMySQL
+		}
+		out = append(out, &s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out, nil
+}
+
+func (m *Manager) find(idOrName string) (*Snapshot, error) {
+	all, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range all {
+		if s.ID == idOrName || (s.Name != "" && s.Name == idOrName) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, idOrName)
+}
+
+// dumpFile names a database's dump inside a snapshot folder. Database names
+// may contain characters that are not safe in file names.
+func dumpFile(db string) string {
+	return strings.NewReplacer("/", "%2F", `\`, "%5C", ":", "%3A").Replace(db) + ".sql.gz"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(nBytes int) string {
+	b := make([]byte, nBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
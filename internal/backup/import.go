@@ -0,0 +1,359 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/projects"
+)
+
+// progressInterval throttles ImportOptions.Progress.
+const progressInterval = 200 * time.Millisecond
+
+// maxStatementLine is the longest line the rewriter looks at. Longer lines
+// are extended INSERTs and similar data, which are passed through untouched.
+const maxStatementLine = 1 << 20
+
+// ImportOptions controls Import.
+type ImportOptions struct {
+	// Engine is the engine the dump is loaded into; empty = MySQL.
+	Engine string
+	// Database is the database the dump is loaded into. It is created when
+	// missing.
+	Database string
+	// Definer replaces the DEFINER of views, triggers, routines and events,
+	// whose original accounts rarely exist locally. Empty leaves them as
+	// they are.
+	Definer string
+	// Progress, if set, is called with the bytes read so far and the total.
+	// For .zip files both count bytes of the archive entry, otherwise bytes
+	// of the file on disk.
+	Progress func(done, total int64)
+}
+
+// ImportResult describes a finished import.
+type ImportResult struct {
+	Database string `json:"database"`
+	// Source is the database named by the dump, if any. Its statements were
+	// rewritten to Database.
+	Source   string `json:"source,omitempty"`
+	Entry    string `json:"entry,omitempty"` // the file used from a .zip
+	Bytes    int64  `json:"bytes"`           // SQL bytes sent to MySQL
+	Definers int    `json:"definers"`        // DEFINER clauses rewritten
+}
+
+// Import streams a .sql, .sql.gz or .zip dump into a database. The format
+// is detected from the content, not the name. MySQL and MariaDB dumps are
+// rewritten as described on ImportOptions; PostgreSQL dumps are loaded as
+// they are. Cancelling ctx stops the import; statements already run are not
+// rolled back.
+func (m *Manager) Import(ctx context.Context, file string, opts ImportOptions) (*ImportResult, error) {
+	if opts.Database == "" {
+		return nil, errors.New("import: no target database")
+	}
+	engineName, engine, err := m.engine(opts.Engine)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ImportResult{Database: opts.Database}
+	src, err := openDump(f, info.Size(), res)
+	if err != nil {
+		return nil, fmt.Errorf("import %s: %w", path.Base(file), err)
+	}
+	defer src.Close()
+
+	pr := &progressReader{ctx: ctx, r: src.counted, total: src.total, fn: opts.Progress}
+	var sql io.Reader = pr
+	if src.gz {
+		gz, err := gzip.NewReader(pr)
+		if err != nil {
+			return nil, fmt.Errorf("import %s: %w", path.Base(file), err)
+		}
+		defer gz.Close()
+		sql = gz
+	}
+	rw := newRewriter(sql, opts.Database, opts.Definer)
+	rw.passThrough = engineName == projects.EnginePostgres
+
+	// The rewriter runs in its own goroutine, so that a dump naming several
+	// databases can stop the stream as soon as the second one shows up.
+	piped, w := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n, err := rw.writeTo(w)
+		res.Bytes = n
+		w.CloseWithError(err)
+	}()
+
+	err = engine.Restore(ctx, opts.Database, piped)
+	// Unblock the rewriter if the client stopped reading early.
+	piped.CloseWithError(errors.New("database client exited"))
+	wg.Wait()
+
+	res.Source, res.Definers = rw.source, rw.definers
+	if rw.err != nil {
+		return nil, rw.err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	pr.report(true)
+	return res, nil
+}
+
+// dumpSource is a dump file opened for reading. counted is where progress
+// is measured: the file itself, or the zip entry for archives.
+type dumpSource struct {
+	counted io.Reader
+	total   int64
+	gz      bool
+	closer  io.Closer
+}
+
+func (d *dumpSource) Close() error {
+	if d.closer != nil {
+		return d.closer.Close()
+	}
+	return nil
+}
+
+// openDump sniffs the file: gzip and zip are recognised by their magic
+// bytes, anything else is taken to be plain SQL.
+func openDump(f *os.File, size int64, res *ImportResult) (*dumpSource, error) {
+	var magic [4]byte
+	n, _ := io.ReadFull(f, magic[:])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch {
+	case n >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return &dumpSource{counted: f, total: size, gz: true}, nil
+	case n == 4 && string(magic[:]) == "PK\x03\x04":
+		return openZipDump(f, size, res)
+	}
+	return &dumpSource{counted: f, total: size}, nil
+}
+
+// openZipDump picks the largest .sql or .sql.gz file in the archive.
+func openZipDump(f *os.File, size int64, res *ImportResult) (*dumpSource, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, err
+	}
+	var best *zip.File
+	for _, zf := range zr.File {
+		name := strings.ToLower(zf.Name)
+		if strings.HasPrefix(path.Base(name), ".") || strings.HasPrefix(name, "__macosx/") {
+			continue
+		}
+		if !strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".sql.gz") {
+			continue
+		}
+		if best == nil || zf.UncompressedSize64 > best.UncompressedSize64 {
+			best = zf
+		}
+	}
+	if best == nil {
+		return nil, errors.New("the archive contains no .sql or .sql.gz file")
+	}
+	rc, err := best.Open()
+	if err != nil {
+		return nil, err
+	}
+	res.Entry = best.Name
+	return &dumpSource{
+		counted: rc,
+		total:   int64(best.UncompressedSize64),
+		gz:      strings.HasSuffix(strings.ToLower(best.Name), ".gz"),
+		closer:  rc,
+	}, nil
+}
+
+// progressReader counts bytes, reports them at most every
+// progressInterval and fails reads once ctx is cancelled.
+type progressReader struct {
+	ctx   context.Context
+	r     io.Reader
+	total int64
+	fn    func(done, total int64)
+
+	done int64
+	last time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	p.report(false)
+	return n, err
+}
+
+func (p *progressReader) report(final bool) {
+	if p.fn == nil {
+		return
+	}
+	if !final && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+	done := p.done
+	if final {
+		done = p.total
+	}
+	p.fn(done, p.total)
+}
+
+var (
+	// definerPattern matches DEFINER=user@host with the parts quoted in
+	// any of the ways mysqldump and phpMyAdmin write them.
+	definerPattern = regexp.MustCompile("(?i)DEFINER\\s*=\\s*(?:`(?:[^`]|``)*`|'(?:[^'\\\\]|\\\\.)*'|[^\\s@*/]+)\\s*@\\s*(?:`(?:[^`]|``)*`|'(?:[^'\\\\]|\\\\.)*'|[^\\s*/]+)")
+	// databasePattern matches the statements that name a database:
+	// CREATE DATABASE, DROP DATABASE and USE, including the versioned
+	// comments mysqldump wraps parts of them in.
+	databasePattern = regexp.MustCompile("(?i)^(\\s*(?:/\\*!\\d*\\s*)?(?:CREATE\\s+(?:DATABASE|SCHEMA)(?:\\s+/\\*!\\d*\\s*IF\\s+NOT\\s+EXISTS\\s*\\*/|\\s+IF\\s+NOT\\s+EXISTS)?|DROP\\s+(?:DATABASE|SCHEMA)(?:\\s+IF\\s+EXISTS)?|USE)\\s+)(`(?:[^`]|``)*`|[A-Za-z0-9_$]+)")
+)
+
+// rewriter copies a dump line by line, pointing its database statements at
+// the target database and replacing definers.
+type rewriter struct {
+	r           *bufio.Reader
+	target      string
+	definer     string
+	passThrough bool // copy without rewriting
+
+	source   string // first database the dump named
+	definers int
+	err      error // set when the dump cannot be loaded into one database
+}
+
+func newRewriter(r io.Reader, target, definer string) *rewriter {
+	return &rewriter{r: bufio.NewReaderSize(r, maxStatementLine), target: target, definer: definer}
+}
+
+func (rw *rewriter) writeTo(w io.Writer) (int64, error) {
+	var total int64
+	long := false // inside a line longer than the buffer
+	for {
+		line, err := rw.r.ReadSlice('\n')
+		if len(line) > 0 {
+			out := line
+			if !long && !rw.passThrough && err != bufio.ErrBufferFull {
+				out = rw.rewrite(line)
+				if rw.err != nil {
+					return total, rw.err
+				}
+			}
+			n, werr := w.Write(out)
+			total += int64(n)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		switch err {
+		case nil:
+			long = false
+		case bufio.ErrBufferFull:
+			long = true
+		case io.EOF:
+			return total, nil
+		default:
+			return total, err
+		}
+	}
+}
+
+func (rw *rewriter) rewrite(line []byte) []byte {
+	// Data lines are by far the most common; leave them alone cheaply.
+	if bytes.HasPrefix(line, []byte("INSERT ")) || bytes.HasPrefix(line, []byte("(")) {
+		return line
+	}
+	s := string(line)
+	changed := false
+
+	if loc := databasePattern.FindStringSubmatchIndex(s); loc != nil {
+		name := unquoteIdent(s[loc[4]:loc[5]])
+		switch {
+		case rw.source == "":
+			rw.source = name
+		case name != rw.source:
+			rw.err = fmt.Errorf("the dump contains more than one database (%s, %s); import each one separately", rw.source, name)
+			return nil
+		}
+		// Import has already created the target. Dropping it would also
+		// drop tables the dump does not contain, and creating it again
+		// fails without IF NOT EXISTS, so only USE is kept.
+		if !strings.HasSuffix(strings.ToUpper(strings.TrimSpace(s[loc[2]:loc[3]])), "USE") {
+			return []byte("-- " + s)
+		}
+		if name != rw.target {
+			s = s[:loc[4]] + quoteIdent(rw.target) + s[loc[5]:]
+			changed = true
+		}
+	} else if rw.source != "" && rw.source != rw.target {
+		// Views and triggers qualify tables with the database name.
+		qualified := quoteIdent(rw.source) + "."
+		if strings.Contains(s, qualified) {
+			s = strings.ReplaceAll(s, qualified, quoteIdent(rw.target)+".")
+			changed = true
+		}
+	}
+
+	if rw.definer != "" && definerPattern.MatchString(s) {
+		s = definerPattern.ReplaceAllStringFunc(s, func(string) string {
+			rw.definers++
+			return "DEFINER=" + rw.definer
+		})
+		changed = true
+	}
+	if !changed {
+		return line
+	}
+	return []byte(s)
+}
+
+// Account formats user@host for use as a definer.
+func Account(user, host string) string {
+	return quoteIdent(user) + "@" + quoteIdent(host)
+}
+
+func quoteIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && s[0] == '`' && s[len(s)-1] == '`' {
+		return strings.ReplaceAll(s[1:len(s)-1], "``", "`")
+	}
+	return s
+}
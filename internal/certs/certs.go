@@ -0,0 +1,284 @@
+// Package certs manages the local development CA and the per-project TLS
+// certificates Apache serves on the HTTPS port.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-local-server/internal/config"
+)
+
+const (
+	caCertFile = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour // browsers reject longer-lived leaves
+	renewBefore  = 30 * 24 * time.Hour
+)
+
+// Authority is the local root CA. Its key never leaves CADir; only leaf
+// certificates are written to CertsDir, which is mounted into Apache.
+type Authority struct {
+	caDir    string
+	certsDir string
+	cert     *x509.Certificate
+	certPEM  []byte
+	key      crypto.Signer
+	mu       sync.Mutex
+}
+
+var (
+	defaultMu sync.Mutex
+	defaultCA *Authority
+)
+
+// Default returns the app's CA, creating it on first use.
+func Default() (*Authority, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultCA != nil {
+		return defaultCA, nil
+	}
+	ca, err := LoadOrCreate(config.CADir, config.CertsDir)
+	if err != nil {
+		return nil, err
+	}
+	defaultCA = ca
+	return ca, nil
+}
+
+// LoadOrCreate loads the CA from caDir, generating a new one if none exists.
+func LoadOrCreate(caDir, certsDir string) (*Authority, error) {
+	a := &Authority{caDir: caDir, certsDir: certsDir}
+
+	certPEM, err := os.ReadFile(filepath.Join(caDir, caCertFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if err := a.create(); err != nil {
+			return nil, fmt.Errorf("create CA: %w", err)
+		}
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(caDir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	if a.cert, err = parseCert(certPEM); err != nil {
+		return nil, fmt.Errorf("%s: %w", caCertFile, err)
+	}
+	if a.key, err = parseKey(keyPEM); err != nil {
+		return nil, fmt.Errorf("%s: %w", caKeyFile, err)
+	}
+	a.certPEM = certPEM
+	return a, nil
+}
+
+func (a *Authority) create() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	owner := "unknown"
+	if u, err := user.Current(); err == nil {
+		owner = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		owner += "@" + host
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"GoLocalServer development CA"},
+			OrganizationalUnit: []string{owner},
+			CommonName:         "GoLocalServer " + owner,
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(a.caDir, 0700); err != nil {
+		return err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(a.caDir, caKeyFile), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(a.caDir, caCertFile), certPEM, 0644); err != nil {
+		return err
+	}
+
+	a.cert, _ = x509.ParseCertificate(der)
+	a.certPEM = certPEM
+	a.key = key
+	return nil
+}
+
+// CertPath is the CA certificate users import to trust local sites.
+func (a *Authority) CertPath() string {
+	return filepath.Join(a.caDir, caCertFile)
+}
+
+// Export writes the CA certificate (never the key) to dst.
+func (a *Authority) Export(dst string) error {
+	return os.WriteFile(dst, a.certPEM, 0644)
+}
+
+// LeafFiles returns the file names of a project's certificate and key inside
+// CertsDir.
+func LeafFiles(projectID string) (cert, key string) {
+	return projectID + ".pem", projectID + "-key.pem"
+}
+
+// Issue makes sure CertsDir holds a certificate for domain and *.domain under
+// the project's id, signed by this CA. An existing certificate is kept while
+// it still matches and is not close to expiry.
+func (a *Authority) Issue(projectID, domain string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	certName, keyName := LeafFiles(projectID)
+	certPath := filepath.Join(a.certsDir, certName)
+	keyPath := filepath.Join(a.certsDir, keyName)
+	names := []string{domain, "*." + domain}
+
+	if a.current(certPath, keyPath, names) {
+		return nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"GoLocalServer development certificate"},
+			CommonName:   domain,
+		},
+		DNSNames:    names,
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(a.certsDir, 0755); err != nil {
+		return err
+	}
+	// Apache in the container runs as another user and must read the key.
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// Remove deletes a project's certificate and key.
+func (a *Authority) Remove(projectID string) {
+	certName, keyName := LeafFiles(projectID)
+	os.Remove(filepath.Join(a.certsDir, certName))
+	os.Remove(filepath.Join(a.certsDir, keyName))
+}
+
+// current reports whether the certificate at certPath is usable as is.
+func (a *Authority) current(certPath, keyPath string, names []string) bool {
+	if _, err := os.Stat(keyPath); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return false
+	}
+	cert, err := parseCert(data)
+	if err != nil {
+		return false
+	}
+	if time.Until(cert.NotAfter) < renewBefore || cert.CheckSignatureFrom(a.cert) != nil {
+		return false
+	}
+	if len(cert.DNSNames) != len(names) {
+		return false
+	}
+	for i := range names {
+		if cert.DNSNames[i] != names[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return signer, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
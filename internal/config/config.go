@@ -2,40 +2,110 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/platform"
 )
 
 const AppName = "GoLocalServer"
 const AppVersion = "1.0.0"
 
 var ConfigDir string
+var DataDir string
+var StateDir string
 var ConfigFile string
 var ProjectsDir string
 var LogDir string
+var APITokenFile string
+var APISocket string
+var CADir string
+var CertsDir string
 
 func init() {
-	home, _ := os.UserHomeDir()
-	ConfigDir = filepath.Join(home, "Library", "Application Support", "GoLocalServer")
+	// macOS: everything under ~/Library/Application Support/GoLocalServer.
+	// Linux: XDG config, data and state directories.
+	dirs := platform.UserDirs(AppName)
+	ConfigDir = dirs.Config
+	DataDir = dirs.Data
+	StateDir = dirs.State
 	ProjectsDir = filepath.Join(ConfigDir, "projects")
-	LogDir = filepath.Join(ConfigDir, "logs")
+	LogDir = filepath.Join(StateDir, "logs")
 	ConfigFile = filepath.Join(ConfigDir, "config.json")
+	APITokenFile = filepath.Join(ConfigDir, "api-token")
+	APISocket = filepath.Join(ConfigDir, "api.sock")
+	CADir = filepath.Join(DataDir, "ca")
+	CertsDir = filepath.Join(DataDir, "certs")
 }
 
+// Service backends: the Docker Compose stack, or the web server, PHP-FPM
+// and MySQL run natively as child processes.
+const (
+	BackendDocker = "docker"
+	BackendNative = "native"
+)
+
+// Web servers of the native backend.
+const (
+	WebServerApache = "apache"
+	WebServerNginx  = "nginx"
+)
+
 type AppConfig struct {
-	NginxPath       string `json:"nginx_path"`
-	PHPPath         string `json:"php_path"`
-	MySQLPath       string `json:"mysql_path"`
-	DNSPort         int    `json:"dns_port"`
-	HTTPPort        int    `json:"http_port"`
-	HTTPSPort       int    `json:"https_port"`
-	MySQLPort       int    `json:"mysql_port"`
-	Domain          string `json:"domain"`
-	PreferredEditor string `json:"preferred_editor"` // Cursor, Windsurf, or VSCode
+	ServiceBackend  string   `json:"service_backend"` // docker (default) or native
+	WebServer       string   `json:"web_server"`      // native backend: apache (default) or nginx
+	ApachePath      string   `json:"apache_path"`
+	NginxPath       string   `json:"nginx_path"`
+	PHPPath         string   `json:"php_path"`
+	MySQLPath       string   `json:"mysql_path"`
+	DNSPort         int      `json:"dns_port"`
+	DNSUpstreams    []string `json:"dns_upstreams"` // host[:port]; empty = system resolvers
+	DNSAnswer       string   `json:"dns_answer"`    // loopback (default), lan, or a fixed IP
+	HTTPPort        int      `json:"http_port"`
+	HTTPSPort       int      `json:"https_port"`
+	MySQLPort       int      `json:"mysql_port"`
+	Domain          string   `json:"domain"`
+	PreferredEditor string   `json:"preferred_editor"` // Cursor, Windsurf, or VSCode
+	APIPort         int      `json:"api_port"`         // 0 = Unix socket only
+
+	// Docker stack, rendered into docker-compose.yml by pkg/compose.
+	PhpMyAdminPort    int      `json:"phpmyadmin_port"`
+	MySQLRootPassword string   `json:"mysql_root_password"` // only applied when the MySQL volume is created
+	PHPImage          string   `json:"php_image"`           // PHP-FPM base image; {version} is replaced, e.g. php:{version}-fpm
+	MySQLImage        string   `json:"mysql_image"`
+	PhpMyAdminImage   string   `json:"phpmyadmin_image"`
+	OptionalServices  []string `json:"optional_services"` // phpmyadmin and registry service IDs, e.g. redis
+
+	// Also accept LiveReload browser extensions on livereload.ProtocolPort.
+	LiveReloadProtocol bool `json:"livereload_protocol"`
+
+	// SMTP port of the mail catcher that PHP's mail() delivers to; 0 = off.
+	MailPort int `json:"mail_port"`
+
+	// Database snapshots: unnamed ones beyond the newest BackupKeep, or older
+	// than BackupKeepDays, are pruned. 0 = no limit.
+	BackupKeep     int `json:"backup_keep"`
+	BackupKeepDays int `json:"backup_keep_days"`
+
+	// Database engines besides MySQL, started only while a project uses
+	// them. They share MySQLRootPassword; a port of 0 is not published.
+	MariaDBImage  string `json:"mariadb_image"`
+	MariaDBPort   int    `json:"mariadb_port"`
+	PostgresImage string `json:"postgres_image"`
+	PostgresPort  int    `json:"postgres_port"`
+
+	// Host ports of the optional registry services, by port name (e.g.
+	// "redis", "minio-console"), replacing their defaults. 0 = not published.
+	ServicePorts map[string]int `json:"service_ports,omitempty"`
 }
 
 func DefaultConfig() *AppConfig {
 	return &AppConfig{
+		ServiceBackend:  BackendDocker,
+		WebServer:       WebServerApache,
+		ApachePath:      "/opt/homebrew/opt/httpd/bin/httpd",
 		NginxPath:       "/opt/homebrew/opt/nginx/bin/nginx",
 		PHPPath:         "/opt/homebrew/opt/php/sbin/php-fpm",
 		MySQLPath:       "/opt/homebrew/opt/mysql/bin/mysqld",
@@ -45,6 +115,24 @@ func DefaultConfig() *AppConfig {
 		MySQLPort:       3306,
 		Domain:          "localhost",
 		PreferredEditor: "VSCode",
+		APIPort:         35731,
+
+		PhpMyAdminPort:    8081,
+		MySQLRootPassword: "root",
+		PHPImage:          "php:{version}-fpm",
+		MySQLImage:        "mysql:8.0",
+		PhpMyAdminImage:   "phpmyadmin/phpmyadmin:latest",
+		OptionalServices:  []string{"phpmyadmin"},
+
+		MailPort: 1025,
+
+		BackupKeep:     10,
+		BackupKeepDays: 30,
+
+		MariaDBImage:  "mariadb:11.4",
+		MariaDBPort:   3307,
+		PostgresImage: "postgres:16",
+		PostgresPort:  5432,
 	}
 }
 
@@ -62,6 +150,63 @@ func (c *AppConfig) Save() error {
 	return os.WriteFile(ConfigFile, data, 0644)
 }
 
+// Native reports whether the services run natively instead of in Docker.
+func (c *AppConfig) Native() bool {
+	return c.ServiceBackend == BackendNative
+}
+
+// Nginx reports whether the native backend serves sites with nginx rather
+// than Apache.
+func (c *AppConfig) Nginx() bool {
+	return c.WebServer == WebServerNginx
+}
+
+// ServiceEnabled reports whether an optional Docker service is switched on.
+func (c *AppConfig) ServiceEnabled(name string) bool {
+	for _, s := range c.OptionalServices {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PHPImageFor returns the PHP-FPM base image for a PHP version.
+func (c *AppConfig) PHPImageFor(version string) string {
+	if !strings.Contains(c.PHPImage, "{version}") {
+		// A fixed image cannot serve several versions.
+		return "php:" + version + "-fpm"
+	}
+	return strings.ReplaceAll(c.PHPImage, "{version}", version)
+}
+
+// SiteURL is the browser URL of a local domain, including the HTTP port when
+// it is not 80.
+func (c *AppConfig) SiteURL(domain string) string {
+	if c.HTTPPort == 0 || c.HTTPPort == 80 {
+		return "http://" + domain
+	}
+	return fmt.Sprintf("http://%s:%d", domain, c.HTTPPort)
+}
+
+// PhpMyAdminURL is where the phpMyAdmin container is published.
+func (c *AppConfig) PhpMyAdminURL() string {
+	return fmt.Sprintf("http://localhost:%d", c.PhpMyAdminPort)
+}
+
+// Editor returns how to launch the preferred editor on this platform.
+func (c *AppConfig) Editor() platform.Editor {
+	appName, _ := c.GetEditorInfo()
+	switch c.PreferredEditor {
+	case "Cursor":
+		return platform.Editor{AppName: appName, Command: "cursor"}
+	case "Windsurf":
+		return platform.Editor{AppName: appName, Command: "windsurf"}
+	default:
+		return platform.Editor{AppName: appName, Command: "code"}
+	}
+}
+
 func (c *AppConfig) GetEditorInfo() (appName, displayName string) {
 	switch c.PreferredEditor {
 	case "Cursor":
@@ -77,6 +222,8 @@ func (c *AppConfig) GetEditorInfo() (appName, displayName string) {
 
 func EnsureDirs() {
 	os.MkdirAll(ConfigDir, 0755)
+	os.MkdirAll(DataDir, 0755)
 	os.MkdirAll(ProjectsDir, 0755)
 	os.MkdirAll(LogDir, 0755)
+	os.MkdirAll(CertsDir, 0755)
 }
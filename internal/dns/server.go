@@ -1,24 +1,65 @@
 package dns
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/miekg/dns"
 	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
 )
 
+// fallbackUpstreams are used when neither the settings nor resolv.conf name
+// a resolver.
+var fallbackUpstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// zoneTTL is how long the project list and answer addresses are cached
+// between queries.
+const zoneTTL = 5 * time.Second
+
+// Values of AppConfig.DNSAnswer; anything else is taken as a fixed IP.
+const (
+	AnswerLoopback = "loopback"
+	AnswerLAN      = "lan" // the machine's address, for other devices on the network
+)
+
+const defaultTTL = 300
+
+// Server answers the local domain for project names and forwards everything
+// else to the upstream resolvers for clients on this machine. It listens on
+// UDP and TCP.
 type Server struct {
-	config  *config.AppConfig
-	server  *dns.Server
+	config   *config.AppConfig
+	projects *projects.Manager
+
+	mu      sync.Mutex
+	udp     *dns.Server
+	tcp     *dns.Server
 	running bool
+	addr    atomic.Value // string; read by handlers without taking mu
+
+	zoneMu   sync.Mutex
+	zone     *zone
+	loadedAt time.Time
 }
 
-func NewServer(cfg *config.AppConfig) *Server {
+// zone is a snapshot of what the server answers for the local domain.
+type zone struct {
+	projects []*projects.Project
+	v4, v6   net.IP // default answer; nil means NODATA for that type
+}
+
+func NewServer(cfg *config.AppConfig, pm *projects.Manager) *Server {
 	return &Server{
-		config: cfg,
+		config:   cfg,
+		projects: pm,
 	}
 }
 
@@ -30,13 +71,42 @@ func (s *Server) handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
 		}
 	}()
 
+	if len(r.Question) != 1 {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeFormatError)
+		w.WriteMsg(m)
+		return
+	}
+	q := r.Question[0]
+
+	if !s.isLocalDomain(q.Name) {
+		// Listening on the LAN must not make this an open resolver: other
+		// devices only get the local zone.
+		if !isLoopback(w.RemoteAddr()) {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			w.WriteMsg(m)
+			return
+		}
+		s.forward(w, r)
+		return
+	}
+
 	m := new(dns.Msg)
 	m.SetReply(r)
-	m.Compress = false
+	m.Authoritative = true
 
-	for _, q := range r.Question {
-		if s.isLocalDomain(q.Name) {
-			s.addLocalRecord(m, q)
+	z := s.currentZone()
+	name := strings.ToLower(strings.TrimSuffix(q.Name, "."))
+	p := z.project(name)
+	if p == nil && name != s.config.Domain {
+		m.Rcode = dns.RcodeNameError
+		m.Ns = append(m.Ns, s.soa())
+	} else {
+		s.addLocalRecord(m, q, z, p)
+		if len(m.Answer) == 0 {
+			// NODATA: the name exists, the type does not
+			m.Ns = append(m.Ns, s.soa())
 		}
 	}
 
@@ -45,96 +115,404 @@ func (s *Server) handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
+// forward relays a query to the first upstream that answers, over the same
+// transport the client used.
+func (s *Server) forward(w dns.ResponseWriter, r *dns.Msg) {
+	network := "udp"
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		network = "tcp"
+	}
+	client := &dns.Client{Net: network, Timeout: 2 * time.Second}
+
+	var lastErr error
+	for _, upstream := range s.upstreams() {
+		resp, _, err := client.Exchange(r, upstream)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := w.WriteMsg(resp); err != nil {
+			fmt.Printf("DNS write error: %v\n", err)
+		}
+		return
+	}
+
+	fmt.Printf("DNS forward failed for %s: %v\n", r.Question[0].Name, lastErr)
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+	w.WriteMsg(m)
+}
+
+// isLoopback reports whether a query came from this machine.
+func isLoopback(addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.IsLoopback()
+	case *net.TCPAddr:
+		return a.IP.IsLoopback()
+	}
+	return false
+}
+
+// upstreams returns host:port resolvers from the settings, falling back to
+// the system's resolvers and then to public ones.
+func (s *Server) upstreams() []string {
+	var servers []string
+	for _, u := range s.config.DNSUpstreams {
+		if u = strings.TrimSpace(u); u != "" {
+			servers = append(servers, withPort(u))
+		}
+	}
+	if len(servers) > 0 {
+		return servers
+	}
+
+	// With systemd-resolved, /etc/resolv.conf names its stub, which routes
+	// the local domain back here once the resolver is installed; ask the
+	// real upstreams it uses instead.
+	for _, file := range []string{"/run/systemd/resolve/resolv.conf", "/etc/resolv.conf"} {
+		cc, err := dns.ClientConfigFromFile(file)
+		if err != nil {
+			continue
+		}
+		for _, host := range cc.Servers {
+			addr := net.JoinHostPort(host, cc.Port)
+			if !s.isSelf(addr) {
+				servers = append(servers, addr)
+			}
+		}
+		if len(servers) > 0 {
+			return servers
+		}
+	}
+	return fallbackUpstreams
+}
+
+// isSelf reports whether addr is this server, so a resolv.conf that points
+// at it does not make queries loop.
+func (s *Server) isSelf(addr string) bool {
+	own, _ := s.addr.Load().(string)
+	ownHost, ownPort, err := net.SplitHostPort(own)
+	if err != nil {
+		return false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || port != ownPort {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == ownHost || ip.IsLoopback() || net.ParseIP(ownHost).IsUnspecified()
+}
+
+func withPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
+
 func (s *Server) isLocalDomain(name string) bool {
 	domain := strings.ToLower(strings.TrimSuffix(name, "."))
 	return strings.HasSuffix(domain, "."+s.config.Domain) || domain == s.config.Domain
 }
 
-func (s *Server) addLocalRecord(m *dns.Msg, q dns.Question) {
+// project returns the project whose domain is name or a parent of it
+// (vhosts carry ServerAlias *.domain), preferring the longest match.
+func (z *zone) project(name string) *projects.Project {
+	var best *projects.Project
+	for _, p := range z.projects {
+		d := strings.ToLower(p.Domain)
+		if name != d && !strings.HasSuffix(name, "."+d) {
+			continue
+		}
+		if best == nil || len(d) > len(best.Domain) {
+			best = p
+		}
+	}
+	return best
+}
+
+func (s *Server) currentZone() *zone {
+	s.zoneMu.Lock()
+	defer s.zoneMu.Unlock()
+
+	if s.zone != nil && time.Since(s.loadedAt) < zoneTTL {
+		return s.zone
+	}
+
+	z := &zone{}
+	if s.projects != nil {
+		list, err := s.projects.List()
+		if err != nil {
+			fmt.Printf("DNS: failed to load projects: %v\n", err)
+		}
+		z.projects = list
+	}
+	z.v4, z.v6 = answerAddrs(s.config.DNSAnswer)
+
+	s.zone = z
+	s.loadedAt = time.Now()
+	return z
+}
+
+// answerAddrs returns the addresses project names resolve to in the given
+// DNSAnswer mode.
+func answerAddrs(mode string) (v4, v6 net.IP) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", AnswerLoopback:
+		return net.IPv4(127, 0, 0, 1), net.IPv6loopback
+	case AnswerLAN:
+		v4, v6 = outboundAddr("udp4", "192.0.2.1:53"), outboundAddr("udp6", "[2001:db8::1]:53")
+		if v4 == nil && v6 == nil {
+			fmt.Println("DNS: no LAN address found, answering with loopback")
+			return net.IPv4(127, 0, 0, 1), net.IPv6loopback
+		}
+		return v4, v6
+	}
+	ip := net.ParseIP(strings.TrimSpace(mode))
+	if ip == nil {
+		fmt.Printf("DNS: invalid dns_answer %q, answering with loopback\n", mode)
+		return net.IPv4(127, 0, 0, 1), net.IPv6loopback
+	}
+	if ip.To4() != nil {
+		return ip, nil
+	}
+	return nil, ip
+}
+
+// outboundAddr returns the local address the system would use to reach
+// target, i.e. the address of the interface on the default route. Dialing
+// UDP sends no packets.
+func outboundAddr(network, target string) net.IP {
+	conn, err := net.Dial(network, target)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsLoopback() || addr.IP.IsLinkLocalUnicast() {
+		return nil
+	}
+	return addr.IP
+}
+
+// addLocalRecord answers q from the project's records, falling back to the
+// zone's default address for A and AAAA. A CNAME answers every type and is
+// followed once when its target is a local name.
+func (s *Server) addLocalRecord(m *dns.Msg, q dns.Question, z *zone, p *projects.Project) {
+	name := strings.ToLower(strings.TrimSuffix(q.Name, "."))
+
+	var records []projects.DNSRecord
+	if p != nil {
+		for _, rec := range p.DNSRecords {
+			if rec.FQDN(p.Domain) == name {
+				records = append(records, rec)
+			}
+		}
+	}
+
+	for _, rec := range records {
+		if rec.Type != "CNAME" {
+			continue
+		}
+		m.Answer = append(m.Answer, recordRR(q.Name, rec))
+		if q.Qtype == dns.TypeCNAME {
+			return
+		}
+		target := strings.ToLower(strings.TrimSuffix(rec.Value, "."))
+		if tp := z.project(target); tp != nil && target != name {
+			tq := dns.Question{Name: dns.Fqdn(target), Qtype: q.Qtype, Qclass: q.Qclass}
+			for _, trec := range tp.DNSRecords {
+				if trec.FQDN(tp.Domain) == target && trec.Type == "CNAME" {
+					// Do not chase chains; the client will.
+					return
+				}
+			}
+			s.addLocalRecord(m, tq, z, tp)
+		}
+		return
+	}
+
+	answered := len(m.Answer)
+	qtype := dns.TypeToString[q.Qtype]
+	for _, rec := range records {
+		if rec.Type == qtype {
+			m.Answer = append(m.Answer, recordRR(q.Name, rec))
+		}
+	}
+	if len(m.Answer) > answered {
+		return
+	}
+
 	switch q.Qtype {
 	case dns.TypeA:
-		rr := &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeA,
-				Class:  dns.ClassINET,
-				Ttl:    300,
-			},
-			A: net.ParseIP("127.0.0.1"),
-		}
-		m.Answer = append(m.Answer, rr)
+		if z.v4 != nil {
+			m.Answer = append(m.Answer, &dns.A{Hdr: header(q.Name, dns.TypeA, defaultTTL), A: z.v4})
+		}
 	case dns.TypeAAAA:
-		rr := &dns.AAAA{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeAAAA,
-				Class:  dns.ClassINET,
-				Ttl:    300,
-			},
-			AAAA: net.ParseIP("::1"),
-		}
-		m.Answer = append(m.Answer, rr)
+		if z.v6 != nil {
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: header(q.Name, dns.TypeAAAA, defaultTTL), AAAA: z.v6})
+		}
 	case dns.TypeSOA:
-		soa := &dns.SOA{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeSOA,
-				Class:  dns.ClassINET,
-				Ttl:    300,
-			},
-			Ns:      "ns.golocalserver.",
-			Mbox:    "admin.golocalserver.",
-			Serial:  2024010101,
-			Refresh: 3600,
-			Retry:   1800,
-			Expire:  604800,
-			Minttl:  86400,
+		if name == s.config.Domain {
+			m.Answer = append(m.Answer, s.soa())
 		}
-		m.Answer = append(m.Answer, soa)
 	}
 }
 
+func header(name string, rrtype uint16, ttl uint32) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+}
+
+// recordRR converts a validated project record into a resource record.
+func recordRR(name string, rec projects.DNSRecord) dns.RR {
+	ttl := rec.TTL
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	rrtype := dns.StringToType[rec.Type]
+	hdr := header(name, rrtype, ttl)
+
+	switch rec.Type {
+	case "A", "AAAA":
+		ip := net.ParseIP(rec.Value)
+		if rec.Type == "A" {
+			return &dns.A{Hdr: hdr, A: ip}
+		}
+		return &dns.AAAA{Hdr: hdr, AAAA: ip}
+	case "CNAME":
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(rec.Value)}
+	case "MX":
+		return &dns.MX{Hdr: hdr, Preference: rec.Priority, Mx: dns.Fqdn(rec.Value)}
+	case "SRV":
+		return &dns.SRV{Hdr: hdr, Priority: rec.Priority, Weight: rec.Weight, Port: rec.Port, Target: dns.Fqdn(rec.Value)}
+	}
+
+	// TXT: strings longer than 255 bytes are split into several.
+	var txt []string
+	for v := rec.Value; len(v) > 0; {
+		n := len(v)
+		if n > 255 {
+			n = 255
+		}
+		txt = append(txt, v[:n])
+		v = v[n:]
+	}
+	return &dns.TXT{Hdr: hdr, Txt: txt}
+}
+
+// soa is the local zone's SOA record, used for SOA queries and as the
+// authority section of negative answers.
+func (s *Server) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     header(dns.Fqdn(s.config.Domain), dns.TypeSOA, defaultTTL),
+		Ns:      "ns.golocalserver.",
+		Mbox:    "admin.golocalserver.",
+		Serial:  2024010101,
+		Refresh: 3600,
+		Retry:   1800,
+		Expire:  604800,
+		Minttl:  60,
+	}
+}
+
+// Start binds UDP and TCP on DNSPort and serves in the background. It listens
+// on 127.0.0.1, or on every interface when DNSAnswer points other devices at
+// this machine. Bind errors are returned before Start returns.
 func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.running {
 		return fmt.Errorf("DNS server already running")
 	}
 
-	dns.HandleFunc(".", s.handleDNSRequest)
+	host := "127.0.0.1"
+	if mode := strings.TrimSpace(s.config.DNSAnswer); mode != "" && mode != AnswerLoopback {
+		host = "0.0.0.0"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(s.config.DNSPort))
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("DNS listen udp %s: %w", addr, err)
+	}
+	// Same port as UDP, which matters when DNSPort is 0.
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("DNS listen tcp %s: %w", addr, err)
+	}
 
-	addr := fmt.Sprintf("127.0.0.1:%d", s.config.DNSPort)
-	s.server = &dns.Server{Addr: addr, Net: "udp"}
-	s.running = true
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", s.handleDNSRequest)
 
-	go func() {
-		// Recover from panics in the server goroutine
-		defer func() {
-			if rec := recover(); rec != nil {
-				fmt.Printf("DNS server panic: %v\n%s\n", rec, debug.Stack())
-				s.running = false
+	started := make(chan struct{}, 2)
+	failed := make(chan error, 2)
+	notify := func() { started <- struct{}{} }
+	s.udp = &dns.Server{PacketConn: pc, Handler: mux, NotifyStartedFunc: notify}
+	s.tcp = &dns.Server{Listener: l, Handler: mux, NotifyStartedFunc: notify}
+
+	for _, srv := range []*dns.Server{s.udp, s.tcp} {
+		go func(srv *dns.Server) {
+			// Recover from panics in the server goroutine
+			defer func() {
+				if rec := recover(); rec != nil {
+					fmt.Printf("DNS server panic: %v\n%s\n", rec, debug.Stack())
+				}
+			}()
+
+			if err := srv.ActivateAndServe(); err != nil {
+				failed <- err
+				fmt.Printf("DNS server error: %v\n", err)
 			}
-		}()
+		}(srv)
+	}
 
-		if err := s.server.ListenAndServe(); err != nil {
-			fmt.Printf("DNS server error: %v\n", err)
-			s.running = false
+	// Wait until both servers accept queries so a Stop right after Start
+	// cannot race their startup.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case err := <-failed:
+			s.udp.Shutdown()
+			s.tcp.Shutdown()
+			pc.Close()
+			l.Close()
+			return fmt.Errorf("DNS server failed to start: %w", err)
 		}
-	}()
+	}
+
+	s.running = true
+	s.addr.Store(pc.LocalAddr().String())
 
+	// Pick up a changed DNSAnswer on restart.
+	s.zoneMu.Lock()
+	s.zone = nil
+	s.zoneMu.Unlock()
 	return nil
 }
 
 func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if !s.running {
 		return nil
 	}
-
 	s.running = false
-	if s.server != nil {
-		return s.server.Shutdown()
-	}
-	return nil
+	s.addr.Store("")
+
+	return errors.Join(s.udp.Shutdown(), s.tcp.Shutdown())
 }
 
 func (s *Server) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.running
 }
+
+// Addr returns the bound host:port, or "" when stopped.
+func (s *Server) Addr() string {
+	addr, _ := s.addr.Load().(string)
+	return addr
+}
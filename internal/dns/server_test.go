@@ -0,0 +1,399 @@
+package dns
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+)
+
+// newTestServer returns a server on a free port whose project list holds
+// projs.
+func newTestServer(t *testing.T, projs ...*projects.Project) (*Server, *config.AppConfig) {
+	t.Helper()
+	dir := config.ProjectsDir
+	config.ProjectsDir = t.TempDir()
+	t.Cleanup(func() { config.ProjectsDir = dir })
+
+	cfg := config.DefaultConfig()
+	cfg.DNSPort = 0
+	// Nothing listens on the discard port; tests that forward set their own.
+	cfg.DNSUpstreams = []string{"127.0.0.1:9"}
+	pm := projects.NewManager(cfg)
+	for _, p := range projs {
+		if err := pm.Save(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewServer(cfg, pm), cfg
+}
+
+func start(t *testing.T, s *Server) string {
+	t.Helper()
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s.Addr()
+}
+
+func exchange(t *testing.T, network, addr, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	c := &dns.Client{Net: network, Timeout: 5 * time.Second}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	r, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("%s %s over %s: %v", dns.TypeToString[qtype], name, network, err)
+	}
+	return r
+}
+
+var blog = &projects.Project{
+	ID:     "blog",
+	Domain: "blog.localhost",
+	DNSRecords: []projects.DNSRecord{
+		{Name: "@", Type: "MX", Value: "mail.blog.localhost", Priority: 10},
+		{Name: "@", Type: "TXT", Value: "v=spf1 -all"},
+		{Name: "api", Type: "A", Value: "10.1.2.3"},
+		{Name: "www", Type: "CNAME", Value: "blog.localhost"},
+	},
+}
+
+func TestLocalZone(t *testing.T) {
+	s, _ := newTestServer(t, blog)
+	addr := start(t, s)
+
+	tests := []struct {
+		name   string
+		qtype  uint16
+		rcode  int
+		answer []string // the answers' values, in order
+	}{
+		{"blog.localhost", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.1"}},
+		{"BLOG.localhost", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.1"}},
+		{"deep.sub.blog.localhost", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.1"}},
+		{"blog.localhost", dns.TypeAAAA, dns.RcodeSuccess, []string{"::1"}},
+		{"localhost", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.1"}},
+		{"api.blog.localhost", dns.TypeA, dns.RcodeSuccess, []string{"10.1.2.3"}},
+		{"blog.localhost", dns.TypeMX, dns.RcodeSuccess, []string{"10 mail.blog.localhost."}},
+		{"blog.localhost", dns.TypeTXT, dns.RcodeSuccess, []string{`"v=spf1 -all"`}},
+		{"www.blog.localhost", dns.TypeA, dns.RcodeSuccess, []string{"blog.localhost.", "127.0.0.1"}},
+		{"missing.localhost", dns.TypeA, dns.RcodeNameError, nil},
+		{"missing.localhost", dns.TypeAAAA, dns.RcodeNameError, nil},
+		// NODATA: the name exists without records of the type.
+		{"blog.localhost", dns.TypeSRV, dns.RcodeSuccess, nil},
+		{"api.blog.localhost", dns.TypeMX, dns.RcodeSuccess, nil},
+	}
+	for _, network := range []string{"udp", "tcp"} {
+		for _, tt := range tests {
+			r := exchange(t, network, addr, tt.name, tt.qtype)
+			label := network + " " + dns.TypeToString[tt.qtype] + " " + tt.name
+			if r.Rcode != tt.rcode {
+				t.Errorf("%s: rcode %s, want %s", label, dns.RcodeToString[r.Rcode], dns.RcodeToString[tt.rcode])
+			}
+			if !r.Authoritative {
+				t.Errorf("%s: not authoritative", label)
+			}
+			var got []string
+			for _, rr := range r.Answer {
+				got = append(got, strings.TrimPrefix(rr.String(), rr.Header().String()))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.answer, ",") {
+				t.Errorf("%s: answer %q, want %q", label, got, tt.answer)
+			}
+			if len(tt.answer) == 0 {
+				if len(r.Ns) != 1 || r.Ns[0].Header().Rrtype != dns.TypeSOA {
+					t.Errorf("%s: authority %v, want the zone's SOA", label, r.Ns)
+				}
+			}
+		}
+	}
+}
+
+func TestAnswerModes(t *testing.T) {
+	tests := []struct {
+		answer  string
+		a, aaaa string // "" = NODATA
+		allIPs  bool   // listening on every interface
+	}{
+		{"", "127.0.0.1", "::1", false},
+		{AnswerLoopback, "127.0.0.1", "::1", false},
+		{"10.0.0.5", "10.0.0.5", "", true},
+		{"fd00::5", "", "fd00::5", true},
+	}
+	for _, tt := range tests {
+		s, cfg := newTestServer(t, blog)
+		cfg.DNSAnswer = tt.answer
+		addr := start(t, s)
+		if host, _, _ := net.SplitHostPort(addr); net.ParseIP(host).IsUnspecified() != tt.allIPs {
+			t.Errorf("answer %q: listening on %s", tt.answer, host)
+		}
+		_, port, _ := net.SplitHostPort(addr)
+		addr = net.JoinHostPort("127.0.0.1", port)
+
+		for qtype, want := range map[uint16]string{dns.TypeA: tt.a, dns.TypeAAAA: tt.aaaa} {
+			r := exchange(t, "udp", addr, "blog.localhost", qtype)
+			var got string
+			switch rr := firstAnswer(r).(type) {
+			case *dns.A:
+				got = rr.A.String()
+			case *dns.AAAA:
+				got = rr.AAAA.String()
+			}
+			if r.Rcode != dns.RcodeSuccess || got != want {
+				t.Errorf("answer %q: %s = %q (%s), want %q", tt.answer, dns.TypeToString[qtype], got, dns.RcodeToString[r.Rcode], want)
+			}
+		}
+		s.Stop()
+	}
+}
+
+func firstAnswer(r *dns.Msg) dns.RR {
+	if len(r.Answer) == 0 {
+		return nil
+	}
+	return r.Answer[0]
+}
+
+// fakeUpstream answers every A query with 192.0.2.10 and records the
+// transport each query came in on.
+type fakeUpstream struct {
+	mu       sync.Mutex
+	networks []string
+}
+
+func (u *fakeUpstream) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	u.mu.Lock()
+	u.networks = append(u.networks, w.RemoteAddr().Network())
+	u.mu.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.RecursionAvailable = true
+	if r.Question[0].Qtype == dns.TypeA {
+		m.Answer = append(m.Answer, &dns.A{Hdr: header(r.Question[0].Name, dns.TypeA, 60), A: net.IPv4(192, 0, 2, 10)})
+	} else {
+		m.Rcode = dns.RcodeNameError
+	}
+	w.WriteMsg(m)
+}
+
+// startUpstream serves u on UDP and TCP on one free port.
+func startUpstream(t *testing.T, u *fakeUpstream) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for _, srv := range []*dns.Server{{PacketConn: pc, Handler: u}, {Listener: l, Handler: u}} {
+		wg.Add(1)
+		srv.NotifyStartedFunc = wg.Done
+		go srv.ActivateAndServe()
+		t.Cleanup(func() { srv.Shutdown() })
+	}
+	wg.Wait()
+	return pc.LocalAddr().String()
+}
+
+func TestForward(t *testing.T) {
+	u := &fakeUpstream{}
+	s, cfg := newTestServer(t, blog)
+	cfg.DNSUpstreams = []string{"127.0.0.1:9", startUpstream(t, u)}
+	addr := start(t, s)
+
+	for _, network := range []string{"udp", "tcp"} {
+		r := exchange(t, network, addr, "example.com", dns.TypeA)
+		if r.Rcode != dns.RcodeSuccess || len(r.Answer) != 1 || r.Answer[0].(*dns.A).A.String() != "192.0.2.10" {
+			t.Errorf("%s: forwarded answer %v", network, r)
+		}
+		if r.Authoritative {
+			t.Errorf("%s: forwarded answer is authoritative", network)
+		}
+		r = exchange(t, network, addr, "example.com", dns.TypeAAAA)
+		if r.Rcode != dns.RcodeNameError {
+			t.Errorf("%s: upstream's NXDOMAIN came back as %s", network, dns.RcodeToString[r.Rcode])
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	// The dead first upstream is skipped; each query reaches the live one
+	// over the client's transport.
+	if got := strings.Join(u.networks, ","); got != "udp,udp,tcp,tcp" {
+		t.Errorf("upstream saw %s, want udp,udp,tcp,tcp", got)
+	}
+}
+
+func TestForwardFailure(t *testing.T) {
+	s, _ := newTestServer(t)
+	addr := start(t, s)
+
+	r := exchange(t, "tcp", addr, "example.com", dns.TypeA)
+	if r.Rcode != dns.RcodeServerFailure {
+		t.Errorf("rcode %s, want SERVFAIL", dns.RcodeToString[r.Rcode])
+	}
+}
+
+func TestMalformedQuery(t *testing.T) {
+	s, _ := newTestServer(t)
+	addr := start(t, s)
+
+	m := new(dns.Msg)
+	m.SetQuestion("a.localhost.", dns.TypeA)
+	m.Question = append(m.Question, dns.Question{Name: "b.localhost.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	r, _, err := (&dns.Client{Net: "udp"}).Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Rcode != dns.RcodeFormatError {
+		t.Errorf("rcode %s, want FORMERR", dns.RcodeToString[r.Rcode])
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	s, _ := newTestServer(t)
+	if s.IsRunning() || s.Addr() != "" {
+		t.Fatal("running before Start")
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop before Start: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Start(); err != nil {
+			t.Fatalf("start %d: %v", i+1, err)
+		}
+		if err := s.Start(); err == nil {
+			t.Error("second Start succeeded")
+		}
+		addr := s.Addr()
+		if !s.IsRunning() || addr == "" {
+			t.Fatal("not running after Start")
+		}
+		if r := exchange(t, "udp", addr, "localhost", dns.TypeA); r.Rcode != dns.RcodeSuccess {
+			t.Errorf("start %d: rcode %s", i+1, dns.RcodeToString[r.Rcode])
+		}
+
+		if err := s.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		if s.IsRunning() || s.Addr() != "" {
+			t.Error("still running after Stop")
+		}
+		// Both ports are released.
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			t.Fatalf("udp port still bound: %v", err)
+		}
+		pc.Close()
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatalf("tcp port still bound: %v", err)
+		}
+		l.Close()
+	}
+}
+
+func TestBindConflict(t *testing.T) {
+	for _, network := range []string{"udp", "tcp"} {
+		var taken string
+		if network == "udp" {
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pc.Close()
+			taken = pc.LocalAddr().String()
+		} else {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			taken = l.Addr().String()
+		}
+		_, port, _ := net.SplitHostPort(taken)
+
+		s, cfg := newTestServer(t)
+		cfg.DNSPort, _ = strconv.Atoi(port)
+		err := s.Start()
+		if err == nil {
+			s.Stop()
+			t.Fatalf("%s port in use: Start succeeded", network)
+		}
+		if !strings.Contains(err.Error(), "listen "+network) {
+			t.Errorf("%s port in use: err = %v", network, err)
+		}
+		if s.IsRunning() || s.Addr() != "" {
+			t.Errorf("%s port in use: running after a failed Start", network)
+		}
+		if network == "tcp" {
+			// The UDP socket bound before the TCP failure is released.
+			pc, err := net.ListenPacket("udp", taken)
+			if err != nil {
+				t.Errorf("udp port leaked after a failed Start: %v", err)
+			} else {
+				pc.Close()
+			}
+		}
+	}
+}
+
+// lanWriter is a ResponseWriter for a query from another device.
+type lanWriter struct {
+	msg *dns.Msg
+}
+
+func (w *lanWriter) LocalAddr() net.Addr { return &net.UDPAddr{IP: net.IPv4zero, Port: 53} }
+func (w *lanWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 5353}
+}
+func (w *lanWriter) WriteMsg(m *dns.Msg) error   { w.msg = m; return nil }
+func (w *lanWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *lanWriter) Close() error                { return nil }
+func (w *lanWriter) TsigStatus() error           { return nil }
+func (w *lanWriter) TsigTimersOnly(bool)         {}
+func (w *lanWriter) Hijack()                     {}
+
+func TestLANClientsOnlyGetLocalZone(t *testing.T) {
+	u := &fakeUpstream{}
+	s, cfg := newTestServer(t, blog)
+	cfg.DNSAnswer = "10.0.0.5"
+	cfg.DNSUpstreams = []string{startUpstream(t, u)}
+
+	for _, tt := range []struct {
+		name  string
+		rcode int
+	}{
+		{"blog.localhost", dns.RcodeSuccess},
+		{"missing.localhost", dns.RcodeNameError},
+		{"example.com", dns.RcodeRefused},
+	} {
+		w := &lanWriter{}
+		q := new(dns.Msg)
+		q.SetQuestion(dns.Fqdn(tt.name), dns.TypeA)
+		s.handleDNSRequest(w, q)
+		if w.msg == nil || w.msg.Rcode != tt.rcode {
+			t.Errorf("%s from the LAN: %v, want %s", tt.name, w.msg, dns.RcodeToString[tt.rcode])
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.networks) != 0 {
+		t.Errorf("a LAN query reached the upstream %d times", len(u.networks))
+	}
+}
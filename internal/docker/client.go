@@ -0,0 +1,154 @@
+// Package docker is a minimal Docker Engine API client. It talks HTTP over
+// the daemon's Unix socket (or DOCKER_HOST) and covers only what the app
+// needs: container state and health, logs, exec and the event stream.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go-local-server/internal/platform"
+)
+
+// APIVersion is the Engine API version requested. 1.41 is Docker 20.10.
+const APIVersion = "1.41"
+
+// ErrNotFound is returned when a container or exec instance does not exist.
+var ErrNotFound = errors.New("docker: not found")
+
+// ErrNoSocket is returned by NewClient when no Docker socket can be found.
+var ErrNoSocket = errors.New("docker: no engine socket found")
+
+type Client struct {
+	network string // "unix" or "tcp"
+	address string
+	http    *http.Client
+}
+
+// NewClient connects to $DOCKER_HOST, or to the first Docker socket found on
+// this machine.
+func NewClient() (*Client, error) {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return NewClientWithHost(host)
+	}
+	sock := platform.DockerSocket()
+	if sock == "" {
+		return nil, ErrNoSocket
+	}
+	return NewClientWithHost("unix://" + sock)
+}
+
+// NewClientWithHost accepts unix:///path/to/docker.sock or tcp://host:port.
+func NewClientWithHost(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("docker: invalid host %q: %w", host, err)
+	}
+
+	c := &Client{}
+	switch u.Scheme {
+	case "unix":
+		c.network, c.address = "unix", u.Path
+	case "tcp", "http":
+		c.network, c.address = "tcp", u.Host
+	default:
+		return nil, fmt.Errorf("docker: unsupported host scheme %q", u.Scheme)
+	}
+
+	c.http = &http.Client{
+		Transport: &http.Transport{
+			DialContext:        c.dial,
+			MaxIdleConns:       4,
+			IdleConnTimeout:    30 * time.Second,
+			DisableCompression: true,
+		},
+	}
+	return c, nil
+}
+
+func (c *Client) dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, c.network, c.address)
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	u := "http://docker/v" + APIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// do sends a request and returns the response if it has a 2xx status.
+// The caller must close the body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func responseError(resp *http.Response) error {
+	var msg struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &msg) != nil || msg.Message == "" {
+		msg.Message = strings.TrimSpace(string(data))
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, msg.Message)
+	}
+	return fmt.Errorf("docker: %s (HTTP %d)", msg.Message, resp.StatusCode)
+}
+
+// Ping checks that the daemon answers.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/_ping", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
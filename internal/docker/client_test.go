@@ -0,0 +1,315 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine serves handler on a Unix socket and returns a client for it.
+func fakeEngine(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewClientWithHost("unix://" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// frame encodes one chunk of a multiplexed stream.
+func frame(stream byte, payload string) []byte {
+	hdr := make([]byte, 8, 8+len(payload))
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestPing(t *testing.T) {
+	var path string
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = io.WriteString(w, "OK")
+	}))
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v" + APIVersion + "/_ping"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+}
+
+func TestContainerInspect(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v"+APIVersion+"/containers/golocal-mysql/json" {
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(w, map[string]string{"message": "No such container"})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"Id":   "abc",
+			"Name": "/golocal-mysql",
+			"State": map[string]interface{}{
+				"Status":  "running",
+				"Running": true,
+				"Health":  map[string]string{"Status": "healthy"},
+			},
+		})
+	}))
+	ctx := context.Background()
+
+	ct, err := c.ContainerInspect(ctx, "golocal-mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct.Name != "golocal-mysql" || !ct.State.Running || ct.State.HealthStatus() != "healthy" {
+		t.Errorf("got %+v", ct)
+	}
+
+	_, err = c.ContainerInspect(ctx, "missing")
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "No such container") {
+		t.Errorf("err = %v, want ErrNotFound with the daemon's message", err)
+	}
+}
+
+func TestContainerLogs(t *testing.T) {
+	for _, tty := range []bool{false, true} {
+		c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/json") {
+				writeJSON(w, map[string]interface{}{"Id": "abc", "Config": map[string]interface{}{"Tty": tty}})
+				return
+			}
+			if r.URL.Query().Get("tail") != "10" {
+				t.Errorf("query = %s, want tail=10", r.URL.RawQuery)
+			}
+			if tty {
+				_, _ = io.WriteString(w, "out\nerr\n")
+				return
+			}
+			_, _ = w.Write(frame(streamStdout, "out\n"))
+			_, _ = w.Write(frame(streamStderr, "err\n"))
+		}))
+
+		rc, err := c.ContainerLogs(context.Background(), "golocal-php", LogsOptions{Tail: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "out\nerr\n" {
+			t.Errorf("tty=%v: logs = %q", tty, data)
+		}
+	}
+}
+
+func TestDemux(t *testing.T) {
+	var src bytes.Buffer
+	src.Write(frame(streamStdout, "one "))
+	src.Write(frame(streamStderr, "oops"))
+	src.Write(frame(streamStdout, "two"))
+
+	var stdout, stderr bytes.Buffer
+	if err := Demux(&stdout, &stderr, &src); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "one two" || stderr.String() != "oops" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	if err := Demux(nil, nil, bytes.NewReader(frame(7, "x"))); err == nil {
+		t.Error("unknown stream id: want an error")
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("filters"); got != `{"type":["container"]}` {
+			t.Errorf("filters = %s", got)
+		}
+		_, _ = io.WriteString(w, `{"Type":"container","Action":"start","Actor":{"Attributes":{"name":"golocal-web"}}}`+"\n")
+		_, _ = io.WriteString(w, `{"Type":"container","Action":"die","Actor":{"Attributes":{"name":"golocal-web"}}}`+"\n")
+	}))
+
+	events, errs := c.Events(context.Background(), map[string][]string{"type": {"container"}})
+	var got []string
+	for ev := range events {
+		got = append(got, ev.Name()+" "+ev.Action)
+	}
+	if strings.Join(got, ",") != "golocal-web start,golocal-web die" {
+		t.Errorf("events = %v", got)
+	}
+	// The stream ending without cancellation is reported.
+	if err := <-errs; !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+// execEngine fakes the exec endpoints. The instance keeps running for a
+// few inspections after its stream closes, as the daemon's can.
+type execEngine struct {
+	t        *testing.T
+	exitCode int
+
+	mu       sync.Mutex
+	created  map[string]interface{}
+	inspects int
+}
+
+func (e *execEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/v"+APIVersion) {
+	case "/containers/golocal-mysql/exec":
+		e.mu.Lock()
+		_ = json.NewDecoder(r.Body).Decode(&e.created)
+		e.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{"Id": "e1"})
+	case "/exec/e1/start":
+		_, _ = io.Copy(io.Discard, r.Body)
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			e.t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		_ = brw.Flush()
+
+		in, _ := io.ReadAll(brw)
+		_, _ = conn.Write(frame(streamStdout, "read "+string(in)))
+		_, _ = conn.Write(frame(streamStderr, "warning"))
+	case "/exec/e1/json":
+		e.mu.Lock()
+		e.inspects++
+		running, code := e.inspects < 3, e.exitCode
+		e.mu.Unlock()
+		if running {
+			code = 0
+		}
+		writeJSON(w, map[string]interface{}{"Running": running, "ExitCode": code})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestExec(t *testing.T) {
+	for _, code := range []int{0, 3} {
+		engine := &execEngine{t: t, exitCode: code}
+		c := fakeEngine(t, engine)
+
+		var stdout, stderr bytes.Buffer
+		err := c.Exec(context.Background(), "golocal-mysql", ExecOptions{
+			Cmd:    []string{"mysql", "-uroot"},
+			Env:    []string{"MYSQL_PWD=secret"},
+			Stdin:  strings.NewReader("SELECT 1;"),
+			Stdout: &stdout,
+			Stderr: &stderr,
+		})
+
+		var exitErr *ExitError
+		switch {
+		case code == 0 && err != nil:
+			t.Fatalf("exit 0: err = %v", err)
+		case code != 0 && (!errors.As(err, &exitErr) || exitErr.Code != code):
+			t.Fatalf("exit %d: err = %v, want *ExitError", code, err)
+		}
+		if stdout.String() != "read SELECT 1;" || stderr.String() != "warning" {
+			t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+		}
+		engine.mu.Lock()
+		if engine.inspects != 3 {
+			t.Errorf("inspected %d times, want until the exec stopped running (3)", engine.inspects)
+		}
+		if engine.created["AttachStdin"] != true {
+			t.Errorf("create body = %v, want AttachStdin", engine.created)
+		}
+		engine.mu.Unlock()
+	}
+}
+
+func TestExecCancelledWhileRunning(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/exec"):
+			writeJSON(w, map[string]string{"Id": "e1"})
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			conn, brw, _ := w.(http.Hijacker).Hijack()
+			_, _ = brw.WriteString("HTTP/1.1 101 UPGRADED\r\n\r\n")
+			_ = brw.Flush()
+			conn.Close()
+		default:
+			writeJSON(w, map[string]interface{}{"Running": true})
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.Exec(ctx, "golocal-mysql", ExecOptions{Cmd: []string{"sleep", "60"}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/golocal-broken/exec"):
+			w.WriteHeader(http.StatusInternalServerError)
+			writeJSON(w, map[string]string{"message": "cannot exec in a restarting container"})
+		case strings.HasSuffix(r.URL.Path, "/exec"):
+			writeJSON(w, map[string]string{"Id": "e1"})
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			_, _ = io.Copy(io.Discard, r.Body)
+			conn, brw, _ := w.(http.Hijacker).Hijack()
+			_, _ = brw.WriteString("HTTP/1.1 101 UPGRADED\r\n\r\n")
+			// A whole frame, then one cut short.
+			_, _ = brw.Write(frame(streamStdout, "partial"))
+			_, _ = brw.Write(frame(streamStdout, "the rest of the dump")[:12])
+			_ = brw.Flush()
+			conn.Close()
+		default:
+			writeJSON(w, map[string]interface{}{"Running": false})
+		}
+	}))
+	ctx := context.Background()
+
+	err := c.Exec(ctx, "golocal-broken", ExecOptions{Cmd: []string{"true"}})
+	if err == nil || errors.Is(err, ErrExecStarted) {
+		t.Errorf("create failed: err = %v, want an error before the start", err)
+	}
+
+	var stdout bytes.Buffer
+	err = c.Exec(ctx, "golocal-mysql", ExecOptions{Cmd: []string{"mysqldump"}, Stdout: &stdout})
+	if !errors.Is(err, ErrExecStarted) {
+		t.Errorf("stream cut: err = %v, want ErrExecStarted", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "partial") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
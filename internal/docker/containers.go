@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Container is the subset of GET /containers/{id}/json the app uses.
+type Container struct {
+	ID     string         `json:"Id"`
+	Name   string         `json:"Name"`
+	State  ContainerState `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Tty    bool              `json:"Tty"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+type ContainerState struct {
+	Status   string  `json:"Status"` // created, running, paused, restarting, removing, exited, dead
+	Running  bool    `json:"Running"`
+	Pid      int     `json:"Pid"`
+	ExitCode int     `json:"ExitCode"`
+	Health   *Health `json:"Health"`
+}
+
+type Health struct {
+	Status string `json:"Status"` // starting, healthy, unhealthy
+}
+
+// HealthStatus returns the healthcheck status, or "" when the container has
+// no healthcheck.
+func (s ContainerState) HealthStatus() string {
+	if s.Health == nil {
+		return ""
+	}
+	return s.Health.Status
+}
+
+// ContainerInspect returns a container by name or id. The error wraps
+// ErrNotFound when the container does not exist.
+func (c *Client) ContainerInspect(ctx context.Context, name string) (*Container, error) {
+	var ct Container
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(name)+"/json", nil, &ct); err != nil {
+		return nil, err
+	}
+	ct.Name = strings.TrimPrefix(ct.Name, "/")
+	return &ct, nil
+}
+
+// LogsOptions selects which log lines ContainerLogs returns.
+type LogsOptions struct {
+	Follow     bool
+	Tail       int // 0 = all
+	Timestamps bool
+}
+
+// ContainerLogs streams a container's stdout and stderr merged into one
+// reader. The caller must close it; cancelling ctx also ends a followed
+// stream.
+func (c *Client) ContainerLogs(ctx context.Context, name string, opts LogsOptions) (io.ReadCloser, error) {
+	// Containers without a TTY multiplex stdout/stderr into framed chunks.
+	ct, err := c.ContainerInspect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("stdout", "1")
+	q.Set("stderr", "1")
+	if opts.Follow {
+		q.Set("follow", "1")
+	}
+	if opts.Tail > 0 {
+		q.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "1")
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(ct.ID)+"/logs", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ct.Config.Tty {
+		return resp.Body, nil
+	}
+	return &muxReader{rc: resp.Body}, nil
+}
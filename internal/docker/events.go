@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Event is one message from GET /events.
+type Event struct {
+	Type   string `json:"Type"`   // container, image, network, volume, ...
+	Action string `json:"Action"` // start, die, stop, health_status: healthy, ...
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time int64 `json:"time"`
+}
+
+// Name returns the container name for container events.
+func (e Event) Name() string {
+	return e.Actor.Attributes["name"]
+}
+
+// Events subscribes to the daemon's event stream. filters uses the Engine
+// API filter names, e.g. {"type": {"container"}, "container": {"golocal-mysql"}}.
+//
+// Events are delivered on the first channel until ctx is cancelled or the
+// stream breaks; then both channels are closed. A broken stream sends its
+// error on the second channel first.
+func (c *Client) Events(ctx context.Context, filters map[string][]string) (<-chan Event, <-chan error) {
+	events := make(chan Event, 16)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		q := url.Values{}
+		if len(filters) > 0 {
+			data, err := json.Marshal(filters)
+			if err != nil {
+				errs <- err
+				return
+			}
+			q.Set("filters", string(data))
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "/events", q, nil)
+		if err != nil {
+			if ctx.Err() == nil {
+				errs <- err
+			}
+			return
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var ev Event
+			if err := dec.Decode(&ev); err != nil {
+				if ctx.Err() == nil {
+					if errors.Is(err, io.EOF) {
+						err = io.ErrUnexpectedEOF
+					}
+					errs <- err
+				}
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, errs
+}
@@ -0,0 +1,171 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ExecOptions describes a command to run inside a running container.
+type ExecOptions struct {
+	Cmd        []string
+	Env        []string
+	User       string
+	WorkingDir string
+	Stdin      io.Reader // nil = no stdin
+	Stdout     io.Writer // nil = discard
+	Stderr     io.Writer // nil = discard
+}
+
+// ExitError is returned by Exec when the command exits non-zero.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ErrExecStarted wraps the errors Exec returns after the command started:
+// stdin may be partly read and stdout partly written by then, so the
+// command must not simply be run again.
+var ErrExecStarted = errors.New("docker: exec started")
+
+// Exec runs a command in a container and waits for it to finish, like
+// `docker exec -i`. A non-zero exit is reported as *ExitError, and a
+// failure once the command is running wraps ErrExecStarted.
+func (c *Client) Exec(ctx context.Context, container string, opts ExecOptions) error {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(container)+"/exec", nil, map[string]interface{}{
+		"Cmd":          opts.Cmd,
+		"Env":          opts.Env,
+		"User":         opts.User,
+		"WorkingDir":   opts.WorkingDir,
+		"AttachStdin":  opts.Stdin != nil,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Tty":          false,
+	})
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	conn, br, err := c.hijack(ctx, "/exec/"+created.ID+"/start", map[string]interface{}{
+		"Detach": false,
+		"Tty":    false,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if opts.Stdin != nil {
+		go func() {
+			io.Copy(conn, opts.Stdin)
+			if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+				cw.CloseWrite()
+			}
+		}()
+	}
+
+	if err := Demux(opts.Stdout, opts.Stderr, br); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: stream: %w", ErrExecStarted, err)
+	}
+
+	code, err := c.execExitCode(ctx, created.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrExecStarted, err)
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// execExitCode waits for an exec instance to finish and returns its exit
+// code. The stream can close before the daemon records the exit, so the
+// instance is inspected until it is no longer running.
+func (c *Client) execExitCode(ctx context.Context, id string) (int, error) {
+	delay := 10 * time.Millisecond
+	for {
+		var inspect struct {
+			Running  bool `json:"Running"`
+			ExitCode int  `json:"ExitCode"`
+		}
+		if err := c.getJSON(ctx, "/exec/"+id+"/json", nil, &inspect); err != nil {
+			return 0, err
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay < 500*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// hijack POSTs to an attach-style endpoint and takes over the connection,
+// returning it together with a reader positioned after the HTTP response.
+func (c *Client) hijack(ctx context.Context, path string, body interface{}) (net.Conn, *bufio.Reader, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(path, nil), bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	conn, err := c.dial(ctx, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer conn.Close()
+		return nil, nil, responseError(resp)
+	}
+	return conn, br, nil
+}
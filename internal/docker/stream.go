@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Attached streams of non-TTY containers are framed: an 8-byte header
+// (stream id, 3 zero bytes, big-endian payload length) precedes each chunk.
+const (
+	streamStdin  = 0
+	streamStdout = 1
+	streamStderr = 2
+)
+
+// Demux copies a framed stream from src, writing stdout frames to stdout and
+// stderr frames to stderr. Either writer may be nil to discard that stream.
+func Demux(stdout, stderr io.Writer, src io.Reader) error {
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(src, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var dst io.Writer
+		switch hdr[0] {
+		case streamStdin, streamStdout:
+			dst = stdout
+		case streamStderr:
+			dst = stderr
+		default:
+			return fmt.Errorf("docker: unexpected stream id %d", hdr[0])
+		}
+		if dst == nil {
+			dst = io.Discard
+		}
+
+		size := int64(binary.BigEndian.Uint32(hdr[4:]))
+		if _, err := io.CopyN(dst, src, size); err != nil {
+			return err
+		}
+	}
+}
+
+// muxReader strips the frame headers from a multiplexed stream, merging
+// stdout and stderr.
+type muxReader struct {
+	rc        io.ReadCloser
+	remaining uint32
+	hdr       [8]byte
+}
+
+func (m *muxReader) Read(p []byte) (int, error) {
+	for m.remaining == 0 {
+		if _, err := io.ReadFull(m.rc, m.hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		m.remaining = binary.BigEndian.Uint32(m.hdr[4:])
+	}
+
+	if uint32(len(p)) > m.remaining {
+		p = p[:m.remaining]
+	}
+	n, err := m.rc.Read(p)
+	m.remaining -= uint32(n)
+	if err == io.EOF && m.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (m *muxReader) Close() error {
+	return m.rc.Close()
+}
@@ -0,0 +1,156 @@
+// Package events is the in-process publish/subscribe bus for state changes.
+// The service manager, the project workflows and live reload publish what
+// happened; the GUI, the tray, desktop notifications and the control API's
+// event stream subscribe instead of polling.
+package events
+
+import "sync"
+
+// Event is one state change. Kind names it on the wire, e.g. in the API's
+// event stream.
+type Event interface {
+	Kind() string
+}
+
+// ServiceStarted is published when a service is seen running after it was
+// not.
+type ServiceStarted struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+}
+
+// ServiceStopped is published when a running service is seen stopped,
+// whether it was stopped or exited on its own.
+type ServiceStopped struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+}
+
+// ServiceFailed is published when starting, restarting or checking a
+// service fails.
+type ServiceFailed struct {
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	Error   string `json:"error"`
+}
+
+// ProjectCreated is published once a project is saved and its vhost is
+// live.
+type ProjectCreated struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+	Domain  string `json:"domain"`
+}
+
+// ProjectUpdated is published after a project's settings are applied.
+type ProjectUpdated struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+	Domain  string `json:"domain"`
+}
+
+// ProjectDeleted is published after a project and its vhost are removed.
+type ProjectDeleted struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+}
+
+// VhostReloaded is published after the vhosts and the compose file are
+// regenerated and applied to the running stack.
+type VhostReloaded struct{}
+
+// FileChanged is published when live reload flushes a burst of changes in
+// a project.
+type FileChanged struct {
+	Project string   `json:"project"`
+	Paths   []string `json:"paths"`  // relative to the project root
+	Failed  bool     `json:"failed"` // a watch task failed
+}
+
+func (ServiceStarted) Kind() string { return "service.started" }
+func (ServiceStopped) Kind() string { return "service.stopped" }
+func (ServiceFailed) Kind() string  { return "service.failed" }
+func (ProjectCreated) Kind() string { return "project.created" }
+func (ProjectUpdated) Kind() string { return "project.updated" }
+func (ProjectDeleted) Kind() string { return "project.deleted" }
+func (VhostReloaded) Kind() string  { return "vhost.reloaded" }
+func (FileChanged) Kind() string    { return "file.changed" }
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 64
+
+// Bus fans events out to subscribers. Publish never blocks: a subscriber
+// that falls behind misses events, so subscribers should treat an event as
+// a hint to re-read state rather than as the state itself. The zero value
+// is not usable; a nil *Bus accepts and discards events, so publishers work
+// without one.
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func New() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives events on C until it is closed.
+type Subscription struct {
+	C <-chan Event
+
+	bus *Bus
+	ch  chan Event
+}
+
+// Subscribe returns a subscription to every event published from now on.
+func (b *Bus) Subscribe() *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, bus: b, ch: ch}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	b := s.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish sends e to every subscriber that has room for it.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
+
+// Close closes every subscription; later subscriptions start closed.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// drain returns the events waiting on s without blocking.
+func drain(s *Subscription) []Event {
+	var got []Event
+	for {
+		select {
+		case e, ok := <-s.C:
+			if !ok {
+				return got
+			}
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestPublishReachesEverySubscriber(t *testing.T) {
+	b := New()
+	defer b.Close()
+	first, second := b.Subscribe(), b.Subscribe()
+
+	b.Publish(ProjectCreated{Project: "blog", Name: "Blog", Domain: "blog.test"})
+	b.Publish(VhostReloaded{})
+
+	for i, s := range []*Subscription{first, second} {
+		got := drain(s)
+		if len(got) != 2 {
+			t.Fatalf("subscriber %d got %v, want 2 events", i, got)
+		}
+		if p, ok := got[0].(ProjectCreated); !ok || p.Project != "blog" {
+			t.Errorf("subscriber %d: first event %#v", i, got[0])
+		}
+		if got[1].Kind() != "vhost.reloaded" {
+			t.Errorf("subscriber %d: second event %s", i, got[1].Kind())
+		}
+	}
+}
+
+func TestSubscribeOnlySeesLaterEvents(t *testing.T) {
+	b := New()
+	defer b.Close()
+	b.Publish(VhostReloaded{})
+	s := b.Subscribe()
+	b.Publish(ServiceStarted{Service: "mysql"})
+	if got := drain(s); len(got) != 1 || got[0].Kind() != "service.started" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := New()
+	defer b.Close()
+	gone, kept := b.Subscribe(), b.Subscribe()
+
+	gone.Close()
+	gone.Close() // safe twice
+	if _, ok := <-gone.C; ok {
+		t.Error("C still open after Close")
+	}
+
+	b.Publish(ServiceStopped{Service: "apache"})
+	if got := drain(kept); len(got) != 1 {
+		t.Errorf("remaining subscriber got %v", got)
+	}
+	b.mu.Lock()
+	n := len(b.subs)
+	b.mu.Unlock()
+	if n != 1 {
+		t.Errorf("%d subscribers registered, want 1", n)
+	}
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	b := New()
+	defer b.Close()
+	slow, fast := b.Subscribe(), b.Subscribe()
+
+	var fastGot int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range fast.C {
+			fastGot++
+		}
+	}()
+
+	const published = subscriberBuffer * 3
+	finished := make(chan struct{})
+	go func() {
+		for i := 0; i < published; i++ {
+			b.Publish(FileChanged{Project: "blog"})
+		}
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a subscriber that is not reading")
+	}
+
+	// The slow subscriber keeps the first events and misses the rest.
+	if got := len(drain(slow)); got != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events, want %d", got, subscriberBuffer)
+	}
+	b.Publish(VhostReloaded{})
+	if got := drain(slow); len(got) != 1 {
+		t.Errorf("after catching up, got %v, want the new event", got)
+	}
+
+	fast.Close()
+	<-done
+	if fastGot < subscriberBuffer {
+		t.Errorf("reading subscriber got %d events, want at least %d", fastGot, subscriberBuffer)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New()
+	s := b.Subscribe()
+	b.Publish(VhostReloaded{})
+	b.Close()
+
+	// Buffered events are still delivered before C closes.
+	if e, ok := <-s.C; !ok || e.Kind() != "vhost.reloaded" {
+		t.Errorf("got %v, %v; want the buffered event", e, ok)
+	}
+	if _, ok := <-s.C; ok {
+		t.Error("C open after the bus closed")
+	}
+	s.Close() // after the bus closed it
+
+	late := b.Subscribe()
+	if _, ok := <-late.C; ok {
+		t.Error("subscription after Close is open")
+	}
+	late.Close()
+	b.Publish(VhostReloaded{}) // no subscribers, no panic
+	b.Close()
+}
+
+func TestNilBus(t *testing.T) {
+	var b *Bus
+	b.Publish(VhostReloaded{})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	b := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				b.Publish(ServiceStarted{Service: "php82"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s := b.Subscribe()
+				drain(s)
+				s.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	b.Close()
+}
@@ -5,34 +5,81 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 
+	"go-local-server/internal/events"
 	"go-local-server/internal/projects"
 )
 
 const DefaultPort = 35730
 
+// Change is sent to clients once a burst of file changes settles.
+type Change struct {
+	Paths []string     `json:"paths"`           // relative to the project root, slash-separated
+	CSS   bool         `json:"css"`             // only stylesheets changed; swap them instead of reloading
+	Error *TaskFailure `json:"error,omitempty"` // a watch task failed; show it instead of reloading
+}
+
+func newChange(paths []string) Change {
+	sort.Strings(paths)
+	c := Change{Paths: paths, CSS: len(paths) > 0}
+	for _, p := range paths {
+		if !isStylesheet(p) {
+			c.CSS = false
+		}
+	}
+	return c
+}
+
+// isStylesheet reports whether a change to path only affects styles. Sass
+// and Less sources count, since pages only load their compiled output.
+func isStylesheet(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".css", ".scss", ".sass", ".less":
+		return true
+	}
+	return false
+}
+
 type projectState struct {
-	watcher  *fsnotify.Watcher
-	clients  map[string]chan struct{}
+	root     string
+	domain   string
+	watch    projects.WatchRules
+	rules    *rules
+	watcher  *fsnotify.Watcher // nil when polling
+	stop     chan struct{}     // ends pollLoop
+	clients  map[string]chan Change
 	debounce *time.Timer
+	changed  map[string]bool // paths seen since the last flush, guarded by Manager.mu
+
+	flushMu sync.Mutex      // serialises flush, and so the watch tasks
+	ctx     context.Context // cancelled by stopWatching to end running tasks
+	cancel  context.CancelFunc
 }
 
 type Manager struct {
-	port int
+	port         int
+	protocolPort int
 
 	mu       sync.Mutex
 	projects map[string]*projectState
+	events   *events.Bus
 
-	srv *http.Server
+	servers []*http.Server
 }
 
 func NewManager(port int) *Manager {
@@ -42,39 +89,122 @@ func NewManager(port int) *Manager {
 	return &Manager{port: port, projects: make(map[string]*projectState)}
 }
 
+// SetProtocolPort makes Start also listen on port for browser extensions
+// that speak the LiveReload protocol (normally ProtocolPort); 0 turns that
+// off. It takes effect on the next Start.
+func (m *Manager) SetProtocolPort(port int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.protocolPort = port
+}
+
+// SetEvents publishes every flushed change to bus as events.FileChanged.
+func (m *Manager) SetEvents(bus *events.Bus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = bus
+}
+
+// Start binds the event server; a port already in use is reported here.
 func (m *Manager) Start() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.srv != nil {
+	if m.servers != nil {
 		return nil
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/events", m.handleEvents)
+	mux.HandleFunc("/livereload.js", m.handleScript)
+	mux.Handle("/livereload", m.protocolHandler())
 
-	m.srv = &http.Server{Addr: fmt.Sprintf("127.0.0.1:%d", m.port), Handler: mux}
-	go func() {
-		_ = m.srv.ListenAndServe()
-	}()
+	ports := []int{m.port}
+	if m.protocolPort > 0 && m.protocolPort != m.port {
+		ports = append(ports, m.protocolPort)
+	}
+	var listeners []net.Listener
+	for _, port := range ports {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("live reload: %w", err)
+		}
+		listeners = append(listeners, l)
+	}
+
+	for _, l := range listeners {
+		srv := &http.Server{Handler: mux}
+		m.servers = append(m.servers, srv)
+		go func(l net.Listener) {
+			_ = srv.Serve(l)
+		}(l)
+	}
 	return nil
 }
 
+// Stop disables every project and shuts the event servers down.
 func (m *Manager) Stop(ctx context.Context) error {
 	m.mu.Lock()
-	srv := m.srv
-	m.srv = nil
-	projects := m.projects
+	servers := m.servers
+	m.servers = nil
+	states := m.projects
 	m.projects = make(map[string]*projectState)
+	for _, st := range states {
+		st.closeClients()
+	}
 	m.mu.Unlock()
 
-	for projectID := range projects {
-		_ = m.Disable(projectID)
+	for _, st := range states {
+		_ = st.stopWatching()
 	}
 
-	if srv == nil {
-		return nil
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Shutdown(ctx))
 	}
-	return srv.Shutdown(ctx)
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether live reload is running for the project.
+func (m *Manager) Enabled(projectID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.projects[projectID] != nil
+}
+
+// Sync enables live reload for the active projects that have it switched on
+// and disables it for every other project. A project whose path, domain or
+// watch rules changed is watched again.
+func (m *Manager) Sync(list []*projects.Project) error {
+	want := make(map[string]*projects.Project)
+	for _, p := range list {
+		if p.LiveReload && p.IsActive {
+			want[p.ID] = p
+		}
+	}
+
+	m.mu.Lock()
+	var stale []string
+	for id, st := range m.projects {
+		if p, ok := want[id]; !ok || p.Path != st.root || strings.ToLower(p.Domain) != st.domain || !reflect.DeepEqual(p.Watch, st.watch) {
+			stale = append(stale, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range stale {
+		_ = m.Disable(id)
+	}
+
+	var errs []error
+	for _, p := range want {
+		if err := m.Enable(p); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.ID, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (m *Manager) Enable(p *projects.Project) error {
@@ -92,30 +222,57 @@ func (m *Manager) Enable(p *projects.Project) error {
 	}
 	m.mu.Unlock()
 
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return err
+	if p.Path == "" {
+		return fmt.Errorf("project path is empty")
 	}
 
-	st := &projectState{watcher: watcher, clients: make(map[string]chan struct{})}
-
-	// Start watching directories
-	root := p.Path
-	if root == "" {
-		_ = watcher.Close()
-		return fmt.Errorf("project path is empty")
+	st := &projectState{
+		root:    p.Path,
+		domain:  strings.ToLower(p.Domain),
+		watch:   p.Watch,
+		rules:   newRules(p.Path, p.Watch),
+		clients: make(map[string]chan Change),
+		changed: make(map[string]bool),
 	}
+	st.ctx, st.cancel = context.WithCancel(context.Background())
 
-	if err := addWatchRecursive(watcher, root); err != nil {
-		_ = watcher.Close()
-		return err
+	// Walk the tree once up front so errors are reported here
+	var snapshot map[string]fileStamp
+	if p.Watch.Poll {
+		var err error
+		if snapshot, err = st.scan(); err != nil {
+			st.cancel()
+			return err
+		}
+		st.stop = make(chan struct{})
+	} else {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			st.cancel()
+			return err
+		}
+		st.watcher = watcher
+		if err := st.addWatches(p.Path); err != nil {
+			st.cancel()
+			_ = watcher.Close()
+			return err
+		}
 	}
 
 	m.mu.Lock()
+	if _, ok := m.projects[p.ID]; ok {
+		// Enabled concurrently while we were walking the tree
+		m.mu.Unlock()
+		return st.stopWatching()
+	}
 	m.projects[p.ID] = st
 	m.mu.Unlock()
 
-	go m.watchLoop(p.ID, st)
+	if st.watcher != nil {
+		go m.watchLoop(p.ID, st)
+	} else {
+		go m.pollLoop(p.ID, st, snapshot, p.Watch.PollInterval())
+	}
 	return nil
 }
 
@@ -123,39 +280,103 @@ func (m *Manager) Disable(projectID string) error {
 	m.mu.Lock()
 	st := m.projects[projectID]
 	delete(m.projects, projectID)
+	if st != nil {
+		st.closeClients()
+	}
 	m.mu.Unlock()
 
 	if st == nil {
 		return nil
 	}
+	return st.stopWatching()
+}
 
-	for _, ch := range st.clients {
+// closeClients ends the project's event streams. It is called with m.mu
+// held, after the state was removed from Manager.projects.
+func (st *projectState) closeClients() {
+	for id, ch := range st.clients {
 		close(ch)
+		delete(st.clients, id)
 	}
+}
 
-	if st.debounce != nil {
-		st.debounce.Stop()
+// stopWatching ends watchLoop or pollLoop and any running task. A pending
+// debounced reload finds the state gone and does nothing.
+func (st *projectState) stopWatching() error {
+	st.cancel()
+	if st.watcher == nil {
+		close(st.stop)
+		return nil
 	}
 	return st.watcher.Close()
 }
 
 func (m *Manager) EndpointURL(projectID string) string {
-	return fmt.Sprintf("http://127.0.0.1:%d/events?project=%s", m.port, projectID)
+	return fmt.Sprintf("http://127.0.0.1:%d/events?project=%s", m.port, url.QueryEscape(projectID))
 }
 
-func (m *Manager) ClientScript(projectID string) string {
-	// Use SSE (EventSource) for maximum compatibility
-	return fmt.Sprintf(`<script>
-(function(){
-  try {
-    var es = new EventSource('%s');
-    es.onmessage = function(){ location.reload(); };
-    es.onerror = function(){};
-  } catch (e) {}
-})();
-</script>`, m.EndpointURL(projectID))
+// ScriptURL is the address of the client script for a project.
+func ScriptURL(port int, projectID string) string {
+	return fmt.Sprintf("http://127.0.0.1:%d/livereload.js?project=%s", port, url.QueryEscape(projectID))
+}
+
+// handleScript serves the client that Apache injects into HTML pages. It
+// listens on the project's event stream, swaps stylesheets when only styles
+// changed and reloads the page otherwise.
+func (m *Manager) handleScript(w http.ResponseWriter, r *http.Request) {
+	endpoint, _ := json.Marshal(m.EndpointURL(r.URL.Query().Get("project")))
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, clientScript, endpoint)
 }
 
+// clientScript matches a changed stylesheet to <link> tags by base name
+// without extension, so app.scss also refreshes app.css; when nothing
+// matches (e.g. a bundle built from several files) every stylesheet is
+// refreshed. The new link is loaded before the old one is removed to avoid
+// a flash of unstyled content. A failed watch task is shown in an overlay
+// until the next successful change, or a click.
+const clientScript = `(function(){
+  if (!window.EventSource || window.__golocalLiveReload) return;
+  window.__golocalLiveReload = true;
+  function stem(p){ p = p.split('?')[0].split('#')[0]; p = p.substring(p.lastIndexOf('/') + 1); var i = p.lastIndexOf('.'); return i > 0 ? p.substring(0, i) : p; }
+  function swap(link){
+    var url = link.href.replace(/([?&])_lr=\d+&?/, '$1').replace(/[?&]$/, '');
+    var next = link.cloneNode();
+    next.href = url + (url.indexOf('?') < 0 ? '?' : '&') + '_lr=' + Date.now();
+    next.onload = next.onerror = function(){ if (link.parentNode) link.parentNode.removeChild(link); };
+    link.parentNode.insertBefore(next, link.nextSibling);
+  }
+  function swapStyles(paths){
+    var links = Array.prototype.slice.call(document.querySelectorAll('link[rel~="stylesheet"][href]'));
+    var stems = paths.map(stem);
+    var hits = links.filter(function(l){ return stems.indexOf(stem(l.href)) >= 0; });
+    (hits.length ? hits : links).forEach(swap);
+  }
+  function overlay(f){
+    var el = document.getElementById('__golocal_lr_error');
+    if (!f) { if (el) el.parentNode.removeChild(el); return; }
+    if (!el) {
+      el = document.createElement('pre');
+      el.id = '__golocal_lr_error';
+      el.style.cssText = 'position:fixed;top:0;left:0;right:0;bottom:0;z-index:2147483647;margin:0;padding:24px;overflow:auto;white-space:pre-wrap;background:rgba(24,24,24,.95);color:#ff8a80;font:13px/1.5 ui-monospace,Menlo,Consolas,monospace;cursor:pointer';
+      el.title = 'Click to dismiss';
+      el.onclick = function(){ overlay(null); };
+      document.body.appendChild(el);
+    }
+    el.textContent = 'Watch task failed: ' + f.command + (f.exit_code >= 0 ? ' (exit ' + f.exit_code + ')' : '') + '\n\n' + f.output;
+  }
+  var es = new EventSource(%s);
+  es.onmessage = function(e){
+    var c;
+    try { c = JSON.parse(e.data); } catch (err) { return; }
+    if (c.error) { overlay(c.error); return; }
+    overlay(null);
+    if (c.css) swapStyles(c.paths || []); else location.reload();
+  };
+})();
+`
+
 func (m *Manager) handleEvents(w http.ResponseWriter, r *http.Request) {
 	// Allow EventSource from project domains (e.g. http://myproject.test)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -196,7 +417,7 @@ func (m *Manager) handleEvents(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("X-Accel-Buffering", "no")
 
 	clientID := randomID(8)
-	ch := make(chan struct{}, 10)
+	ch := make(chan Change, 10)
 
 	m.mu.Lock()
 	// st may have been removed while we were building
@@ -209,16 +430,15 @@ func (m *Manager) handleEvents(w http.ResponseWriter, r *http.Request) {
 	st2.clients[clientID] = ch
 	m.mu.Unlock()
 
+	// Disable closes ch for clients still registered; the handler only
+	// unregisters itself.
 	defer func() {
 		m.mu.Lock()
-		if cur := m.projects[projectID]; cur != nil {
-			delete(cur.clients, clientID)
-		}
+		delete(st2.clients, clientID)
 		m.mu.Unlock()
-		close(ch)
 	}()
 
-	// Initial ping
+	// Initial ping; not JSON, so clients ignore it
 	_, _ = fmt.Fprintf(w, "data: ready\n\n")
 	flusher.Flush()
 
@@ -227,298 +447,23 @@ func (m *Manager) handleEvents(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-notify:
 			return
-		case _, ok := <-ch:
+		case c, ok := <-ch:
 			if !ok {
 				return
 			}
-			_, _ = fmt.Fprintf(w, "data: reload\n\n")
+			data, _ := json.Marshal(c)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
 			flusher.Flush()
 		}
 	}
 }
 
-func (m *Manager) watchLoop(projectID string, st *projectState) {
-	trigger := func() {
-		m.mu.Lock()
-		cur := m.projects[projectID]
-		m.mu.Unlock()
-		if cur == nil {
-			return
-		}
-
-		// Broadcast (non-blocking)
-		for _, ch := range cur.clients {
-			select {
-			case ch <- struct{}{}:
-			default:
-			}
-		}
-	}
-
-	debounced := func() {
-		if st.debounce != nil {
-			st.debounce.Stop()
-		}
-		st.debounce = time.AfterFunc(800*time.Millisecond, trigger)
-	}
-
-	for {
-		select {
-		case ev, ok := <-st.watcher.Events:
-			if !ok {
-				return
-			}
-			// If a new directory was created, add watch
-			if ev.Op&(fsnotify.Create) != 0 {
-				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
-					_ = addWatchRecursive(st.watcher, ev.Name)
-				}
-			}
-
-			// Skip ignored paths
-			if isIgnoredPath(ev.Name) {
-				continue
-			}
-			
-			// Skip temp/swap files
-			if isTempFile(ev.Name) {
-				continue
-			}
-			
-			// Only watch specific file extensions
-			if !isWatchedExtension(ev.Name) {
-				continue
-			}
-			
-			// Only trigger on write/create/remove/rename
-			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
-				debounced()
-			}
-		case _, ok := <-st.watcher.Errors:
-			if !ok {
-				return
-			}
-		}
-	}
-}
-
-func addWatchRecursive(w *fsnotify.Watcher, root string) error {
-	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			if isIgnoredPath(path) {
-				return filepath.SkipDir
-			}
-			_ = w.Add(path)
-		}
-		return nil
-	})
-}
-
-func isIgnoredPath(path string) bool {
-	p := strings.ToLower(path)
-	ignored := []string{
-		"/.git/", "/node_modules/", "/vendor/", "/.idea/", "/.vscode/",
-		"/storage/", "/dist/", "/bin/", "/.cache/", "/tmp/",
-		"/logs/", "/.DS_Store", "/Thumbs.db",
-	}
-	for _, ig := range ignored {
-		if strings.Contains(p, ig) {
-			return true
-		}
-	}
-	return false
-}
-
-func isTempFile(path string) bool {
-	p := strings.ToLower(path)
-	// Common temp file patterns
-	tempPatterns := []string{
-		".swp", ".swo", ".swn", ".swm", // vim swap files
-		"~", ".tmp", ".temp", ".bak", ".backup",
-		".sass-cache", ".eslintcache", ".cache-loader",
-		".log", ".pid", ".seed", ".pid.lock",
-	}
-	for _, pattern := range tempPatterns {
-		if strings.HasSuffix(p, pattern) || strings.Contains(p, pattern) {
-			return true
-		}
-	}
-	return false
-}
-
-func isWatchedExtension(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	// Only watch web development files
-	watched := []string{
-		".php", ".html", ".htm", ".js", ".jsx", ".ts", ".tsx",
-		".css", ".scss", ".sass", ".less", ".json", ".xml",
-		".vue", ".svelte", ".twig", ".blade.php", ".phtml",
-		".md", ".markdown", ".yaml", ".yml",
-	}
-	for _, w := range watched {
-		if ext == w || strings.HasSuffix(strings.ToLower(path), w) {
-			return true
-		}
-	}
-	return false
-}
-
 func randomID(nBytes int) string {
 	b := make([]byte, nBytes)
 	_, _ = rand.Read(b)
 	return hex.EncodeToString(b)
 }
 
-// TryInjectScript attempts to inject the live reload client script into a project's entry file.
-// It is best-effort and won't error if it cannot find a suitable file.
-func (m *Manager) TryInjectScript(p *projects.Project) error {
-	if p == nil {
-		return nil
-	}
-	entryCandidates := []string{}
-
-	// Prefer configured document root
-	if strings.TrimSpace(p.DocumentRoot) != "" {
-		entryCandidates = append(entryCandidates, filepath.Join(p.Path, p.DocumentRoot, "index.php"))
-		entryCandidates = append(entryCandidates, filepath.Join(p.Path, p.DocumentRoot, "index.html"))
-	}
-
-	entryCandidates = append(entryCandidates,
-		filepath.Join(p.Path, "public", "index.php"),
-		filepath.Join(p.Path, "public", "index.html"),
-		filepath.Join(p.Path, "index.php"),
-		filepath.Join(p.Path, "index.html"),
-	)
-
-	// If we can't find a common entry file, best-effort scan for an index file.
-	// Many existing projects use custom document roots (e.g. htdocs/, web/, src/).
-	foundExistingCandidate := false
-	for _, f := range entryCandidates {
-		if _, err := os.Stat(f); err == nil {
-			foundExistingCandidate = true
-			break
-		}
-	}
-	if !foundExistingCandidate {
-		if found := findIndexFiles(p.Path, 3); len(found) > 0 {
-			entryCandidates = append(entryCandidates, found...)
-		}
-	}
-
-	script := m.ClientScript(p.ID)
-	marker := "GoLocal LiveReload"
-	for _, f := range entryCandidates {
-		data, err := os.ReadFile(f)
-		if err != nil {
-			continue
-		}
-		if strings.Contains(string(data), marker) {
-			return nil
-		}
-
-		updated, ok := injectIntoHTML(f, string(data), script, marker)
-		if !ok {
-			continue
-		}
-
-		// Write back atomically
-		tmp := f + ".tmp"
-		if err := os.WriteFile(tmp, []byte(updated), 0644); err != nil {
-			return err
-		}
-		return os.Rename(tmp, f)
-	}
-	return nil
-}
-
-func injectIntoHTML(filename string, src string, script string, marker string) (string, bool) {
-	// Add marker comment to avoid duplicates
-	injectBlock := fmt.Sprintf("\n<!-- %s -->\n%s\n", marker, script)
-
-	lower := strings.ToLower(src)
-	idx := strings.LastIndex(lower, "</body>")
-	if idx >= 0 {
-		return src[:idx] + injectBlock + src[idx:], true
-	}
-
-	// If it looks like an HTML/PHP page, append at end
-	if strings.Contains(lower, "<html") || strings.Contains(lower, "<!doctype") || strings.Contains(lower, "<body") {
-		return src + injectBlock, true
-	}
-
-	// PHP-safe fallback:
-	// If this is a PHP entry file, it's common to not have </body> or even a closing ?>.
-	// We need to ensure we're in HTML context before injecting the script, otherwise
-	// we'll create nested PHP tags which causes parse errors.
-	if strings.EqualFold(filepath.Ext(filename), ".php") {
-		trimmed := strings.TrimSpace(src)
-		
-		// Check if file ends with an open PHP block (no closing ?>)
-		// In this case we need to close PHP first, then inject script
-		endsInPHPMode := false
-		lastClose := strings.LastIndex(trimmed, "?>")
-		lastOpen := strings.LastIndex(trimmed, "<?")
-		
-		if lastOpen > lastClose {
-			// Last thing is <?php or <? - we're in PHP mode
-			endsInPHPMode = true
-		}
-		
-		if endsInPHPMode {
-			// Close PHP context first, then inject script in HTML context
-			phpMarker := fmt.Sprintf("\n\n/* %s */\n?>\n", marker)
-			return src + phpMarker + script + "\n", true
-		}
-		
-		// If not in PHP mode, or file ends properly with ?>, just append the script
-		return src + "\n" + script + "\n", true
-	}
-
-	// Otherwise don't touch unknown files
-	return src, false
-}
-
-func findIndexFiles(root string, maxDepth int) []string {
-	var results []string
-	rootClean := filepath.Clean(root)
-	rootDepth := strings.Count(rootClean, string(os.PathSeparator))
-
-	_ = filepath.WalkDir(rootClean, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			if isIgnoredPath(path) {
-				return filepath.SkipDir
-			}
-			depth := strings.Count(filepath.Clean(path), string(os.PathSeparator)) - rootDepth
-			if maxDepth > 0 && depth > maxDepth {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		name := strings.ToLower(d.Name())
-		if name != "index.php" && name != "index.html" {
-			return nil
-		}
-		if isIgnoredPath(path) {
-			return nil
-		}
-		results = append(results, path)
-		// Cap to avoid scanning too many candidates
-		if len(results) >= 20 {
-			return fsnotify.ErrEventOverflow
-		}
-		return nil
-	})
-
-	return results
-}
-
 // TailFile streams last N lines from a file (utility for future use).
 func TailFile(path string, maxLines int) ([]string, error) {
 	f, err := os.Open(path)
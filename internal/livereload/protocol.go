@@ -0,0 +1,174 @@
+package livereload
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// ProtocolPort is where LiveReload browser extensions look for a server.
+const ProtocolPort = 35729
+
+const protocolV7 = "http://livereload.com/protocols/official-7"
+
+// protocolMessage covers the commands of the LiveReload v7 protocol that the
+// server sends or reads.
+type protocolMessage struct {
+	Command    string   `json:"command"`
+	Protocols  []string `json:"protocols,omitempty"`
+	ServerName string   `json:"serverName,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	LiveCSS    bool     `json:"liveCSS,omitempty"`
+	LiveImg    bool     `json:"liveImg,omitempty"`
+	Message    string   `json:"message,omitempty"`
+}
+
+// protocolHandler serves the LiveReload v7 WebSocket protocol. Browser
+// extensions connect from their own origin, so any origin is accepted; the
+// server only listens on loopback.
+func (m *Manager) protocolHandler() http.Handler {
+	return websocket.Server{
+		Handler:   m.handleProtocol,
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+	}
+}
+
+// handleProtocol answers the client's hello, then waits for the info command
+// naming the page URL. The page's host picks the project whose changes are
+// sent; until then the connection stays idle.
+func (m *Manager) handleProtocol(ws *websocket.Conn) {
+	defer ws.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	msgs := make(chan protocolMessage)
+	go func() {
+		defer close(msgs)
+		for {
+			var msg protocolMessage
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var (
+		st       *projectState
+		ch       chan Change
+		clientID = randomID(8)
+	)
+	leave := func() {
+		if st == nil {
+			return
+		}
+		m.mu.Lock()
+		delete(st.clients, clientID)
+		m.mu.Unlock()
+		st, ch = nil, nil
+	}
+	defer leave()
+
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			switch msg.Command {
+			case "hello":
+				if !containsString(msg.Protocols, protocolV7) {
+					return
+				}
+				hello := protocolMessage{Command: "hello", Protocols: []string{protocolV7}, ServerName: "GoLocalServer"}
+				if err := websocket.JSON.Send(ws, hello); err != nil {
+					return
+				}
+			case "info":
+				leave()
+				st, ch = m.joinByURL(msg.URL, clientID)
+			}
+		case c, ok := <-ch:
+			if !ok {
+				// The project was disabled; the client reconnects later.
+				return
+			}
+			for _, cmd := range protocolReloads(c) {
+				if err := websocket.JSON.Send(ws, cmd); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// joinByURL registers a client with the project serving pageURL.
+func (m *Manager) joinByURL(pageURL, clientID string) (*projectState, chan Change) {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Hostname())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best *projectState
+	for _, st := range m.projects {
+		if st.domain == "" || (host != st.domain && !strings.HasSuffix(host, "."+st.domain)) {
+			continue
+		}
+		if best == nil || len(st.domain) > len(best.domain) {
+			best = st
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	ch := make(chan Change, 10)
+	best.clients[clientID] = ch
+	return best, ch
+}
+
+// protocolReloads turns a change into reload commands. A style change names
+// each stylesheet with a .css extension, since the client only hot-swaps
+// .css paths and refreshes every stylesheet when none matches by name; any
+// other change needs a single full reload. A failed task becomes an alert.
+func protocolReloads(c Change) []protocolMessage {
+	if c.Error != nil {
+		msg := fmt.Sprintf("Watch task failed: %s\n\n%s", c.Error.Command, c.Error.Output)
+		return []protocolMessage{{Command: "alert", Message: msg}}
+	}
+	if !c.CSS {
+		for _, p := range c.Paths {
+			if !isStylesheet(p) {
+				return []protocolMessage{{Command: "reload", Path: "/" + p, LiveCSS: true, LiveImg: true}}
+			}
+		}
+	}
+	var cmds []protocolMessage
+	for _, p := range c.Paths {
+		if ext := path.Ext(p); ext != ".css" {
+			p = strings.TrimSuffix(p, ext) + ".css"
+		}
+		cmds = append(cmds, protocolMessage{Command: "reload", Path: "/" + p, LiveCSS: true, LiveImg: true})
+	}
+	return cmds
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package livereload
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go-local-server/internal/projects"
+)
+
+// defaultIgnore lists what is never watched unless an include glob names it:
+// dependency and build folders, editor files and swap files.
+var defaultIgnore = []string{
+	".git/", "node_modules/", "vendor/", ".idea/", ".vscode/",
+	"storage/", "dist/", "bin/", ".cache/", "tmp/", "logs/",
+	".DS_Store", "Thumbs.db",
+	"*.swp", "*.swo", "*.swn", "*.swm", "*~", ".#*", "#*#", "4913",
+	"*.tmp", "*.temp", "*.bak", "*.backup", "*.log", "*.pid", "*.pid.lock",
+	".sass-cache/", ".eslintcache", ".cache-loader/",
+}
+
+// defaultInclude is used when a project sets no include globs.
+var defaultInclude = []string{
+	"*.php", "*.phtml", "*.html", "*.htm", "*.twig",
+	"*.js", "*.jsx", "*.ts", "*.tsx", "*.vue", "*.svelte",
+	"*.css", "*.scss", "*.sass", "*.less",
+	"*.json", "*.xml", "*.md", "*.markdown", "*.yaml", "*.yml",
+}
+
+// glob is one compiled .gitignore-style pattern.
+type glob struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// prefix is the literal folder the pattern is anchored to ("" when it
+	// can match anywhere); an include glob can reach into ignored folders
+	// on that path.
+	prefix string
+}
+
+// compileGlob translates a pattern to a regular expression over
+// slash-separated paths relative to the folder the pattern belongs to. It
+// returns nil for blank lines and comments.
+func compileGlob(pattern string) *glob {
+	p := strings.TrimRight(pattern, " \t\r")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return nil
+	}
+	g := &glob{}
+	if strings.HasPrefix(p, "!") {
+		g.negate = true
+		p = p[1:]
+	}
+	p = strings.TrimPrefix(p, `\`) // "\#file" and "\!file"
+	if strings.HasSuffix(p, "/") {
+		g.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to its folder.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if anchored {
+		var lit []string
+		for _, seg := range strings.Split(p, "/") {
+			if strings.ContainsAny(seg, `*?[\`) {
+				break
+			}
+			lit = append(lit, seg)
+		}
+		g.prefix = strings.Join(lit, "/")
+	} else {
+		p = "**/" + p
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "/**") && i+3 == len(p):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(p):
+			i++
+			re.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+
+	var err error
+	if g.re, err = regexp.Compile(re.String()); err != nil {
+		return nil
+	}
+	return g
+}
+
+func (g *glob) match(rel string, isDir bool) bool {
+	if g.dirOnly && !isDir {
+		return false
+	}
+	return g.re.MatchString(rel)
+}
+
+func compileGlobs(patterns []string) []*glob {
+	var out []*glob
+	for _, p := range patterns {
+		if g := compileGlob(p); g != nil {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// rules decides which paths of one project are watched. Paths are
+// slash-separated and relative to the project root. A rules value is only
+// used by the goroutine watching its project.
+type rules struct {
+	root    string
+	include []*glob
+	exclude []*glob
+	ignore  []*glob
+	tasks   []task
+	// gitignore holds the patterns of each .gitignore file, keyed by the
+	// folder that contains it ("" for the root). Folders without one map to
+	// nil.
+	gitignore map[string][]*glob
+}
+
+func newRules(root string, w projects.WatchRules) *rules {
+	r := &rules{
+		root:      root,
+		include:   compileGlobs(w.Include),
+		exclude:   compileGlobs(w.Exclude),
+		ignore:    compileGlobs(defaultIgnore),
+		gitignore: make(map[string][]*glob),
+	}
+	if len(r.include) == 0 {
+		r.include = compileGlobs(defaultInclude)
+	}
+	for _, t := range w.Tasks {
+		if g := compileGlob(t.Glob); g != nil {
+			r.tasks = append(r.tasks, task{glob: g, command: t.Command})
+		}
+	}
+	return r
+}
+
+// rel converts an absolute path to the form the rules match against. ok is
+// false for paths outside the root.
+func (r *rules) rel(p string) (string, bool) {
+	rel, err := filepath.Rel(r.root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// loadGitignore (re)reads the .gitignore in folder dir, given relative to
+// the root.
+func (r *rules) loadGitignore(dir string) {
+	data, err := os.ReadFile(filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		r.gitignore[dir] = nil
+		return
+	}
+	var globs []*glob
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if g := compileGlob(sc.Text()); g != nil {
+			globs = append(globs, g)
+		}
+	}
+	r.gitignore[dir] = globs
+}
+
+func (r *rules) hasGitignoreEntry(dir string) bool {
+	_, ok := r.gitignore[dir]
+	return ok
+}
+
+// watchDir reports whether folder rel is scanned or watched.
+func (r *rules) watchDir(rel string) bool {
+	if rel == "." || rel == "" {
+		return true
+	}
+	if matchAncestors(r.exclude, rel, true) {
+		return false
+	}
+	if !r.ignored(rel, true) {
+		return true
+	}
+	for _, g := range r.include {
+		if g.prefix != "" && (rel == g.prefix || strings.HasPrefix(g.prefix, rel+"/") || strings.HasPrefix(rel, g.prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchFile reports whether a change to file rel triggers a reload.
+func (r *rules) watchFile(rel string) bool {
+	if matchAncestors(r.exclude, rel, false) {
+		return false
+	}
+	included := false
+	for _, g := range r.include {
+		if matchAncestors([]*glob{g}, rel, false) {
+			if g.prefix != "" {
+				// Named explicitly, so it wins over ignored folders.
+				return true
+			}
+			included = true
+		}
+	}
+	return included && !r.ignored(rel, false)
+}
+
+// relevant reports whether a change to file rel triggers a reload or a
+// task. Task globs are matched even outside the include globs, so sources
+// such as .ts files need not be included themselves.
+func (r *rules) relevant(rel string) bool {
+	return r.watchFile(rel) || len(r.tasksFor([]string{rel})) > 0
+}
+
+// ignored applies the built-in list and every .gitignore above rel. As in
+// git, a file inside an ignored folder cannot be re-included.
+func (r *rules) ignored(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		if r.ignoredEntry(parts[:i], i < len(parts) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredEntry evaluates one path with its own name last. The last matching
+// pattern wins, and deeper .gitignore files come after shallower ones.
+func (r *rules) ignoredEntry(parts []string, isDir bool) bool {
+	p := strings.Join(parts, "/")
+	ignored := false
+	for _, g := range r.ignore {
+		if g.match(p, isDir) {
+			ignored = true
+		}
+	}
+	for depth := 0; depth < len(parts); depth++ {
+		dir := strings.Join(parts[:depth], "/")
+		for _, g := range r.gitignore[dir] {
+			if g.match(strings.Join(parts[depth:], "/"), isDir) {
+				ignored = !g.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// matchAncestors reports whether any glob matches rel or one of its
+// folders.
+func matchAncestors(globs []*glob, rel string, isDir bool) bool {
+	if len(globs) == 0 {
+		return false
+	}
+	for p, dir := rel, isDir; p != "." && p != "/" && p != ""; p, dir = path.Dir(p), true {
+		for _, g := range globs {
+			if g.match(p, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,363 @@
+package livereload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-local-server/internal/projects"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		// Unanchored patterns match at any depth.
+		{"*.php", "index.php", false, true},
+		{"*.php", "app/Http/Kernel.php", false, true},
+		{"*.php", "index.phtml", false, false},
+		{"*.php", "app/php", false, false},
+		{"?.js", "a.js", false, true},
+		{"?.js", "ab.js", false, false},
+		{"?.js", "dir/a.js", false, true},
+		// * and ? stop at slashes.
+		{"a*c", "ab/c", false, false},
+		// A slash anchors the pattern to its folder.
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"src/*.js", "src/app.js", false, true},
+		{"src/*.js", "lib/src/app.js", false, false},
+		{"src/*.js", "src/deep/app.js", false, false},
+		// ** spans folders.
+		{"**/cache", "cache", true, true},
+		{"**/cache", "a/b/cache", true, true},
+		{"public/**", "public/build/app.js", false, true},
+		{"public/**", "public", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"**.min.js", "dist/app.min.js", false, true},
+		// A trailing slash only matches folders.
+		{"logs/", "logs", true, true},
+		{"logs/", "logs", false, false},
+		{"logs/", "app/logs", true, true},
+		// Character classes, including negated ones.
+		{"*.[ch]", "main.c", false, true},
+		{"*.[ch]", "main.o", false, false},
+		{"*.[!o]", "main.c", false, true},
+		{"*.[!o]", "main.o", false, false},
+		{"[abc", "[abc", false, true},
+		// Escapes and regexp metacharacters are literal.
+		{`\#notes`, "#notes", false, true},
+		{`\!important`, "!important", false, true},
+		{"a+b.(x)", "a+b.(x)", false, true},
+		{"a+b.(x)", "aab.(x)", false, false},
+		{`file\*`, "file*", false, true},
+		{`file\*`, "filex", false, false},
+		// Trailing spaces are trimmed.
+		{"*.log  ", "app.log", false, true},
+	}
+	for _, tt := range tests {
+		g := compileGlob(tt.pattern)
+		if g == nil {
+			t.Errorf("compileGlob(%q) = nil", tt.pattern)
+			continue
+		}
+		if got := g.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q matching %q (dir %v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestCompileGlobSkipsBlankAndComments(t *testing.T) {
+	for _, p := range []string{"", "   ", "# comment", "!", "/", "!/"} {
+		if g := compileGlob(p); g != nil {
+			t.Errorf("compileGlob(%q) = %v, want nil", p, g.re)
+		}
+	}
+}
+
+func TestCompileGlobFlags(t *testing.T) {
+	tests := []struct {
+		pattern string
+		negate  bool
+		dirOnly bool
+		prefix  string
+	}{
+		{"*.php", false, false, ""},
+		{"!keep.log", true, false, ""},
+		{"vendor/", false, true, ""},
+		{"public/build/**", false, false, "public/build"},
+		{"/vendor/acme/*.php", false, false, "vendor/acme"},
+		{"src/**/*.ts", false, false, "src"},
+		{"*/assets", false, false, ""},
+	}
+	for _, tt := range tests {
+		g := compileGlob(tt.pattern)
+		if g.negate != tt.negate || g.dirOnly != tt.dirOnly || g.prefix != tt.prefix {
+			t.Errorf("%q: negate %v dirOnly %v prefix %q, want %v %v %q",
+				tt.pattern, g.negate, g.dirOnly, g.prefix, tt.negate, tt.dirOnly, tt.prefix)
+		}
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	r := newRules(t.TempDir(), projects.WatchRules{})
+
+	files := []struct {
+		path string
+		want bool
+	}{
+		{"index.php", true},
+		{"resources/views/home.blade.php", true},
+		{"public/css/app.css", true},
+		{"src/App.vue", true},
+		{"composer.json", true},
+		{"README.md", true},
+		{"image.png", false},
+		{"Makefile", false},
+		// Built-in ignores: dependency folders, editor and swap files.
+		{"vendor/laravel/framework/src/Foundation/Application.php", false},
+		{"node_modules/vue/index.js", false},
+		{"app/node_modules/x/index.js", false},
+		{".git/HEAD", false},
+		{"storage/logs/laravel.log", false},
+		{".index.php.swp", false},
+		{"index.php~", false},
+		{".#index.php", false},
+		{"#index.php#", false},
+		{"4913", false},
+		{"app.php.bak", false},
+		{".DS_Store", false},
+		// "logs/" only names folders.
+		{"logs.md", true},
+	}
+	for _, tt := range files {
+		if got := r.watchFile(tt.path); got != tt.want {
+			t.Errorf("watchFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	dirs := []struct {
+		path string
+		want bool
+	}{
+		{".", true},
+		{"", true},
+		{"app", true},
+		{"public/css", true},
+		{"vendor", false},
+		{"node_modules", false},
+		{"src/node_modules", false},
+		{".git", false},
+		{".idea", false},
+		{"bin", false},
+	}
+	for _, tt := range dirs {
+		if got := r.watchDir(tt.path); got != tt.want {
+			t.Errorf("watchDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIncludeAndExclude(t *testing.T) {
+	r := newRules(t.TempDir(), projects.WatchRules{
+		Include: []string{"*.php", "templates/**", "vendor/acme/theme/**", "/config.ini"},
+		Exclude: []string{"tests/", "*.generated.php", "templates/cache/"},
+	})
+
+	files := []struct {
+		path string
+		want bool
+	}{
+		// Include replaces the defaults.
+		{"index.php", true},
+		{"public/app.js", false},
+		{"templates/home.html.twig", true},
+		{"templates/partials/nav.svg", true},
+		{"config.ini", true},
+		{"app/config.ini", false},
+		// An include glob that names an ignored folder reaches into it...
+		{"vendor/acme/theme/style.css", true},
+		// ...but only that folder.
+		{"vendor/acme/other/index.php", false},
+		// Exclude wins over include, at any level.
+		{"tests/FeatureTest.php", false},
+		{"app/tests/UnitTest.php", false},
+		{"app/Models/User.generated.php", false},
+		{"templates/cache/home.php", false},
+	}
+	for _, tt := range files {
+		if got := r.watchFile(tt.path); got != tt.want {
+			t.Errorf("watchFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	dirs := []struct {
+		path string
+		want bool
+	}{
+		// Folders on the way to an include glob's prefix are walked.
+		{"vendor", true},
+		{"vendor/acme", true},
+		{"vendor/acme/theme", true},
+		{"vendor/acme/theme/css", true},
+		{"vendor/acme/other", false},
+		{"vendor/laravel", false},
+		{"tests", false},
+		{"app/tests", false},
+		{"templates/cache", false},
+		{"templates", true},
+	}
+	for _, tt := range dirs {
+		if got := r.watchDir(tt.path); got != tt.want {
+			t.Errorf("watchDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), `# build output
+/build/
+*.cache.php
+docs/*.md
+!docs/README.md
+secret?.json
+\#draft.html
+`)
+	writeFile(t, filepath.Join(root, "app", ".gitignore"), `local.php
+!keep.cache.php
+generated/
+`)
+	writeFile(t, filepath.Join(root, "app", "generated", ".gitignore"), "!*.php\n")
+
+	r := newRules(root, projects.WatchRules{})
+	for _, dir := range []string{"", "app", "app/generated", "docs"} {
+		r.loadGitignore(dir)
+	}
+
+	files := []struct {
+		path string
+		want bool
+	}{
+		{"index.php", true},
+		{"build/index.html", false},
+		{"src/build/index.html", true},
+		{"routes.cache.php", false},
+		{"app/routes.cache.php", false},
+		{"docs/guide.md", false},
+		{"docs/api/guide.md", true},
+		// Negation re-includes a file...
+		{"docs/README.md", true},
+		{"secret1.json", false},
+		{"secret10.json", true},
+		{"#draft.html", false},
+		// ...and a deeper .gitignore overrides a shallower one.
+		{"app/local.php", false},
+		{"local.php", true},
+		{"app/keep.cache.php", true},
+		{"app/sub/keep.cache.php", true},
+		// A file inside an ignored folder cannot be re-included.
+		{"app/generated/models.php", false},
+	}
+	for _, tt := range files {
+		if got := r.watchFile(tt.path); got != tt.want {
+			t.Errorf("watchFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"build", false},
+		{"src/build", true},
+		{"app", true},
+		{"app/generated", false},
+		{"docs", true},
+	} {
+		if got := r.watchDir(tt.path); got != tt.want {
+			t.Errorf("watchDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Removing a .gitignore and reloading forgets its patterns.
+	if err := os.Remove(filepath.Join(root, "app", ".gitignore")); err != nil {
+		t.Fatal(err)
+	}
+	r.loadGitignore("app")
+	if !r.hasGitignoreEntry("app") {
+		t.Error("a folder without .gitignore has no entry")
+	}
+	if !r.watchFile("app/local.php") {
+		t.Error("app/local.php is still ignored after its .gitignore was removed")
+	}
+}
+
+func TestRel(t *testing.T) {
+	root := t.TempDir()
+	r := newRules(root, projects.WatchRules{})
+	tests := []struct {
+		path string
+		rel  string
+		ok   bool
+	}{
+		{filepath.Join(root, "app", "index.php"), "app/index.php", true},
+		{root, ".", true},
+		{filepath.Join(root, "..", "other"), "", false},
+		{filepath.Dir(root), "", false},
+		{filepath.Join(root, "..x"), "..x", true},
+	}
+	for _, tt := range tests {
+		rel, ok := r.rel(tt.path)
+		if rel != tt.rel || ok != tt.ok {
+			t.Errorf("rel(%q) = %q, %v, want %q, %v", tt.path, rel, ok, tt.rel, tt.ok)
+		}
+	}
+}
+
+func TestRelevantWithTasks(t *testing.T) {
+	r := newRules(t.TempDir(), projects.WatchRules{
+		Tasks: []projects.WatchTask{
+			{Glob: "resources/ts/**/*.ts", Command: "npm run build"},
+			{Glob: "*.scss", Command: "sass"},
+			{Glob: "assets/**/*.coffee", Command: "coffee -c assets"},
+		},
+	})
+	tests := []struct {
+		path  string
+		want  bool
+		tasks int
+	}{
+		{"index.php", true, 0},
+		{"resources/ts/app.ts", true, 1},
+		{"resources/ts/lib/util.ts", true, 1},
+		{"resources/app.ts", true, 0},
+		// Task sources need not be included themselves.
+		{"assets/js/app.coffee", true, 1},
+		{"lib/app.coffee", false, 0},
+		{"public/css/app.scss", true, 1},
+		{"image.png", false, 0},
+	}
+	for _, tt := range tests {
+		if got := r.relevant(tt.path); got != tt.want {
+			t.Errorf("relevant(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+		if got := len(r.tasksFor([]string{tt.path})); got != tt.tasks {
+			t.Errorf("tasksFor(%q) = %d tasks, want %d", tt.path, got, tt.tasks)
+		}
+	}
+}
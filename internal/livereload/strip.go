@@ -0,0 +1,64 @@
+package livereload
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go-local-server/internal/projects"
+)
+
+// injectedScript matches the <script> block that earlier releases wrote into
+// project entry files, together with the marker and line breaks around it.
+// The three alternatives are: before </body> or appended to an HTML page;
+// appended to a PHP file that ended in PHP mode (which also added "?>"); and
+// appended to any other PHP file, without a marker.
+var injectedScript = regexp.MustCompile(
+	`(?:\r?\n<!-- GoLocal LiveReload -->\r?\n|\r?\n\r?\n/\* GoLocal LiveReload \*/\r?\n\?>\r?\n|\r?\n)?` +
+		`<script>\s*\(function\(\)\{\s*try \{\s*var es = new EventSource\('http://127\.0\.0\.1:\d+/events\?project=[^']*'\);\s*` +
+		`es\.onmessage = function\(\)\{ location\.reload\(\); \};\s*es\.onerror = function\(\)\{\};\s*` +
+		`\} catch \(e\) \{\}\s*\}\)\(\);\s*</script>\r?\n?`)
+
+// maxStripSize skips files too large to be hand-written entry points.
+const maxStripSize = 4 << 20
+
+// StripInjected removes live reload scripts that earlier releases wrote into
+// the HTML and PHP files under root, and returns the files it changed (or
+// would change, with dryRun).
+func StripInjected(root string, dryRun bool) ([]string, error) {
+	var changed []string
+	r := newRules(root, projects.WatchRules{})
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if rel, ok := r.rel(path); !ok || !r.watchDir(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".php", ".phtml", ".html", ".htm":
+		default:
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxStripSize {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || !injectedScript.Match(data) {
+			return nil
+		}
+		changed = append(changed, path)
+		if dryRun {
+			return nil
+		}
+		return os.WriteFile(path, injectedScript.ReplaceAll(data, nil), info.Mode().Perm())
+	})
+	return changed, err
+}
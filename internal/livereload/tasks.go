@@ -0,0 +1,128 @@
+package livereload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"go-local-server/internal/config"
+)
+
+const (
+	taskTimeout = 5 * time.Minute
+	// maxTaskOutput bounds the output kept for the browser overlay; the
+	// log file gets all of it.
+	maxTaskOutput = 16 << 10
+)
+
+// TaskFailure describes a watch task that failed; it is sent to clients
+// instead of a reload.
+type TaskFailure struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"` // -1 when the command could not run or timed out
+	Output   string `json:"output"`    // combined stdout and stderr, possibly truncated
+}
+
+// task is a compiled projects.WatchTask.
+type task struct {
+	glob    *glob
+	command string
+}
+
+// TaskLogPath is where the output of a project's watch tasks is appended.
+func TaskLogPath(projectID string) string {
+	return filepath.Join(config.LogDir, "livereload-"+projectID+".log")
+}
+
+// tasksFor returns the tasks triggered by paths, in configured order.
+func (r *rules) tasksFor(paths []string) []task {
+	var out []task
+	for _, t := range r.tasks {
+		for _, p := range paths {
+			if matchAncestors([]*glob{t.glob}, p, false) {
+				out = append(out, t)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// runTasks runs the tasks triggered by paths one after another and stops at
+// the first failure.
+func (st *projectState) runTasks(projectID string, paths []string) *TaskFailure {
+	for _, t := range st.rules.tasksFor(paths) {
+		if f := st.runTask(projectID, t.command); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func (st *projectState) runTask(projectID, command string) *TaskFailure {
+	ctx, cancel := context.WithTimeout(st.ctx, taskTimeout)
+	defer cancel()
+
+	// A login shell picks up the PATH of node version managers and
+	// Homebrew, which apps started from the desktop do not inherit.
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.CommandContext(ctx, shell, "-lc", command)
+	cmd.Dir = st.root
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", taskTimeout)
+	}
+	logTask(projectID, command, start, out.Bytes(), err)
+	if err == nil {
+		return nil
+	}
+	if st.ctx.Err() != nil {
+		// Live reload was switched off while the task ran.
+		return nil
+	}
+
+	f := &TaskFailure{Command: command, ExitCode: -1, Output: out.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		f.ExitCode = exitErr.ExitCode()
+	} else {
+		f.Output += err.Error() + "\n"
+	}
+	if len(f.Output) > maxTaskOutput {
+		f.Output = "...\n" + f.Output[len(f.Output)-maxTaskOutput:]
+	}
+	return f
+}
+
+// logTask appends one run to the project's task log.
+func logTask(projectID, command string, start time.Time, output []byte, err error) {
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	_ = os.MkdirAll(config.LogDir, 0755)
+	f, ferr := os.OpenFile(TaskLogPath(projectID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if ferr != nil {
+		fmt.Printf("Live reload task log: %v\n", ferr)
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "==> %s %s (%s, %s)\n", start.Format("2006-01-02 15:04:05"), command, status, time.Since(start).Round(time.Millisecond))
+	f.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		f.Write([]byte("\n"))
+	}
+}
@@ -0,0 +1,248 @@
+package livereload
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"go-local-server/internal/events"
+)
+
+// fileStamp is what pollLoop compares between scans.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// walk visits the folders the rules allow under dir, reading each new
+// folder's .gitignore before its entries are matched, and calls file for the
+// files that trigger a reload.
+func (st *projectState) walk(dir string, folder func(abs string) error, file func(rel string, d fs.DirEntry)) error {
+	return filepath.WalkDir(dir, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if abs == dir {
+				return err
+			}
+			return nil
+		}
+		rel, ok := st.rules.rel(abs)
+		if !ok {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			if !st.rules.watchDir(rel) {
+				return filepath.SkipDir
+			}
+			if dir := gitignoreDir(rel); !st.rules.hasGitignoreEntry(dir) {
+				st.rules.loadGitignore(dir)
+			}
+			if folder != nil {
+				return folder(abs)
+			}
+			return nil
+		}
+		if file != nil && st.rules.relevant(rel) {
+			file(rel, d)
+		}
+		return nil
+	})
+}
+
+// addWatches watches dir and the folders below it.
+func (st *projectState) addWatches(dir string) error {
+	return st.walk(dir, func(abs string) error {
+		_ = st.watcher.Add(abs)
+		return nil
+	}, nil)
+}
+
+// scan records the watched files for pollLoop, plus the .gitignore files
+// of the folders seen so far so that edits to them are noticed.
+func (st *projectState) scan() (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	stamp := func(rel string, d fs.DirEntry) {
+		if info, err := d.Info(); err == nil {
+			files[rel] = fileStamp{info.Size(), info.ModTime()}
+		}
+	}
+	err := st.walk(st.root, nil, stamp)
+	for dir := range st.rules.gitignore {
+		rel := path.Join(dir, ".gitignore")
+		if info, err := os.Stat(filepath.Join(st.root, filepath.FromSlash(rel))); err == nil {
+			files[rel] = fileStamp{info.Size(), info.ModTime()}
+		}
+	}
+	return files, err
+}
+
+func gitignoreDir(rel string) string {
+	if rel == "." {
+		return ""
+	}
+	return rel
+}
+
+func (m *Manager) watchLoop(projectID string, st *projectState) {
+	for {
+		select {
+		case ev, ok := <-st.watcher.Events:
+			if !ok {
+				return
+			}
+			rel, ok := st.rules.rel(ev.Name)
+			if !ok {
+				continue
+			}
+			if path.Base(rel) == ".gitignore" {
+				st.rules.loadGitignore(gitignoreDir(path.Dir(rel)))
+				continue
+			}
+
+			// If a new directory was created, add watch
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if st.rules.watchDir(rel) {
+						_ = st.addWatches(ev.Name)
+					}
+					continue
+				}
+			}
+
+			// Only trigger on write/create/remove/rename
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 && st.rules.relevant(rel) {
+				m.changed(projectID, st, rel)
+			}
+		case _, ok := <-st.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// pollLoop rescans the project every interval, for folders where fsnotify
+// misses changes (network mounts, some VM shares).
+func (m *Manager) pollLoop(projectID string, st *projectState, prev map[string]fileStamp, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := st.scan()
+		if err != nil {
+			// The folder is unreachable; keep the last snapshot.
+			continue
+		}
+
+		// New .gitignore rules hide or reveal files that did not change, so
+		// rescan with them and only compare files present both times.
+		rulesChanged := false
+		for _, rel := range changedStamps(prev, cur) {
+			if path.Base(rel) == ".gitignore" {
+				st.rules.loadGitignore(gitignoreDir(path.Dir(rel)))
+				rulesChanged = true
+			}
+		}
+		if rulesChanged {
+			if cur, err = st.scan(); err != nil {
+				continue
+			}
+		}
+
+		for _, rel := range changedStamps(prev, cur) {
+			_, before := prev[rel]
+			_, after := cur[rel]
+			if path.Base(rel) == ".gitignore" || (rulesChanged && before != after) {
+				continue
+			}
+			m.changed(projectID, st, rel)
+		}
+		prev = cur
+	}
+}
+
+// changedStamps lists the files added, modified or removed between two
+// scans.
+func changedStamps(prev, cur map[string]fileStamp) []string {
+	var out []string
+	for rel, stamp := range cur {
+		if old, ok := prev[rel]; !ok || old != stamp {
+			out = append(out, rel)
+		}
+	}
+	for rel := range prev {
+		if _, ok := cur[rel]; !ok {
+			out = append(out, rel)
+		}
+	}
+	return out
+}
+
+// changed records a changed file and restarts the debounce timer. It is only
+// called from the goroutine watching the project.
+func (m *Manager) changed(projectID string, st *projectState, rel string) {
+	m.mu.Lock()
+	st.changed[rel] = true
+	m.mu.Unlock()
+
+	if st.debounce != nil {
+		st.debounce.Stop()
+	}
+	st.debounce = time.AfterFunc(st.watch.Debounce(), func() {
+		m.flush(projectID, st)
+	})
+}
+
+// flush runs the watch tasks for the collected changes and then tells the
+// project's clients to reload, or shows them the failure. Flushes of one
+// project never overlap: files that change while tasks run (their output,
+// usually) are collected and flushed afterwards.
+func (m *Manager) flush(projectID string, st *projectState) {
+	st.flushMu.Lock()
+	defer st.flushMu.Unlock()
+
+	m.mu.Lock()
+	if m.projects[projectID] != st || len(st.changed) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	paths := make([]string, 0, len(st.changed))
+	for p := range st.changed {
+		paths = append(paths, p)
+	}
+	st.changed = make(map[string]bool)
+	m.mu.Unlock()
+
+	c := newChange(paths)
+	if f := st.runTasks(projectID, c.Paths); f != nil {
+		c = Change{Paths: c.Paths, Error: f}
+	}
+	m.broadcast(projectID, st, c)
+}
+
+// broadcast sends c to the project's clients and publishes it on the
+// event bus.
+func (m *Manager) broadcast(projectID string, st *projectState, c Change) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.projects[projectID] != st {
+		return
+	}
+	m.events.Publish(events.FileChanged{Project: projectID, Paths: c.Paths, Failed: c.Error != nil})
+
+	// Broadcast (non-blocking)
+	for _, ch := range st.clients {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
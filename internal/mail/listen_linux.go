@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"net"
+	"strings"
+)
+
+// With native dockerd, host.docker.internal resolves to the bridge gateway,
+// so the catcher listens on every interface and turns away peers that are
+// neither local nor on a Docker network.
+const listenHost = "0.0.0.0"
+
+func allowedPeer(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	if tcp.IP.IsLoopback() {
+		return true
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		// docker0 is the default bridge; compose networks get br-<id>.
+		if iface.Name != "docker0" && !strings.HasPrefix(iface.Name, "br-") {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.Contains(tcp.IP) {
+				return true
+			}
+		}
+	}
+	return false
+}
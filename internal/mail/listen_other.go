@@ -0,0 +1,11 @@
+//go:build !darwin && !linux
+
+package mail
+
+import "net"
+
+const listenHost = "127.0.0.1"
+
+func allowedPeer(net.Addr) bool {
+	return true
+}
@@ -0,0 +1,176 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+)
+
+// maxParts bounds how many MIME parts of one message are looked at, so a
+// hostile message cannot make parsing expensive.
+const maxParts = 200
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// charsetReader keeps words in charsets other than UTF-8 and ASCII as raw
+// bytes rather than failing the whole header.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
+
+// part is one leaf of a MIME tree with its transfer encoding undone.
+type part struct {
+	header      netmail.Header
+	contentType string
+	params      map[string]string
+	body        []byte
+}
+
+// parse fills the header and body fields of m from the raw message and
+// returns its parts, which Attachment.Index refers to. A message that is not
+// valid MIME is still stored; its source becomes the text body.
+func parse(m *Message, raw []byte) []part {
+	msg, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		m.Text = string(raw)
+		return nil
+	}
+
+	m.Headers = make(map[string][]string, len(msg.Header))
+	for k, vs := range msg.Header {
+		for _, v := range vs {
+			m.Headers[k] = append(m.Headers[k], decodeHeader(v))
+		}
+	}
+	m.Subject = decodeHeader(msg.Header.Get("Subject"))
+	if from := msg.Header.Get("From"); from != "" {
+		m.From = decodeHeader(from)
+	}
+	m.HeaderTo = nil
+	for _, key := range []string{"To", "Cc"} {
+		if list, err := msg.Header.AddressList(key); err == nil {
+			for _, a := range list {
+				m.HeaderTo = append(m.HeaderTo, a.String())
+			}
+		}
+	}
+	if d, err := msg.Header.Date(); err == nil {
+		m.Sent = d
+	}
+
+	parts := walk(msg.Header, msg.Body)
+	for i, p := range parts {
+		name := p.params["filename"]
+		if name == "" {
+			name = p.params["name"]
+		}
+		disposition, _, _ := mime.ParseMediaType(p.header.Get("Content-Disposition"))
+		switch {
+		case disposition != "attachment" && name == "" && p.contentType == "text/plain" && m.Text == "":
+			m.Text = string(p.body)
+		case disposition != "attachment" && name == "" && p.contentType == "text/html" && m.HTML == "":
+			m.HTML = string(p.body)
+		default:
+			m.Attachments = append(m.Attachments, Attachment{
+				Index:       i,
+				Filename:    decodeHeader(name),
+				ContentType: p.contentType,
+				ContentID:   strings.Trim(p.header.Get("Content-Id"), "<>"),
+				Inline:      disposition == "inline",
+				Size:        len(p.body),
+			})
+		}
+	}
+	return parts
+}
+
+// walk returns the leaf parts of a message in document order.
+func walk(h netmail.Header, body io.Reader) []part {
+	var out []part
+	var visit func(h netmail.Header, body io.Reader)
+	visit = func(h netmail.Header, body io.Reader) {
+		if len(out) >= maxParts {
+			return
+		}
+		ct, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+		if err != nil {
+			ct, params = "text/plain", map[string]string{}
+		}
+		if strings.HasPrefix(ct, "multipart/") && params["boundary"] != "" {
+			mr := multipart.NewReader(body, params["boundary"])
+			for len(out) < maxParts {
+				// NextRawPart leaves quoted-printable bodies alone, so every
+				// part is decoded the same way below.
+				p, err := mr.NextRawPart()
+				if err != nil {
+					return
+				}
+				visit(netmail.Header(p.Header), p)
+			}
+			return
+		}
+		data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+		if err != nil && len(data) == 0 {
+			return
+		}
+		out = append(out, part{header: h, contentType: ct, params: params, body: data})
+	}
+	visit(h, body)
+	return out
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &stripSpace{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// stripSpace drops the line breaks base64 bodies are wrapped with.
+type stripSpace struct {
+	r io.Reader
+}
+
+func (s *stripSpace) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		j := 0
+		for _, c := range p[:n] {
+			if c != '\r' && c != '\n' && c != ' ' && c != '\t' {
+				p[j] = c
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
+
+// Address returns the bare mailbox of an address such as
+// "Jane <jane@example.com>", or s trimmed when it cannot be parsed.
+func Address(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if a, err := netmail.ParseAddress(s); err == nil {
+		return a.Address
+	}
+	return strings.Trim(s, "<>")
+}
+
+func decodeHeader(v string) string {
+	if d, err := wordDecoder.DecodeHeader(v); err == nil {
+		return d
+	}
+	return v
+}
@@ -0,0 +1,377 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/config"
+)
+
+const (
+	// maxMessageSize is advertised with SIZE; larger messages are refused.
+	maxMessageSize = 25 << 20
+	maxRecipients  = 100
+	idleTimeout    = 5 * time.Minute
+)
+
+// Server is the SMTP side of the mail catcher. It accepts any sender and
+// recipient, never relays, and accepts AUTH with any credentials so that
+// framework mailers configured for a real server work unchanged.
+type Server struct {
+	config *config.AppConfig
+	store  *Store
+
+	mu       sync.Mutex
+	listener net.Listener
+	running  bool
+	conns    map[net.Conn]bool
+	wg       sync.WaitGroup
+
+	// OnMessage, if set, is called after a message has been stored.
+	OnMessage func(*Message)
+}
+
+func NewServer(cfg *config.AppConfig, store *Store) *Server {
+	return &Server{
+		config: cfg,
+		store:  store,
+		conns:  make(map[net.Conn]bool),
+	}
+}
+
+// Start listens on MailPort. Containers reach the server through
+// host.docker.internal; see listenHost for the address used on each OS.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return fmt.Errorf("mail server already running")
+	}
+	addr := net.JoinHostPort(listenHost, strconv.Itoa(s.config.MailPort))
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("mail listen %s: %w", addr, err)
+	}
+	s.listener = l
+	s.running = true
+	s.wg.Add(1)
+	go s.serve(l)
+	return nil
+}
+
+// Stop closes the listener and every open session.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	err := s.listener.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Addr returns the address the server listens on, or "" when stopped.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+func (s *Server) serve(l net.Listener) {
+	defer s.wg.Done()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			fmt.Printf("Mail accept: %v\n", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if !allowedPeer(c.RemoteAddr()) {
+			c.Close()
+			continue
+		}
+
+		s.mu.Lock()
+		if !s.running {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, c)
+				s.mu.Unlock()
+				c.Close()
+			}()
+			defer func() {
+				if rec := recover(); rec != nil {
+					fmt.Printf("Mail session panic: %v\n%s\n", rec, debug.Stack())
+				}
+			}()
+			s.session(c)
+		}()
+	}
+}
+
+// session runs one SMTP conversation (RFC 5321, without relaying).
+func (s *Server) session(c net.Conn) {
+	tp := textproto.NewConn(c)
+	reply := func(code int, msg string) bool {
+		c.SetWriteDeadline(time.Now().Add(idleTimeout))
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	if !reply(220, hostname+" GoLocalServer mail catcher ready") {
+		return
+	}
+
+	var (
+		helo       bool
+		from       string
+		haveFrom   bool
+		recipients []string
+	)
+	reset := func() {
+		from, haveFrom, recipients = "", false, nil
+	}
+
+	for {
+		c.SetReadDeadline(time.Now().Add(idleTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		arg = strings.TrimSpace(arg)
+
+		switch verb {
+		case "HELO":
+			helo = true
+			reset()
+			reply(250, hostname)
+		case "EHLO":
+			helo = true
+			reset()
+			c.SetWriteDeadline(time.Now().Add(idleTimeout))
+			tp.PrintfLine("250-%s", hostname)
+			tp.PrintfLine("250-SIZE %d", maxMessageSize)
+			tp.PrintfLine("250-8BITMIME")
+			tp.PrintfLine("250-PIPELINING")
+			tp.PrintfLine("250-SMTPUTF8")
+			tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "AUTH":
+			if !s.auth(tp, reply, arg) {
+				return
+			}
+		case "MAIL":
+			addr, params, ok := pathArg(arg, "FROM:")
+			switch {
+			case !helo:
+				reply(503, "5.5.1 Send HELO or EHLO first")
+			case !ok:
+				reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+			case haveFrom:
+				reply(503, "5.5.1 Sender already given")
+			case sizeParam(params) > maxMessageSize:
+				reply(552, "5.3.4 Message too big")
+			default:
+				from, haveFrom, recipients = addr, true, nil
+				reply(250, "2.1.0 OK")
+			}
+		case "RCPT":
+			addr, _, ok := pathArg(arg, "TO:")
+			switch {
+			case !haveFrom:
+				reply(503, "5.5.1 Send MAIL first")
+			case !ok || addr == "":
+				reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+			case len(recipients) >= maxRecipients:
+				reply(452, "4.5.3 Too many recipients")
+			default:
+				recipients = append(recipients, addr)
+				reply(250, "2.1.5 OK")
+			}
+		case "DATA":
+			if len(recipients) == 0 {
+				reply(503, "5.5.1 Send RCPT first")
+				continue
+			}
+			if !reply(354, "End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			c.SetReadDeadline(time.Now().Add(idleTimeout))
+			dr := tp.DotReader()
+			data, err := io.ReadAll(io.LimitReader(dr, maxMessageSize+1))
+			if err != nil {
+				return
+			}
+			if len(data) > maxMessageSize {
+				// Read the rest so the session stays in step; a second
+				// DotReader would take the next commands for the body.
+				if _, err := io.Copy(io.Discard, dr); err != nil {
+					return
+				}
+				reply(552, "5.3.4 Message too big")
+				reset()
+				continue
+			}
+			m, err := s.store.Save(from, recipients, data)
+			reset()
+			if err != nil {
+				fmt.Printf("Mail store: %v\n", err)
+				reply(451, "4.3.0 Could not store the message")
+				continue
+			}
+			reply(250, "2.0.0 OK: queued as "+m.ID)
+			if s.OnMessage != nil {
+				s.OnMessage(m)
+			}
+		case "RSET":
+			reset()
+			reply(250, "2.0.0 OK")
+		case "NOOP":
+			reply(250, "2.0.0 OK")
+		case "VRFY":
+			reply(252, "2.5.0 Cannot verify, but will accept")
+		case "STARTTLS":
+			reply(502, "5.5.1 TLS is not supported")
+		case "QUIT":
+			reply(221, "2.0.0 Bye")
+			return
+		default:
+			reply(500, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+// auth accepts AUTH PLAIN and AUTH LOGIN with any credentials. It returns
+// false when the connection broke.
+func (s *Server) auth(tp *textproto.Conn, reply func(int, string) bool, arg string) bool {
+	mech, initial, _ := strings.Cut(arg, " ")
+	// Each challenge is answered by one base64 line; "*" cancels.
+	ask := func(challenge string) (string, bool) {
+		if !reply(334, challenge) {
+			return "", false
+		}
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", false
+		}
+		return line, true
+	}
+	switch strings.ToUpper(mech) {
+	case "PLAIN":
+		if initial == "" {
+			line, ok := ask("")
+			if !ok {
+				return false
+			}
+			initial = line
+		}
+		if initial == "*" {
+			return reply(501, "5.7.0 Authentication cancelled")
+		}
+	case "LOGIN":
+		for _, prompt := range []string{"Username:", "Password:"} {
+			if initial != "" && prompt == "Username:" {
+				continue
+			}
+			line, ok := ask(base64.StdEncoding.EncodeToString([]byte(prompt)))
+			if !ok {
+				return false
+			}
+			if line == "*" {
+				return reply(501, "5.7.0 Authentication cancelled")
+			}
+		}
+	default:
+		return reply(504, "5.5.4 Unrecognized authentication type")
+	}
+	return reply(235, "2.7.0 Authentication successful")
+}
+
+// pathArg parses "FROM:<addr> PARAMS" or "TO:<addr> PARAMS". The null
+// sender "<>" gives an empty address.
+func pathArg(arg, prefix string) (addr, params string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if strings.HasPrefix(rest, "<") {
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			return "", "", false
+		}
+		return rest[1:end], strings.TrimSpace(rest[end+1:]), true
+	}
+	// Some clients leave out the brackets.
+	addr, params, _ = strings.Cut(rest, " ")
+	return addr, params, addr != ""
+}
+
+// sizeParam returns the SIZE= value of MAIL parameters, or 0.
+func sizeParam(params string) int {
+	for _, p := range strings.Fields(params) {
+		if k, v, ok := strings.Cut(p, "="); ok && strings.EqualFold(k, "SIZE") {
+			n, _ := strconv.Atoi(v)
+			return n
+		}
+	}
+	return 0
+}
+
+// MsmtpConfig is the msmtp configuration the PHP containers use as their
+// sendmail, pointed at this server on the Docker host.
+func MsmtpConfig(cfg *config.AppConfig) []byte {
+	var b bytes.Buffer
+	b.WriteString("# Generated by GoLocalServer: PHP's mail() goes to the mail catcher.\n")
+	b.WriteString("defaults\n")
+	b.WriteString("auth off\n")
+	b.WriteString("tls off\n\n")
+	b.WriteString("account golocal\n")
+	b.WriteString("host host.docker.internal\n")
+	fmt.Fprintf(&b, "port %d\n", cfg.MailPort)
+	b.WriteString("from php@" + cfg.Domain + "\n\n")
+	b.WriteString("account default : golocal\n")
+	return b.Bytes()
+}
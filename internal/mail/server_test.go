@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"go-local-server/internal/config"
+)
+
+// startServer runs a mail server on a free port and returns a connection
+// to it, past the greeting.
+func startServer(t *testing.T) (*Server, *textproto.Conn) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.MailPort = 0
+	s := NewServer(cfg, NewStore(t.TempDir()))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	_, port, _ := net.SplitHostPort(s.Addr())
+	c, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDeadline(time.Now().Add(30 * time.Second))
+	tp := textproto.NewConn(c)
+	t.Cleanup(func() { tp.Close() })
+	expect(t, tp, 220)
+	return s, tp
+}
+
+func expect(t *testing.T, tp *textproto.Conn, code int) string {
+	t.Helper()
+	_, msg, err := tp.ReadResponse(code)
+	if err != nil {
+		t.Fatalf("want %d: %v", code, err)
+	}
+	return msg
+}
+
+func send(t *testing.T, tp *textproto.Conn, cmd string, code int) string {
+	t.Helper()
+	if err := tp.PrintfLine("%s", cmd); err != nil {
+		t.Fatal(err)
+	}
+	return expect(t, tp, code)
+}
+
+func TestDeliver(t *testing.T) {
+	s, tp := startServer(t)
+	send(t, tp, "EHLO client", 250)
+	send(t, tp, "MAIL FROM:<app@blog.test>", 250)
+	send(t, tp, "RCPT TO:<user@example.com>", 250)
+	send(t, tp, "DATA", 354)
+	w := tp.DotWriter()
+	w.Write([]byte("Subject: Hi\r\n\r\n.leading dot\r\nbody\r\n"))
+	w.Close()
+	msg := expect(t, tp, 250)
+	send(t, tp, "QUIT", 221)
+
+	id := strings.TrimPrefix(msg, "2.0.0 OK: queued as ")
+	raw, err := s.store.Raw(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "\n.leading dot\nbody") {
+		t.Errorf("stored %q", raw)
+	}
+}
+
+func TestOversizeMessage(t *testing.T) {
+	s, tp := startServer(t)
+	send(t, tp, "HELO client", 250)
+	send(t, tp, "MAIL FROM:<app@blog.test>", 250)
+	send(t, tp, "RCPT TO:<user@example.com>", 250)
+	send(t, tp, "DATA", 354)
+
+	w := tp.DotWriter()
+	bw := bufio.NewWriter(w)
+	line := strings.Repeat("x", 998) + "\r\n"
+	// The server stores lines ending in \n alone.
+	for n := 0; n <= maxMessageSize; n += len(line) - 1 {
+		bw.WriteString(line)
+	}
+	bw.Flush()
+	w.Close()
+	// The next command follows right behind the message, as with
+	// PIPELINING, and must not be read as part of it.
+	if err := tp.PrintfLine("QUIT"); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, tp, 552)
+	expect(t, tp, 221)
+
+	if msgs, err := s.store.List(""); err != nil || len(msgs) != 0 {
+		t.Errorf("stored %d messages (%v)", len(msgs), err)
+	}
+}
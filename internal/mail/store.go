@@ -0,0 +1,336 @@
+// Package mail catches the mail that projects send. A small SMTP server
+// accepts every message and keeps it on disk, where the GUI, golocalctl and
+// the control API read it back per recipient.
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-local-server/internal/config"
+)
+
+// maxMessages is how many messages are kept; the oldest are dropped first.
+const maxMessages = 500
+
+var (
+	// ErrNotFound is returned for unknown message ids.
+	ErrNotFound = errors.New("message not found")
+	// ErrNoAttachment is returned for attachment indexes a message lacks.
+	ErrNoAttachment = errors.New("attachment not found")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-z]+-[0-9a-f]+$`)
+
+// Message is a caught message. List fills the summary fields; Get also
+// parses the headers and bodies from the stored MIME source.
+type Message struct {
+	ID           string    `json:"id"`
+	Received     time.Time `json:"received"`
+	EnvelopeFrom string    `json:"envelope_from"`
+	Recipients   []string  `json:"recipients"` // envelope recipients, one mailbox each
+	From         string    `json:"from"`
+	HeaderTo     []string  `json:"to,omitempty"` // To and Cc as written in the message
+	Subject      string    `json:"subject"`
+	Sent         time.Time `json:"sent"` // Date header; zero when missing
+	Size         int       `json:"size"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	Headers map[string][]string `json:"headers,omitempty"`
+	Text    string              `json:"text,omitempty"`
+	HTML    string              `json:"html,omitempty"`
+}
+
+// Attachment describes a MIME part that is not the text or HTML body.
+type Attachment struct {
+	Index       int    `json:"index"` // pass to Store.Attachment
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"` // referenced as cid: from the HTML body
+	Inline      bool   `json:"inline,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// Mailbox is one envelope recipient with the number of messages caught for
+// it.
+type Mailbox struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// Store keeps each message as <id>.eml with its summary in <id>.json. The
+// GUI and golocalctl may use the same folder at once; every call reads the
+// folder afresh.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir is where caught mail is kept.
+func DefaultDir() string {
+	return filepath.Join(config.DataDir, "mail")
+}
+
+// Dir returns the folder the store writes to.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save stores a message as received over SMTP and drops the oldest messages
+// beyond the limit.
+func (s *Store) Save(from string, recipients []string, raw []byte) (*Message, error) {
+	now := time.Now()
+	m := &Message{
+		ID:           strconv.FormatInt(now.UnixNano(), 36) + "-" + randomHex(3),
+		Received:     now,
+		EnvelopeFrom: from,
+		Recipients:   recipients,
+		Size:         len(raw),
+	}
+	parse(m, raw)
+	summary := *m
+	summary.Headers, summary.Text, summary.HTML = nil, "", ""
+	meta, err := json.MarshalIndent(&summary, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.path(m.ID, ".eml"), raw, 0600); err != nil {
+		return nil, err
+	}
+	// The summary is written last: List only sees complete messages.
+	if err := os.WriteFile(s.path(m.ID, ".json"), meta, 0600); err != nil {
+		os.Remove(s.path(m.ID, ".eml"))
+		return nil, err
+	}
+	s.prune()
+	return m, nil
+}
+
+// List returns the summaries of the messages sent to recipient (every
+// message when it is empty), newest first.
+func (s *Store) List(recipient string) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	recipient = Address(recipient)
+	if recipient == "" {
+		return all, nil
+	}
+	var out []*Message
+	for _, m := range all {
+		if m.sentTo(recipient) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// Mailboxes lists the envelope recipients seen, sorted by address.
+func (s *Store) Mailboxes() ([]Mailbox, error) {
+	msgs, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, m := range msgs {
+		for _, r := range m.Recipients {
+			counts[strings.ToLower(r)]++
+		}
+	}
+	out := make([]Mailbox, 0, len(counts))
+	for addr, n := range counts {
+		out = append(out, Mailbox{Address: addr, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+	return out, nil
+}
+
+// Get returns a message with its headers and bodies.
+func (s *Store) Get(id string) (*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(s.path(id, ".eml"))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	m.Attachments = nil
+	parse(m, raw)
+	return m, nil
+}
+
+// Raw returns the MIME source of a message.
+func (s *Store) Raw(id string) ([]byte, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id, ".eml"))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return data, nil
+}
+
+// Attachment returns the decoded content of a message part by the index
+// listed in Message.Attachments.
+func (s *Store) Attachment(id string, index int) (Attachment, []byte, error) {
+	raw, err := s.Raw(id)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	var m Message
+	parts := parse(&m, raw)
+	for _, a := range m.Attachments {
+		if a.Index == index {
+			return a, parts[index].body, nil
+		}
+	}
+	return Attachment{}, nil, ErrNoAttachment
+}
+
+// Delete removes one message.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.load(id); err != nil {
+		return err
+	}
+	return s.remove(id)
+}
+
+// Clear removes the messages sent to recipient, or every message when it
+// is empty, and returns how many were removed.
+func (s *Store) Clear(recipient string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.list()
+	if err != nil {
+		return 0, err
+	}
+	recipient = Address(recipient)
+	n := 0
+	for _, m := range all {
+		if recipient != "" && !m.sentTo(recipient) {
+			continue
+		}
+		if err := s.remove(m.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) path(id, ext string) string {
+	return filepath.Join(s.dir, id+ext)
+}
+
+func (s *Store) load(id string) (*Message, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id, ".json"))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("message %s: %v", id, err)
+	}
+	return &m, nil
+}
+
+// list loads every summary, newest first. Unreadable ones are skipped.
+func (s *Store) list() ([]*Message, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []*Message
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if m, err := s.load(id); err == nil {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Received.After(out[j].Received) })
+	return out, nil
+}
+
+func (s *Store) remove(id string) error {
+	if err := os.Remove(s.path(id, ".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.path(id, ".eml")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) prune() {
+	all, err := s.list()
+	if err != nil || len(all) <= maxMessages {
+		return
+	}
+	for _, m := range all[maxMessages:] {
+		if err := s.remove(m.ID); err != nil {
+			fmt.Printf("Mail: prune %s: %v\n", m.ID, err)
+		}
+	}
+}
+
+func (m *Message) sentTo(addr string) bool {
+	for _, r := range m.Recipients {
+		if strings.EqualFold(r, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func notFound(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func randomHex(nBytes int) string {
+	b := make([]byte, nBytes)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,86 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-local-server/internal/platform"
+)
+
+var _ packageManager = (*Apt)(nil)
+
+// Apt installs Debian and Ubuntu packages with apt-get, as root. PHP
+// versions other than the distribution's need a repository that ships them
+// side by side, such as ppa:ondrej/php.
+type Apt struct {
+	r Runner
+}
+
+// NewApt returns an Apt that runs its commands through r, or ExecRunner
+// when r is nil.
+func NewApt(r Runner) *Apt {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	return &Apt{r: r}
+}
+
+func (a *Apt) Name() string   { return "apt" }
+func (a *Apt) runner() Runner { return a.r }
+
+// aptPHP is the prefix of a PHP version's packages: php, or php8.1.
+func aptPHP(version string) string {
+	return "php" + version
+}
+
+func (a *Apt) Packages(ctx context.Context, dep Dependency) ([]string, error) {
+	switch dep.Kind {
+	case KindServer, KindTool:
+		// Debian has no mysql-server; default-mysql-server is MySQL on
+		// Ubuntu and MariaDB on Debian, both with /usr/sbin/mysqld.
+		return []string{map[string]string{"httpd": "apache2", "nginx": "nginx", "mysql": "default-mysql-server", "composer": "composer"}[dep.ID]}, nil
+	case KindPHP:
+		p := aptPHP(dep.PHPVersion)
+		return []string{p + "-fpm", p + "-cli", p + "-mysql"}, nil
+	case KindExtension:
+		return []string{aptPHP(dep.PHPVersion) + "-" + dep.Extension}, nil
+	}
+	return nil, fmt.Errorf("%s: %w", dep.ID, ErrUnsupported)
+}
+
+func (a *Apt) IsInstalled(ctx context.Context, dep Dependency) (bool, error) {
+	return isInstalled(ctx, a, dep)
+}
+
+func (a *Apt) installed(ctx context.Context, pkg string) bool {
+	out, err := a.r.Run(ctx, nil, "dpkg-query", "-W", "-f=${Status}", pkg)
+	return err == nil && strings.Contains(string(out), "install ok installed")
+}
+
+func (a *Apt) InstallWithProgress(ctx context.Context, dep Dependency, callback ProgressCallback) error {
+	return installWithProgress(ctx, a, dep, callback)
+}
+
+func (a *Apt) installArgs(pkgs []string) []string {
+	return platform.PrivilegedArgs(append([]string{"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y"}, pkgs...))
+}
+
+func (a *Apt) BinPath(ctx context.Context, dep Dependency) (string, error) {
+	switch dep.Kind {
+	case KindExtension:
+		return "", nil
+	case KindPHP:
+		// Every version's php-fpm is named after it.
+		version := dep.PHPVersion
+		if version == "" {
+			v, err := phpMinorVersion(ctx, a.r, "php")
+			if err != nil {
+				return "", err
+			}
+			version = v
+		}
+		return "/usr/sbin/php-fpm" + version, nil
+	}
+	return map[string]string{"httpd": "/usr/sbin/apache2", "nginx": "/usr/sbin/nginx", "mysql": "/usr/sbin/mysqld", "composer": "/usr/bin/composer"}[dep.ID], nil
+}
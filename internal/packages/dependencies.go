@@ -0,0 +1,138 @@
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+)
+
+// Kind groups dependencies by what they are.
+type Kind string
+
+const (
+	KindServer    Kind = "server"    // httpd, nginx, mysql
+	KindPHP       Kind = "php"       // PHP-FPM and the CLI
+	KindExtension Kind = "extension" // a PHP extension
+	KindTool      Kind = "tool"      // composer
+)
+
+// Extensions are the PHP extensions that can be installed, beyond the ones
+// PHP is built with.
+var Extensions = []string{"redis", "xdebug", "imagick", "intl"}
+
+// Dependency is something the native stack can be given. IDs are the same
+// on every package manager: httpd, nginx, mysql, composer, php for the
+// package manager's own PHP, php@8.1 for a version, and php-redis or
+// php@8.1-redis for an extension.
+type Dependency struct {
+	ID    string
+	Title string
+	Kind  Kind
+	// PHPVersion is the version of a PHP or extension dependency; empty for
+	// the package manager's default PHP.
+	PHPVersion string
+	// Extension is the name of an extension dependency, e.g. redis.
+	Extension string
+}
+
+// Servers and tools, by ID.
+var (
+	Apache   = Dependency{ID: "httpd", Title: "Apache", Kind: KindServer}
+	Nginx    = Dependency{ID: "nginx", Title: "Nginx", Kind: KindServer}
+	MySQL    = Dependency{ID: "mysql", Title: "MySQL", Kind: KindServer}
+	Composer = Dependency{ID: "composer", Title: "Composer", Kind: KindTool}
+)
+
+// PHP is the dependency of a PHP version; "" is the package manager's
+// default PHP.
+func PHP(version string) Dependency {
+	if version == "" {
+		return Dependency{ID: "php", Title: "PHP", Kind: KindPHP}
+	}
+	return Dependency{ID: "php@" + version, Title: "PHP " + version, Kind: KindPHP, PHPVersion: version}
+}
+
+// Extension is the dependency of a PHP extension for a PHP version; "" is
+// the package manager's default PHP.
+func Extension(version, name string) Dependency {
+	php := PHP(version)
+	return Dependency{
+		ID:         php.ID + "-" + name,
+		Title:      php.Title + " " + name,
+		Kind:       KindExtension,
+		PHPVersion: version,
+		Extension:  name,
+	}
+}
+
+// Builtins lists the dependencies in display order: the servers, PHP and
+// every PHP version projects can use, the extensions of the default PHP and
+// Composer. Lookup also knows the extensions of the other versions.
+var Builtins = builtins()
+
+func builtins() []Dependency {
+	deps := []Dependency{Apache, Nginx, MySQL, PHP("")}
+	for _, v := range projects.PHPVersions {
+		deps = append(deps, PHP(v))
+	}
+	for _, ext := range Extensions {
+		deps = append(deps, Extension("", ext))
+	}
+	return append(deps, Composer)
+}
+
+// Lookup finds a dependency by ID, e.g. nginx, php@8.1 or php@8.1-redis.
+func Lookup(id string) (Dependency, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, d := range []Dependency{Apache, Nginx, MySQL, Composer} {
+		if id == d.ID {
+			return d, nil
+		}
+	}
+	if id == "apache" || id == "apache2" {
+		return Apache, nil
+	}
+
+	php, ext, _ := strings.Cut(id, "-")
+	var version string
+	switch {
+	case php == "php":
+	case strings.HasPrefix(php, "php@"):
+		version = strings.TrimPrefix(php, "php@")
+		if !projects.ValidPHPVersion(version) {
+			return Dependency{}, fmt.Errorf("unknown PHP version %q (want one of %s)", version, strings.Join(projects.PHPVersions, ", "))
+		}
+	default:
+		return Dependency{}, fmt.Errorf("unknown dependency %q (want one of %s, or php@<version>-<extension>)", id, strings.Join(IDs(), ", "))
+	}
+	if ext == "" {
+		return PHP(version), nil
+	}
+	for _, e := range Extensions {
+		if ext == e {
+			return Extension(version, ext), nil
+		}
+	}
+	return Dependency{}, fmt.Errorf("unknown PHP extension %q (want one of %s)", ext, strings.Join(Extensions, ", "))
+}
+
+// IDs lists the IDs of Builtins.
+func IDs() []string {
+	ids := make([]string, len(Builtins))
+	for i, d := range Builtins {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+// Stack is what the native backend needs for cfg: its web server, PHP and
+// MySQL.
+func Stack(cfg *config.AppConfig) []Dependency {
+	web := Apache
+	if cfg.Nginx() {
+		web = Nginx
+	}
+	return []Dependency{web, PHP(""), MySQL}
+}
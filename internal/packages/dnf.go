@@ -0,0 +1,93 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-local-server/internal/platform"
+)
+
+var _ packageManager = (*Dnf)(nil)
+
+// Dnf installs Fedora and RHEL packages with dnf, as root. PHP versions
+// come from the Remi repository's side-by-side packages (php81-php-fpm),
+// which has to be enabled first.
+type Dnf struct {
+	r Runner
+}
+
+// NewDnf returns a Dnf that runs its commands through r, or ExecRunner
+// when r is nil.
+func NewDnf(r Runner) *Dnf {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	return &Dnf{r: r}
+}
+
+func (d *Dnf) Name() string   { return "dnf" }
+func (d *Dnf) runner() Runner { return d.r }
+
+// dnfPHP is the prefix of a PHP version's packages: php, or php81-php from
+// Remi.
+func dnfPHP(version string) string {
+	if version == "" {
+		return "php"
+	}
+	return "php" + strings.ReplaceAll(version, ".", "") + "-php"
+}
+
+// dnfExtensions are the package names of the extensions after the PHP
+// prefix.
+var dnfExtensions = map[string]string{
+	"redis":   "pecl-redis6",
+	"xdebug":  "pecl-xdebug3",
+	"imagick": "pecl-imagick",
+	"intl":    "intl",
+}
+
+func (d *Dnf) Packages(ctx context.Context, dep Dependency) ([]string, error) {
+	switch dep.Kind {
+	case KindServer, KindTool:
+		return []string{map[string]string{"httpd": "httpd", "nginx": "nginx", "mysql": "mysql-server", "composer": "composer"}[dep.ID]}, nil
+	case KindPHP:
+		p := dnfPHP(dep.PHPVersion)
+		return []string{p + "-fpm", p + "-cli", p + "-mysqlnd"}, nil
+	case KindExtension:
+		if name, ok := dnfExtensions[dep.Extension]; ok {
+			return []string{dnfPHP(dep.PHPVersion) + "-" + name}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", dep.ID, ErrUnsupported)
+}
+
+func (d *Dnf) IsInstalled(ctx context.Context, dep Dependency) (bool, error) {
+	return isInstalled(ctx, d, dep)
+}
+
+func (d *Dnf) installed(ctx context.Context, pkg string) bool {
+	_, err := d.r.Run(ctx, nil, "rpm", "-q", pkg)
+	return err == nil
+}
+
+func (d *Dnf) InstallWithProgress(ctx context.Context, dep Dependency, callback ProgressCallback) error {
+	return installWithProgress(ctx, d, dep, callback)
+}
+
+func (d *Dnf) installArgs(pkgs []string) []string {
+	return platform.PrivilegedArgs(append([]string{"dnf", "install", "-y"}, pkgs...))
+}
+
+func (d *Dnf) BinPath(ctx context.Context, dep Dependency) (string, error) {
+	switch dep.Kind {
+	case KindExtension:
+		return "", nil
+	case KindPHP:
+		if dep.PHPVersion == "" {
+			return "/usr/sbin/php-fpm", nil
+		}
+		return "/opt/remi/php" + strings.ReplaceAll(dep.PHPVersion, ".", "") + "/root/usr/sbin/php-fpm", nil
+	}
+	return map[string]string{"httpd": "/usr/sbin/httpd", "nginx": "/usr/sbin/nginx", "mysql": "/usr/libexec/mysqld", "composer": "/usr/bin/composer"}[dep.ID], nil
+}
@@ -0,0 +1,109 @@
+package packages
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var _ packageManager = (*Homebrew)(nil)
+
+// Homebrew installs formulae with brew. PHP 7.4 and 8.0 come from the
+// shivammathur/php tap and the extensions from shivammathur/extensions,
+// which brew taps on first use.
+type Homebrew struct {
+	r Runner
+}
+
+// NewHomebrew returns a Homebrew that runs brew through r, or ExecRunner
+// when r is nil.
+func NewHomebrew(r Runner) *Homebrew {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	return &Homebrew{r: r}
+}
+
+func (h *Homebrew) Name() string   { return "brew" }
+func (h *Homebrew) runner() Runner { return h.r }
+
+func (h *Homebrew) Packages(ctx context.Context, dep Dependency) ([]string, error) {
+	switch dep.Kind {
+	case KindServer, KindTool:
+		return []string{dep.ID}, nil
+	case KindPHP:
+		return []string{phpFormula(dep.PHPVersion)}, nil
+	case KindExtension:
+		if dep.Extension == "intl" {
+			// Homebrew's PHP is built with it.
+			return nil, nil
+		}
+		version := dep.PHPVersion
+		if version == "" {
+			prefix, err := h.prefix(ctx, "php")
+			if err != nil {
+				return nil, err
+			}
+			if version, err = phpMinorVersion(ctx, h.r, filepath.Join(prefix, "bin", "php")); err != nil {
+				return nil, err
+			}
+		}
+		return []string{"shivammathur/extensions/" + dep.Extension + "@" + version}, nil
+	}
+	return nil, fmt.Errorf("%s: %w", dep.ID, ErrUnsupported)
+}
+
+func phpFormula(version string) string {
+	switch version {
+	case "":
+		return "php"
+	case "7.4", "8.0":
+		return "shivammathur/php/php@" + version
+	}
+	return "php@" + version
+}
+
+func (h *Homebrew) IsInstalled(ctx context.Context, dep Dependency) (bool, error) {
+	return isInstalled(ctx, h, dep)
+}
+
+func (h *Homebrew) installed(ctx context.Context, pkg string) bool {
+	out, err := h.r.Run(ctx, nil, "brew", "list", "--versions", pkg)
+	return err == nil && strings.TrimSpace(string(out)) != ""
+}
+
+func (h *Homebrew) InstallWithProgress(ctx context.Context, dep Dependency, callback ProgressCallback) error {
+	return installWithProgress(ctx, h, dep, callback)
+}
+
+func (h *Homebrew) installArgs(pkgs []string) []string {
+	return append([]string{"brew", "install"}, pkgs...)
+}
+
+// prefix is where a formula is linked, installed or not.
+func (h *Homebrew) prefix(ctx context.Context, formula string) (string, error) {
+	out, err := h.r.Run(ctx, nil, "brew", "--prefix", formula)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (h *Homebrew) BinPath(ctx context.Context, dep Dependency) (string, error) {
+	var formula, bin string
+	switch dep.Kind {
+	case KindExtension:
+		return "", nil
+	case KindPHP:
+		formula, bin = phpFormula(dep.PHPVersion), "sbin/php-fpm"
+	default:
+		formula = dep.ID
+		bin = map[string]string{"httpd": "bin/httpd", "nginx": "bin/nginx", "mysql": "bin/mysqld", "composer": "bin/composer"}[dep.ID]
+	}
+	prefix, err := h.prefix(ctx, formula)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(prefix, bin), nil
+}
@@ -0,0 +1,297 @@
+// Package packages installs what the native service backend runs on with
+// the system's package manager: Homebrew on macOS, apt or dnf on Linux.
+// Dependencies are named the same everywhere (see Lookup); each
+// PackageManager maps them to its own packages. Commands go through a
+// Runner, so a fake one can stand in for the real package manager.
+package packages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-local-server/internal/config"
+)
+
+// ErrNoPackageManager is returned by Detect when neither Homebrew, apt nor
+// dnf is available.
+var ErrNoPackageManager = errors.New("no supported package manager found (want brew, apt or dnf)")
+
+// ErrUnsupported is returned for a dependency a package manager has no
+// package for.
+var ErrUnsupported = errors.New("not available from this package manager")
+
+// ProgressCallback receives installation progress: a percentage, or -1 for
+// a line of output without one.
+type ProgressCallback func(percent int, message string)
+
+// PackageManager installs dependencies.
+type PackageManager interface {
+	// Name is brew, apt or dnf.
+	Name() string
+	// Packages returns the packages that provide dep. It is empty when dep
+	// comes with another package, e.g. intl with Homebrew's PHP.
+	Packages(ctx context.Context, dep Dependency) ([]string, error)
+	IsInstalled(ctx context.Context, dep Dependency) (bool, error)
+	// InstallWithProgress installs dep unless it is installed, streaming
+	// the package manager's output to callback.
+	InstallWithProgress(ctx context.Context, dep Dependency, callback ProgressCallback) error
+	// BinPath is where the binary of a dependency installed by this package
+	// manager is, e.g. php-fpm for PHP; empty for extensions.
+	BinPath(ctx context.Context, dep Dependency) (string, error)
+}
+
+// Runner runs the commands of a package manager.
+type Runner interface {
+	// Run runs name with args and returns its standard output. When onLine
+	// is not nil, every line of stdout and stderr is also passed to it as
+	// it is printed. A non-zero exit is an error.
+	Run(ctx context.Context, onLine func(line string), name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands on this machine.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, onLine func(line string), name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if onLine != nil {
+		// exec copies stdout and stderr in separate goroutines.
+		var mu sync.Mutex
+		out, errOut := &lineWriter{mu: &mu, fn: onLine}, &lineWriter{mu: &mu, fn: onLine}
+		defer out.flush()
+		defer errOut.flush()
+		cmd.Stdout = &teeWriter{&stdout, out}
+		cmd.Stderr = &teeWriter{&stderr, errOut}
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("%s: %w: %s", name, err, lastLine(msg))
+		}
+		return stdout.Bytes(), fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+type teeWriter struct {
+	buf   *bytes.Buffer
+	lines *lineWriter
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	return w.lines.Write(p)
+}
+
+// lineWriter calls fn with each complete line written to it.
+type lineWriter struct {
+	mu      *sync.Mutex
+	fn      func(string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		// Progress bars redraw with \r.
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimSpace(string(w.partial[:i])); line != "" {
+			w.fn(line)
+		}
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if line := strings.TrimSpace(string(w.partial)); line != "" {
+		w.fn(line)
+	}
+	w.partial = nil
+}
+
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
+
+// Detect returns the package manager of this machine: Homebrew when it is
+// installed, else apt or dnf. A nil runner runs commands with ExecRunner.
+func Detect(ctx context.Context, r Runner) (PackageManager, error) {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	candidates := []struct {
+		cmd string
+		pm  PackageManager
+	}{
+		{"brew", NewHomebrew(r)},
+		{"apt-get", NewApt(r)},
+		{"dnf", NewDnf(r)},
+	}
+	for _, c := range candidates {
+		if _, err := r.Run(ctx, nil, c.cmd, "--version"); err == nil {
+			return c.pm, nil
+		}
+	}
+	return nil, ErrNoPackageManager
+}
+
+// Result is what EnsureAll did about one dependency.
+type Result struct {
+	Dependency Dependency
+	// Installed is set when the dependency was installed by this run; it
+	// is false when it was already there or installing it failed.
+	Installed bool
+	Err       error
+}
+
+// EnsureAll installs every dependency in deps that is missing, carrying on
+// past failures, and returns a result per dependency in the same order.
+// progress, if not nil, receives each installation's progress.
+func EnsureAll(ctx context.Context, pm PackageManager, deps []Dependency, progress func(dep Dependency, percent int, message string)) []Result {
+	results := make([]Result, 0, len(deps))
+	for _, dep := range deps {
+		res := Result{Dependency: dep}
+		installed, err := pm.IsInstalled(ctx, dep)
+		switch {
+		case err != nil:
+			res.Err = err
+		case !installed:
+			res.Err = pm.InstallWithProgress(ctx, dep, func(percent int, message string) {
+				if progress != nil {
+					progress(dep, percent, message)
+				}
+			})
+			res.Installed = res.Err == nil
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// InstallStack installs what the native backend needs for cfg (see Stack)
+// and returns a result per dependency, like EnsureAll. cfg's binary paths
+// are pointed at what pm installed, unless they already lead to a binary
+// that was there before; cfg is not saved.
+func InstallStack(ctx context.Context, pm PackageManager, cfg *config.AppConfig, progress func(dep Dependency, percent int, message string)) []Result {
+	results := EnsureAll(ctx, pm, Stack(cfg), progress)
+	for i, res := range results {
+		var field *string
+		switch {
+		case res.Err != nil:
+			continue
+		case res.Dependency.ID == Apache.ID:
+			field = &cfg.ApachePath
+		case res.Dependency.ID == Nginx.ID:
+			field = &cfg.NginxPath
+		case res.Dependency.ID == MySQL.ID:
+			field = &cfg.MySQLPath
+		case res.Dependency.Kind == KindPHP:
+			field = &cfg.PHPPath
+		default:
+			continue
+		}
+		if _, err := os.Stat(*field); err == nil && !res.Installed {
+			continue
+		}
+		path, err := pm.BinPath(ctx, res.Dependency)
+		if err != nil {
+			results[i].Err = fmt.Errorf("installed, but its binary was not found: %w", err)
+			continue
+		}
+		*field = path
+	}
+	return results
+}
+
+// Errors joins the failures in results, naming each dependency; nil when
+// everything succeeded.
+func Errors(results []Result) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Dependency.ID, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// packageManager is what the Homebrew, apt and dnf implementations provide
+// to the shared IsInstalled and InstallWithProgress.
+type packageManager interface {
+	PackageManager
+	runner() Runner
+	// installed reports whether one package is installed.
+	installed(ctx context.Context, pkg string) bool
+	// installArgs is the command line that installs pkgs.
+	installArgs(pkgs []string) []string
+}
+
+func isInstalled(ctx context.Context, pm packageManager, dep Dependency) (bool, error) {
+	pkgs, err := pm.Packages(ctx, dep)
+	if err != nil {
+		return false, err
+	}
+	for _, pkg := range pkgs {
+		if !pm.installed(ctx, pkg) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+var progressPattern = regexp.MustCompile(`(\d{1,3})%`)
+
+func installWithProgress(ctx context.Context, pm packageManager, dep Dependency, callback ProgressCallback) error {
+	pkgs, err := pm.Packages(ctx, dep)
+	if err != nil {
+		return err
+	}
+	if installed, err := isInstalled(ctx, pm, dep); err != nil {
+		return err
+	} else if installed {
+		callback(100, "Already installed")
+		return nil
+	}
+
+	callback(0, fmt.Sprintf("Installing %s with %s...", strings.Join(pkgs, " "), pm.Name()))
+	argv := pm.installArgs(pkgs)
+	_, err = pm.runner().Run(ctx, func(line string) {
+		if m := progressPattern.FindStringSubmatch(line); m != nil {
+			if percent, _ := strconv.Atoi(m[1]); percent <= 100 {
+				callback(percent, line)
+				return
+			}
+		}
+		callback(-1, line)
+	}, argv[0], argv[1:]...)
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %w", dep.Title, err)
+	}
+	callback(100, "Installed")
+	return nil
+}
+
+// phpMinorVersion asks a PHP binary for its version, e.g. 8.3.
+func phpMinorVersion(ctx context.Context, r Runner, php string) (string, error) {
+	out, err := r.Run(ctx, nil, php, "-r", `echo PHP_MAJOR_VERSION, ".", PHP_MINOR_VERSION;`)
+	if err != nil {
+		return "", fmt.Errorf("cannot tell the PHP version: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
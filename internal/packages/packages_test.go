@@ -0,0 +1,346 @@
+package packages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-local-server/internal/config"
+)
+
+// fakeRunner answers commands from a table instead of running them.
+type fakeRunner struct {
+	mu    sync.Mutex
+	calls []string
+	// out holds the stdout of command lines; a missing one fails.
+	out map[string]string
+	// lines holds what a command prints while it runs.
+	lines map[string][]string
+	// fail makes command lines fail even when out has them.
+	fail map[string]bool
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{out: map[string]string{}, lines: map[string][]string{}, fail: map[string]bool{}}
+}
+
+func (r *fakeRunner) Run(ctx context.Context, onLine func(line string), name string, args ...string) ([]byte, error) {
+	// Running as a user, install commands go through sudo or pkexec.
+	if name == "sudo" || name == "pkexec" {
+		name, args = args[0], args[1:]
+	}
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	r.mu.Lock()
+	r.calls = append(r.calls, cmd)
+	r.mu.Unlock()
+
+	if onLine != nil {
+		for _, line := range r.lines[cmd] {
+			onLine(line)
+		}
+	}
+	out, ok := r.out[cmd]
+	if !ok || r.fail[cmd] {
+		return nil, fmt.Errorf("%s: exit status 1", name)
+	}
+	return []byte(out), nil
+}
+
+func (r *fakeRunner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *fakeRunner) installs() []string {
+	var installs []string
+	for _, c := range r.Calls() {
+		if strings.Contains(c, " install ") {
+			installs = append(installs, c)
+		}
+	}
+	return installs
+}
+
+func mustLookup(t *testing.T, id string) Dependency {
+	t.Helper()
+	dep, err := Lookup(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dep
+}
+
+func TestPackages(t *testing.T) {
+	r := newFakeRunner()
+	r.out["brew --prefix php"] = "/opt/homebrew/opt/php\n"
+	r.out[`/opt/homebrew/opt/php/bin/php -r echo PHP_MAJOR_VERSION, ".", PHP_MINOR_VERSION;`] = "8.3"
+	managers := map[string]PackageManager{"brew": NewHomebrew(r), "apt": NewApt(r), "dnf": NewDnf(r)}
+
+	tests := []struct {
+		pm   string
+		id   string
+		want string // space-separated; "-" for none
+	}{
+		{"brew", "httpd", "httpd"},
+		{"brew", "mysql", "mysql"},
+		{"brew", "php", "php"},
+		{"brew", "php@8.2", "php@8.2"},
+		{"brew", "php@7.4", "shivammathur/php/php@7.4"},
+		{"brew", "php@8.0", "shivammathur/php/php@8.0"},
+		{"brew", "php@8.1-redis", "shivammathur/extensions/redis@8.1"},
+		{"brew", "php-xdebug", "shivammathur/extensions/xdebug@8.3"},
+		{"brew", "php-intl", "-"},
+
+		{"apt", "httpd", "apache2"},
+		{"apt", "mysql", "default-mysql-server"},
+		{"apt", "nginx", "nginx"},
+		{"apt", "composer", "composer"},
+		{"apt", "php", "php-fpm php-cli php-mysql"},
+		{"apt", "php@8.1", "php8.1-fpm php8.1-cli php8.1-mysql"},
+		{"apt", "php-redis", "php-redis"},
+		{"apt", "php@8.2-intl", "php8.2-intl"},
+
+		{"dnf", "httpd", "httpd"},
+		{"dnf", "nginx", "nginx"},
+		{"dnf", "mysql", "mysql-server"},
+		{"dnf", "php", "php-fpm php-cli php-mysqlnd"},
+		{"dnf", "php@8.1", "php81-php-fpm php81-php-cli php81-php-mysqlnd"},
+		{"dnf", "php@8.2-redis", "php82-php-pecl-redis6"},
+		{"dnf", "php-xdebug", "php-pecl-xdebug3"},
+		{"dnf", "php@8.3-intl", "php83-php-intl"},
+	}
+	for _, tt := range tests {
+		pkgs, err := managers[tt.pm].Packages(context.Background(), mustLookup(t, tt.id))
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.pm, tt.id, err)
+			continue
+		}
+		got := strings.Join(pkgs, " ")
+		if got == "" {
+			got = "-"
+		}
+		if got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.pm, tt.id, got, tt.want)
+		}
+	}
+
+	// An extension dnf has no package for.
+	_, err := NewDnf(r).Packages(context.Background(), Dependency{ID: "php-gd", Kind: KindExtension, Extension: "gd"})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("dnf php-gd: err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestInstallCommands(t *testing.T) {
+	tests := []struct {
+		name      string
+		pm        func(Runner) PackageManager
+		id        string
+		installed string // the command that finds a package installed
+		want      string
+	}{
+		{
+			"brew", func(r Runner) PackageManager { return NewHomebrew(r) }, "php@8.2",
+			"brew list --versions php@8.2",
+			"brew install php@8.2",
+		},
+		{
+			"apt", func(r Runner) PackageManager { return NewApt(r) }, "php",
+			"dpkg-query -W -f=${Status} php-fpm",
+			"env DEBIAN_FRONTEND=noninteractive apt-get install -y php-fpm php-cli php-mysql",
+		},
+		{
+			"apt mysql", func(r Runner) PackageManager { return NewApt(r) }, "mysql",
+			"dpkg-query -W -f=${Status} default-mysql-server",
+			"env DEBIAN_FRONTEND=noninteractive apt-get install -y default-mysql-server",
+		},
+		{
+			"dnf", func(r Runner) PackageManager { return NewDnf(r) }, "php@8.1",
+			"rpm -q php81-php-fpm",
+			"dnf install -y php81-php-fpm php81-php-cli php81-php-mysqlnd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newFakeRunner()
+			r.out[tt.want] = ""
+			pm := tt.pm(r)
+			dep := mustLookup(t, tt.id)
+
+			if installed, err := pm.IsInstalled(context.Background(), dep); err != nil || installed {
+				t.Fatalf("IsInstalled = %v, %v; want false", installed, err)
+			}
+			if calls := r.Calls(); len(calls) != 1 || calls[0] != tt.installed {
+				t.Errorf("IsInstalled ran %q, want %q", calls, tt.installed)
+			}
+
+			if err := pm.InstallWithProgress(context.Background(), dep, func(int, string) {}); err != nil {
+				t.Fatal(err)
+			}
+			if installs := r.installs(); len(installs) != 1 || installs[0] != tt.want {
+				t.Errorf("installed with %q, want %q", installs, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallSkipsInstalled(t *testing.T) {
+	r := newFakeRunner()
+	r.out["brew list --versions nginx"] = "nginx 1.27.0\n"
+	var messages []string
+	err := NewHomebrew(r).InstallWithProgress(context.Background(), Nginx, func(percent int, message string) {
+		messages = append(messages, fmt.Sprintf("%d %s", percent, message))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.installs()) != 0 {
+		t.Errorf("installed again: %v", r.installs())
+	}
+	if strings.Join(messages, "|") != "100 Already installed" {
+		t.Errorf("progress %q", messages)
+	}
+}
+
+func TestInstallProgress(t *testing.T) {
+	r := newFakeRunner()
+	install := "dnf install -y nginx"
+	r.out[install] = ""
+	r.lines[install] = []string{
+		"Downloading Packages:",
+		"(1/2): nginx-core 45% [=====     ]",
+		"(2/2): nginx 100% [==========]",
+		"Disk usage 250%",
+		"Complete!",
+	}
+
+	var got []string
+	err := NewDnf(r).InstallWithProgress(context.Background(), Nginx, func(percent int, message string) {
+		got = append(got, fmt.Sprintf("%d %s", percent, message))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"0 Installing nginx with dnf...",
+		"-1 Downloading Packages:",
+		"45 (1/2): nginx-core 45% [=====     ]",
+		"100 (2/2): nginx 100% [==========]",
+		"-1 Disk usage 250%",
+		"-1 Complete!",
+		"100 Installed",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("progress:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		available []string
+		want      string
+	}{
+		{[]string{"brew", "apt-get"}, "brew"},
+		{[]string{"apt-get", "dnf"}, "apt"},
+		{[]string{"dnf"}, "dnf"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		r := newFakeRunner()
+		for _, cmd := range tt.available {
+			r.out[cmd+" --version"] = ""
+		}
+		pm, err := Detect(context.Background(), r)
+		switch {
+		case tt.want == "" && !errors.Is(err, ErrNoPackageManager):
+			t.Errorf("%v: err = %v, want ErrNoPackageManager", tt.available, err)
+		case tt.want != "" && (err != nil || pm.Name() != tt.want):
+			t.Errorf("%v: got %v, %v; want %s", tt.available, pm, err, tt.want)
+		}
+	}
+}
+
+func TestEnsureAll(t *testing.T) {
+	r := newFakeRunner()
+	// nginx is installed; PHP's install fails; MySQL's works.
+	r.out["dpkg-query -W -f=${Status} nginx"] = "install ok installed"
+	r.out["env DEBIAN_FRONTEND=noninteractive apt-get install -y php-fpm php-cli php-mysql"] = ""
+	r.fail["env DEBIAN_FRONTEND=noninteractive apt-get install -y php-fpm php-cli php-mysql"] = true
+	r.out["env DEBIAN_FRONTEND=noninteractive apt-get install -y default-mysql-server"] = ""
+	r.lines["env DEBIAN_FRONTEND=noninteractive apt-get install -y default-mysql-server"] = []string{"Unpacking default-mysql-server 50%"}
+
+	deps := []Dependency{Nginx, PHP(""), MySQL}
+	var progress []string
+	results := EnsureAll(context.Background(), NewApt(r), deps, func(dep Dependency, percent int, message string) {
+		progress = append(progress, fmt.Sprintf("%s %d", dep.ID, percent))
+	})
+
+	if len(results) != len(deps) {
+		t.Fatalf("%d results for %d dependencies", len(results), len(deps))
+	}
+	want := []struct {
+		installed bool
+		failed    bool
+	}{
+		{false, false},
+		{false, true},
+		{true, false},
+	}
+	for i, res := range results {
+		if res.Dependency.ID != deps[i].ID {
+			t.Errorf("result %d is for %s, want %s", i, res.Dependency.ID, deps[i].ID)
+		}
+		if res.Installed != want[i].installed || (res.Err != nil) != want[i].failed {
+			t.Errorf("%s: installed %v, err %v", res.Dependency.ID, res.Installed, res.Err)
+		}
+	}
+	if got := strings.Join(progress, ","); got != "php 0,mysql 0,mysql 50,mysql 100" {
+		t.Errorf("progress %s", got)
+	}
+
+	err := Errors(results)
+	if err == nil || !strings.HasPrefix(err.Error(), "php: failed to install PHP: ") || strings.Contains(err.Error(), "mysql") {
+		t.Errorf("Errors = %v", err)
+	}
+	if err := Errors(results[2:]); err != nil {
+		t.Errorf("Errors of successes = %v", err)
+	}
+}
+
+func TestInstallStack(t *testing.T) {
+	r := newFakeRunner()
+	// Apache was there and its path is set; MySQL was there, but its path
+	// leads nowhere; PHP is installed now.
+	r.out["dpkg-query -W -f=${Status} apache2"] = "install ok installed"
+	r.out["dpkg-query -W -f=${Status} default-mysql-server"] = "install ok installed"
+	r.out["env DEBIAN_FRONTEND=noninteractive apt-get install -y php-fpm php-cli php-mysql"] = ""
+	r.out[`php -r echo PHP_MAJOR_VERSION, ".", PHP_MINOR_VERSION;`] = "8.2\n"
+
+	apache := filepath.Join(t.TempDir(), "httpd")
+	if err := os.WriteFile(apache, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.ApachePath = apache
+	cfg.MySQLPath = filepath.Join(t.TempDir(), "missing", "mysqld")
+
+	results := InstallStack(context.Background(), NewApt(r), cfg, nil)
+	if err := Errors(results); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ApachePath != apache {
+		t.Errorf("ApachePath = %q, want the existing %q", cfg.ApachePath, apache)
+	}
+	if cfg.PHPPath != "/usr/sbin/php-fpm8.2" {
+		t.Errorf("PHPPath = %q", cfg.PHPPath)
+	}
+	if cfg.MySQLPath != "/usr/sbin/mysqld" {
+		t.Errorf("MySQLPath = %q", cfg.MySQLPath)
+	}
+}
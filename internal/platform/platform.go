@@ -0,0 +1,83 @@
+// Package platform hides the differences between macOS and Linux: where the
+// app keeps its files, how URLs, folders and editors are opened, and how
+// Docker is started.
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Dirs are the per-user directories the app writes to. On macOS they all
+// live under ~/Library/Application Support/GoLocalServer; on Linux they
+// follow the XDG base directory spec.
+type Dirs struct {
+	Config string // config.json, projects/, api-token
+	Data   string // generated resources: docker/, apache/, certs
+	State  string // logs and other runtime state
+}
+
+// Editor describes how to launch a code editor.
+type Editor struct {
+	AppName string // macOS application name, used with "open -a"
+	Command string // CLI launcher, used on Linux
+}
+
+// OpenURL opens url in the default browser.
+func OpenURL(url string) error {
+	return openCmd(url).Run()
+}
+
+// OpenFolder reveals path in the system file manager.
+func OpenFolder(path string) error {
+	return openCmd(path).Run()
+}
+
+// OpenInEditor opens path in the given editor.
+func OpenInEditor(ed Editor, path string) error {
+	if cmd := editorCmd(ed, path); cmd != nil {
+		return cmd.Run()
+	}
+	return fmt.Errorf("%s is not installed", ed.Command)
+}
+
+// DockerSocket returns the path of the local Docker Engine socket, or ""
+// when none is found.
+func DockerSocket() string {
+	candidates := []string{"/var/run/docker.sock", "/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		// Docker Desktop (macOS and Linux) and rootless dockerd
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".docker", "desktop", "docker.sock"),
+		)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "docker.sock"))
+	}
+	for _, c := range candidates {
+		if st, err := os.Stat(c); err == nil && st.Mode()&os.ModeSocket != 0 {
+			return c
+		}
+	}
+	return ""
+}
+
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, fallback)
+}
+
+func lookPath(names ...string) string {
+	for _, n := range names {
+		if p, err := exec.LookPath(n); err == nil {
+			return p
+		}
+	}
+	return ""
+}
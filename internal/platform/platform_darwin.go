@@ -0,0 +1,93 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const dockerDesktopApp = "/Applications/Docker.app"
+
+// UserDirs keeps everything under Application Support, as earlier releases did.
+func UserDirs(appName string) Dirs {
+	home, _ := os.UserHomeDir()
+	base := filepath.Join(home, "Library", "Application Support", appName)
+	return Dirs{Config: base, Data: base, State: base}
+}
+
+func openCmd(target string) *exec.Cmd {
+	return exec.Command("open", target)
+}
+
+func editorCmd(ed Editor, path string) *exec.Cmd {
+	return exec.Command("open", "-a", ed.AppName, path)
+}
+
+// NativeDockerd reports whether Docker is provided by a system daemon rather
+// than Docker Desktop. On macOS it always is Desktop (or a VM like it).
+func NativeDockerd() bool {
+	return false
+}
+
+// DockerDesktopInstalled checks for /Applications/Docker.app.
+func DockerDesktopInstalled() bool {
+	_, err := os.Stat(dockerDesktopApp)
+	return err == nil
+}
+
+// StartDockerDesktop launches Docker Desktop.
+func StartDockerDesktop() error {
+	if !DockerDesktopInstalled() {
+		return fmt.Errorf("Docker Desktop not found in /Applications")
+	}
+	return exec.Command("open", "-a", "Docker").Run()
+}
+
+// DockerBinaryCandidates lists where the docker CLI is usually installed.
+func DockerBinaryCandidates() []string {
+	return []string{
+		"/usr/local/bin/docker",
+		"/opt/homebrew/bin/docker",
+		"/usr/bin/docker",
+		dockerDesktopApp + "/Contents/Resources/bin/docker",
+		dockerDesktopApp + "/Contents/MacOS/docker",
+		"/usr/local/docker/bin/docker",
+	}
+}
+
+// PrivilegedArgs prefixes a command line with sudo so it runs as root. The
+// administrator dialog RunPrivileged uses cannot stream output, so this
+// needs a terminal.
+func PrivilegedArgs(argv []string) []string {
+	if os.Geteuid() == 0 {
+		return argv
+	}
+	return append([]string{"sudo"}, argv...)
+}
+
+// RunPrivileged runs a shell script as root, asking for an administrator
+// password with the standard macOS dialog.
+func RunPrivileged(script string) error {
+	cmd := exec.Command("sh", "-c", script)
+	if os.Geteuid() != 0 {
+		quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(script)
+		cmd = exec.Command("osascript", "-e", `do shell script "`+quoted+`" with administrator privileges`)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ProcessExecutable returns the path of the program a process runs, as ps
+// reports it.
+func ProcessExecutable(pid int) (string, error) {
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return "", fmt.Errorf("no process %d", pid)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
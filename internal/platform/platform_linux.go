@@ -0,0 +1,100 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// UserDirs resolves $XDG_CONFIG_HOME, $XDG_DATA_HOME and $XDG_STATE_HOME
+// (defaulting to ~/.config, ~/.local/share and ~/.local/state).
+func UserDirs(appName string) Dirs {
+	name := strings.ToLower(appName)
+	return Dirs{
+		Config: filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), name),
+		Data:   filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), name),
+		State:  filepath.Join(xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")), name),
+	}
+}
+
+func openCmd(target string) *exec.Cmd {
+	return exec.Command("xdg-open", target)
+}
+
+func editorCmd(ed Editor, path string) *exec.Cmd {
+	bin := lookPath(ed.Command)
+	if bin == "" {
+		return nil
+	}
+	return exec.Command(bin, path)
+}
+
+// NativeDockerd reports whether a system dockerd socket is present, in which
+// case Docker Desktop handling is skipped.
+func NativeDockerd() bool {
+	return DockerSocket() != "" && !DockerDesktopInstalled()
+}
+
+// DockerDesktopInstalled checks for Docker Desktop for Linux, which ships a
+// systemd user unit.
+func DockerDesktopInstalled() bool {
+	return exec.Command("systemctl", "--user", "cat", "docker-desktop").Run() == nil
+}
+
+// StartDockerDesktop starts Docker Desktop's user service.
+func StartDockerDesktop() error {
+	if !DockerDesktopInstalled() {
+		return fmt.Errorf("Docker Desktop is not installed; start dockerd with: sudo systemctl start docker")
+	}
+	return exec.Command("systemctl", "--user", "start", "docker-desktop").Run()
+}
+
+// DockerBinaryCandidates lists where the docker CLI is usually installed.
+func DockerBinaryCandidates() []string {
+	return []string{
+		"/usr/bin/docker",
+		"/usr/local/bin/docker",
+		"/snap/bin/docker",
+	}
+}
+
+// RunPrivileged runs a shell script as root: through sudo when attached to a
+// terminal, otherwise through pkexec's graphical prompt.
+func RunPrivileged(script string) error {
+	argv := PrivilegedArgs([]string{"sh", "-c", script})
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// PrivilegedArgs prefixes a command line so it runs as root, the way
+// RunPrivileged does.
+func PrivilegedArgs(argv []string) []string {
+	switch {
+	case os.Geteuid() == 0:
+		return argv
+	case isTerminal(os.Stdin):
+		return append([]string{"sudo"}, argv...)
+	default:
+		return append([]string{"pkexec"}, argv...)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// ProcessExecutable returns the path of the program a process runs.
+func ProcessExecutable(pid int) (string, error) {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		return "", err
+	}
+	// The binary was replaced, e.g. by a package upgrade, while running.
+	return strings.TrimSuffix(exe, " (deleted)"), nil
+}
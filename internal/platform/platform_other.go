@@ -0,0 +1,72 @@
+//go:build !darwin && !linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// UserDirs uses os.UserConfigDir for everything on other systems.
+func UserDirs(appName string) Dirs {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		base = home
+	}
+	dir := filepath.Join(base, appName)
+	return Dirs{Config: dir, Data: dir, State: dir}
+}
+
+func openCmd(target string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	}
+	return exec.Command("xdg-open", target)
+}
+
+func editorCmd(ed Editor, path string) *exec.Cmd {
+	bin := lookPath(ed.Command)
+	if bin == "" {
+		return nil
+	}
+	return exec.Command(bin, path)
+}
+
+func NativeDockerd() bool {
+	return DockerSocket() != ""
+}
+
+func DockerDesktopInstalled() bool {
+	return false
+}
+
+func StartDockerDesktop() error {
+	return fmt.Errorf("starting Docker Desktop is not supported on %s", runtime.GOOS)
+}
+
+func DockerBinaryCandidates() []string {
+	return nil
+}
+
+// PrivilegedArgs returns argv unchanged: like RunPrivileged, it relies on
+// already running as root.
+func PrivilegedArgs(argv []string) []string {
+	return argv
+}
+
+func RunPrivileged(script string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("run as root to change system files on %s", runtime.GOOS)
+	}
+	return exec.Command("sh", "-c", script).Run()
+}
+
+// ProcessExecutable is not supported on other systems.
+func ProcessExecutable(pid int) (string, error) {
+	return "", errors.ErrUnsupported
+}
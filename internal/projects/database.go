@@ -0,0 +1,113 @@
+package projects
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Database engines a project can use. Each one runs as the compose service
+// of the same name, which is also the host name projects connect to.
+const (
+	EngineMySQL    = "mysql"
+	EngineMariaDB  = "mariadb"
+	EnginePostgres = "postgres"
+)
+
+// Engines are the supported database engines, default first.
+var Engines = []string{EngineMySQL, EngineMariaDB, EnginePostgres}
+
+// ParseEngine accepts an engine name or a common alias and returns the
+// engine; empty means MySQL.
+func ParseEngine(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "mysql":
+		return EngineMySQL, nil
+	case "mariadb", "maria":
+		return EngineMariaDB, nil
+	case "postgres", "postgresql", "pgsql", "pg":
+		return EnginePostgres, nil
+	}
+	return "", fmt.Errorf("unknown database engine %q (use one of %s)", s, strings.Join(Engines, ", "))
+}
+
+// EngineName returns the configured engine, defaulting for projects saved
+// before the engine was configurable.
+func (d DatabaseConfig) EngineName() string {
+	if d.Engine == "" {
+		return EngineMySQL
+	}
+	return d.Engine
+}
+
+// EnginePort is the port an engine listens on inside the compose network.
+func EnginePort(engine string) int {
+	if engine == EnginePostgres {
+		return 5432
+	}
+	return 3306
+}
+
+// EngineTitle is the display name of an engine.
+func EngineTitle(engine string) string {
+	switch engine {
+	case EngineMariaDB:
+		return "MariaDB"
+	case EnginePostgres:
+		return "PostgreSQL"
+	}
+	return "MySQL"
+}
+
+// Driver is the PDO driver name for the database.
+func (d DatabaseConfig) Driver() string {
+	if d.EngineName() == EnginePostgres {
+		return "pgsql"
+	}
+	return "mysql"
+}
+
+// DSN is the PDO data source name for the database.
+func (d DatabaseConfig) DSN() string {
+	if d.Driver() == "pgsql" {
+		return fmt.Sprintf("pgsql:host=%s;port=%d;dbname=%s", d.DBHost, d.DBPort, d.DBName)
+	}
+	return fmt.Sprintf("mysql:host=%s;port=%d;dbname=%s;charset=utf8mb4", d.DBHost, d.DBPort, d.DBName)
+}
+
+// URL is the database as a DATABASE_URL, as read by Laravel, Symfony and
+// most other frameworks.
+func (d DatabaseConfig) URL() string {
+	scheme := "mysql"
+	if d.EngineName() == EnginePostgres {
+		scheme = "postgresql"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(d.DBUser, d.DBPassword),
+		Host:   fmt.Sprintf("%s:%d", d.DBHost, d.DBPort),
+		Path:   "/" + d.DBName,
+	}
+	return u.String()
+}
+
+// ActiveEngines returns the distinct database engines used by active
+// projects with a database, sorted.
+func ActiveEngines(list []*Project) []string {
+	seen := map[string]bool{}
+	var engines []string
+	for _, p := range list {
+		if !p.IsActive || p.Database.DBName == "" || p.Database.DBUser == "" {
+			continue
+		}
+		e := p.Database.EngineName()
+		if seen[e] {
+			continue
+		}
+		seen[e] = true
+		engines = append(engines, e)
+	}
+	sort.Strings(engines)
+	return engines
+}
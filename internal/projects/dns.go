@@ -0,0 +1,128 @@
+package projects
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNSRecordTypes are the record types a project can declare.
+var DNSRecordTypes = []string{"A", "AAAA", "CNAME", "TXT", "MX", "SRV"}
+
+// DNSRecord is an extra record served by the local DNS server for a project.
+// Name is relative to the project domain: "" (or "@") is the domain itself,
+// "mail" is mail.<domain> and "_sip._tcp" is _sip._tcp.<domain>.
+type DNSRecord struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Value    string `json:"value"` // address, target host name or TXT text
+	TTL      uint32 `json:"ttl,omitempty"`
+	Priority uint16 `json:"priority,omitempty"` // MX and SRV
+	Weight   uint16 `json:"weight,omitempty"`   // SRV
+	Port     uint16 `json:"port,omitempty"`     // SRV
+}
+
+// FQDN returns the record's fully qualified name (without the trailing dot)
+// under domain.
+func (r DNSRecord) FQDN(domain string) string {
+	name := strings.TrimSuffix(strings.TrimSpace(r.Name), ".")
+	if name == "" || name == "@" {
+		return strings.ToLower(domain)
+	}
+	return strings.ToLower(name + "." + domain)
+}
+
+// Validate checks the record's type and value. Type is normalised to upper
+// case.
+func (r *DNSRecord) Validate() error {
+	r.Type = strings.ToUpper(strings.TrimSpace(r.Type))
+	r.Name = strings.TrimSpace(r.Name)
+	r.Value = strings.TrimSpace(r.Value)
+
+	name := strings.TrimSuffix(r.Name, ".")
+	if name != "" && name != "@" && !validHostName(name) {
+		return fmt.Errorf("invalid record name %q", r.Name)
+	}
+	if r.Value == "" {
+		return fmt.Errorf("%s record %q needs a value", r.Type, r.Name)
+	}
+
+	switch r.Type {
+	case "A":
+		if ip := net.ParseIP(r.Value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("A record %q: %q is not an IPv4 address", r.Name, r.Value)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(r.Value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("AAAA record %q: %q is not an IPv6 address", r.Name, r.Value)
+		}
+	case "CNAME":
+		// The domain itself always answers A/AAAA, which a CNAME may not
+		// coexist with.
+		if name == "" || name == "@" {
+			return fmt.Errorf("CNAME is not allowed on the project domain itself")
+		}
+		fallthrough
+	case "MX":
+		if !validHostName(strings.TrimSuffix(r.Value, ".")) {
+			return fmt.Errorf("%s record %q: invalid target %q", r.Type, r.Name, r.Value)
+		}
+	case "SRV":
+		if !validHostName(strings.TrimSuffix(r.Value, ".")) {
+			return fmt.Errorf("SRV record %q: invalid target %q", r.Name, r.Value)
+		}
+		if r.Port == 0 {
+			return fmt.Errorf("SRV record %q needs a port", r.Name)
+		}
+	case "TXT":
+		if len(r.Value) > 255*16 {
+			return fmt.Errorf("TXT record %q is too long", r.Name)
+		}
+	default:
+		return fmt.Errorf("unsupported record type %q (use one of %s)", r.Type, strings.Join(DNSRecordTypes, ", "))
+	}
+	return nil
+}
+
+// ValidateDNSRecords validates every record and rejects a CNAME that shares
+// its name with another record.
+func ValidateDNSRecords(records []DNSRecord) error {
+	types := map[string][]string{}
+	for i := range records {
+		if err := records[i].Validate(); err != nil {
+			return err
+		}
+		name := records[i].FQDN("")
+		types[name] = append(types[name], records[i].Type)
+	}
+	for name, ts := range types {
+		if len(ts) < 2 {
+			continue
+		}
+		for _, t := range ts {
+			if t == "CNAME" {
+				return fmt.Errorf("CNAME %q cannot be combined with other records", strings.TrimSuffix(name, "."))
+			}
+		}
+	}
+	return nil
+}
+
+func validHostName(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -14,6 +14,7 @@ import (
 )
 
 type DatabaseConfig struct {
+	Engine     string `json:"engine,omitempty"` // one of Engines; empty = MySQL
 	DBName     string `json:"db_name"`
 	DBUser     string `json:"db_user"`
 	DBPassword string `json:"db_password"`
@@ -33,6 +34,9 @@ type Project struct {
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	IsActive       bool           `json:"is_active"`
+	DNSRecords     []DNSRecord    `json:"dns_records,omitempty"`
+	LiveReload     bool           `json:"live_reload"`
+	Watch          WatchRules     `json:"watch"`
 }
 
 type Manager struct {
@@ -47,8 +51,13 @@ func NewManager(cfg *config.AppConfig) *Manager {
 	}
 }
 
+// IDFromName derives the project id (and file name) from its display name.
+func IDFromName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
+
 func (m *Manager) Create(name, path, phpVersion string, dbConfig DatabaseConfig) (*Project, error) {
-	id := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+	id := IDFromName(name)
 	domain := fmt.Sprintf("%s.%s", id, m.config.Domain)
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -87,7 +96,7 @@ func (m *Manager) Create(name, path, phpVersion string, dbConfig DatabaseConfig)
 }
 
 func (m *Manager) CreateWithSubdomain(name, subdomain, path, phpVersion string, dbConfig DatabaseConfig) (*Project, error) {
-	id := strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+	id := IDFromName(name)
 	domain := fmt.Sprintf("%s.%s", subdomain, m.config.Domain)
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -347,38 +356,52 @@ func (m *Manager) CopyMVCTemplate(project *Project) error {
 	})
 }
 
-// GenerateDBConfig creates a database configuration file for the project
+// dbConfigMarker marks a db_config.php written by GenerateDBConfig.
+const dbConfigMarker = "Auto-generated by Go Local Server"
+
+// GenerateDBConfig writes the project's database configuration file. A
+// file it generated before is rewritten, so it follows engine, host and
+// port changes; one without the marker belongs to the user and is kept.
 func (m *Manager) GenerateDBConfig(project *Project) error {
 	configPath := filepath.Join(project.Path, "db_config.php")
 
-	// Don't overwrite existing file
-	if _, err := os.Stat(configPath); err == nil {
+	if existing, err := os.ReadFile(configPath); err == nil && !strings.Contains(string(existing), dbConfigMarker) {
 		return nil
 	}
 
+	// charset and collation are MySQL/MariaDB settings; PostgreSQL only
+	// takes a client encoding.
+	charset := "    'charset'  => 'utf8mb4',\n    'collation' => 'utf8mb4_unicode_ci',\n"
+	if project.Database.Driver() == "pgsql" {
+		charset = "    'charset'  => 'utf8',\n"
+	}
+
 	phpTemplate := `<?php
 /**
  * Database Configuration
- * Auto-generated by Go Local Server
+ * ` + dbConfigMarker + `
  */
 
 return [
+    'driver'   => '%s',
+    'dsn'      => '%s',
     'host'     => '%s',
     'port'     => %d,
     'database' => '%s',
     'username' => '%s',
     'password' => '%s',
-    'charset'  => 'utf8mb4',
-    'collation' => 'utf8mb4_unicode_ci',
-];
+%s];
 `
 
 	content := fmt.Sprintf(phpTemplate,
+		project.Database.Driver(),
+		project.Database.DSN(),
 		project.Database.DBHost,
 		project.Database.DBPort,
 		project.Database.DBName,
 		project.Database.DBUser,
 		project.Database.DBPassword,
+		charset,
 	)
 
 	return os.WriteFile(configPath, []byte(content), 0644)
@@ -0,0 +1,53 @@
+package projects
+
+import (
+	"sort"
+	"strings"
+)
+
+// PHPVersions are the PHP versions a project can run on, newest first. Each
+// version in use gets its own PHP-FPM container.
+var PHPVersions = []string{"8.3", "8.2", "8.1", "8.0", "7.4"}
+
+const DefaultPHPVersion = "8.3"
+
+// ValidPHPVersion reports whether v is one of PHPVersions.
+func ValidPHPVersion(v string) bool {
+	for _, known := range PHPVersions {
+		if v == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PHPService is the compose service (and FastCGI host name) that runs a PHP
+// version, e.g. "php74" for 7.4.
+func PHPService(version string) string {
+	return "php" + strings.ReplaceAll(version, ".", "")
+}
+
+// PHP returns the project's PHP version, defaulting for projects saved before
+// the version was configurable.
+func (p *Project) PHP() string {
+	if p.PHPVersion == "" {
+		return DefaultPHPVersion
+	}
+	return p.PHPVersion
+}
+
+// ActivePHPVersions returns the distinct PHP versions used by active
+// projects, sorted.
+func ActivePHPVersions(list []*Project) []string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, p := range list {
+		if !p.IsActive || seen[p.PHP()] {
+			continue
+		}
+		seen[p.PHP()] = true
+		versions = append(versions, p.PHP())
+	}
+	sort.Strings(versions)
+	return versions
+}
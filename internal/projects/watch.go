@@ -0,0 +1,114 @@
+package projects
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// WatchRules tune which files trigger a live reload for a project.
+//
+// Globs follow .gitignore syntax: "*" and "?" stay within one path segment,
+// "**" spans segments, a pattern without a slash matches at any depth and a
+// trailing slash matches folders only. Paths are relative to the project
+// folder.
+type WatchRules struct {
+	// Include replaces the default list of web file extensions. Folders that
+	// are ignored (by .gitignore or the built-in list such as vendor/) stay
+	// ignored unless a glob names them, e.g. "public/build/**".
+	Include []string `json:"include,omitempty"`
+	// Exclude is applied on top of .gitignore and the built-in list and
+	// wins over Include.
+	Exclude []string `json:"exclude,omitempty"`
+
+	DebounceMillis int  `json:"debounce_ms,omitempty"` // 0 = 800ms
+	Poll           bool `json:"poll,omitempty"`        // scan instead of fsnotify, for network folders
+	PollMillis     int  `json:"poll_ms,omitempty"`     // 0 = 1s
+
+	// Tasks build assets before the browser is told about a change.
+	Tasks []WatchTask `json:"tasks,omitempty"`
+}
+
+// WatchTask runs Command in the project folder when a file matching Glob
+// changes, e.g. "resources/scss/**" -> "npm run build:css". The page only
+// reloads once every triggered task has succeeded. Glob should not match
+// the task's own output, or the task would trigger itself.
+type WatchTask struct {
+	Glob    string `json:"glob"`
+	Command string `json:"command"`
+}
+
+// Default and minimum timings for WatchRules.
+const (
+	DefaultDebounce     = 800 * time.Millisecond
+	DefaultPollInterval = time.Second
+	MinPollInterval     = 250 * time.Millisecond
+)
+
+// Debounce is how long changes are collected before clients are told.
+func (r WatchRules) Debounce() time.Duration {
+	if r.DebounceMillis <= 0 {
+		return DefaultDebounce
+	}
+	return time.Duration(r.DebounceMillis) * time.Millisecond
+}
+
+// PollInterval is the time between scans when Poll is set.
+func (r WatchRules) PollInterval() time.Duration {
+	if r.PollMillis <= 0 {
+		return DefaultPollInterval
+	}
+	return time.Duration(r.PollMillis) * time.Millisecond
+}
+
+// Validate checks the glob syntax, tasks and timings, and drops empty globs.
+func (r *WatchRules) Validate() error {
+	var err error
+	if r.Include, err = cleanGlobs("include", r.Include); err != nil {
+		return err
+	}
+	if r.Exclude, err = cleanGlobs("exclude", r.Exclude); err != nil {
+		return err
+	}
+	r.Tasks = append([]WatchTask(nil), r.Tasks...)
+	for i := range r.Tasks {
+		t := &r.Tasks[i]
+		t.Command = strings.TrimSpace(t.Command)
+		globs, err := cleanGlobs("task", []string{t.Glob})
+		if err != nil {
+			return err
+		}
+		if len(globs) == 0 || t.Command == "" {
+			return fmt.Errorf("task %d needs a glob and a command", i+1)
+		}
+		t.Glob = globs[0]
+	}
+	if r.DebounceMillis < 0 || r.DebounceMillis > 60000 {
+		return fmt.Errorf("debounce must be between 0 and 60000 ms")
+	}
+	if r.PollMillis < 0 || (r.PollMillis > 0 && time.Duration(r.PollMillis)*time.Millisecond < MinPollInterval) {
+		return fmt.Errorf("poll interval must be at least %d ms", MinPollInterval.Milliseconds())
+	}
+	return nil
+}
+
+func cleanGlobs(kind string, globs []string) ([]string, error) {
+	var out []string
+	for _, g := range globs {
+		g = strings.TrimSpace(g)
+		if g == "" {
+			continue
+		}
+		if strings.HasPrefix(g, "!") {
+			return nil, fmt.Errorf("%s glob %q: negation is only supported in .gitignore", kind, g)
+		}
+		// path.Match reports malformed character classes; "**" is two
+		// stars to it, which is fine for a syntax check.
+		if _, err := path.Match(strings.Trim(g, "/"), ""); err != nil {
+			return nil, fmt.Errorf("%s glob %q: %v", kind, g, err)
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
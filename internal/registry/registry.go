@@ -0,0 +1,247 @@
+// Package registry describes the optional services that can run next to
+// Apache, PHP and the databases: caches, object storage, search and mail.
+// Each one is a Descriptor. The compose generator renders the enabled ones,
+// the service manager tracks their containers, and their connection
+// variables are set in every PHP-FPM container.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-local-server/internal/config"
+)
+
+// Port is a port a service listens on inside the compose network.
+type Port struct {
+	// Name keys AppConfig.ServicePorts, which overrides Host.
+	Name      string
+	Container int
+	// Host is the default published port; 0 keeps the port off the host.
+	Host int
+	// Web ports serve a browser UI.
+	Web bool
+}
+
+// Volume is a named volume that keeps a service's data across restarts.
+type Volume struct {
+	Name string
+	Path string // mount point in the container
+}
+
+// Healthcheck is rendered as the compose healthcheck of the service.
+type Healthcheck struct {
+	Test     []string // e.g. CMD redis-cli ping
+	Interval string
+	Timeout  string
+	Retries  int
+}
+
+// EnvVar is one environment variable, kept in a slice so generated files
+// are stable.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// Descriptor is everything needed to run an optional service and connect
+// projects to it.
+type Descriptor struct {
+	ID          string // compose service name, container golocal-<ID>
+	Title       string
+	Description string
+	Image       string
+	Command     []string
+	Ports       []Port
+	Env         []EnvVar
+	Volumes     []Volume
+	Healthcheck *Healthcheck
+	// Connection is exported to the PHP-FPM containers while the service
+	// is enabled, under the names frameworks read by default.
+	Connection []EnvVar
+}
+
+// Builtins are the services that ship with the app, in display order.
+var Builtins = []Descriptor{
+	{
+		ID:          "redis",
+		Title:       "Redis",
+		Description: "Cache, queue and session store",
+		Image:       "redis:7-alpine",
+		Command:     []string{"redis-server", "--appendonly", "yes"},
+		Ports:       []Port{{Name: "redis", Container: 6379, Host: 6379}},
+		Volumes:     []Volume{{Name: "redis-data", Path: "/data"}},
+		Healthcheck: &Healthcheck{Test: []string{"CMD", "redis-cli", "ping"}, Interval: "10s", Timeout: "3s", Retries: 5},
+		Connection: []EnvVar{
+			{"REDIS_HOST", "redis"},
+			{"REDIS_PORT", "6379"},
+		},
+	},
+	{
+		ID:          "memcached",
+		Title:       "Memcached",
+		Description: "In-memory cache",
+		Image:       "memcached:1.6-alpine",
+		Ports:       []Port{{Name: "memcached", Container: 11211, Host: 11211}},
+		Connection: []EnvVar{
+			{"MEMCACHED_HOST", "memcached"},
+			{"MEMCACHED_PORT", "11211"},
+		},
+	},
+	{
+		ID:          "minio",
+		Title:       "MinIO",
+		Description: "S3-compatible object storage",
+		Image:       "minio/minio:latest",
+		Command:     []string{"server", "/data", "--console-address", ":9001"},
+		Ports: []Port{
+			{Name: "minio", Container: 9000, Host: 9000},
+			{Name: "minio-console", Container: 9001, Host: 9001, Web: true},
+		},
+		Env: []EnvVar{
+			{"MINIO_ROOT_USER", "golocal"},
+			{"MINIO_ROOT_PASSWORD", "golocal-secret"},
+		},
+		Volumes:     []Volume{{Name: "minio-data", Path: "/data"}},
+		Healthcheck: &Healthcheck{Test: []string{"CMD", "mc", "ready", "local"}, Interval: "10s", Timeout: "5s", Retries: 5},
+		Connection: []EnvVar{
+			{"AWS_ACCESS_KEY_ID", "golocal"},
+			{"AWS_SECRET_ACCESS_KEY", "golocal-secret"},
+			{"AWS_DEFAULT_REGION", "us-east-1"},
+			{"AWS_ENDPOINT", "http://minio:9000"},
+			{"AWS_USE_PATH_STYLE_ENDPOINT", "true"},
+		},
+	},
+	{
+		ID:          "meilisearch",
+		Title:       "Meilisearch",
+		Description: "Full-text search engine",
+		Image:       "getmeili/meilisearch:v1.10",
+		Ports:       []Port{{Name: "meilisearch", Container: 7700, Host: 7700, Web: true}},
+		Env: []EnvVar{
+			{"MEILI_MASTER_KEY", "golocal-master-key"},
+			{"MEILI_ENV", "development"},
+			{"MEILI_NO_ANALYTICS", "true"},
+		},
+		Volumes:     []Volume{{Name: "meilisearch-data", Path: "/meili_data"}},
+		Healthcheck: &Healthcheck{Test: []string{"CMD-SHELL", "wget -qO- http://localhost:7700/health || exit 1"}, Interval: "10s", Timeout: "3s", Retries: 5},
+		Connection: []EnvVar{
+			{"MEILISEARCH_HOST", "http://meilisearch:7700"},
+			{"MEILISEARCH_KEY", "golocal-master-key"},
+		},
+	},
+	{
+		ID:          "mailpit",
+		Title:       "Mailpit",
+		Description: "SMTP catcher with a web inbox for framework mailers",
+		Image:       "axllent/mailpit:latest",
+		Ports: []Port{
+			{Name: "mailpit", Container: 8025, Host: 8025, Web: true},
+			// The app's own catcher already listens on 1025 on the host.
+			{Name: "mailpit-smtp", Container: 1025},
+		},
+		Healthcheck: &Healthcheck{Test: []string{"CMD", "/mailpit", "readyz"}, Interval: "10s", Timeout: "3s", Retries: 5},
+		Connection: []EnvVar{
+			{"MAIL_MAILER", "smtp"},
+			{"MAIL_HOST", "mailpit"},
+			{"MAIL_PORT", "1025"},
+		},
+	},
+}
+
+// Lookup finds a service by ID or title, ignoring case.
+func Lookup(name string) (Descriptor, bool) {
+	for _, d := range Builtins {
+		if strings.EqualFold(d.ID, name) || strings.EqualFold(d.Title, name) {
+			return d, true
+		}
+	}
+	return Descriptor{}, false
+}
+
+// IDs lists the IDs of all services.
+func IDs() []string {
+	ids := make([]string, len(Builtins))
+	for i, d := range Builtins {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+// Enabled returns the services switched on in cfg, with the host ports
+// from cfg applied.
+func Enabled(cfg *config.AppConfig) []Descriptor {
+	var list []Descriptor
+	for _, d := range Builtins {
+		if cfg.ServiceEnabled(d.ID) {
+			list = append(list, d.Resolve(cfg))
+		}
+	}
+	return list
+}
+
+// SetEnabled switches a service on or off in cfg. It does not save cfg.
+func SetEnabled(cfg *config.AppConfig, id string, on bool) error {
+	d, ok := Lookup(id)
+	if !ok {
+		return fmt.Errorf("unknown service %q (want one of %s)", id, strings.Join(IDs(), ", "))
+	}
+	var list []string
+	for _, s := range cfg.OptionalServices {
+		if s != d.ID {
+			list = append(list, s)
+		}
+	}
+	if on {
+		list = append(list, d.ID)
+	}
+	sort.Strings(list)
+	cfg.OptionalServices = list
+	return nil
+}
+
+// Resolve returns a copy of d with the host ports overridden in cfg.
+func (d Descriptor) Resolve(cfg *config.AppConfig) Descriptor {
+	ports := make([]Port, len(d.Ports))
+	for i, p := range d.Ports {
+		if host, ok := cfg.ServicePorts[p.Name]; ok {
+			p.Host = host
+		}
+		ports[i] = p
+	}
+	d.Ports = ports
+	return d
+}
+
+// Published returns the ports mapped to the host.
+func (d Descriptor) Published() []Port {
+	var ports []Port
+	for _, p := range d.Ports {
+		if p.Host != 0 {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// WebURL is the address of the service's browser UI on the host, or "" if
+// it has none or the port is not published.
+func (d Descriptor) WebURL() string {
+	for _, p := range d.Ports {
+		if p.Web && p.Host != 0 {
+			return fmt.Sprintf("http://localhost:%d", p.Host)
+		}
+	}
+	return ""
+}
+
+// ConnectionEnv is the combined connection variables of the enabled
+// services, for the PHP-FPM containers.
+func ConnectionEnv(cfg *config.AppConfig) []EnvVar {
+	var env []EnvVar
+	for _, d := range Enabled(cfg) {
+		env = append(env, d.Connection...)
+	}
+	return env
+}
@@ -0,0 +1,108 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go-local-server/internal/projects"
+)
+
+// The hosts method owns only the lines between these markers.
+const (
+	hostsBegin = "# BEGIN GoLocalServer"
+	hostsEnd   = "# END GoLocalServer"
+)
+
+// hostsBlock returns the fenced lines for every project domain.
+func hostsBlock(projs []*projects.Project) []string {
+	seen := map[string]bool{}
+	var domains []string
+	for _, p := range projs {
+		d := strings.ToLower(p.Domain)
+		if d != "" && !seen[d] {
+			seen[d] = true
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+	sort.Strings(domains)
+
+	lines := []string{hostsBegin}
+	for _, d := range domains {
+		lines = append(lines, "127.0.0.1\t"+d, "::1\t"+d)
+	}
+	return append(lines, hostsEnd)
+}
+
+// hostsPlan plans replacing the fenced block in /etc/hosts with block; a nil
+// block removes it.
+func hostsPlan(block []string) (*Plan, error) {
+	old, err := os.ReadFile(hostsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	content, err := replaceBlock(string(old), block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", hostsFile, err)
+	}
+
+	plan := &Plan{Method: Hosts}
+	if content != string(old) {
+		plan.Changes = append(plan.Changes, FileChange{Path: hostsFile, Old: old, New: []byte(content)})
+	}
+	return plan, nil
+}
+
+// replaceBlock swaps the fenced block in content for block, keeping its
+// position, or appends block when there is none.
+func replaceBlock(content string, block []string) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var out []string
+	at, inBlock := -1, false
+	for _, l := range lines {
+		switch strings.TrimSpace(l) {
+		case hostsBegin:
+			if inBlock {
+				return "", fmt.Errorf("nested %q", hostsBegin)
+			}
+			inBlock = true
+			if at < 0 {
+				at = len(out)
+			}
+			continue
+		case hostsEnd:
+			if !inBlock {
+				return "", fmt.Errorf("%q without %q", hostsEnd, hostsBegin)
+			}
+			inBlock = false
+			continue
+		}
+		if !inBlock {
+			out = append(out, l)
+		}
+	}
+	if inBlock {
+		return "", fmt.Errorf("%q is never closed", hostsBegin)
+	}
+
+	fenced := make([]string, len(block))
+	for i, l := range block {
+		fenced[i] = l + "\n"
+	}
+	if at < 0 {
+		at = len(out)
+		if at > 0 && !strings.HasSuffix(out[at-1], "\n") {
+			out[at-1] += "\n"
+		}
+	}
+	out = append(out[:at], append(fenced, out[at:]...)...)
+	return strings.Join(out, ""), nil
+}
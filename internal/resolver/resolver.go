@@ -0,0 +1,220 @@
+// Package resolver points the operating system at the app's DNS server for
+// AppConfig.Domain, or lists the project domains in /etc/hosts where that is
+// not possible. Every change is computed as a Plan first, so it can be shown
+// as a diff before anything is written.
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/platform"
+	"go-local-server/internal/projects"
+)
+
+// Method is a way of routing the local domain to the DNS server.
+type Method string
+
+const (
+	MacOSResolver   Method = "macos"            // /etc/resolver/<domain>
+	SystemdResolved Method = "systemd-resolved" // resolved.conf.d drop-in
+	NetworkManager  Method = "networkmanager"   // NetworkManager's dnsmasq plugin
+	Hosts           Method = "hosts"            // fenced block in /etc/hosts
+)
+
+// ErrUnsupported is returned for a method this system does not offer.
+var ErrUnsupported = errors.New("resolver method not available on this system")
+
+const header = "# Generated by GoLocalServer; remove with \"golocalctl resolver uninstall\".\n"
+
+const (
+	macResolverDir  = "/etc/resolver"
+	resolvedDropIn  = "/etc/systemd/resolved.conf.d/golocalserver.conf"
+	dnsmasqSnippet  = "/etc/NetworkManager/dnsmasq.d/golocalserver.conf"
+	hostsFile       = "/etc/hosts"
+	resolvedReload  = "systemctl restart systemd-resolved"
+	nmReload        = "systemctl reload NetworkManager"
+	macFlushCommand = "killall -HUP mDNSResponder || true"
+)
+
+// FileChange replaces the content of one system file.
+type FileChange struct {
+	Path string
+	Old  []byte // nil when the file does not exist
+	New  []byte // nil to delete the file
+}
+
+// Plan is the set of changes needed to install or remove a method.
+type Plan struct {
+	Method  Method
+	Changes []FileChange
+	Reload  string // shell command run after the files are written
+}
+
+// Empty reports whether the system is already in the planned state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Diff renders the plan as a unified diff.
+func (p *Plan) Diff() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		b.WriteString(unifiedDiff(c.Path, string(c.Old), string(c.New)))
+	}
+	if p.Reload != "" && !p.Empty() {
+		fmt.Fprintf(&b, "# then: %s\n", p.Reload)
+	}
+	return b.String()
+}
+
+// Apply writes the changes as root, prompting for credentials the platform's
+// way, and runs the reload command.
+func (p *Plan) Apply() error {
+	if p.Empty() {
+		return nil
+	}
+
+	script := []string{"set -e"}
+	for _, c := range p.Changes {
+		if c.New == nil {
+			script = append(script, "rm -f "+shellQuote(c.Path))
+			continue
+		}
+		// Stage the content in a user-owned temp file; cat keeps the
+		// owner and mode of an existing target such as /etc/hosts.
+		tmp, err := os.CreateTemp("", "golocal-resolver-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(c.New); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		script = append(script,
+			"mkdir -p "+shellQuote(filepath.Dir(c.Path)),
+			"cat "+shellQuote(tmp.Name())+" > "+shellQuote(c.Path),
+		)
+	}
+	if p.Reload != "" {
+		script = append(script, p.Reload)
+	}
+
+	if err := platform.RunPrivileged(strings.Join(script, "\n")); err != nil {
+		return fmt.Errorf("%s: %w", p.Method, err)
+	}
+	return nil
+}
+
+// Methods lists the methods available on this system, preferred first.
+// Hosts is always last.
+func Methods() []Method {
+	return append(available(), Hosts)
+}
+
+// Detect returns the preferred method for this system.
+func Detect() Method {
+	return Methods()[0]
+}
+
+// ParseMethod accepts a method name; "" means Detect.
+func ParseMethod(s string) (Method, error) {
+	if s == "" {
+		return Detect(), nil
+	}
+	for _, m := range Methods() {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupported, s)
+}
+
+// Install plans routing cfg.Domain to the DNS server with method m. The hosts
+// method lists the domains of projs instead, since /etc/hosts has no
+// wildcards.
+func Install(cfg *config.AppConfig, m Method, projs []*projects.Project) (*Plan, error) {
+	switch m {
+	case MacOSResolver:
+		content := fmt.Sprintf("%snameserver 127.0.0.1\nport %d\n", header, cfg.DNSPort)
+		return filePlan(m, filepath.Join(macResolverDir, cfg.Domain), []byte(content), macFlushCommand)
+	case SystemdResolved:
+		content := fmt.Sprintf("%s[Resolve]\nDNS=127.0.0.1:%d\nDomains=~%s\n", header, cfg.DNSPort, cfg.Domain)
+		return filePlan(m, resolvedDropIn, []byte(content), resolvedReload)
+	case NetworkManager:
+		content := fmt.Sprintf("%sserver=/%s/127.0.0.1#%d\n", header, cfg.Domain, cfg.DNSPort)
+		return filePlan(m, dnsmasqSnippet, []byte(content), nmReload)
+	case Hosts:
+		return hostsPlan(hostsBlock(projs))
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, m)
+}
+
+// Uninstall plans removing what Install wrote for method m.
+func Uninstall(cfg *config.AppConfig, m Method) (*Plan, error) {
+	switch m {
+	case MacOSResolver:
+		return filePlan(m, filepath.Join(macResolverDir, cfg.Domain), nil, macFlushCommand)
+	case SystemdResolved:
+		return filePlan(m, resolvedDropIn, nil, resolvedReload)
+	case NetworkManager:
+		return filePlan(m, dnsmasqSnippet, nil, nmReload)
+	case Hosts:
+		return hostsPlan(nil)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, m)
+}
+
+// Status is "installed", "outdated" (installed for other settings or
+// projects) or "not installed".
+func Status(cfg *config.AppConfig, m Method, projs []*projects.Project) (string, error) {
+	install, err := Install(cfg, m, projs)
+	if err != nil {
+		return "", err
+	}
+	if install.Empty() {
+		return "installed", nil
+	}
+	uninstall, err := Uninstall(cfg, m)
+	if err != nil {
+		return "", err
+	}
+	if uninstall.Empty() {
+		return "not installed", nil
+	}
+	return "outdated", nil
+}
+
+// filePlan plans setting path to content, or deleting it when content is nil.
+// A file that exists without our header is never touched.
+func filePlan(m Method, path string, content []byte, reload string) (*Plan, error) {
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil && !strings.HasPrefix(string(old), header) {
+		return nil, fmt.Errorf("%s exists and was not written by GoLocalServer; remove it first", path)
+	}
+	if os.IsNotExist(err) {
+		old = nil
+	}
+
+	plan := &Plan{Method: m, Reload: reload}
+	if (old == nil && content == nil) || (old != nil && string(old) == string(content)) {
+		return plan, nil
+	}
+	plan.Changes = append(plan.Changes, FileChange{Path: path, Old: old, New: content})
+	return plan, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,6 @@
+package resolver
+
+// available lists the methods macOS supports besides Hosts.
+func available() []Method {
+	return []Method{MacOSResolver}
+}
@@ -0,0 +1,39 @@
+package resolver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// available lists the methods this Linux system supports besides Hosts:
+// systemd-resolved when it is running, and NetworkManager when it manages
+// DNS through its dnsmasq plugin.
+func available() []Method {
+	var methods []Method
+	if exec.Command("systemctl", "is-active", "--quiet", "systemd-resolved").Run() == nil {
+		methods = append(methods, SystemdResolved)
+	}
+	if networkManagerDnsmasq() {
+		methods = append(methods, NetworkManager)
+	}
+	return methods
+}
+
+func networkManagerDnsmasq() bool {
+	files, _ := filepath.Glob("/etc/NetworkManager/conf.d/*.conf")
+	files = append([]string{"/etc/NetworkManager/NetworkManager.conf"}, files...)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.ReplaceAll(strings.TrimSpace(line), " ", "") == "dns=dnsmasq" {
+				return true
+			}
+		}
+	}
+	return false
+}
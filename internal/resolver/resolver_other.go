@@ -0,0 +1,7 @@
+//go:build !darwin && !linux
+
+package resolver
+
+func available() []Method {
+	return nil
+}
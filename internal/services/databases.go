@@ -0,0 +1,367 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-local-server/internal/projects"
+)
+
+// DatabaseProvisioner manages the databases and users of one database
+// engine. Every method runs the engine's own client inside its container as
+// the administrative user.
+type DatabaseProvisioner interface {
+	// Engine is one of projects.Engines.
+	Engine() string
+	// Service is the service the engine runs as.
+	Service() ServiceID
+	// Running reports whether the engine is up.
+	Running(ctx context.Context) bool
+
+	// Databases lists the databases, leaving out the engine's own.
+	Databases(ctx context.Context) ([]string, error)
+	CreateDatabase(ctx context.Context, name string) error
+	DropDatabase(ctx context.Context, name string) error
+	// CreateUser creates a user that may connect from any container, or
+	// resets its password when it exists.
+	CreateUser(ctx context.Context, user, password string) error
+	// Grant gives the user full control of the database.
+	Grant(ctx context.Context, database, user string) error
+
+	// Dump writes a plain SQL dump of the database that Restore loads back.
+	Dump(ctx context.Context, database string, w io.Writer) error
+	// Restore runs SQL against the database, creating it when missing.
+	Restore(ctx context.Context, database string, r io.Reader) error
+}
+
+// NewProvisioner returns the provisioner of a database engine, whose
+// clients run through sm.Exec. MySQL and MariaDB log in as root with
+// rootPassword; PostgreSQL needs no password inside its container.
+func NewProvisioner(sm ServiceManagerInterface, rootPassword, engine string) (DatabaseProvisioner, error) {
+	engine, err := projects.ParseEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+	if engine == projects.EnginePostgres {
+		return &postgresProvisioner{sm: sm}, nil
+	}
+	return &mysqlProvisioner{sm: sm, engine: engine, rootPassword: rootPassword}, nil
+}
+
+// Provisioner returns the provisioner of a database engine.
+func (dsm *DockerServiceManager) Provisioner(engine string) (DatabaseProvisioner, error) {
+	return NewProvisioner(dsm, dsm.Config.MySQLRootPassword, engine)
+}
+
+// CreateDatabase provisions a project's database; see ProvisionDatabase.
+func (dsm *DockerServiceManager) CreateDatabase(ctx context.Context, db projects.DatabaseConfig) error {
+	p, err := dsm.Provisioner(db.EngineName())
+	if err != nil {
+		return err
+	}
+	return ProvisionDatabase(ctx, p, db)
+}
+
+// ProvisionDatabase creates the project's database and user and grants the
+// user full access. The password is always reset to the one in the project,
+// which phpMyAdmin and db_config.php rely on.
+func ProvisionDatabase(ctx context.Context, p DatabaseProvisioner, db projects.DatabaseConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	if err := p.CreateDatabase(ctx, db.DBName); err != nil {
+		return err
+	}
+	if err := p.CreateUser(ctx, db.DBUser, db.DBPassword); err != nil {
+		return err
+	}
+	return p.Grant(ctx, db.DBName, db.DBUser)
+}
+
+// databaseServices are the compose services of the database engines active
+// projects use besides MySQL, which is always part of the stack.
+func (dsm *DockerServiceManager) databaseServices() []string {
+	list, _ := projects.NewManager(dsm.Config).List()
+	var services []string
+	for _, e := range projects.ActiveEngines(list) {
+		if e != projects.EngineMySQL {
+			services = append(services, e)
+		}
+	}
+	return services
+}
+
+// mysqlProvisioner serves MySQL and MariaDB, which differ only in client
+// names and in how passwords are set.
+type mysqlProvisioner struct {
+	sm           ServiceManagerInterface
+	engine       string
+	rootPassword string
+}
+
+func (p *mysqlProvisioner) Engine() string     { return p.engine }
+func (p *mysqlProvisioner) Service() ServiceID { return ServiceID(p.engine) }
+
+func (p *mysqlProvisioner) Running(ctx context.Context) bool {
+	return running(ctx, p.sm, p.Service())
+}
+
+func (p *mysqlProvisioner) mariadb() bool {
+	return p.engine == projects.EngineMariaDB
+}
+
+// command returns a client command line logged in as root. MariaDB images
+// from 11.0 on only ship the mariadb-* names.
+func (p *mysqlProvisioner) command(tool string) []string {
+	if p.mariadb() {
+		tool = map[string]string{"mysql": "mariadb", "mysqldump": "mariadb-dump"}[tool]
+	}
+	return []string{tool, "-uroot", "-p" + p.rootPassword}
+}
+
+func (p *mysqlProvisioner) run(ctx context.Context, what, sql string) ([]byte, error) {
+	var out, stderr bytes.Buffer
+	cmd := append(p.command("mysql"), "-N", "-B", "-e", sql)
+	if err := p.sm.Exec(ctx, p.Service(), cmd, nil, &out, &stderr); err != nil {
+		return nil, clientError(what, err, &stderr)
+	}
+	return out.Bytes(), nil
+}
+
+func (p *mysqlProvisioner) Databases(ctx context.Context) ([]string, error) {
+	out, err := p.run(ctx, "list databases", "SHOW DATABASES")
+	if err != nil {
+		return nil, err
+	}
+	system := map[string]bool{"information_schema": true, "mysql": true, "performance_schema": true, "sys": true}
+	var dbs []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if db := strings.TrimSpace(line); db != "" && !system[db] {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs, nil
+}
+
+func (p *mysqlProvisioner) CreateDatabase(ctx context.Context, name string) error {
+	_, err := p.run(ctx, "failed to create database",
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;", mysqlIdent(name)))
+	return err
+}
+
+func (p *mysqlProvisioner) DropDatabase(ctx context.Context, name string) error {
+	_, err := p.run(ctx, "failed to drop database", "DROP DATABASE IF EXISTS "+mysqlIdent(name)+";")
+	return err
+}
+
+func (p *mysqlProvisioner) CreateUser(ctx context.Context, user, password string) error {
+	// MySQL 8.0 defaults to caching_sha2_password, which older PHP clients
+	// and phpMyAdmin setups cannot use. 8.4 disables mysql_native_password
+	// and 9.0 removes it, so those get the server's default; MariaDB has no
+	// such plugin.
+	identified := "IDENTIFIED BY " + mysqlString(password)
+	if !p.mariadb() {
+		out, err := p.run(ctx, "failed to read the server version", "SELECT VERSION();")
+		if err != nil {
+			return err
+		}
+		if hasNativePassword(strings.TrimSpace(string(out))) {
+			identified = "IDENTIFIED WITH mysql_native_password BY " + mysqlString(password)
+		}
+	}
+	account := mysqlString(user) + "@'%'"
+	if _, err := p.run(ctx, "failed to create user", "CREATE USER IF NOT EXISTS "+account+" "+identified+";"); err != nil {
+		return err
+	}
+	_, err := p.run(ctx, "failed to set user password", "ALTER USER "+account+" "+identified+";")
+	return err
+}
+
+// hasNativePassword reports whether a MySQL server version, e.g. 8.0.36,
+// comes with mysql_native_password enabled.
+func hasNativePassword(version string) bool {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return false
+	}
+	return major < 8 || major == 8 && minor < 4
+}
+
+func (p *mysqlProvisioner) Grant(ctx context.Context, database, user string) error {
+	_, err := p.run(ctx, "failed to grant privileges",
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO %s@'%%'; FLUSH PRIVILEGES;", mysqlIdent(database), mysqlString(user)))
+	return err
+}
+
+func (p *mysqlProvisioner) Dump(ctx context.Context, database string, w io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := append(p.command("mysqldump"),
+		"--single-transaction", "--quick", "--routines", "--triggers", "--events",
+		"--add-drop-database", "--databases", database)
+	if err := p.sm.Exec(ctx, p.Service(), cmd, nil, w, &stderr); err != nil {
+		return clientError("dump "+database, err, &stderr)
+	}
+	return nil
+}
+
+func (p *mysqlProvisioner) Restore(ctx context.Context, database string, r io.Reader) error {
+	if err := p.CreateDatabase(ctx, database); err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := append(p.command("mysql"), "--default-character-set=utf8mb4", "--max-allowed-packet=1G", database)
+	if err := p.sm.Exec(ctx, p.Service(), cmd, r, io.Discard, &stderr); err != nil {
+		return clientError("restore "+database, err, &stderr)
+	}
+	return nil
+}
+
+// postgresProvisioner runs psql as the postgres superuser, which the
+// official image trusts on the container's local socket.
+type postgresProvisioner struct {
+	sm ServiceManagerInterface
+}
+
+func (p *postgresProvisioner) Engine() string     { return projects.EnginePostgres }
+func (p *postgresProvisioner) Service() ServiceID { return projects.EnginePostgres }
+
+func (p *postgresProvisioner) Running(ctx context.Context) bool {
+	return running(ctx, p.sm, p.Service())
+}
+
+// query runs one statement and returns its unaligned, tuples-only output.
+func (p *postgresProvisioner) query(ctx context.Context, what, sql string) (string, error) {
+	var out, stderr bytes.Buffer
+	cmd := []string{"psql", "-U", "postgres", "-X", "-A", "-t", "-v", "ON_ERROR_STOP=1", "-c", sql}
+	if err := p.sm.Exec(ctx, p.Service(), cmd, nil, &out, &stderr); err != nil {
+		return "", clientError(what, err, &stderr)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// script runs SQL from r against a database, stopping at the first error.
+func (p *postgresProvisioner) script(ctx context.Context, what, database string, r io.Reader) error {
+	var stderr bytes.Buffer
+	cmd := []string{"psql", "-U", "postgres", "-X", "-q", "-v", "ON_ERROR_STOP=1", "-d", database}
+	if err := p.sm.Exec(ctx, p.Service(), cmd, r, io.Discard, &stderr); err != nil {
+		return clientError(what, err, &stderr)
+	}
+	return nil
+}
+
+func (p *postgresProvisioner) Databases(ctx context.Context) ([]string, error) {
+	out, err := p.query(ctx, "list databases",
+		"SELECT datname FROM pg_database WHERE NOT datistemplate AND datname <> 'postgres' ORDER BY 1")
+	if err != nil {
+		return nil, err
+	}
+	var dbs []string
+	for _, line := range strings.Split(out, "\n") {
+		if db := strings.TrimSpace(line); db != "" {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs, nil
+}
+
+func (p *postgresProvisioner) CreateDatabase(ctx context.Context, name string) error {
+	// CREATE DATABASE has no IF NOT EXISTS and cannot run inside a DO block.
+	exists, err := p.query(ctx, "failed to create database", "SELECT 1 FROM pg_database WHERE datname = "+pgString(name))
+	if err != nil || exists != "" {
+		return err
+	}
+	_, err = p.query(ctx, "failed to create database", "CREATE DATABASE "+pgIdent(name)+" ENCODING 'UTF8'")
+	return err
+}
+
+func (p *postgresProvisioner) DropDatabase(ctx context.Context, name string) error {
+	_, err := p.query(ctx, "failed to drop database", "DROP DATABASE IF EXISTS "+pgIdent(name)+" WITH (FORCE)")
+	return err
+}
+
+func (p *postgresProvisioner) CreateUser(ctx context.Context, user, password string) error {
+	sql := fmt.Sprintf(`DO $$ BEGIN
+  IF EXISTS (SELECT FROM pg_roles WHERE rolname = %[1]s) THEN
+    ALTER ROLE %[2]s WITH LOGIN PASSWORD %[3]s;
+  ELSE
+    CREATE ROLE %[2]s WITH LOGIN PASSWORD %[3]s;
+  END IF;
+END $$;`, pgString(user), pgIdent(user), pgString(password))
+	return p.script(ctx, "failed to create user", "postgres", strings.NewReader(sql))
+}
+
+// Grant makes the user the database's owner, which also covers the public
+// schema on PostgreSQL 15 and later.
+func (p *postgresProvisioner) Grant(ctx context.Context, database, user string) error {
+	sql := fmt.Sprintf("ALTER DATABASE %[1]s OWNER TO %[2]s;\nGRANT ALL PRIVILEGES ON DATABASE %[1]s TO %[2]s;\n",
+		pgIdent(database), pgIdent(user))
+	return p.script(ctx, "failed to grant privileges", "postgres", strings.NewReader(sql))
+}
+
+// Dump leaves out owners and grants, so that Restore can load the dump as
+// whoever owns the target database.
+func (p *postgresProvisioner) Dump(ctx context.Context, database string, w io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := []string{"pg_dump", "-U", "postgres", "--clean", "--if-exists", "--no-owner", "--no-privileges", database}
+	if err := p.sm.Exec(ctx, p.Service(), cmd, nil, w, &stderr); err != nil {
+		return clientError("dump "+database, err, &stderr)
+	}
+	return nil
+}
+
+// Restore runs the SQL as the database's owner, so the project user owns
+// the tables it creates.
+func (p *postgresProvisioner) Restore(ctx context.Context, database string, r io.Reader) error {
+	if err := p.CreateDatabase(ctx, database); err != nil {
+		return err
+	}
+	owner, err := p.query(ctx, "restore "+database, "SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = "+pgString(database))
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != "postgres" {
+		r = io.MultiReader(strings.NewReader("SET ROLE "+pgIdent(owner)+";\n"), r)
+	}
+	return p.script(ctx, "restore "+database, database, r)
+}
+
+func running(ctx context.Context, sm ServiceManagerInterface, id ServiceID) bool {
+	status, err := sm.Status(ctx, id)
+	return err == nil && status == StatusRunning
+}
+
+// clientError adds what a database client printed, minus the command-line
+// password warning, to a failed exec.
+func clientError(what string, err error, stderr *bytes.Buffer) error {
+	var lines []string
+	for _, l := range strings.Split(stderr.String(), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.Contains(l, "password on the command line") {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return fmt.Errorf("%s: %w - %s", what, err, strings.Join(lines, "; "))
+}
+
+func mysqlIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+func mysqlString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func pgIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func pgString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"go-local-server/internal/projects"
+	"go-local-server/internal/services"
+	"go-local-server/internal/services/servicestest"
+)
+
+func TestCreateUserAuthPlugin(t *testing.T) {
+	tests := []struct {
+		engine  string
+		version string
+		native  bool
+	}{
+		{projects.EngineMySQL, "5.7.44-log", true},
+		{projects.EngineMySQL, "8.0.36", true},
+		{projects.EngineMySQL, "8.4.2", false},
+		{projects.EngineMySQL, "9.1.0", false},
+		{projects.EngineMariaDB, "", false},
+	}
+	for _, tt := range tests {
+		fake := servicestest.New(services.ServiceID(tt.engine))
+		fake.SetExec(func(ctx context.Context, id services.ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+			if cmd[len(cmd)-1] == "SELECT VERSION();" {
+				_, err := io.WriteString(stdout, tt.version+"\n")
+				return err
+			}
+			return nil
+		})
+		p, err := services.NewProvisioner(fake, "root", tt.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.CreateUser(context.Background(), "blog", "secret"); err != nil {
+			t.Fatalf("%s %s: %v", tt.engine, tt.version, err)
+		}
+
+		var statements []string
+		for _, call := range fake.Calls() {
+			if strings.Contains(call, "USER") {
+				statements = append(statements, call)
+			}
+		}
+		if len(statements) != 2 {
+			t.Fatalf("%s %s: statements %q, want CREATE USER and ALTER USER", tt.engine, tt.version, statements)
+		}
+		for _, s := range statements {
+			if got := strings.Contains(s, "mysql_native_password"); got != tt.native {
+				t.Errorf("%s %s: %q uses mysql_native_password = %v, want %v", tt.engine, tt.version, s, got, tt.native)
+			}
+			if !strings.Contains(s, "IDENTIFIED") || !strings.Contains(s, "'secret'") {
+				t.Errorf("%s %s: %q does not set the password", tt.engine, tt.version, s)
+			}
+		}
+	}
+}
@@ -1,28 +1,28 @@
 package services
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"go-local-server/internal/config"
+	"go-local-server/internal/docker"
+	"go-local-server/internal/events"
+	"go-local-server/internal/platform"
+	"go-local-server/internal/registry"
 	"go-local-server/pkg/apache"
 )
 
-func findDockerBinary() string {
-	locations := []string{
-		"/usr/local/bin/docker",
-		"/opt/homebrew/bin/docker",
-		"/usr/bin/docker",
-		"/Applications/Docker.app/Contents/Resources/bin/docker",
-		"/Applications/Docker.app/Contents/MacOS/docker",
-		"/usr/local/docker/bin/docker",
-	}
+// FindDockerBinary returns the docker CLI path, falling back to "docker" on
+// $PATH when none of the usual install locations exist.
+func FindDockerBinary() string {
+	locations := platform.DockerBinaryCandidates()
 
 	if home, err := os.UserHomeDir(); err == nil {
 		locations = append(locations,
@@ -40,329 +40,394 @@ func findDockerBinary() string {
 	return "docker"
 }
 
-// DockerServiceManager uses Docker Compose to manage services
+// ComposeEnv is the environment for docker compose runs.
+func ComposeEnv() []string {
+	return os.Environ()
+}
+
+// DockerServiceManager uses Docker Compose to manage services. Container
+// state, logs and exec go through the Engine API when its socket is
+// reachable, and through the docker CLI otherwise.
 type DockerServiceManager struct {
-	Config       *config.AppConfig
-	Services     map[string]*Service
-	composeFile  string
+	Config *config.AppConfig
+	// Events, if set, receives the status changes of the services and
+	// VhostReloaded after every Reload.
+	Events *events.Bus
+
+	mu          sync.Mutex
+	services    map[ServiceID]*Service // guarded by mu
+	composeFile string
+	engine      *docker.Client
 }
 
 func NewDockerServiceManager(cfg *config.AppConfig) *DockerServiceManager {
 	dsm := &DockerServiceManager{
-		Config:      cfg,
-		Services:    make(map[string]*Service),
-		composeFile: filepath.Join(config.ConfigDir, "..", "docker-compose.yml"),
-	}
-
-	// Prefer the app-copied docker resources directory (works when running from .app)
-	candidate := filepath.Join(config.ConfigDir, "docker", "docker-compose.yml")
-	if st, err := os.Stat(candidate); err == nil && !st.IsDir() {
-		dsm.composeFile = candidate
-	} else {
-		// Fallback to local project directory for docker-compose
-		cwd, _ := os.Getwd()
-		dsm.composeFile = filepath.Join(cwd, "docker-compose.yml")
+		Config:   cfg,
+		services: make(map[ServiceID]*Service),
 	}
 
-	dsm.Services["nginx"] = &Service{
-		Name:    "apache",
-		Status:  StatusStopped,
-		LogFile: filepath.Join(config.LogDir, "apache.log"),
+	if engine, err := docker.NewClient(); err == nil {
+		dsm.engine = engine
 	}
 
-	dsm.Services["php-fpm"] = &Service{
-		Name:    "php",
-		Status:  StatusStopped,
-		LogFile: filepath.Join(config.LogDir, "php-fpm.log"),
+	// The compose file is generated from cfg into the app's docker directory;
+	// write it now if this is the first run.
+	dsm.composeFile = ComposeFilePath()
+	if _, err := os.Stat(dsm.composeFile); os.IsNotExist(err) {
+		if _, err := CopyDockerResources(cfg); err != nil {
+			fmt.Printf("docker resources not prepared: %v\n", err)
+		}
 	}
 
-	dsm.Services["mysql"] = &Service{
-		Name:    "mysql",
-		Status:  StatusStopped,
-		LogFile: filepath.Join(config.LogDir, "mysql.log"),
+	logs := map[ServiceID]string{Apache: "apache.log", PHP: "php-fpm.log", MySQL: "mysql.log"}
+	for _, id := range CoreServices {
+		dsm.services[id] = &Service{
+			ID:      id,
+			Name:    ServiceName(id),
+			Status:  StatusStopped,
+			LogFile: filepath.Join(config.LogDir, logs[id]),
+		}
 	}
-
+	dsm.syncOptionalServices()
 	return dsm
 }
 
-func (dsm *DockerServiceManager) dockerCompose(args ...string) *exec.Cmd {
-	dockerPath := findDockerBinary()
-	cmd := exec.Command(dockerPath, append([]string{"compose", "-f", dsm.composeFile}, args...)...)
-	cmd.Dir = filepath.Dir(dsm.composeFile)
-	return cmd
-}
-
-func (dsm *DockerServiceManager) dockerComposeWithTimeout(timeout time.Duration, args ...string) *exec.Cmd {
-	dockerPath := findDockerBinary()
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	_ = cancel
+func (dsm *DockerServiceManager) dockerCompose(ctx context.Context, args ...string) *exec.Cmd {
+	dockerPath := FindDockerBinary()
 	cmd := exec.CommandContext(ctx, dockerPath, append([]string{"compose", "-f", dsm.composeFile}, args...)...)
 	cmd.Dir = filepath.Dir(dsm.composeFile)
+	cmd.Env = ComposeEnv()
 	return cmd
 }
 
-func (dsm *DockerServiceManager) StartNginx() error {
-	svc := dsm.Services["nginx"]
-	if svc.Status == StatusRunning {
-		return fmt.Errorf("apache already running")
-	}
-
-	// Ensure docker nginx has the latest configs (and fastcgi_pass rewritten)
-	if err := dsm.ReloadNginx(); err != nil {
-		svc.Status = StatusError
-		return fmt.Errorf("failed to prepare nginx config: %v", err)
-	}
-
-	// Start apache container
-	cmd := dsm.dockerCompose("up", "-d", "apache")
-	output, err := cmd.CombinedOutput()
+// compose runs a docker compose subcommand and includes its output in the
+// error.
+func (dsm *DockerServiceManager) compose(ctx context.Context, what string, args ...string) error {
+	output, err := dsm.dockerCompose(ctx, args...).CombinedOutput()
 	if err != nil {
-		svc.Status = StatusError
-		return fmt.Errorf("failed to start apache: %v\n%s", err, output)
+		return fmt.Errorf("failed to %s: %v\n%s", what, err, output)
 	}
+	return nil
+}
 
-	svc.Status = StatusRunning
-	svc.PID = 1 // Docker manages PID
-
-	time.Sleep(1 * time.Second)
-	return dsm.CheckNginxStatus()
+// ComposeFile returns the docker-compose.yml the manager operates on.
+func (dsm *DockerServiceManager) ComposeFile() string {
+	return dsm.composeFile
 }
 
-func (dsm *DockerServiceManager) StopNginx() error {
-	svc := dsm.Services["nginx"]
-	if svc.Status == StatusStopped {
-		return nil
+// RunCompose runs an arbitrary docker compose subcommand against the managed
+// stack, streaming combined output to w.
+func (dsm *DockerServiceManager) RunCompose(w io.Writer, args ...string) error {
+	cmd := dsm.dockerCompose(context.Background(), args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker compose %s: %w", strings.Join(args, " "), err)
 	}
-
-	cmd := dsm.dockerCompose("stop", "apache")
-	cmd.Run()
-
-	svc.Status = StatusStopped
-	svc.PID = 0
 	return nil
 }
 
-func (dsm *DockerServiceManager) ReloadNginx() error {
-	// Regenerate apache vhosts into app support
-	gen := apache.NewGenerator(dsm.Config)
-	gen.GenerateAllVhosts()
-
-	// Copy vhosts to repo ./apache/sites (mounted into container)
-	srcSitesDir := filepath.Join(config.ConfigDir, "apache", "sites")
-	dstSitesDir := filepath.Join(filepath.Dir(dsm.composeFile), "apache", "sites")
-	os.MkdirAll(dstSitesDir, 0755)
-
-	entries, err := os.ReadDir(srcSitesDir)
-	if err == nil {
-		for _, e := range entries {
-			if e.IsDir() {
-				continue
-			}
-			in, rerr := os.ReadFile(filepath.Join(srcSitesDir, e.Name()))
-			if rerr != nil {
-				continue
-			}
-			_ = os.WriteFile(filepath.Join(dstSitesDir, e.Name()), in, 0644)
+// containers returns the compose services behind id. It is empty when the
+// service is not in use: PHP before any project exists, or a registry
+// service that is switched off.
+func (dsm *DockerServiceManager) containers(id ServiceID) ([]string, error) {
+	switch id {
+	case Apache:
+		return []string{"apache"}, nil
+	case PHP:
+		return dsm.phpServices(), nil
+	case MySQL:
+		return append([]string{"mysql"}, dsm.databaseServices()...), nil
+	}
+	if d, ok := registry.Lookup(string(id)); ok {
+		if !dsm.Config.ServiceEnabled(d.ID) {
+			return nil, nil
 		}
+		return []string{d.ID}, nil
 	}
-
-	// Reload apache in container if it's already running (ignore errors otherwise)
-	cmd := dsm.dockerCompose("exec", "apache", "apachectl", "-k", "graceful")
-	_ = cmd.Run()
-	return nil
+	for _, svc := range dsm.composeServices() {
+		if svc == string(id) {
+			return []string{svc}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownService, id)
 }
 
-func (dsm *DockerServiceManager) CheckNginxStatus() error {
-	cmd := dsm.dockerComposeWithTimeout(2*time.Second, "ps", "apache", "--format", "{{.Status}}")
-	output, err := cmd.Output()
-	if err != nil {
-		dsm.Services["nginx"].Status = StatusStopped
-		return fmt.Errorf("apache not running")
+// startable is containers for Start, which cannot act on an empty set.
+func (dsm *DockerServiceManager) startable(id ServiceID) ([]string, error) {
+	names, err := dsm.containers(id)
+	if err != nil || len(names) > 0 {
+		return names, err
 	}
-
-	status := strings.TrimSpace(string(output))
-	if strings.Contains(status, "Up") {
-		dsm.Services["nginx"].Status = StatusRunning
-		return nil
+	if id == PHP {
+		return nil, fmt.Errorf("no project uses PHP yet")
 	}
-
-	dsm.Services["nginx"].Status = StatusStopped
-	return fmt.Errorf("apache not running")
+	return nil, fmt.Errorf("%s is not enabled; turn it on in Settings first", ServiceName(id))
 }
 
-func (dsm *DockerServiceManager) StartPHP() error {
-	// Apache image already bundles PHP, nothing to start separately
-	svc := dsm.Services["php-fpm"]
-	svc.Status = StatusRunning
-	svc.PID = 1
-	return nil
-}
+func (dsm *DockerServiceManager) Start(ctx context.Context, id ServiceID) error {
+	names, err := dsm.startable(id)
+	if err != nil {
+		return err
+	}
 
-func (dsm *DockerServiceManager) StopPHP() error {
-	svc := dsm.Services["php-fpm"]
-	svc.Status = StatusStopped
-	svc.PID = 0
-	return nil
-}
+	args := []string{"up", "-d"}
+	switch id {
+	case Apache:
+		// Make sure the vhosts and compose file are current
+		if err := dsm.Reload(ctx); err != nil {
+			return dsm.fail(id, fmt.Errorf("failed to prepare apache config: %v", err))
+		}
+	case PHP:
+		args = append(args, "--build")
+	}
+	if err := dsm.compose(ctx, "start "+string(id), append(args, names...)...); err != nil {
+		return dsm.fail(id, err)
+	}
 
-func (dsm *DockerServiceManager) ReloadPHP() error {
+	// Give the server a moment before checking on it.
+	wait := time.Second
+	if id == MySQL {
+		wait = 3 * time.Second
+	}
+	if err := sleep(ctx, wait); err != nil {
+		return err
+	}
+	status, err := dsm.Status(ctx, id)
+	if err != nil {
+		return err
+	}
+	if status != StatusRunning {
+		return dsm.fail(id, fmt.Errorf("%s not running", id))
+	}
 	return nil
 }
 
-func (dsm *DockerServiceManager) RestartPHP() error {
-	if err := dsm.StopPHP(); err != nil {
+func (dsm *DockerServiceManager) Stop(ctx context.Context, id ServiceID) error {
+	names, err := dsm.containers(id)
+	if err != nil {
 		return err
 	}
-	return dsm.StartPHP()
-}
-
-func (dsm *DockerServiceManager) CheckPHPStatus() error {
-	// PHP status is tied to apache container
-	if err := dsm.CheckNginxStatus(); err != nil {
-		dsm.Services["php-fpm"].Status = StatusStopped
-		return fmt.Errorf("php not running")
+	if len(names) > 0 {
+		if err := dsm.compose(ctx, "stop "+string(id), append([]string{"stop"}, names...)...); err != nil {
+			return err
+		}
 	}
-	dsm.Services["php-fpm"].Status = StatusRunning
+	dsm.setStatus(id, StatusStopped)
 	return nil
 }
 
-func (dsm *DockerServiceManager) StartMySQL() error {
-	svc := dsm.Services["mysql"]
-	if svc.Status == StatusRunning {
-		return fmt.Errorf("mysql already running")
+func (dsm *DockerServiceManager) Restart(ctx context.Context, id ServiceID) error {
+	names, err := dsm.startable(id)
+	if err != nil {
+		return err
+	}
+	if err := dsm.compose(ctx, "restart "+string(id), append([]string{"restart"}, names...)...); err != nil {
+		return dsm.fail(id, err)
 	}
+	_, err = dsm.Status(ctx, id)
+	return err
+}
 
-	cmd := dsm.dockerCompose("up", "-d", "mysql")
-	output, err := cmd.CombinedOutput()
+// Status reports a service as running when all of its containers are up.
+// PHP counts as stopped while no project needs a PHP-FPM container. MySQL
+// is only its own container: the other engines have their own IDs.
+func (dsm *DockerServiceManager) Status(ctx context.Context, id ServiceID) (ServiceStatus, error) {
+	names, err := dsm.containers(id)
 	if err != nil {
-		svc.Status = StatusError
-		return fmt.Errorf("failed to start mysql: %v\n%s", err, output)
+		return StatusStopped, err
 	}
-
-	svc.Status = StatusRunning
-	svc.PID = 1
-
-	time.Sleep(3 * time.Second)
-	return dsm.CheckMySQLStatus()
+	if id == MySQL {
+		names = names[:1]
+	}
+	status := StatusStopped
+	if len(names) > 0 {
+		status = StatusRunning
+	}
+	for _, name := range names {
+		running, _, err := dsm.containerState(ctx, name)
+		if err != nil {
+			return StatusStopped, err
+		}
+		if !running {
+			status = StatusStopped
+			break
+		}
+	}
+	dsm.setStatus(id, status)
+	return status, nil
 }
 
-func (dsm *DockerServiceManager) StopMySQL() error {
-	svc := dsm.Services["mysql"]
-	if svc.Status == StatusStopped {
-		return nil
+// setStatus records the state of a tracked service and publishes the
+// change; other IDs are ignored.
+func (dsm *DockerServiceManager) setStatus(id ServiceID, status ServiceStatus) {
+	dsm.mu.Lock()
+	svc, ok := dsm.services[id]
+	var prev ServiceStatus
+	if ok {
+		prev, svc.Status = svc.Status, status
 	}
+	dsm.mu.Unlock()
 
-	cmd := dsm.dockerCompose("stop", "mysql")
-	cmd.Run()
+	if ok {
+		publishStatus(dsm.Events, id, ServiceName(id), prev, status)
+	}
+}
 
-	svc.Status = StatusStopped
-	svc.PID = 0
-	return nil
+// fail marks a service as failed, publishes why and returns err.
+func (dsm *DockerServiceManager) fail(id ServiceID, err error) error {
+	dsm.setStatus(id, StatusError)
+	dsm.Events.Publish(events.ServiceFailed{Service: string(id), Name: ServiceName(id), Error: err.Error()})
+	return err
 }
 
-func (dsm *DockerServiceManager) CheckMySQLStatus() error {
-	cmd := dsm.dockerComposeWithTimeout(2*time.Second, "ps", "mysql", "--format", "{{.Status}}")
-	output, err := cmd.Output()
-	if err != nil {
-		dsm.Services["mysql"].Status = StatusStopped
-		return fmt.Errorf("mysql not running")
+// ids returns the IDs of the tracked services.
+func (dsm *DockerServiceManager) ids() []ServiceID {
+	dsm.mu.Lock()
+	defer dsm.mu.Unlock()
+	ids := make([]ServiceID, 0, len(dsm.services))
+	for id := range dsm.services {
+		ids = append(ids, id)
 	}
+	return ids
+}
 
-	status := strings.TrimSpace(string(output))
-	if strings.Contains(status, "Up") {
-		dsm.Services["mysql"].Status = StatusRunning
-		return nil
-	}
+func (dsm *DockerServiceManager) Reload(ctx context.Context) error {
+	// Regenerate apache vhosts into app support
+	gen := apache.NewGenerator(dsm.Config)
+	gen.GenerateAllVhosts()
 
-	dsm.Services["mysql"].Status = StatusStopped
-	return fmt.Errorf("mysql not running")
-}
+	// Mirror vhosts to ./apache/sites (mounted into container). Vhosts of
+	// deleted projects go too: they point at certificates that no longer
+	// exist, and Apache refuses to start with them.
+	srcSitesDir := filepath.Join(config.DataDir, "apache", "sites")
+	dstSitesDir := filepath.Join(filepath.Dir(dsm.composeFile), "apache", "sites")
+	os.MkdirAll(dstSitesDir, 0755)
 
-func (dsm *DockerServiceManager) CreateDatabase(dbName, dbUser, dbPassword string) error {
-	quotedDB := "`" + strings.ReplaceAll(dbName, "`", "``") + "`"
-	// Escape SQL string values
-	escapeSQL := func(s string) string {
-		s = strings.ReplaceAll(s, "\\", "\\\\")
-		s = strings.ReplaceAll(s, "'", "\\'")
-		return s
+	entries, err := os.ReadDir(srcSitesDir)
+	if err == nil {
+		current := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			current[e.Name()] = true
+			in, rerr := os.ReadFile(filepath.Join(srcSitesDir, e.Name()))
+			if rerr != nil {
+				continue
+			}
+			_ = os.WriteFile(filepath.Join(dstSitesDir, e.Name()), in, 0644)
+		}
+		if copied, err := os.ReadDir(dstSitesDir); err == nil {
+			for _, e := range copied {
+				if !e.IsDir() && filepath.Ext(e.Name()) == ".conf" && !current[e.Name()] {
+					_ = os.Remove(filepath.Join(dstSitesDir, e.Name()))
+				}
+			}
+		}
 	}
-	userEsc := escapeSQL(dbUser)
-	passEsc := escapeSQL(dbPassword)
 
-	cmd := dsm.dockerCompose("exec", "mysql", "mysql", "-uroot", "-proot",
-		"-e", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;", quotedDB))
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create database: %v - %s", err, output)
+	// Project paths outside $HOME are bind-mounted, so the compose file can
+	// change too; the running stack picks that up on the next compose up.
+	if _, err := WriteComposeFile(dsm.Config); err != nil {
+		fmt.Printf("failed to regenerate docker-compose.yml: %v\n", err)
 	}
 
-	// Create user if missing
-	cmd = dsm.dockerCompose("exec", "mysql", "mysql", "-uroot", "-proot",
-		"-e", fmt.Sprintf("CREATE USER IF NOT EXISTS '%s'@'%%' IDENTIFIED WITH mysql_native_password BY '%s';", userEsc, passEsc))
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create user: %v - %s", err, output)
-	}
+	// Reload apache in container if it's already running (ignore errors otherwise)
+	_, _ = dsm.execOutput(ctx, Apache, "apachectl", "-k", "graceful")
 
-	// Always reset password to match project config (important for phpMyAdmin login)
-	cmd = dsm.dockerCompose("exec", "mysql", "mysql", "-uroot", "-proot",
-		"-e", fmt.Sprintf("ALTER USER '%s'@'%%' IDENTIFIED WITH mysql_native_password BY '%s';", userEsc, passEsc))
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to set user password: %v - %s", err, output)
+	// A project may have switched to a PHP version whose container is not up yet
+	if running, _, _ := dsm.containerState(ctx, "apache"); running {
+		if phpServices := dsm.phpServices(); len(phpServices) > 0 {
+			if err := dsm.compose(ctx, "start php-fpm", append([]string{"up", "-d"}, phpServices...)...); err != nil {
+				return err
+			}
+		}
 	}
 
-	cmd = dsm.dockerCompose("exec", "mysql", "mysql", "-uroot", "-proot",
-		"-e", fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO '%s'@'%%'; FLUSH PRIVILEGES;", quotedDB, userEsc))
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to grant privileges: %v - %s", err, output)
+	// ...or to a database engine that is not up yet
+	if running, _, _ := dsm.containerState(ctx, "mysql"); running {
+		if dbServices := dsm.databaseServices(); len(dbServices) > 0 {
+			if err := dsm.compose(ctx, "start "+strings.Join(dbServices, ", "), append([]string{"up", "-d"}, dbServices...)...); err != nil {
+				return err
+			}
+		}
 	}
-
+	dsm.Events.Publish(events.VhostReloaded{})
 	return nil
 }
 
-func (dsm *DockerServiceManager) StartAll() error {
-	// Start all services with docker-compose up -d
-	cmd := dsm.dockerComposeWithTimeout(30*time.Second, "up", "-d")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to start services: %v\n%s", err, output)
+func (dsm *DockerServiceManager) StartAll(ctx context.Context) error {
+	// Start all services with docker-compose up -d. No timeout: the first
+	// run builds the PHP-FPM images, which takes minutes; ctx cancels it.
+	if err := dsm.compose(ctx, "start services", "up", "-d"); err != nil {
+		return err
 	}
 
-	dsm.Services["nginx"].Status = StatusRunning
-	dsm.Services["php-fpm"].Status = StatusRunning
-	dsm.Services["mysql"].Status = StatusRunning
-
-	time.Sleep(3 * time.Second)
-	dsm.RefreshStatuses()
+	if err := sleep(ctx, 3*time.Second); err != nil {
+		return err
+	}
+	dsm.RefreshStatuses(ctx)
 	return nil
 }
 
-func (dsm *DockerServiceManager) StopAll() error {
-	cmd := dsm.dockerCompose("down")
-	cmd.Run()
-
-	for _, svc := range dsm.Services {
-		svc.Status = StatusStopped
-		svc.PID = 0
+func (dsm *DockerServiceManager) StopAll(ctx context.Context) error {
+	if err := dsm.compose(ctx, "stop services", "down"); err != nil {
+		return err
+	}
+	for _, id := range dsm.ids() {
+		dsm.setStatus(id, StatusStopped)
 	}
 	return nil
 }
 
-func (dsm *DockerServiceManager) ReloadAll() error {
-	return dsm.ReloadNginx()
+func (dsm *DockerServiceManager) RefreshStatuses(ctx context.Context) {
+	dsm.syncOptionalServices()
+	for _, id := range dsm.ids() {
+		dsm.Status(ctx, id)
+	}
 }
 
-func (dsm *DockerServiceManager) RefreshStatuses() {
-	dsm.CheckNginxStatus()
-	dsm.CheckPHPStatus()
-	dsm.CheckMySQLStatus()
+// Services returns the tracked services: the core ones, then the enabled
+// registry services in registry order.
+func (dsm *DockerServiceManager) Services() []Service {
+	dsm.mu.Lock()
+	defer dsm.mu.Unlock()
+	var list []Service
+	for _, id := range CoreServices {
+		list = append(list, *dsm.services[id])
+	}
+	for _, id := range dsm.optionalServices() {
+		if svc, ok := dsm.services[ServiceID(id)]; ok {
+			list = append(list, *svc)
+		}
+	}
+	return list
 }
 
-func (dsm *DockerServiceManager) GetServices() map[string]*Service {
-	return dsm.Services
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // CheckDockerAvailable verifies Docker is installed and running
 func CheckDockerAvailable() bool {
-	dockerPath := findDockerBinary()
+	if engine, err := docker.NewClient(); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if engine.Ping(ctx) == nil {
+			return true
+		}
+	}
+
+	dockerPath := FindDockerBinary()
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, dockerPath, "version")
@@ -372,26 +437,26 @@ func CheckDockerAvailable() bool {
 	return true
 }
 
-// IsDockerDesktopInstalled checks if Docker Desktop app exists
+// IsDockerDesktopInstalled checks if Docker Desktop is installed
 func IsDockerDesktopInstalled() bool {
-	_, err := os.Stat("/Applications/Docker.app")
-	return err == nil
+	return platform.DockerDesktopInstalled()
 }
 
 // StartDockerDesktop launches Docker Desktop application
 func StartDockerDesktop() error {
-	if !IsDockerDesktopInstalled() {
-		return fmt.Errorf("Docker Desktop not found in /Applications")
-	}
-	cmd := exec.Command("open", "-a", "Docker")
-	return cmd.Run()
+	return platform.StartDockerDesktop()
 }
 
-// EnsureDockerRunning checks Docker and auto-starts Docker Desktop if needed
+// EnsureDockerRunning checks Docker and auto-starts Docker Desktop if needed.
+// With a native dockerd (Linux) there is nothing to launch, so it only
+// reports that the daemon is down.
 func EnsureDockerRunning() error {
 	if CheckDockerAvailable() {
 		return nil
 	}
+	if platform.NativeDockerd() {
+		return fmt.Errorf("dockerd is not responding; start it with: sudo systemctl start docker")
+	}
 	if !IsDockerDesktopInstalled() {
 		return fmt.Errorf("Docker Desktop not installed")
 	}
@@ -402,107 +467,21 @@ func EnsureDockerRunning() error {
 	return nil
 }
 
-// StreamContainerLogs returns a channel for streaming container logs
-func (dsm *DockerServiceManager) StreamContainerLogs(containerName string, follow bool) (chan string, error) {
-	outputChan := make(chan string, 100)
-	
-	args := []string{"compose", "-f", dsm.composeFile, "logs"}
-	if follow {
-		args = append(args, "-f")
-	}
-	args = append(args, containerName)
-	
-	dockerPath := findDockerBinary()
-	cmd := exec.Command(dockerPath, args...)
-	cmd.Dir = filepath.Dir(dsm.composeFile)
-	
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		close(outputChan)
-		return outputChan, err
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		close(outputChan)
-		return outputChan, err
-	}
-	
-	if err := cmd.Start(); err != nil {
-		close(outputChan)
-		return outputChan, err
-	}
-	
-	go func() {
-		defer close(outputChan)
-		
-		// Read stdout
-		go func() {
-			scanner := bufio.NewScanner(stdout)
-			for scanner.Scan() {
-				outputChan <- scanner.Text()
-			}
-		}()
-		
-		// Read stderr
-		go func() {
-			scanner := bufio.NewScanner(stderr)
-			for scanner.Scan() {
-				outputChan <- scanner.Text()
-			}
-		}()
-		
-		cmd.Wait()
-	}()
-	
-	return outputChan, nil
-}
-
-// GetServiceHealth returns detailed health status for a service
-func (dsm *DockerServiceManager) GetServiceHealth(serviceName string) (status string, health string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	
-	dockerPath := findDockerBinary()
-	
-	// Get container status
-	cmd := exec.CommandContext(ctx, dockerPath, "compose", "-f", dsm.composeFile, "ps", serviceName, "--format", "{{.Status}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return "stopped", "unhealthy", err
-	}
-	
-	statusStr := strings.TrimSpace(string(output))
-	if !strings.Contains(statusStr, "Up") {
-		return "stopped", "unhealthy", nil
-	}
-	
-	// Check if healthcheck is available
-	cmd = exec.CommandContext(ctx, dockerPath, "inspect", "--format", "{{.State.Health.Status}}", serviceName)
-	healthOutput, err := cmd.Output()
-	if err != nil {
-		return "running", "unknown", nil
-	}
-	
-	healthStr := strings.TrimSpace(string(healthOutput))
-	if healthStr == "" || healthStr == "<no value>" {
-		return "running", "unknown", nil
-	}
-	
-	return "running", healthStr, nil
-}
-
-// GetAllHealthStatus returns health status for all services
-func (dsm *DockerServiceManager) GetAllHealthStatus() map[string]map[string]string {
+// Health returns the status and health of every container in use.
+func (dsm *DockerServiceManager) Health(ctx context.Context) map[string]map[string]string {
 	result := make(map[string]map[string]string)
-	
-	services := []string{"apache", "mysql", "phpmyadmin"}
-	for _, svc := range services {
-		status, health, _ := dsm.GetServiceHealth(svc)
+	for _, svc := range dsm.composeServices() {
+		status, health := "stopped", "unhealthy"
+		if running, h, err := dsm.containerState(ctx, svc); err == nil && running {
+			status, health = "running", h
+			if health == "" {
+				health = "unknown"
+			}
+		}
 		result[svc] = map[string]string{
 			"status": status,
 			"health": health,
 		}
 	}
-	
 	return result
 }
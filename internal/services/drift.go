@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go-local-server/internal/docker"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/registry"
+	"go-local-server/pkg/compose"
+)
+
+// ComposeDrift lists the services whose running containers no longer match
+// the generated docker-compose.yml: their definition in the file changed,
+// they were disabled in the settings, or were enabled but never created.
+// Each container is compared with its own service's hash. An empty
+// result means the stack is current or not running at all; `docker compose up
+// -d --remove-orphans` brings it back in line.
+func (dsm *DockerServiceManager) ComposeDrift(ctx context.Context) ([]string, error) {
+	want, err := compose.FileHashes(dsm.composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	type state struct {
+		exists, running bool
+		hash            string
+	}
+	// Check every PHP version, database engine and registry service, so
+	// containers no longer in use are reported too.
+	services := append([]string{"apache", "mysql", "phpmyadmin"}, registry.IDs()...)
+	for _, e := range projects.Engines {
+		if e != projects.EngineMySQL {
+			services = append(services, e)
+		}
+	}
+	for _, v := range projects.PHPVersions {
+		services = append(services, projects.PHPService(v))
+	}
+	states := make(map[string]state, len(services))
+	anyRunning := false
+	for _, svc := range services {
+		exists, running, hash, err := dsm.containerHash(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		states[svc] = state{exists, running, hash}
+		anyRunning = anyRunning || running
+	}
+	if !anyRunning {
+		return nil, nil
+	}
+
+	var drifted []string
+	for _, svc := range services {
+		st := states[svc]
+		switch {
+		case st.running && st.hash != want[svc]:
+			drifted = append(drifted, svc)
+		case !st.exists && dsm.serviceExpected(svc):
+			drifted = append(drifted, svc)
+		}
+	}
+	return drifted, nil
+}
+
+// serviceExpected reports whether the generated compose file defines svc.
+func (dsm *DockerServiceManager) serviceExpected(svc string) bool {
+	switch svc {
+	case "apache", "mysql":
+		return true
+	case "phpmyadmin":
+		return dsm.Config.ServiceEnabled(svc)
+	}
+	if _, ok := registry.Lookup(svc); ok {
+		return dsm.Config.ServiceEnabled(svc)
+	}
+	for _, name := range append(dsm.phpServices(), dsm.databaseServices()...) {
+		if svc == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containerHash returns the state of a service's container and the value of
+// its compose.HashLabel.
+func (dsm *DockerServiceManager) containerHash(ctx context.Context, service string) (exists, running bool, hash string, err error) {
+	name := ContainerName(service)
+
+	if dsm.engine != nil {
+		ct, err := dsm.engine.ContainerInspect(ctx, name)
+		if errors.Is(err, docker.ErrNotFound) {
+			return false, false, "", nil
+		}
+		if err == nil {
+			return true, ct.State.Running, ct.Config.Labels[compose.HashLabel], nil
+		}
+	}
+
+	format := fmt.Sprintf(`{{.State.Running}} {{index .Config.Labels %q}}`, compose.HashLabel)
+	out, err := exec.CommandContext(ctx, FindDockerBinary(), "inspect", "--format", format, name).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// docker inspect exits 1 for a missing container
+			return false, false, "", nil
+		}
+		return false, false, "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return false, false, "", nil
+	}
+	if len(fields) > 1 && fields[1] != "<no" {
+		hash = fields[1]
+	}
+	return true, fields[0] == "true", hash, nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-local-server/internal/docker"
+	"go-local-server/internal/projects"
+	"go-local-server/pkg/compose"
+)
+
+// containerPrefix matches container_name in docker-compose.yml.
+const containerPrefix = "golocal-"
+
+// composeServices are the compose services whose containers the app tracks:
+// the fixed ones plus a PHP-FPM service per PHP version, a service per
+// extra database engine in use and the enabled registry services.
+func (dsm *DockerServiceManager) composeServices() []string {
+	services := append([]string{"apache"}, dsm.phpServices()...)
+	services = append(services, "mysql")
+	services = append(services, dsm.databaseServices()...)
+	services = append(services, "phpmyadmin")
+	return append(services, dsm.optionalServices()...)
+}
+
+// phpServices are the PHP-FPM compose services active projects need.
+func (dsm *DockerServiceManager) phpServices() []string {
+	list, _ := projects.NewManager(dsm.Config).List()
+	var services []string
+	for _, v := range projects.ActivePHPVersions(list) {
+		services = append(services, projects.PHPService(v))
+	}
+	return services
+}
+
+// ContainerName returns the container name of a compose service.
+func ContainerName(service string) string {
+	return containerPrefix + service
+}
+
+// containerState reports whether a service's container is running and its
+// healthcheck status ("" without a healthcheck). It asks the Engine API and
+// falls back to `docker compose ps` when the socket is unavailable.
+func (dsm *DockerServiceManager) containerState(ctx context.Context, service string) (running bool, health string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if dsm.engine != nil {
+		ct, err := dsm.engine.ContainerInspect(ctx, ContainerName(service))
+		if errors.Is(err, docker.ErrNotFound) {
+			return false, "", nil
+		}
+		if err == nil {
+			return ct.State.Running, ct.State.HealthStatus(), nil
+		}
+	}
+
+	output, err := dsm.dockerCompose(ctx, "ps", service, "--format", "{{.Status}}").Output()
+	if err != nil {
+		return false, "", err
+	}
+	status := strings.TrimSpace(string(output))
+	return strings.HasPrefix(status, "Up"), "", nil
+}
+
+// Exec runs a command inside a service's container, like
+// `docker compose exec -T`. For PHP that is the container of the lowest PHP
+// version in use; name a version's service (php83) to pick another. stdin
+// may be nil.
+func (dsm *DockerServiceManager) Exec(ctx context.Context, id ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	names, err := dsm.startable(id)
+	if err != nil {
+		return err
+	}
+	service := names[0]
+
+	if dsm.engine != nil {
+		err := dsm.engine.Exec(ctx, ContainerName(service), docker.ExecOptions{
+			Cmd:    cmd,
+			Stdin:  stdin,
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+		// The CLI only takes over when the exec never started: after that,
+		// running the command again would replay a partly read stdin.
+		var exitErr *docker.ExitError
+		if err == nil || errors.As(err, &exitErr) || errors.Is(err, docker.ErrNotFound) || errors.Is(err, docker.ErrExecStarted) || ctx.Err() != nil {
+			return err
+		}
+		fmt.Printf("docker engine exec failed, using CLI: %v\n", err)
+	}
+
+	c := dsm.dockerCompose(ctx, append([]string{"exec", "-T", service}, cmd...)...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	return c.Run()
+}
+
+// execOutput is Exec with combined output, for short commands.
+func (dsm *DockerServiceManager) execOutput(ctx context.Context, id ServiceID, cmd ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	err := dsm.Exec(ctx, id, cmd, nil, &out, &out)
+	return out.Bytes(), err
+}
+
+// Logs streams the logs of a service's containers. A single container is
+// read through the Engine API when possible; several (PHP) are interleaved
+// by `docker compose logs`.
+func (dsm *DockerServiceManager) Logs(ctx context.Context, id ServiceID, follow bool) (<-chan string, error) {
+	names, err := dsm.startable(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dsm.engine != nil && len(names) == 1 {
+		rc, err := dsm.engine.ContainerLogs(ctx, ContainerName(names[0]), docker.LogsOptions{Follow: follow})
+		if err == nil {
+			return streamLines(ctx, rc), nil
+		}
+	}
+
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "-f")
+	}
+	cmd := dsm.dockerCompose(ctx, append(args, names...)...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+	}()
+	return streamLines(ctx, pr), nil
+}
+
+// streamLines sends r line by line until it ends or ctx is cancelled, then
+// closes r and the channel.
+func streamLines(ctx context.Context, r io.ReadCloser) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		defer r.Close()
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+// WatchStatus calls onChange whenever one of the managed containers starts,
+// stops, dies or changes health, as reported by the Docker event stream.
+// Bursts of events (a stop emits kill, die and stop) are coalesced into one
+// call. It blocks until ctx is cancelled (returning nil) or the stream
+// fails; without an engine socket it returns docker.ErrNoSocket at once.
+func (dsm *DockerServiceManager) WatchStatus(ctx context.Context, onChange func()) error {
+	if dsm.engine == nil {
+		return docker.ErrNoSocket
+	}
+
+	// Every container of the generated stack carries the config-hash label,
+	// including PHP-FPM containers created after the subscription.
+	events, errs := dsm.engine.Events(ctx, map[string][]string{
+		"type":  {"container"},
+		"label": {compose.HashLabel},
+	})
+
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for ev := range events {
+		if !statusAction(ev.Action) {
+			continue
+		}
+		if timer == nil {
+			timer = time.AfterFunc(250*time.Millisecond, onChange)
+		} else {
+			timer.Reset(250 * time.Millisecond)
+		}
+	}
+
+	if err := <-errs; err != nil {
+		return fmt.Errorf("docker events: %w", err)
+	}
+	return nil
+}
+
+func statusAction(action string) bool {
+	switch action {
+	case "create", "start", "restart", "stop", "die", "kill", "pause", "unpause", "destroy", "oom":
+		return true
+	}
+	return strings.HasPrefix(action, "health_status")
+}
@@ -0,0 +1,107 @@
+//go:build linux || darwin
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/docker"
+	"go-local-server/internal/platform"
+)
+
+// newDockerTest returns a Docker manager talking to handler on a Unix
+// socket. A docker CLI standing in for the real one prints "cli:" and its
+// arguments, and reads its stdin.
+func newDockerTest(t *testing.T, handler http.Handler) *DockerServiceManager {
+	t.Helper()
+	for _, loc := range platform.DockerBinaryCandidates() {
+		if _, err := os.Stat(loc); err == nil {
+			t.Skipf("a real docker CLI is installed at %s", loc)
+		}
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cli := filepath.Join(home, "bin", "docker")
+	if err := os.MkdirAll(filepath.Dir(cli), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cli, []byte("#!/bin/sh\necho \"cli: $*\"\ncat >/dev/null\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	engine, err := docker.NewClientWithHost("unix://" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &DockerServiceManager{
+		Config:      config.DefaultConfig(),
+		services:    make(map[ServiceID]*Service),
+		engine:      engine,
+		composeFile: filepath.Join(home, "docker-compose.yml"),
+	}
+}
+
+func TestExecFallsBackOnlyBeforeStart(t *testing.T) {
+	dsm := newDockerTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/golocal-apache/exec"):
+			// The exec cannot be created: nothing has moved yet.
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"message":"engine hiccup"}`)
+		case strings.HasSuffix(r.URL.Path, "/exec"):
+			_, _ = io.WriteString(w, `{"Id":"e1"}`)
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			_, _ = io.Copy(io.Discard, r.Body)
+			conn, brw, _ := w.(http.Hijacker).Hijack()
+			defer conn.Close()
+			_, _ = brw.WriteString("HTTP/1.1 101 UPGRADED\r\n\r\n")
+			_ = brw.Flush()
+			// Read some of stdin, write some output, then break off.
+			_, _ = io.ReadFull(conn, make([]byte, 4))
+			hdr := make([]byte, 8)
+			hdr[0] = 1
+			binary.BigEndian.PutUint32(hdr[4:], 100)
+			_, _ = conn.Write(append(hdr, "-- dump part"...))
+		default:
+			_, _ = io.WriteString(w, `{"Running":false}`)
+		}
+	}))
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	err := dsm.Exec(ctx, Apache, []string{"apachectl", "-t"}, nil, &out, io.Discard)
+	if err != nil || !strings.HasPrefix(out.String(), "cli: ") {
+		t.Errorf("create failed: err = %v, output %q; want the CLI to take over", err, out.String())
+	}
+
+	out.Reset()
+	err = dsm.Exec(ctx, MySQL, []string{"mysql", "app"}, strings.NewReader("INSERT INTO t VALUES (1);"), &out, io.Discard)
+	if !errors.Is(err, docker.ErrExecStarted) {
+		t.Errorf("stream broke: err = %v, want docker.ErrExecStarted", err)
+	}
+	if strings.Contains(out.String(), "cli:") {
+		t.Errorf("the command ran again through the CLI: %q", out.String())
+	}
+}
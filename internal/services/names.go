@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-local-server/internal/registry"
+)
+
+// ErrUnknownService is returned for names that do not map to a service.
+var ErrUnknownService = errors.New("unknown service")
+
+// ParseServiceID maps a user-facing name to a service: apache, php, mysql,
+// or a registry service by ID or title.
+func ParseServiceID(name string) (ServiceID, error) {
+	for _, id := range CoreServices {
+		if strings.EqualFold(name, string(id)) {
+			return id, nil
+		}
+	}
+	if d, ok := registry.Lookup(name); ok {
+		return ServiceID(d.ID), nil
+	}
+	return "", fmt.Errorf("%w %q (want apache, php, mysql or one of %s)", ErrUnknownService, name, strings.Join(registry.IDs(), ", "))
+}
+
+// ServiceName is the display name of a service.
+func ServiceName(id ServiceID) string {
+	switch id {
+	case Apache:
+		return "Apache"
+	case PHP:
+		return "PHP-FPM"
+	case MySQL:
+		return "MySQL"
+	}
+	if d, ok := registry.Lookup(string(id)); ok {
+		return d.Title
+	}
+	return string(id)
+}
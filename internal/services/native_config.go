@@ -0,0 +1,434 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"go-local-server/internal/certs"
+	"go-local-server/internal/config"
+	"go-local-server/internal/livereload"
+	"go-local-server/internal/projects"
+	"go-local-server/pkg/apache"
+)
+
+// nativePHPAddr is where the native PHP-FPM pool listens. It is off the
+// usual 9000 so a PHP-FPM started by Homebrew does not get in the way.
+const nativePHPAddr = "127.0.0.1:9074"
+
+// NativeDir holds the configs the native backend generates, with pid files
+// and the MySQL socket under run/.
+func NativeDir() string {
+	return filepath.Join(config.ConfigDir, "native")
+}
+
+func nativeRunDir() string {
+	return filepath.Join(NativeDir(), "run")
+}
+
+// nativeMySQLDataDir is the native MySQL's data directory, created on its
+// first start.
+func nativeMySQLDataDir() string {
+	return filepath.Join(config.DataDir, "native", "mysql")
+}
+
+func nativeMySQLSocket() string {
+	return filepath.Join(nativeRunDir(), "mysqld.sock")
+}
+
+// nativeSite is one project as the native web server serves it.
+type nativeSite struct {
+	ID       string
+	Domain   string
+	Root     string
+	CertFile string // empty = HTTP only
+	KeyFile  string
+
+	LiveReloadScript string
+}
+
+// nativeModule is an Apache module loaded unless it is built in.
+type nativeModule struct {
+	Name string // e.g. rewrite_module
+	Path string
+}
+
+type nativeConfig struct {
+	Dir       string
+	Run       string
+	LogDir    string
+	HTTPPort  int
+	HTTPSPort int
+	PHPAddr   string
+	HTTPS     bool // some site has a certificate
+	Sites     []nativeSite
+
+	// Apache
+	ServerRoot string
+	MPM        []nativeModule // the first one found is loaded
+	Modules    []nativeModule
+
+	// MySQL
+	DataDir   string
+	Socket    string
+	MySQLPort int
+}
+
+func (m *NativeServiceManager) templateData() nativeConfig {
+	return nativeConfig{
+		Dir:       NativeDir(),
+		Run:       nativeRunDir(),
+		LogDir:    config.LogDir,
+		HTTPPort:  m.Config.HTTPPort,
+		HTTPSPort: m.Config.HTTPSPort,
+		PHPAddr:   nativePHPAddr,
+		DataDir:   nativeMySQLDataDir(),
+		Socket:    nativeMySQLSocket(),
+		MySQLPort: m.Config.MySQLPort,
+	}
+}
+
+// sites lists the projects to serve, issuing their certificates. Every site
+// goes to the one PHP-FPM pool, whatever PHP version it asks for.
+func (m *NativeServiceManager) sites() ([]nativeSite, error) {
+	list, err := projects.NewManager(m.Config).List()
+	if err != nil {
+		return nil, err
+	}
+	ca, caErr := certs.Default()
+
+	var sites []nativeSite
+	for _, p := range list {
+		site := nativeSite{ID: p.ID, Domain: p.Domain, Root: apache.DocumentRoot(p)}
+		if p.LiveReload {
+			site.LiveReloadScript = livereload.ScriptURL(livereload.DefaultPort, p.ID)
+		}
+		if caErr != nil {
+			fmt.Printf("HTTPS disabled for %s: %v\n", p.Domain, caErr)
+		} else if err := ca.Issue(p.ID, p.Domain); err != nil {
+			fmt.Printf("HTTPS disabled for %s: %v\n", p.Domain, err)
+		} else {
+			certFile, keyFile := certs.LeafFiles(p.ID)
+			site.CertFile = filepath.Join(config.CertsDir, certFile)
+			site.KeyFile = filepath.Join(config.CertsDir, keyFile)
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// writeWebConfig generates the nginx or Apache config serving every project
+// and returns its path. bin is the web server binary; Apache's modules are
+// looked up next to it.
+func (m *NativeServiceManager) writeWebConfig(bin string) (string, error) {
+	data := m.templateData()
+	sites, err := m.sites()
+	if err != nil {
+		return "", err
+	}
+	data.Sites = sites
+	for _, s := range sites {
+		if s.CertFile != "" {
+			data.HTTPS = true
+		}
+	}
+
+	if m.Config.Nginx() {
+		if err := writeTemplate(filepath.Join(data.Dir, "fastcgi_params"), fastcgiParams, nil); err != nil {
+			return "", err
+		}
+		conf := filepath.Join(data.Dir, "nginx.conf")
+		return conf, writeTemplate(conf, nginxTemplate, data)
+	}
+
+	data.ServerRoot, data.MPM, data.Modules = apacheModules(bin)
+	conf := filepath.Join(data.Dir, "httpd.conf")
+	return conf, writeTemplate(conf, httpdTemplate, data)
+}
+
+func (m *NativeServiceManager) writePHPConfig() (string, error) {
+	conf := filepath.Join(NativeDir(), "php-fpm.conf")
+	return conf, writeTemplate(conf, phpFPMTemplate, m.templateData())
+}
+
+func (m *NativeServiceManager) writeMySQLConfig() (string, error) {
+	conf := filepath.Join(NativeDir(), "my.cnf")
+	return conf, writeTemplate(conf, myCnfTemplate, m.templateData())
+}
+
+// writeTemplate renders text into path, creating the run directory the
+// configs point to along the way.
+func writeTemplate(path, text string, data any) error {
+	tmpl, err := template.New(filepath.Base(path)).Parse(text)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(nativeRunDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// apacheModules finds the server root and module directory of an httpd
+// binary: Homebrew, macOS, Debian and Fedora lay them out differently.
+// Modules that are not there are left out, as they may be built in.
+func apacheModules(bin string) (serverRoot string, mpm, modules []nativeModule) {
+	if resolved, err := filepath.EvalSymlinks(bin); err == nil {
+		bin = resolved
+	}
+	serverRoot = filepath.Dir(filepath.Dir(bin))
+
+	var dir string
+	for _, d := range []string{"lib/httpd/modules", "modules", "libexec/apache2", "lib/apache2/modules", "lib64/httpd/modules"} {
+		if st, err := os.Stat(filepath.Join(serverRoot, d)); err == nil && st.IsDir() {
+			dir = filepath.Join(serverRoot, d)
+			break
+		}
+	}
+	if dir == "" {
+		return serverRoot, nil, nil
+	}
+
+	find := func(names ...string) []nativeModule {
+		var found []nativeModule
+		for _, name := range names {
+			path := filepath.Join(dir, "mod_"+name+".so")
+			if _, err := os.Stat(path); err == nil {
+				found = append(found, nativeModule{Name: name + "_module", Path: path})
+			}
+		}
+		return found
+	}
+	mpm = find("mpm_event", "mpm_prefork", "mpm_worker")
+	if len(mpm) > 1 {
+		mpm = mpm[:1]
+	}
+	modules = find("unixd", "authz_core", "authz_host", "dir", "mime", "log_config",
+		"autoindex", "alias", "env", "setenvif", "headers", "rewrite", "filter", "substitute",
+		"proxy", "proxy_fcgi", "socache_shmcb", "ssl")
+	return serverRoot, mpm, modules
+}
+
+const nginxTemplate = `# Generated by GoLocalServer; changes are overwritten.
+daemon off;
+worker_processes auto;
+pid "{{.Run}}/nginx.pid";
+error_log stderr;
+
+events {
+    worker_connections 1024;
+}
+
+http {
+    types {
+        text/html html htm;
+        text/css css;
+        text/plain txt;
+        application/javascript js mjs;
+        application/json json map;
+        application/xml xml;
+        application/pdf pdf;
+        application/wasm wasm;
+        image/png png;
+        image/jpeg jpg jpeg;
+        image/gif gif;
+        image/webp webp;
+        image/svg+xml svg;
+        image/x-icon ico;
+        font/woff woff;
+        font/woff2 woff2;
+    }
+    default_type application/octet-stream;
+
+    access_log "{{.LogDir}}/nginx-access.log";
+    sendfile on;
+    client_max_body_size 256m;
+
+    client_body_temp_path "{{.Run}}/client_body";
+    fastcgi_temp_path "{{.Run}}/fastcgi";
+    proxy_temp_path "{{.Run}}/proxy";
+    uwsgi_temp_path "{{.Run}}/uwsgi";
+    scgi_temp_path "{{.Run}}/scgi";
+{{range .Sites}}
+    server {
+        listen {{$.HTTPPort}};
+{{- if .CertFile}}
+        listen {{$.HTTPSPort}} ssl;
+        ssl_certificate "{{.CertFile}}";
+        ssl_certificate_key "{{.KeyFile}}";
+{{- end}}
+        server_name {{.Domain}} *.{{.Domain}};
+        root "{{.Root}}";
+        index index.php index.html;
+
+        location / {
+            try_files $uri $uri/ /index.php?$query_string;
+        }
+
+        location ~ \.php$ {
+            try_files $uri =404;
+            fastcgi_pass {{$.PHPAddr}};
+            fastcgi_index index.php;
+            include "{{$.Dir}}/fastcgi_params";
+        }
+{{- if .LiveReloadScript}}
+
+        # Live reload: the client is added to HTML responses on the way out.
+        sub_filter '</body>' '<script src="{{.LiveReloadScript}}" async></script></body>';
+        sub_filter_once on;
+{{- end}}
+    }
+{{end -}}
+}
+`
+
+const fastcgiParams = `fastcgi_param SCRIPT_FILENAME $document_root$fastcgi_script_name;
+fastcgi_param QUERY_STRING $query_string;
+fastcgi_param REQUEST_METHOD $request_method;
+fastcgi_param CONTENT_TYPE $content_type;
+fastcgi_param CONTENT_LENGTH $content_length;
+fastcgi_param SCRIPT_NAME $fastcgi_script_name;
+fastcgi_param REQUEST_URI $request_uri;
+fastcgi_param DOCUMENT_URI $document_uri;
+fastcgi_param DOCUMENT_ROOT $document_root;
+fastcgi_param SERVER_PROTOCOL $server_protocol;
+fastcgi_param REQUEST_SCHEME $scheme;
+fastcgi_param HTTPS $https if_not_empty;
+fastcgi_param GATEWAY_INTERFACE CGI/1.1;
+fastcgi_param SERVER_SOFTWARE nginx/$nginx_version;
+fastcgi_param REMOTE_ADDR $remote_addr;
+fastcgi_param REMOTE_PORT $remote_port;
+fastcgi_param SERVER_ADDR $server_addr;
+fastcgi_param SERVER_PORT $server_port;
+fastcgi_param SERVER_NAME $server_name;
+fastcgi_param REDIRECT_STATUS 200;
+`
+
+const httpdTemplate = `# Generated by GoLocalServer; changes are overwritten.
+ServerRoot "{{.ServerRoot}}"
+{{- range .MPM}}
+<IfModule !mpm_event_module>
+<IfModule !mpm_prefork_module>
+<IfModule !mpm_worker_module>
+    LoadModule {{.Name}} "{{.Path}}"
+</IfModule>
+</IfModule>
+</IfModule>
+{{- end}}
+{{- range .Modules}}
+<IfModule !{{.Name}}>
+    LoadModule {{.Name}} "{{.Path}}"
+</IfModule>
+{{- end}}
+
+ServerName localhost
+Listen {{.HTTPPort}}
+PidFile "{{.Run}}/httpd.pid"
+DefaultRuntimeDir "{{.Run}}"
+ErrorLog "/dev/stderr"
+LogFormat "%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-Agent}i\"" combined
+CustomLog "{{.LogDir}}/apache-access.log" combined
+
+TypesConfig /dev/null
+AddType text/html .html .htm
+AddType text/css .css
+AddType text/plain .txt
+AddType application/javascript .js .mjs
+AddType application/json .json .map
+AddType application/xml .xml
+AddType application/pdf .pdf
+AddType application/wasm .wasm
+AddType image/png .png
+AddType image/jpeg .jpg .jpeg
+AddType image/gif .gif
+AddType image/webp .webp
+AddType image/svg+xml .svg
+AddType image/x-icon .ico
+AddType font/woff .woff
+AddType font/woff2 .woff2
+
+DirectoryIndex index.php index.html
+
+<Directory />
+    AllowOverride None
+    Require all denied
+</Directory>
+{{- if .HTTPS}}
+
+<IfModule ssl_module>
+    Listen {{.HTTPSPort}}
+</IfModule>
+{{- end}}
+{{range .Sites}}
+<VirtualHost *:{{$.HTTPPort}}>
+{{- template "site" .}}
+</VirtualHost>
+{{- if .CertFile}}
+
+<IfModule ssl_module>
+<VirtualHost *:{{$.HTTPSPort}}>
+{{- template "site" .}}
+
+    SSLEngine on
+    SSLCertificateFile "{{.CertFile}}"
+    SSLCertificateKeyFile "{{.KeyFile}}"
+</VirtualHost>
+</IfModule>
+{{- end}}
+{{end -}}
+{{define "site"}}
+    ServerName {{.Domain}}
+    ServerAlias *.{{.Domain}}
+    DocumentRoot "{{.Root}}"
+
+    <Directory "{{.Root}}">
+        Options Indexes FollowSymLinks
+        AllowOverride All
+        Require all granted
+    </Directory>
+
+    <FilesMatch "\.php$">
+        SetHandler "proxy:fcgi://` + nativePHPAddr + `"
+    </FilesMatch>
+{{- if .LiveReloadScript}}
+
+    <IfModule mod_substitute.c>
+        AddOutputFilterByType SUBSTITUTE text/html
+        Substitute 's|</body>|<script src="{{.LiveReloadScript}}" async></script></body>|ni'
+    </IfModule>
+{{- end}}
+{{- end}}
+`
+
+const phpFPMTemplate = `; Generated by GoLocalServer; changes are overwritten.
+[global]
+pid = "{{.Run}}/php-fpm.pid"
+error_log = /dev/stderr
+daemonize = no
+
+[www]
+listen = {{.PHPAddr}}
+pm = dynamic
+pm.max_children = 10
+pm.start_servers = 2
+pm.min_spare_servers = 1
+pm.max_spare_servers = 3
+catch_workers_output = yes
+clear_env = no
+`
+
+const myCnfTemplate = `# Generated by GoLocalServer; changes are overwritten.
+[mysqld]
+datadir = "{{.DataDir}}"
+socket = "{{.Socket}}"
+pid-file = "{{.Run}}/mysqld.pid"
+port = {{.MySQLPort}}
+bind-address = 127.0.0.1
+loose-mysqlx = OFF
+`
@@ -0,0 +1,855 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/events"
+	"go-local-server/internal/livereload"
+	"go-local-server/internal/platform"
+	"go-local-server/internal/projects"
+)
+
+const (
+	nativeStartTimeout      = 15 * time.Second
+	nativeMySQLStartTimeout = 2 * time.Minute // the first start initializes the data directory
+	nativeStopTimeout       = 30 * time.Second
+
+	// A service that crashes more than maxCrashes times within crashWindow
+	// is not restarted again.
+	maxCrashes  = 5
+	crashWindow = 5 * time.Minute
+)
+
+// NativeServiceManager runs the stack without Docker: Apache or nginx,
+// PHP-FPM and MySQL are child processes of the app, with configs generated
+// under NativeDir. Their output is appended to Service.LogFile, and a
+// service that crashes is restarted with a growing delay.
+//
+// The servers write pid files, so a golocalctl run sees and stops what the
+// app started; only the process that started a service restarts it, and
+// it learns of an exit from the child itself rather than the pid file.
+type NativeServiceManager struct {
+	Config *config.AppConfig
+	// Events, if set, receives the status changes of the services and
+	// VhostReloaded after every Reload.
+	Events *events.Bus
+
+	mu       sync.Mutex // guards everything below
+	services map[ServiceID]*Service
+	procs    map[ServiceID]*process
+	wanted   map[ServiceID]bool // started and not stopped since
+	crashes  map[ServiceID][]time.Time
+	watchers []func()
+}
+
+// process is a service started by this manager.
+type process struct {
+	cmd      *exec.Cmd
+	done     chan struct{} // closed once the process has exited
+	ready    bool          // it came up; an exit from now on is a crash
+	stopping bool          // Stop asked it to exit
+}
+
+func NewNativeServiceManager(cfg *config.AppConfig) *NativeServiceManager {
+	m := &NativeServiceManager{
+		Config:   cfg,
+		services: make(map[ServiceID]*Service),
+		procs:    make(map[ServiceID]*process),
+		wanted:   make(map[ServiceID]bool),
+		crashes:  make(map[ServiceID][]time.Time),
+	}
+	logs := map[ServiceID]string{Apache: "apache.log", PHP: "php-fpm.log", MySQL: "mysql.log"}
+	name := ServiceName
+	if cfg.Nginx() {
+		logs[Apache] = "nginx.log"
+		name = func(id ServiceID) string {
+			if id == Apache {
+				return "Nginx"
+			}
+			return ServiceName(id)
+		}
+	}
+	for _, id := range CoreServices {
+		m.services[id] = &Service{
+			ID:      id,
+			Name:    name(id),
+			Status:  StatusStopped,
+			LogFile: filepath.Join(config.LogDir, logs[id]),
+		}
+	}
+	return m
+}
+
+// service returns a copy of a core service; the registry services and the
+// other database engines need Docker.
+func (m *NativeServiceManager) service(id ServiceID) (Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	svc, ok := m.services[id]
+	if !ok {
+		if _, err := ParseServiceID(string(id)); err == nil {
+			return Service{}, fmt.Errorf("%s needs the Docker backend", ServiceName(id))
+		}
+		return Service{}, fmt.Errorf("%w %q", ErrUnknownService, id)
+	}
+	return *svc, nil
+}
+
+// binary returns a service's executable: the configured path, else the
+// command of the same name on $PATH.
+func (m *NativeServiceManager) binary(id ServiceID) (string, error) {
+	var path string
+	var names []string
+	switch id {
+	case Apache:
+		path, names = m.Config.ApachePath, []string{"httpd", "apache2"}
+		if m.Config.Nginx() {
+			path, names = m.Config.NginxPath, []string{"nginx"}
+		}
+	case PHP:
+		path, names = m.Config.PHPPath, []string{"php-fpm"}
+	case MySQL:
+		path, names = m.Config.MySQLPath, []string{"mysqld"}
+	}
+	if st, err := os.Stat(path); err == nil && !st.IsDir() {
+		return path, nil
+	}
+	if path != "" {
+		names = append([]string{filepath.Base(path)}, names...)
+	}
+	for _, name := range names {
+		if found, err := exec.LookPath(name); err == nil {
+			return found, nil
+		}
+	}
+	svc, _ := m.service(id)
+	return "", fmt.Errorf("%s not found at %q; install it or set its path in Settings", svc.Name, path)
+}
+
+// command writes a service's config and returns the command that runs it in
+// the foreground. MySQL's data directory is initialized first if needed,
+// logging to log.
+func (m *NativeServiceManager) command(ctx context.Context, id ServiceID, log io.Writer) (*exec.Cmd, error) {
+	bin, err := m.binary(id)
+	if err != nil {
+		return nil, err
+	}
+	root := os.Geteuid() == 0
+
+	var args []string
+	switch id {
+	case Apache:
+		conf, err := m.writeWebConfig(bin)
+		if err != nil {
+			return nil, err
+		}
+		args = []string{"-f", conf, "-DFOREGROUND"}
+		if m.Config.Nginx() {
+			args = []string{"-p", NativeDir(), "-c", conf}
+		}
+	case PHP:
+		conf, err := m.writePHPConfig()
+		if err != nil {
+			return nil, err
+		}
+		args = []string{"-F", "-y", conf}
+		if root {
+			args = append(args, "-R")
+		}
+	case MySQL:
+		conf, err := m.writeMySQLConfig()
+		if err != nil {
+			return nil, err
+		}
+		args = []string{"--defaults-file=" + conf}
+		if root {
+			args = append(args, "--user=root")
+		}
+		if err := m.initMySQL(ctx, bin, args, log); err != nil {
+			return nil, err
+		}
+	}
+	// Not tied to ctx: the process outlives the call that starts it.
+	return exec.Command(bin, args...), nil
+}
+
+// rootPasswordPending marks a data directory whose root user has no
+// password yet.
+func rootPasswordPending() string {
+	return filepath.Join(NativeDir(), "mysql-root-password-pending")
+}
+
+// initMySQL creates the MySQL data directory on first start. root starts
+// without a password; launch sets MySQLRootPassword once the server is up.
+func (m *NativeServiceManager) initMySQL(ctx context.Context, bin string, args []string, log io.Writer) error {
+	dataDir := nativeMySQLDataDir()
+	if _, err := os.Stat(dataDir); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dataDir), 0755); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nativeMySQLStartTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, append(args, "--initialize-insecure")...)
+	cmd.Stdout, cmd.Stderr = log, log
+	if err := cmd.Run(); err != nil {
+		_ = os.RemoveAll(dataDir)
+		return fmt.Errorf("failed to initialize the MySQL data directory: %w", err)
+	}
+	return os.WriteFile(rootPasswordPending(), nil, 0644)
+}
+
+// setRootPassword gives root MySQLRootPassword after the first start.
+func (m *NativeServiceManager) setRootPassword(ctx context.Context) error {
+	if _, err := os.Stat(rootPasswordPending()); err != nil {
+		return nil
+	}
+	var stderr strings.Builder
+	sql := "ALTER USER 'root'@'localhost' IDENTIFIED BY " + mysqlString(m.Config.MySQLRootPassword)
+	if err := m.Exec(ctx, MySQL, []string{"mysql", "-uroot", "--skip-password", "-e", sql}, nil, io.Discard, &stderr); err != nil {
+		return fmt.Errorf("failed to set the MySQL root password: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return os.Remove(rootPasswordPending())
+}
+
+// Start launches a service unless it is already running, here or in
+// another process, and waits until it accepts connections.
+func (m *NativeServiceManager) Start(ctx context.Context, id ServiceID) error {
+	if _, err := m.service(id); err != nil {
+		return err
+	}
+	m.want(id, true)
+	if status, _ := m.Status(ctx, id); status == StatusRunning {
+		return nil
+	}
+	return m.launch(ctx, id)
+}
+
+func (m *NativeServiceManager) launch(ctx context.Context, id ServiceID) error {
+	svc, err := m.service(id)
+	if err != nil {
+		return err
+	}
+	if listening(m.address(id)) {
+		return m.fail(id, fmt.Errorf("%s cannot start: something else is listening on %s", svc.Name, m.address(id)))
+	}
+	if id == Apache {
+		if err := m.checkPorts(svc.Name); err != nil {
+			return m.fail(id, err)
+		}
+	}
+	if err := os.MkdirAll(config.LogDir, 0755); err != nil {
+		return m.fail(id, err)
+	}
+	logf, err := os.OpenFile(svc.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return m.fail(id, err)
+	}
+
+	cmd, err := m.command(ctx, id, logf)
+	if err != nil {
+		logf.Close()
+		return m.fail(id, err)
+	}
+	fmt.Fprintf(logf, "== %s: %s\n", time.Now().Format(time.RFC3339), strings.Join(cmd.Args, " "))
+	cmd.Stdout, cmd.Stderr = logf, logf
+	if err := cmd.Start(); err != nil {
+		logf.Close()
+		return m.fail(id, fmt.Errorf("failed to start %s: %w", svc.Name, err))
+	}
+
+	proc := &process{cmd: cmd, done: make(chan struct{})}
+	m.mu.Lock()
+	m.procs[id] = proc
+	m.mu.Unlock()
+	go m.supervise(id, proc, logf)
+
+	if err := m.waitReady(ctx, id, proc); err != nil {
+		m.mu.Lock()
+		proc.stopping = true
+		m.mu.Unlock()
+		_ = terminate(context.Background(), cmd.Process.Pid, proc.exited)
+		return m.fail(id, err)
+	}
+	m.mu.Lock()
+	proc.ready = true
+	m.mu.Unlock()
+
+	if id == MySQL {
+		if err := m.setRootPassword(ctx); err != nil {
+			return m.fail(id, err)
+		}
+	}
+	m.setStatus(id, StatusRunning, cmd.Process.Pid)
+	return nil
+}
+
+// checkPorts fails early, and with a clear reason, when this user may not
+// bind the web server's ports: below 1024 that needs root on most systems,
+// and the server would otherwise just exit with a line in its log.
+func (m *NativeServiceManager) checkPorts(name string) error {
+	for _, port := range []int{m.Config.HTTPPort, m.Config.HTTPSPort} {
+		if port == 0 {
+			continue
+		}
+		l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err == nil {
+			l.Close()
+			continue
+		}
+		// Anything else, like the port being taken, the server reports.
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%s cannot listen on port %d without root: set the HTTP and HTTPS ports to 8080 and 8443 in Settings, or run golocal as root", name, port)
+		}
+	}
+	return nil
+}
+
+// waitReady waits until a service accepts connections on its address.
+func (m *NativeServiceManager) waitReady(ctx context.Context, id ServiceID, proc *process) error {
+	timeout := nativeStartTimeout
+	if id == MySQL {
+		timeout = nativeMySQLStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	svc, _ := m.service(id)
+	for !listening(m.address(id)) {
+		select {
+		case <-proc.done:
+			return fmt.Errorf("%s exited while starting%s", svc.Name, m.logTail(svc))
+		case <-ctx.Done():
+			return fmt.Errorf("%s is not listening on %s after %v%s", svc.Name, m.address(id), timeout, m.logTail(svc))
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// logTail returns the end of a service's log for error messages.
+func (m *NativeServiceManager) logTail(svc Service) string {
+	lines, err := livereload.TailFile(svc.LogFile, 5)
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	return ":\n" + strings.Join(lines, "\n")
+}
+
+// supervise waits for a process to exit. When a service that came up
+// exits other than cleanly, the crash is published and the service started
+// again.
+func (m *NativeServiceManager) supervise(id ServiceID, proc *process, logf *os.File) {
+	err := proc.cmd.Wait()
+	logf.Close()
+	crashed := !cleanExit(err)
+	close(proc.done)
+
+	m.mu.Lock()
+	if m.procs[id] == proc {
+		delete(m.procs, id)
+	}
+	ready, stopping, wanted := proc.ready, proc.stopping, m.wanted[id]
+	m.mu.Unlock()
+
+	if !ready {
+		// launch reports it.
+		return
+	}
+	if stopping || !crashed || !wanted {
+		m.setStatus(id, StatusStopped, 0)
+		return
+	}
+
+	svc, _ := m.service(id)
+	if err == nil {
+		err = errors.New("exit status 0")
+	}
+	delay, again := m.crashed(id)
+	if !again {
+		m.want(id, false)
+		m.fail(id, fmt.Errorf("%s crashed %d times within %v (%v); not restarting it", svc.Name, maxCrashes+1, crashWindow, err))
+		return
+	}
+	m.fail(id, fmt.Errorf("%s crashed (%v); restarting in %v", svc.Name, err, delay))
+	time.Sleep(delay)
+	if m.wants(id) {
+		_ = m.launch(context.Background(), id)
+	}
+}
+
+// cleanExit reports whether a process exited the way the servers do when
+// asked to stop: with status 0, or on SIGTERM or SIGINT. That is also how a
+// service the app started looks after golocalctl stops it.
+func cleanExit(err error) bool {
+	if err == nil {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	ws, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && (ws.Signal() == syscall.SIGTERM || ws.Signal() == syscall.SIGINT)
+}
+
+// crashed records a crash and returns how long to wait before restarting,
+// or false when the service crashes too often.
+func (m *NativeServiceManager) crashed(id ServiceID) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range m.crashes[id] {
+		if now.Sub(t) < crashWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	m.crashes[id] = recent
+	if len(recent) > maxCrashes {
+		delete(m.crashes, id)
+		return 0, false
+	}
+	return time.Second << (len(recent) - 1), true
+}
+
+func (m *NativeServiceManager) want(id ServiceID, wanted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wanted[id] = wanted
+}
+
+func (m *NativeServiceManager) wants(id ServiceID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.wanted[id]
+}
+
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops a service, whichever process started it.
+func (m *NativeServiceManager) Stop(ctx context.Context, id ServiceID) error {
+	if _, err := m.service(id); err != nil {
+		return err
+	}
+	m.want(id, false)
+
+	m.mu.Lock()
+	proc := m.procs[id]
+	if proc != nil {
+		proc.stopping = true
+	}
+	m.mu.Unlock()
+
+	var err error
+	if proc != nil {
+		err = terminate(ctx, proc.cmd.Process.Pid, proc.exited)
+	} else if pid := m.livePID(id); pid != 0 {
+		err = terminate(ctx, pid, func() bool { return !alive(pid) })
+	}
+	if err != nil {
+		svc, _ := m.service(id)
+		return m.fail(id, fmt.Errorf("failed to stop %s: %w", svc.Name, err))
+	}
+	m.setStatus(id, StatusStopped, 0)
+	return nil
+}
+
+// terminate asks a process to exit and kills it if it has not within
+// nativeStopTimeout.
+func terminate(ctx context.Context, pid int, exited func() bool) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+		// A platform without SIGTERM.
+		return p.Kill()
+	}
+	ctx, cancel := context.WithTimeout(ctx, nativeStopTimeout)
+	defer cancel()
+	for !exited() {
+		if err := sleep(ctx, 100*time.Millisecond); err != nil {
+			if err := p.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				return err
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *NativeServiceManager) Restart(ctx context.Context, id ServiceID) error {
+	if err := m.Stop(ctx, id); err != nil {
+		return err
+	}
+	return m.Start(ctx, id)
+}
+
+// Status reports a service as running while its process, or the one in its
+// pid file, is alive.
+func (m *NativeServiceManager) Status(ctx context.Context, id ServiceID) (ServiceStatus, error) {
+	if _, err := m.service(id); err != nil {
+		return StatusStopped, err
+	}
+	pid := m.runningPID(id)
+	status := StatusStopped
+	if pid != 0 {
+		status = StatusRunning
+	}
+	m.setStatus(id, status, pid)
+	return status, nil
+}
+
+// pidFile is where a service's server writes its pid.
+func (m *NativeServiceManager) pidFile(id ServiceID) string {
+	name := map[ServiceID]string{Apache: "httpd.pid", PHP: "php-fpm.pid", MySQL: "mysqld.pid"}[id]
+	if id == Apache && m.Config.Nginx() {
+		name = "nginx.pid"
+	}
+	return filepath.Join(nativeRunDir(), name)
+}
+
+// pid reads a service's pid file; 0 when there is none.
+func (m *NativeServiceManager) pid(id ServiceID) int {
+	data, err := os.ReadFile(m.pidFile(id))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// runningPID returns the pid of a service's server: the process this
+// manager started, else the one in its pid file; 0 when neither runs.
+func (m *NativeServiceManager) runningPID(id ServiceID) int {
+	m.mu.Lock()
+	proc := m.procs[id]
+	m.mu.Unlock()
+	if proc != nil && !proc.exited() {
+		return proc.cmd.Process.Pid
+	}
+	return m.livePID(id)
+}
+
+// livePID returns the pid in a service's pid file if that process is alive
+// and runs the service's binary. A server killed hard leaves its pid file
+// behind, and the pid may since have gone to an unrelated process; such a
+// pid file is removed so nothing signals that process.
+func (m *NativeServiceManager) livePID(id ServiceID) int {
+	pid := m.pid(id)
+	if pid == 0 || !alive(pid) {
+		return 0
+	}
+	bin, err := m.binary(id)
+	if err != nil {
+		return 0
+	}
+	if !runs(pid, bin) {
+		_ = os.Remove(m.pidFile(id))
+		return 0
+	}
+	return pid
+}
+
+// runs reports whether a process runs bin, following symlinks such as
+// Debian's alternatives. Where the system cannot tell, it assumes so.
+func runs(pid int, bin string) bool {
+	exe, err := platform.ProcessExecutable(pid)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(bin); err == nil {
+		bin = resolved
+	}
+	return exe == bin || filepath.Base(exe) == filepath.Base(bin)
+}
+
+func alive(pid int) bool {
+	return signal(pid, syscall.Signal(0)) == nil
+}
+
+func signal(pid int, sig os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
+
+// address is where a service accepts connections once it is up.
+func (m *NativeServiceManager) address(id ServiceID) string {
+	switch id {
+	case Apache:
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(m.Config.HTTPPort))
+	case PHP:
+		return nativePHPAddr
+	default:
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(m.Config.MySQLPort))
+	}
+}
+
+func listening(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// setStatus records a service's state, publishes the change and notifies
+// WatchStatus callers.
+func (m *NativeServiceManager) setStatus(id ServiceID, status ServiceStatus, pid int) {
+	m.mu.Lock()
+	svc := m.services[id]
+	prev := svc.Status
+	svc.Status, svc.PID = status, pid
+	name := svc.Name
+	watchers := append([]func(){}, m.watchers...)
+	m.mu.Unlock()
+
+	if prev == status {
+		return
+	}
+	publishStatus(m.Events, id, name, prev, status)
+	for _, fn := range watchers {
+		fn()
+	}
+}
+
+// fail marks a service as failed, publishes why and returns err.
+func (m *NativeServiceManager) fail(id ServiceID, err error) error {
+	m.setStatus(id, StatusError, 0)
+	svc, _ := m.service(id)
+	m.Events.Publish(events.ServiceFailed{Service: string(id), Name: svc.Name, Error: err.Error()})
+	return err
+}
+
+// Logs reads a service's log file, following it as it grows.
+func (m *NativeServiceManager) Logs(ctx context.Context, id ServiceID, follow bool) (<-chan string, error) {
+	svc, err := m.service(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(svc.LogFile)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has not been started yet", svc.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !follow {
+		return streamLines(ctx, f), nil
+	}
+	return streamLines(ctx, &followReader{ctx: ctx, File: f}), nil
+}
+
+// followReader reads a file that is being appended to, like tail -f, until
+// ctx is done.
+type followReader struct {
+	ctx context.Context
+	*os.File
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.File.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if sleep(r.ctx, 500*time.Millisecond) != nil {
+			return 0, io.EOF
+		}
+	}
+}
+
+// Exec runs a command on the host next to a service: its binary's
+// directory comes first, so Exec(MySQL, "mysql", ...) runs the client of
+// the configured server, which finds it through MYSQL_UNIX_PORT.
+func (m *NativeServiceManager) Exec(ctx context.Context, id ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if _, err := m.service(id); err != nil {
+		return err
+	}
+	if len(cmd) == 0 {
+		return errors.New("no command")
+	}
+	name := cmd[0]
+	if bin, err := m.binary(id); err == nil && !strings.ContainsRune(name, os.PathSeparator) {
+		if st, err := os.Stat(filepath.Join(filepath.Dir(bin), name)); err == nil && !st.IsDir() {
+			name = filepath.Join(filepath.Dir(bin), name)
+		}
+	}
+
+	c := exec.CommandContext(ctx, name, cmd[1:]...)
+	c.Env = append(os.Environ(),
+		"MYSQL_UNIX_PORT="+nativeMySQLSocket(),
+		"MYSQL_TCP_PORT="+strconv.Itoa(m.Config.MySQLPort),
+	)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	return c.Run()
+}
+
+// StartAll starts MySQL, PHP-FPM and then the web server.
+func (m *NativeServiceManager) StartAll(ctx context.Context) error {
+	var errs []error
+	for _, id := range []ServiceID{MySQL, PHP, Apache} {
+		if err := m.Start(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopAll stops the services in the reverse order of StartAll.
+func (m *NativeServiceManager) StopAll(ctx context.Context) error {
+	var errs []error
+	for _, id := range []ServiceID{Apache, PHP, MySQL} {
+		if err := m.Stop(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reload regenerates the web server config and the certificates and has a
+// running web server pick them up; a config it rejects is an error and the
+// old one stays in use.
+func (m *NativeServiceManager) Reload(ctx context.Context) error {
+	bin, err := m.binary(Apache)
+	if err != nil {
+		return err
+	}
+	conf, err := m.writeWebConfig(bin)
+	if err != nil {
+		return err
+	}
+
+	if status, _ := m.Status(ctx, Apache); status == StatusRunning {
+		var out []byte
+		if m.Config.Nginx() {
+			out, err = exec.CommandContext(ctx, bin, "-t", "-p", NativeDir(), "-c", conf).CombinedOutput()
+			if err == nil {
+				err = signal(m.runningPID(Apache), syscall.SIGHUP)
+			}
+		} else {
+			out, err = exec.CommandContext(ctx, bin, "-f", conf, "-k", "graceful").CombinedOutput()
+		}
+		if err != nil {
+			svc, _ := m.service(Apache)
+			return fmt.Errorf("failed to reload %s: %v\n%s", svc.Name, err, out)
+		}
+	}
+	m.Events.Publish(events.VhostReloaded{})
+	return nil
+}
+
+func (m *NativeServiceManager) RefreshStatuses(ctx context.Context) {
+	for _, id := range CoreServices {
+		m.Status(ctx, id)
+	}
+}
+
+// Services returns the core services; the native backend runs no others.
+func (m *NativeServiceManager) Services() []Service {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Service, 0, len(CoreServices))
+	for _, id := range CoreServices {
+		list = append(list, *m.services[id])
+	}
+	return list
+}
+
+// Provisioner returns the MySQL provisioner; the other engines need Docker.
+func (m *NativeServiceManager) Provisioner(engine string) (DatabaseProvisioner, error) {
+	engine, err := projects.ParseEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+	if engine != projects.EngineMySQL {
+		return nil, fmt.Errorf("%s needs the Docker backend", ServiceName(ServiceID(engine)))
+	}
+	return NewProvisioner(m, m.Config.MySQLRootPassword, engine)
+}
+
+// CreateDatabase provisions a project's database; see ProvisionDatabase.
+func (m *NativeServiceManager) CreateDatabase(ctx context.Context, db projects.DatabaseConfig) error {
+	p, err := m.Provisioner(db.EngineName())
+	if err != nil {
+		return err
+	}
+	return ProvisionDatabase(ctx, p, db)
+}
+
+// Health reports a running service as healthy while it accepts
+// connections.
+func (m *NativeServiceManager) Health(ctx context.Context) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	for _, id := range CoreServices {
+		status, health := "stopped", "unhealthy"
+		if s, _ := m.Status(ctx, id); s == StatusRunning {
+			status = "running"
+			if listening(m.address(id)) {
+				health = "healthy"
+			}
+		}
+		result[string(id)] = map[string]string{
+			"status": status,
+			"health": health,
+		}
+	}
+	return result
+}
+
+// WatchStatus calls onChange after every status change this manager sees,
+// crashes included, until ctx is cancelled.
+func (m *NativeServiceManager) WatchStatus(ctx context.Context, onChange func()) error {
+	m.mu.Lock()
+	m.watchers = append(m.watchers, onChange)
+	n := len(m.watchers) - 1
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	m.watchers[n] = func() {}
+	m.mu.Unlock()
+	return nil
+}
+
+// ComposeDrift reports nothing: there is no compose file natively.
+func (m *NativeServiceManager) ComposeDrift(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,202 @@
+//go:build linux || darwin
+
+package services
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/events"
+)
+
+// newNativeTest returns a native manager whose MySQL binary is sleep, with
+// the config folders in a temporary directory.
+func newNativeTest(t *testing.T) (*NativeServiceManager, string) {
+	t.Helper()
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep on $PATH")
+	}
+	dir := t.TempDir()
+	oldConfig, oldLog := config.ConfigDir, config.LogDir
+	config.ConfigDir, config.LogDir = dir, filepath.Join(dir, "logs")
+	t.Cleanup(func() { config.ConfigDir, config.LogDir = oldConfig, oldLog })
+	if err := os.MkdirAll(nativeRunDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.MySQLPath = sleep
+	return NewNativeServiceManager(cfg), sleep
+}
+
+// startChild runs a shell script as a child process.
+func startChild(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestCleanExit(t *testing.T) {
+	tests := []struct {
+		script string
+		clean  bool
+	}{
+		{"exit 0", true},
+		{"kill -TERM $$", true},
+		{"kill -INT $$", true},
+		{"exit 3", false},
+		{"kill -KILL $$", false},
+		{"kill -SEGV $$", false},
+	}
+	for _, tt := range tests {
+		err := startChild(t, tt.script).Wait()
+		if got := cleanExit(err); got != tt.clean {
+			t.Errorf("%q (%v): clean = %v, want %v", tt.script, err, got, tt.clean)
+		}
+	}
+}
+
+func TestLivePID(t *testing.T) {
+	m, sleep := newNativeTest(t)
+	writePID := func(pid int) {
+		t.Helper()
+		if err := os.WriteFile(m.pidFile(MySQL), []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if pid := m.livePID(MySQL); pid != 0 {
+		t.Errorf("no pid file: livePID = %d", pid)
+	}
+
+	server := exec.Command(sleep, "30")
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Process.Kill(); server.Wait() })
+	writePID(server.Process.Pid)
+	if pid := m.livePID(MySQL); pid != server.Process.Pid {
+		t.Errorf("server running: livePID = %d, want %d", pid, server.Process.Pid)
+	}
+
+	// The pid went to an unrelated process: the test binary.
+	writePID(os.Getpid())
+	if pid := m.livePID(MySQL); pid != 0 {
+		t.Errorf("reused pid: livePID = %d, want 0", pid)
+	}
+	if _, err := os.Stat(m.pidFile(MySQL)); !os.IsNotExist(err) {
+		t.Error("stale pid file kept")
+	}
+
+	// The server is gone.
+	exited := startChild(t, "exit 0")
+	exited.Wait()
+	writePID(exited.Process.Pid)
+	if pid := m.livePID(MySQL); pid != 0 {
+		t.Errorf("dead pid: livePID = %d, want 0", pid)
+	}
+}
+
+// superviseChild has m supervise script as a running MySQL and returns the
+// events published for it.
+func superviseChild(t *testing.T, m *NativeServiceManager, script string) *events.Subscription {
+	t.Helper()
+	m.Events = events.New()
+	sub := m.Events.Subscribe()
+	t.Cleanup(m.Events.Close)
+
+	logf, err := os.Create(filepath.Join(t.TempDir(), "mysql.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc := &process{cmd: exec.Command("sh", "-c", script), done: make(chan struct{}), ready: true}
+	m.want(MySQL, true)
+	m.setStatus(MySQL, StatusRunning, 0)
+	<-sub.C // ServiceStarted
+	m.mu.Lock()
+	m.procs[MySQL] = proc
+	m.mu.Unlock()
+	if err := proc.cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go m.supervise(MySQL, proc, logf)
+	return sub
+}
+
+func TestSuperviseCrash(t *testing.T) {
+	m, _ := newNativeTest(t)
+	sub := superviseChild(t, m, "exit 3")
+
+	select {
+	case e := <-sub.C:
+		failed, ok := e.(events.ServiceFailed)
+		if !ok || !strings.Contains(failed.Error, "crashed (exit status 3); restarting in 1s") {
+			t.Errorf("got %#v, want a crash", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("crash not reported")
+	}
+	// Not restarting it: launching sleep as MySQL never becomes ready.
+	m.want(MySQL, false)
+	if svc, _ := m.service(MySQL); svc.Status != StatusError {
+		t.Errorf("status %s, want error", svc.Status)
+	}
+}
+
+func TestSuperviseCleanExit(t *testing.T) {
+	m, _ := newNativeTest(t)
+	// What golocalctl stopping the service looks like to the app.
+	sub := superviseChild(t, m, "kill -TERM $$")
+
+	select {
+	case e := <-sub.C:
+		if _, ok := e.(events.ServiceStopped); !ok {
+			t.Errorf("got %#v, want ServiceStopped", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit not reported")
+	}
+	if svc, _ := m.service(MySQL); svc.Status != StatusStopped {
+		t.Errorf("status %s, want stopped", svc.Status)
+	}
+}
+
+func TestCheckPorts(t *testing.T) {
+	m, _ := newNativeTest(t)
+
+	// A port that is taken is left for the server to report.
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	m.Config.HTTPPort, m.Config.HTTPSPort = l.Addr().(*net.TCPAddr).Port, 0
+	if err := m.checkPorts("Apache"); err != nil {
+		t.Errorf("taken port: %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root may bind any port")
+	}
+	probe, err := net.Listen("tcp", ":80")
+	if err == nil {
+		probe.Close()
+		t.Skip("this system lets users bind port 80")
+	}
+	m.Config.HTTPPort = 80
+	err = m.checkPorts("Apache")
+	if err == nil || !strings.Contains(err.Error(), "port 80 without root") {
+		t.Errorf("port 80: err = %v", err)
+	}
+}
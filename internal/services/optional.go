@@ -0,0 +1,35 @@
+package services
+
+import (
+	"go-local-server/internal/registry"
+)
+
+// syncOptionalServices makes the tracked services include exactly the
+// registry services enabled in the settings.
+func (dsm *DockerServiceManager) syncOptionalServices() {
+	dsm.mu.Lock()
+	defer dsm.mu.Unlock()
+	enabled := map[ServiceID]bool{}
+	for _, d := range registry.Enabled(dsm.Config) {
+		id := ServiceID(d.ID)
+		enabled[id] = true
+		if _, ok := dsm.services[id]; !ok {
+			dsm.services[id] = &Service{ID: id, Name: d.Title, Status: StatusStopped}
+		}
+	}
+	for _, id := range registry.IDs() {
+		if !enabled[ServiceID(id)] {
+			delete(dsm.services, ServiceID(id))
+		}
+	}
+}
+
+// optionalServices are the compose services of the enabled registry
+// services.
+func (dsm *DockerServiceManager) optionalServices() []string {
+	var ids []string
+	for _, d := range registry.Enabled(dsm.Config) {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
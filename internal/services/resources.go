@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/mail"
+	"go-local-server/internal/projects"
+	"go-local-server/pkg/compose"
+)
+
+// DockerDir is where the app keeps its own copy of the compose stack. Running
+// compose from here avoids macOS Docker file-sharing issues with app bundles.
+func DockerDir() string {
+	return filepath.Join(config.DataDir, "docker")
+}
+
+// CopyDockerResources copies apache/ and php/ from the app bundle (or the
+// current working directory) into DockerDir and renders docker-compose.yml
+// there from cfg and the current projects.
+func CopyDockerResources(cfg *config.AppConfig) (string, error) {
+	dockerDir := DockerDir()
+
+	if err := os.MkdirAll(dockerDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Find source resources
+	exe, _ := os.Executable()
+	exeDir := filepath.Dir(exe)
+	var resourcesDir string
+
+	// Try app bundle Resources
+	marker := filepath.Join("apache", "Dockerfile")
+	candidate := filepath.Clean(filepath.Join(exeDir, "..", "Resources"))
+	if _, err := os.Stat(filepath.Join(candidate, marker)); err == nil {
+		resourcesDir = candidate
+	} else {
+		// Fallback to current working directory
+		if cwd, err := os.Getwd(); err == nil {
+			if _, err := os.Stat(filepath.Join(cwd, marker)); err == nil {
+				resourcesDir = cwd
+			}
+		}
+	}
+
+	if resourcesDir == "" {
+		return "", fmt.Errorf("docker resources (%s) not found", marker)
+	}
+
+	srcApache := filepath.Join(resourcesDir, "apache")
+	dstApache := filepath.Join(dockerDir, "apache")
+	if err := copyDir(srcApache, dstApache); err != nil {
+		return "", err
+	}
+
+	// php directory holds the PHP-FPM Dockerfile and php.ini
+	srcPHP := filepath.Join(resourcesDir, "php")
+	dstPHP := filepath.Join(dockerDir, "php")
+	if err := copyDir(srcPHP, dstPHP); err != nil {
+		return "", err
+	}
+
+	if _, err := WriteComposeFile(cfg); err != nil {
+		return "", fmt.Errorf("generate docker-compose.yml: %w", err)
+	}
+
+	return dockerDir, nil
+}
+
+// WriteComposeFile renders docker-compose.yml into DockerDir and returns the
+// new hash of each service.
+func WriteComposeFile(cfg *config.AppConfig) (map[string]string, error) {
+	// Bind-mount sources must exist or Docker creates them owned by root.
+	if err := os.MkdirAll(config.CertsDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := WriteMsmtpConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	projs, err := projects.NewManager(cfg).List()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return compose.NewGenerator(cfg).WriteFile(ComposeFilePath(), projs)
+}
+
+// WriteMsmtpConfig writes php/msmtprc, which points the PHP containers'
+// sendmail at the mail catcher. The file is rewritten in place: Docker keeps
+// a single-file bind mount on the original inode, so the running containers
+// see the new port without being recreated.
+func WriteMsmtpConfig(cfg *config.AppConfig) error {
+	dir := filepath.Join(DockerDir(), "php")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "msmtprc"), mail.MsmtpConfig(cfg), 0644)
+}
+
+// ComposeFilePath is the generated compose file inside DockerDir.
+func ComposeFilePath() string {
+	return filepath.Join(DockerDir(), "docker-compose.yml")
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		dstPath := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(dstPath, info.Mode())
+		}
+
+		return copyFile(path, dstPath)
+	})
+}
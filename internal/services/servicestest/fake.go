@@ -0,0 +1,339 @@
+// Package servicestest provides an in-memory services.ServiceManagerInterface,
+// so code that drives the stack can be tested without Docker.
+package servicestest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"go-local-server/internal/events"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/services"
+)
+
+var _ services.ServiceManagerInterface = (*Manager)(nil)
+
+// ExecFunc handles Exec calls, e.g. to answer a provisioner's SQL.
+type ExecFunc func(ctx context.Context, id services.ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+// Manager is a fake service manager. Services start stopped, Start and Stop
+// flip their status, and every call is recorded. It is safe for concurrent
+// use.
+type Manager struct {
+	// Events, if set, receives status events like the Docker manager's.
+	Events *events.Bus
+
+	mu       sync.Mutex
+	order    []services.ServiceID
+	services map[services.ServiceID]*services.Service
+	errs     map[string]error
+	calls    []string
+	logs     map[services.ServiceID][]string
+	exec     ExecFunc
+	drift    []string
+	watchers []func()
+}
+
+// New returns a fake managing ids, or the core services when none are given.
+func New(ids ...services.ServiceID) *Manager {
+	if len(ids) == 0 {
+		ids = services.CoreServices
+	}
+	m := &Manager{
+		services: make(map[services.ServiceID]*services.Service),
+		errs:     make(map[string]error),
+		logs:     make(map[services.ServiceID][]string),
+	}
+	for _, id := range ids {
+		m.order = append(m.order, id)
+		m.services[id] = &services.Service{ID: id, Name: services.ServiceName(id), Status: services.StatusStopped}
+	}
+	return m
+}
+
+// Fail makes op ("start", "stop", "restart", "status", "logs", "exec",
+// "reload", "createdb") return err for id; an empty id matches every
+// service, and operations on the whole stack ("startall", "stopall",
+// "reload", "drift"). A nil err clears it.
+func (m *Manager) Fail(op string, id services.ServiceID, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := op + " " + string(id)
+	if err == nil {
+		delete(m.errs, key)
+		return
+	}
+	m.errs[key] = err
+}
+
+// SetStatus changes a service's status as if it had changed on its own and
+// notifies WatchStatus callers.
+func (m *Manager) SetStatus(id services.ServiceID, status services.ServiceStatus) {
+	m.mu.Lock()
+	if svc, ok := m.services[id]; ok {
+		m.changed(svc, status, nil)
+	}
+	watchers := append([]func(){}, m.watchers...)
+	m.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+}
+
+// SetLogs sets the lines Logs returns for id.
+func (m *Manager) SetLogs(id services.ServiceID, lines ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs[id] = lines
+}
+
+// SetExec handles Exec calls; without one they succeed with no output.
+func (m *Manager) SetExec(fn ExecFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exec = fn
+}
+
+// SetDrift sets what ComposeDrift reports.
+func (m *Manager) SetDrift(drift ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drift = drift
+}
+
+// Calls returns the calls made so far, such as "start mysql" or
+// "exec mysql mysql -e SELECT 1".
+func (m *Manager) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+// record logs a call and returns the error configured for it.
+func (m *Manager) record(op string, id services.ServiceID, args ...string) error {
+	call := strings.Join(append([]string{op, string(id)}, args...), " ")
+	m.calls = append(m.calls, strings.TrimSpace(call))
+	if err, ok := m.errs[op+" "+string(id)]; ok {
+		return err
+	}
+	return m.errs[op+" "]
+}
+
+// changed sets svc's status and publishes the change, or the failure when
+// err is set. m.mu must be held.
+func (m *Manager) changed(svc *services.Service, to services.ServiceStatus, err error) {
+	prev := svc.Status
+	svc.Status = to
+	id, name := string(svc.ID), svc.Name
+	switch {
+	case err != nil:
+		m.Events.Publish(events.ServiceFailed{Service: id, Name: name, Error: err.Error()})
+	case prev == to:
+	case to == services.StatusRunning:
+		m.Events.Publish(events.ServiceStarted{Service: id, Name: name})
+	case to == services.StatusStopped && prev == services.StatusRunning:
+		m.Events.Publish(events.ServiceStopped{Service: id, Name: name})
+	}
+}
+
+func (m *Manager) service(id services.ServiceID) (*services.Service, error) {
+	svc, ok := m.services[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", services.ErrUnknownService, id)
+	}
+	return svc, nil
+}
+
+// transition is Start, Stop and Restart: record, then set the status.
+func (m *Manager) transition(op string, id services.ServiceID, to services.ServiceStatus) error {
+	m.mu.Lock()
+	svc, err := m.service(id)
+	if err == nil {
+		err = m.record(op, id)
+		if err != nil {
+			to = services.StatusError
+		}
+		m.changed(svc, to, err)
+	}
+	watchers := append([]func(){}, m.watchers...)
+	m.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+	return err
+}
+
+func (m *Manager) Start(ctx context.Context, id services.ServiceID) error {
+	return m.transition("start", id, services.StatusRunning)
+}
+
+func (m *Manager) Stop(ctx context.Context, id services.ServiceID) error {
+	return m.transition("stop", id, services.StatusStopped)
+}
+
+func (m *Manager) Restart(ctx context.Context, id services.ServiceID) error {
+	return m.transition("restart", id, services.StatusRunning)
+}
+
+func (m *Manager) Status(ctx context.Context, id services.ServiceID) (services.ServiceStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	svc, err := m.service(id)
+	if err != nil {
+		return services.StatusStopped, err
+	}
+	if err := m.record("status", id); err != nil {
+		return services.StatusStopped, err
+	}
+	return svc.Status, nil
+}
+
+func (m *Manager) Logs(ctx context.Context, id services.ServiceID, follow bool) (<-chan string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.service(id); err != nil {
+		return nil, err
+	}
+	if err := m.record("logs", id); err != nil {
+		return nil, err
+	}
+	lines := m.logs[id]
+	ch := make(chan string, len(lines))
+	for _, l := range lines {
+		ch <- l
+	}
+	if !follow {
+		close(ch)
+		return ch, nil
+	}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (m *Manager) Exec(ctx context.Context, id services.ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	m.mu.Lock()
+	err := m.record("exec", id, cmd...)
+	fn := m.exec
+	m.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if fn != nil {
+		return fn(ctx, id, cmd, stdin, stdout, stderr)
+	}
+	if stdin != nil {
+		_, err = io.Copy(io.Discard, stdin)
+	}
+	return err
+}
+
+// setAll sets every service to status unless op fails.
+func (m *Manager) setAll(op string, status services.ServiceStatus) error {
+	m.mu.Lock()
+	err := m.record(op, "")
+	if err == nil {
+		for _, id := range m.order {
+			m.changed(m.services[id], status, nil)
+		}
+	}
+	watchers := append([]func(){}, m.watchers...)
+	m.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+	return err
+}
+
+func (m *Manager) StartAll(ctx context.Context) error {
+	return m.setAll("startall", services.StatusRunning)
+}
+
+func (m *Manager) StopAll(ctx context.Context) error {
+	return m.setAll("stopall", services.StatusStopped)
+}
+
+func (m *Manager) Reload(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record("reload", ""); err != nil {
+		return err
+	}
+	m.Events.Publish(events.VhostReloaded{})
+	return nil
+}
+
+func (m *Manager) RefreshStatuses(ctx context.Context) {}
+
+func (m *Manager) Services() []services.Service {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]services.Service, 0, len(m.order))
+	for _, id := range m.order {
+		list = append(list, *m.services[id])
+	}
+	return list
+}
+
+func (m *Manager) CreateDatabase(ctx context.Context, db projects.DatabaseConfig) error {
+	m.mu.Lock()
+	err := m.record("createdb", services.ServiceID(db.EngineName()), db.DBName, db.DBUser)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p, err := m.Provisioner(db.EngineName())
+	if err != nil {
+		return err
+	}
+	return services.ProvisionDatabase(ctx, p, db)
+}
+
+// Provisioner returns the real provisioner, whose clients run through Exec.
+func (m *Manager) Provisioner(engine string) (services.DatabaseProvisioner, error) {
+	return services.NewProvisioner(m, "root", engine)
+}
+
+func (m *Manager) Health(ctx context.Context) map[string]map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string]map[string]string, len(m.services))
+	for id, svc := range m.services {
+		result[string(id)] = map[string]string{"status": svc.Status.String(), "health": "unknown"}
+	}
+	return result
+}
+
+// WatchStatus calls onChange after every status change until ctx is
+// cancelled.
+func (m *Manager) WatchStatus(ctx context.Context, onChange func()) error {
+	m.mu.Lock()
+	m.watchers = append(m.watchers, onChange)
+	n := len(m.watchers) - 1
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	m.watchers[n] = func() {}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Manager) ComposeDrift(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record("drift", ""); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), m.drift...), nil
+}
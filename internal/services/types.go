@@ -1,33 +1,82 @@
 package services
 
-// ServiceManagerInterface defines the interface for service management.
-// In Docker-only mode this is implemented by DockerServiceManager.
+import (
+	"context"
+	"io"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/events"
+	"go-local-server/internal/projects"
+)
+
+// ServiceID identifies a managed service. The core services have constants;
+// any other ID is a compose service: a database engine, phpmyadmin, a
+// registry service or a single PHP version (php83).
+type ServiceID string
+
+const (
+	Apache ServiceID = "apache"
+	// PHP stands for every PHP-FPM container the active projects need.
+	PHP   ServiceID = "php"
+	MySQL ServiceID = "mysql"
+)
+
+// CoreServices are always part of the stack, in display order.
+var CoreServices = []ServiceID{Apache, PHP, MySQL}
+
+// ServiceManagerInterface runs the stack. DockerServiceManager runs it with
+// Docker Compose and NativeServiceManager as local processes;
+// servicestest.Manager is an in-memory fake for tests.
 type ServiceManagerInterface interface {
-	StartNginx() error
-	StopNginx() error
-	ReloadNginx() error
-	CheckNginxStatus() error
-
-	StartPHP() error
-	StopPHP() error
-	ReloadPHP() error
-	CheckPHPStatus() error
-
-	StartMySQL() error
-	StopMySQL() error
-	CheckMySQLStatus() error
-	CreateDatabase(dbName, dbUser, dbPassword string) error
-
-	StartAll() error
-	StopAll() error
-	ReloadAll() error
-	RefreshStatuses()
-
-	GetServices() map[string]*Service
-
-	// Advanced features
-	StreamContainerLogs(containerName string, follow bool) (chan string, error)
-	GetAllHealthStatus() map[string]map[string]string
+	// Start, Stop and Restart act on one service. MySQL brings the other
+	// database engines projects use along with it.
+	Start(ctx context.Context, id ServiceID) error
+	Stop(ctx context.Context, id ServiceID) error
+	Restart(ctx context.Context, id ServiceID) error
+	// Status checks a service live and records the result.
+	Status(ctx context.Context, id ServiceID) (ServiceStatus, error)
+	// Logs streams a service's output. Without follow the channel is closed
+	// at the end of the log, otherwise when ctx is cancelled.
+	Logs(ctx context.Context, id ServiceID, follow bool) (<-chan string, error)
+	// Exec runs a command in a service's container, streaming stdin and
+	// output; a non-zero exit is an error.
+	Exec(ctx context.Context, id ServiceID, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+	StartAll(ctx context.Context) error
+	StopAll(ctx context.Context) error
+	// Reload regenerates the vhosts and the compose file and applies them to
+	// the running stack.
+	Reload(ctx context.Context) error
+	RefreshStatuses(ctx context.Context)
+	// Services returns a snapshot of the managed services, core ones first.
+	Services() []Service
+
+	// CreateDatabase provisions a project's database and user on its engine.
+	CreateDatabase(ctx context.Context, db projects.DatabaseConfig) error
+	// Provisioner returns the database provisioner for one of
+	// projects.Engines.
+	Provisioner(engine string) (DatabaseProvisioner, error)
+
+	// Health reports status and health per container.
+	Health(ctx context.Context) map[string]map[string]string
+	// WatchStatus calls onChange when services change state, until ctx is
+	// cancelled.
+	WatchStatus(ctx context.Context, onChange func()) error
+	// ComposeDrift lists the services out of date with the settings.
+	ComposeDrift(ctx context.Context) ([]string, error)
+}
+
+// NewServiceManager returns the manager of the configured service backend,
+// publishing its events to bus.
+func NewServiceManager(cfg *config.AppConfig, bus *events.Bus) ServiceManagerInterface {
+	if cfg.Native() {
+		m := NewNativeServiceManager(cfg)
+		m.Events = bus
+		return m
+	}
+	dsm := NewDockerServiceManager(cfg)
+	dsm.Events = bus
+	return dsm
 }
 
 type ServiceStatus int
@@ -38,13 +87,34 @@ const (
 	StatusError
 )
 
+// Service is the last known state of a managed service.
 type Service struct {
-	Name       string
-	Status     ServiceStatus
-	PID        int
-	StartCmd   string
-	StopCmd    string
-	ReloadCmd  string
-	LogFile    string
-	ConfigFile string
+	ID      ServiceID
+	Name    string // display name
+	Status  ServiceStatus
+	PID     int // 0 when the process is not ours to see, e.g. in a container
+	LogFile string
+}
+
+func (s ServiceStatus) String() string {
+	switch s {
+	case StatusRunning:
+		return "running"
+	case StatusError:
+		return "error"
+	default:
+		return "stopped"
+	}
+}
+
+// publishStatus publishes a service's change from prev to status, if it is
+// one subscribers hear about.
+func publishStatus(bus *events.Bus, id ServiceID, name string, prev, status ServiceStatus) {
+	switch {
+	case prev == status:
+	case status == StatusRunning:
+		bus.Publish(events.ServiceStarted{Service: string(id), Name: name})
+	case status == StatusStopped && prev == StatusRunning:
+		bus.Publish(events.ServiceStopped{Service: string(id), Name: name})
+	}
 }
@@ -4,34 +4,75 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"text/template"
 
+	"go-local-server/internal/certs"
 	"go-local-server/internal/config"
+	"go-local-server/internal/livereload"
 	"go-local-server/internal/projects"
 )
 
-const vhostTemplate = `<VirtualHost *:80>
+const vhostTemplate = `{{define "site"}}
     ServerName {{.Domain}}
     ServerAlias *.{{.Domain}}
 
     DocumentRoot "{{.ProjectPath}}"
 
+    DirectoryIndex index.php index.html
+
     <Directory "{{.ProjectPath}}">
         Options Indexes FollowSymLinks
         AllowOverride All
         Require all granted
     </Directory>
 
+    # PHP {{.PHPVersion}} runs in its own PHP-FPM container.
+    <FilesMatch "\.php$">
+        SetHandler "proxy:fcgi://{{.PHPService}}:9000"
+    </FilesMatch>
+
     ErrorLog "/var/log/apache2/{{.ProjectID}}-error.log"
     CustomLog "/var/log/apache2/{{.ProjectID}}-access.log" combined
+{{- if .LiveReloadScript}}
+
+    # Live reload: the client is added to HTML responses on the way out;
+    # project files are never modified.
+    <IfModule mod_substitute.c>
+        AddOutputFilterByType SUBSTITUTE text/html
+        Substitute 's|</body>|<script src="{{.LiveReloadScript}}" async></script></body>|ni'
+    </IfModule>
+{{- end}}
+{{- end -}}
+<VirtualHost *:80>
+{{- template "site" .}}
+</VirtualHost>
+{{- if .SSLCertFile}}
+
+<VirtualHost *:443>
+{{- template "site" .}}
+
+    SSLEngine on
+    SSLCertificateFile "{{.SSLCertFile}}"
+    SSLCertificateKeyFile "{{.SSLKeyFile}}"
 </VirtualHost>
+{{- end}}
 `
 
+// CertsMount is where config.CertsDir is mounted in the apache container.
+const CertsMount = "/etc/golocal/certs"
+
 type VhostData struct {
 	ProjectID   string
 	Domain      string
 	ProjectPath string
+	PHPVersion  string
+	PHPService  string
+	SSLCertFile string // empty = no HTTPS vhost
+	SSLKeyFile  string
+
+	LiveReloadScript string // client script URL; empty = live reload off
 }
 
 type Generator struct {
@@ -42,21 +83,42 @@ func NewGenerator(cfg *config.AppConfig) *Generator {
 	return &Generator{config: cfg}
 }
 
-func (g *Generator) GenerateVhost(project *projects.Project) error {
-	docRoot := project.Path
-
+// DocumentRoot is the directory a project is served from: its DocumentRoot
+// setting, else public/ when it holds an index.php, else the project root.
+func DocumentRoot(project *projects.Project) string {
 	// Use custom DocumentRoot if set
 	if project.DocumentRoot != "" {
-		docRoot = filepath.Join(project.Path, project.DocumentRoot)
-	} else if _, err := os.Stat(filepath.Join(project.Path, "public", "index.php")); err == nil {
-		// Auto-detect MVC public folder
-		docRoot = filepath.Join(project.Path, "public")
+		return filepath.Join(project.Path, project.DocumentRoot)
 	}
+	// Auto-detect MVC public folder
+	if _, err := os.Stat(filepath.Join(project.Path, "public", "index.php")); err == nil {
+		return filepath.Join(project.Path, "public")
+	}
+	return project.Path
+}
 
+func (g *Generator) GenerateVhost(project *projects.Project) error {
 	data := VhostData{
 		ProjectID:   project.ID,
 		Domain:      project.Domain,
-		ProjectPath: docRoot,
+		ProjectPath: DocumentRoot(project),
+		PHPVersion:  project.PHP(),
+		PHPService:  projects.PHPService(project.PHP()),
+	}
+	if project.LiveReload {
+		data.LiveReloadScript = livereload.ScriptURL(livereload.DefaultPort, project.ID)
+	}
+
+	// Serve HTTPS too when the local CA can issue a certificate for the
+	// domain and its subdomains.
+	if ca, err := certs.Default(); err != nil {
+		fmt.Printf("HTTPS disabled for %s: %v\n", project.Domain, err)
+	} else if err := ca.Issue(project.ID, project.Domain); err != nil {
+		fmt.Printf("HTTPS disabled for %s: %v\n", project.Domain, err)
+	} else {
+		certFile, keyFile := certs.LeafFiles(project.ID)
+		data.SSLCertFile = path.Join(CertsMount, certFile)
+		data.SSLKeyFile = path.Join(CertsMount, keyFile)
 	}
 
 	tmpl, err := template.New("vhost").Parse(vhostTemplate)
@@ -71,7 +133,7 @@ func (g *Generator) GenerateVhost(project *projects.Project) error {
 
 	// Docker stack reads from repo ./apache/sites, but also keep a copy in app support
 	// so projects remain portable.
-	vhostPath := filepath.Join(config.ConfigDir, "apache", "sites", project.ID+".conf")
+	vhostPath := filepath.Join(config.DataDir, "apache", "sites", project.ID+".conf")
 	if err := os.MkdirAll(filepath.Dir(vhostPath), 0755); err != nil {
 		return err
 	}
@@ -94,6 +156,10 @@ func (g *Generator) GenerateAllVhosts() error {
 }
 
 func (g *Generator) RemoveVhost(projectID string) {
-	vhostPath := filepath.Join(config.ConfigDir, "apache", "sites", projectID+".conf")
+	vhostPath := filepath.Join(config.DataDir, "apache", "sites", projectID+".conf")
 	os.Remove(vhostPath)
+
+	if ca, err := certs.Default(); err == nil {
+		ca.Remove(projectID)
+	}
 }
@@ -0,0 +1,458 @@
+package compose
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+	"go-local-server/internal/registry"
+	"go-local-server/pkg/apache"
+)
+
+// HashLabel is set on every container to the hash of its service's block in
+// the compose file it was created from, so a running stack can be compared
+// with the file on disk one service at a time.
+const HashLabel = "dev.golocal.config-hash"
+
+const composeTemplate = `# Generated by GoLocalServer from {{.ConfigFile}}.
+# Changes made here are overwritten; edit the app settings instead.
+services:
+  apache:
+    build:
+      context: .
+      dockerfile: ./apache/Dockerfile
+    container_name: golocal-apache
+    labels:
+      {{q $.HashLabel}}: ""
+    ports:
+      - {{q (printf "%d:80" .HTTPPort)}}
+{{- if .HTTPSPort}}
+      - {{q (printf "%d:443" .HTTPSPort)}}
+{{- end}}
+    volumes:
+      - ./apache/sites:/etc/apache2/sites-enabled:ro
+      - {{q (printf "%s:%s:ro" .CertsDir .CertsMount)}}
+{{- range .Mounts}}
+      - {{q (printf "%s:%s:ro" . .)}}
+{{- end}}
+      - {{q (printf "%s:/var/log/apache2" .LogDir)}}
+    restart: unless-stopped
+{{- range .PHP}}
+
+  {{.Service}}:
+    build:
+      context: .
+      dockerfile: ./php/Dockerfile
+      args:
+        PHP_IMAGE: {{q .Image}}
+    image: {{q (printf "golocal/php:%s" .Version)}}
+    container_name: {{q (printf "golocal-%s" .Service)}}
+    labels:
+      {{q $.HashLabel}}: ""
+    volumes:
+{{- range $.Mounts}}
+      - {{q (printf "%s:%s:ro" . .)}}
+{{- end}}
+      - ./php/php.ini:/usr/local/etc/php/php.ini:ro
+      - ./php/msmtprc:/etc/msmtprc:ro
+{{- if $.ServiceEnv}}
+    environment:
+{{- range $.ServiceEnv}}
+      {{.Name}}: {{q .Value}}
+{{- end}}
+{{- end}}
+    extra_hosts:
+      - "host.docker.internal:host-gateway"
+    restart: unless-stopped
+{{- end}}
+
+  mysql:
+    image: {{q .MySQLImage}}
+    container_name: golocal-mysql
+    labels:
+      {{q $.HashLabel}}: ""
+    environment:
+      MYSQL_ROOT_PASSWORD: {{q .MySQLRootPassword}}
+      MYSQL_DATABASE: golocal
+{{- if .MySQLPort}}
+    ports:
+      - {{q (printf "%d:3306" .MySQLPort)}}
+{{- end}}
+    volumes:
+      - mysql-data:/var/lib/mysql
+      - {{q (printf "%s:/var/log/mysql" .LogDir)}}
+    restart: unless-stopped
+{{- with .MariaDB}}
+
+  mariadb:
+    image: {{q .Image}}
+    container_name: golocal-mariadb
+    labels:
+      {{q $.HashLabel}}: ""
+    environment:
+      MARIADB_ROOT_PASSWORD: {{q $.MySQLRootPassword}}
+{{- if .Port}}
+    ports:
+      - {{q (printf "%d:3306" .Port)}}
+{{- end}}
+    volumes:
+      - mariadb-data:/var/lib/mysql
+    restart: unless-stopped
+{{- end}}
+{{- with .Postgres}}
+
+  postgres:
+    image: {{q .Image}}
+    container_name: golocal-postgres
+    labels:
+      {{q $.HashLabel}}: ""
+    environment:
+      POSTGRES_PASSWORD: {{q $.MySQLRootPassword}}
+{{- if .Port}}
+    ports:
+      - {{q (printf "%d:5432" .Port)}}
+{{- end}}
+    volumes:
+      - postgres-data:/var/lib/postgresql/data
+    restart: unless-stopped
+{{- end}}
+{{- if .PhpMyAdmin}}
+
+  phpmyadmin:
+    image: {{q .PhpMyAdminImage}}
+    container_name: golocal-phpmyadmin
+    labels:
+      {{q $.HashLabel}}: ""
+    environment:
+{{- if .MariaDB}}
+      PMA_HOSTS: mysql,mariadb
+{{- else}}
+      PMA_HOST: mysql
+      PMA_PORT: 3306
+{{- end}}
+    ports:
+      - {{q (printf "%d:80" .PhpMyAdminPort)}}
+    depends_on:
+      - mysql
+{{- if .MariaDB}}
+      - mariadb
+{{- end}}
+    restart: unless-stopped
+{{- end}}
+{{- range .Services}}
+
+  {{.ID}}:
+    image: {{q .Image}}
+    container_name: {{q (printf "golocal-%s" .ID)}}
+    labels:
+      {{q $.HashLabel}}: ""
+{{- if .Command}}
+    command:
+{{- range .Command}}
+      - {{q .}}
+{{- end}}
+{{- end}}
+{{- if .Env}}
+    environment:
+{{- range .Env}}
+      {{.Name}}: {{q .Value}}
+{{- end}}
+{{- end}}
+{{- with .Published}}
+    ports:
+{{- range .}}
+      - {{q (printf "%d:%d" .Host .Container)}}
+{{- end}}
+{{- end}}
+{{- if .Volumes}}
+    volumes:
+{{- range .Volumes}}
+      - {{q (printf "%s:%s" .Name .Path)}}
+{{- end}}
+{{- end}}
+{{- with .Healthcheck}}
+    healthcheck:
+      test:
+{{- range .Test}}
+        - {{q .}}
+{{- end}}
+      interval: {{q .Interval}}
+      timeout: {{q .Timeout}}
+      retries: {{.Retries}}
+{{- end}}
+    restart: unless-stopped
+{{- end}}
+
+volumes:
+  mysql-data:
+{{- if .MariaDB}}
+  mariadb-data:
+{{- end}}
+{{- if .Postgres}}
+  postgres-data:
+{{- end}}
+{{- range .Services}}
+{{- range .Volumes}}
+  {{.Name}}:
+{{- end}}
+{{- end}}
+`
+
+type ComposeData struct {
+	ConfigFile        string
+	HashLabel         string
+	HTTPPort          int
+	HTTPSPort         int // 0 = HTTPS not published
+	MySQLPort         int
+	PhpMyAdminPort    int
+	MySQLImage        string
+	PhpMyAdminImage   string
+	MySQLRootPassword string
+	PhpMyAdmin        bool
+	LogDir            string
+	CertsDir          string
+	CertsMount        string
+	Mounts            []string // host directories bind-mounted read-only at the same path
+	PHP               []PHPRuntime
+	MariaDB           *DatabaseServer // nil = no project uses MariaDB
+	Postgres          *DatabaseServer // nil = no project uses PostgreSQL
+	Services          []registry.Descriptor
+	ServiceEnv        []registry.EnvVar // connection variables for the PHP-FPM containers
+}
+
+// PHPRuntime is one PHP-FPM container, serving every project on its version.
+type PHPRuntime struct {
+	Version string
+	Service string
+	Image   string
+}
+
+// DatabaseServer is an optional database engine container. Port 0 keeps
+// it off the host.
+type DatabaseServer struct {
+	Image string
+	Port  int
+}
+
+type Generator struct {
+	config *config.AppConfig
+}
+
+func NewGenerator(cfg *config.AppConfig) *Generator {
+	return &Generator{config: cfg}
+}
+
+// Render returns the compose file for the current settings and projects,
+// together with the hash of each service (its HashLabel). Only the PHP
+// versions used by active projects get a PHP-FPM service, and MariaDB and
+// PostgreSQL are only included when an active project uses them. The
+// optional services enabled in the settings are rendered from the registry.
+func (g *Generator) Render(projs []*projects.Project) ([]byte, map[string]string, error) {
+	tmpl, err := template.New("compose").Funcs(template.FuncMap{"q": quote}).Parse(composeTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := ComposeData{
+		ConfigFile:        config.ConfigFile,
+		HashLabel:         HashLabel,
+		HTTPPort:          g.config.HTTPPort,
+		HTTPSPort:         g.config.HTTPSPort,
+		MySQLPort:         g.config.MySQLPort,
+		PhpMyAdminPort:    g.config.PhpMyAdminPort,
+		MySQLImage:        g.config.MySQLImage,
+		PhpMyAdminImage:   g.config.PhpMyAdminImage,
+		MySQLRootPassword: g.config.MySQLRootPassword,
+		PhpMyAdmin:        g.config.ServiceEnabled("phpmyadmin"),
+		LogDir:            config.LogDir,
+		CertsDir:          config.CertsDir,
+		CertsMount:        apache.CertsMount,
+		Mounts:            projectMounts(projs),
+		Services:          registry.Enabled(g.config),
+		ServiceEnv:        registry.ConnectionEnv(g.config),
+	}
+	for _, v := range projects.ActivePHPVersions(projs) {
+		data.PHP = append(data.PHP, PHPRuntime{
+			Version: v,
+			Service: projects.PHPService(v),
+			Image:   g.config.PHPImageFor(v),
+		})
+	}
+	for _, e := range projects.ActiveEngines(projs) {
+		switch e {
+		case projects.EngineMariaDB:
+			data.MariaDB = &DatabaseServer{Image: g.config.MariaDBImage, Port: g.config.MariaDBPort}
+		case projects.EnginePostgres:
+			data.Postgres = &DatabaseServer{Image: g.config.PostgresImage, Port: g.config.PostgresPort}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, nil, err
+	}
+
+	// Each service's hash covers its own block (image, build args,
+	// environment, ports, volumes, command) with the label still empty, so
+	// a change to one service does not recreate the others.
+	var out bytes.Buffer
+	hashes := make(map[string]string)
+	for _, b := range serviceBlocks(buf.String()) {
+		text := strings.Join(b.lines, "")
+		if b.service != "" {
+			sum := sha256.Sum256([]byte(strings.TrimSpace(text)))
+			hashes[b.service] = hex.EncodeToString(sum[:])[:16]
+			text = strings.Replace(text, hashLine(""), hashLine(hashes[b.service]), 1)
+		}
+		out.WriteString(text)
+	}
+	return out.Bytes(), hashes, nil
+}
+
+func hashLine(hash string) string {
+	return quote(HashLabel) + ": " + quote(hash)
+}
+
+// block is a run of lines of a compose file: one service's definition, or
+// lines outside any service.
+type block struct {
+	service string
+	lines   []string
+}
+
+// serviceBlocks splits a generated compose file by service. A service
+// starts at a two-space-indented key under services: and runs until the
+// next service or top-level key.
+func serviceBlocks(file string) []block {
+	var blocks []block
+	section := ""
+	for _, line := range strings.SplitAfter(file, "\n") {
+		key := strings.TrimRight(line, "\r\n")
+		switch {
+		case key != "" && key[0] != ' ' && key[0] != '#':
+			section = strings.TrimSuffix(key, ":")
+			blocks = append(blocks, block{})
+		case section == "services" && len(key) > 2 && key[:2] == "  " && key[2] != ' ':
+			blocks = append(blocks, block{service: strings.TrimSuffix(strings.TrimSpace(key), ":")})
+		case len(blocks) == 0:
+			blocks = append(blocks, block{})
+		}
+		blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, line)
+	}
+	return blocks
+}
+
+// WriteFile renders the compose file to path, replacing it atomically so a
+// concurrent docker compose run never sees a partial file. It returns the
+// new hash of each service.
+func (g *Generator) WriteFile(path string, projs []*projects.Project) (map[string]string, error) {
+	data, hashes, err := g.Render(projs)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, ".docker-compose-*.yml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// FileHashes reads the hash label of each service back from a generated
+// compose file.
+func FileHashes(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := quote(HashLabel) + ":"
+	hashes := make(map[string]string)
+	for _, b := range serviceBlocks(string(data)) {
+		if b.service == "" {
+			continue
+		}
+		for _, line := range b.lines {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, prefix) {
+				continue
+			}
+			var hash string
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, prefix))), &hash); err != nil {
+				return nil, fmt.Errorf("%s: bad %s label on %s: %v", path, HashLabel, b.service, err)
+			}
+			hashes[b.service] = hash
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("%s was not generated by GoLocalServer (no %s label)", path, HashLabel)
+	}
+	return hashes, nil
+}
+
+// projectMounts returns the directories the apache and PHP-FPM containers
+// must see: the home directory, plus the root of any project outside it.
+func projectMounts(projs []*projects.Project) []string {
+	var roots []string
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, home)
+	}
+	for _, p := range projs {
+		if p.Path != "" {
+			roots = append(roots, filepath.Clean(p.Path))
+		}
+	}
+	sort.Strings(roots)
+
+	// Sorted order puts parents before their children.
+	var mounts []string
+	for _, r := range roots {
+		covered := false
+		for _, m := range mounts {
+			if r == m || strings.HasPrefix(r, m+string(filepath.Separator)) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			mounts = append(mounts, r)
+		}
+	}
+	return mounts
+}
+
+// quote renders s as a double-quoted YAML scalar (JSON strings are valid
+// YAML) and escapes $ so compose does not interpolate it.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return strings.ReplaceAll(string(data), "$", "$$")
+}
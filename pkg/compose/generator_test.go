@@ -0,0 +1,125 @@
+package compose
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-local-server/internal/config"
+	"go-local-server/internal/projects"
+)
+
+func render(t *testing.T, cfg *config.AppConfig, projs []*projects.Project) map[string]string {
+	t.Helper()
+	data, hashes, err := NewGenerator(cfg).Render(projs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for svc, hash := range hashes {
+		if !strings.Contains(string(data), hashLine(hash)) {
+			t.Errorf("%s: label %s not in the file", svc, hash)
+		}
+	}
+	return hashes
+}
+
+func TestRenderHashesEachService(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg := config.DefaultConfig()
+	blog := &projects.Project{ID: "blog", Path: filepath.Join(home, "blog"), PHPVersion: "8.2", IsActive: true}
+	base := render(t, cfg, []*projects.Project{blog})
+	php82 := projects.PHPService("8.2")
+	for _, svc := range []string{"apache", "mysql", "phpmyadmin", php82} {
+		if base[svc] == "" {
+			t.Fatalf("no hash for %s in %v", svc, base)
+		}
+	}
+	if base["apache"] == base["mysql"] {
+		t.Error("services share a hash")
+	}
+
+	tests := []struct {
+		name      string
+		cfg       func(*config.AppConfig)
+		projs     []*projects.Project
+		changed   []string
+		unchanged []string
+	}{
+		{
+			name:      "enabling redis",
+			cfg:       func(c *config.AppConfig) { c.OptionalServices = append(c.OptionalServices, "redis") },
+			projs:     []*projects.Project{blog},
+			changed:   []string{php82},
+			unchanged: []string{"apache", "mysql", "phpmyadmin"},
+		},
+		{
+			name: "adding a PHP version",
+			projs: []*projects.Project{blog,
+				{ID: "shop", Path: filepath.Join(home, "shop"), PHPVersion: "8.1", IsActive: true}},
+			unchanged: []string{"apache", "mysql", "phpmyadmin", php82},
+		},
+		{
+			name: "a project outside home",
+			projs: []*projects.Project{blog,
+				{ID: "srv", Path: "/srv/site", PHPVersion: "8.2", IsActive: true}},
+			changed:   []string{"apache", php82},
+			unchanged: []string{"mysql", "phpmyadmin"},
+		},
+		{
+			name:      "a new MySQL port",
+			cfg:       func(c *config.AppConfig) { c.MySQLPort++ },
+			projs:     []*projects.Project{blog},
+			changed:   []string{"mysql"},
+			unchanged: []string{"apache", "phpmyadmin", php82},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(cfg)
+			}
+			got := render(t, cfg, tt.projs)
+			for _, svc := range tt.changed {
+				if got[svc] == base[svc] {
+					t.Errorf("%s: hash did not change", svc)
+				}
+			}
+			for _, svc := range tt.unchanged {
+				if got[svc] != base[svc] {
+					t.Errorf("%s: hash changed", svc)
+				}
+			}
+		})
+	}
+}
+
+func TestFileHashes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.OptionalServices = append(cfg.OptionalServices, "redis", "mailpit")
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	written, err := NewGenerator(cfg).WriteFile(path, []*projects.Project{{ID: "blog", PHPVersion: "8.3", IsActive: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := FileHashes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(written) {
+		t.Errorf("read %v, wrote %v", read, written)
+	}
+	for svc, hash := range written {
+		if read[svc] != hash {
+			t.Errorf("%s: read %q, wrote %q", svc, read[svc], hash)
+		}
+	}
+	if _, ok := read["mysql-data"]; ok {
+		t.Error("a volume was read as a service")
+	}
+}
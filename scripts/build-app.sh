@@ -82,10 +82,6 @@ if [ -f "$PROJECT_ROOT/logo.png" ]; then
 fi
 
 # Copy required files into app bundle Resources
-if [ -d "$PROJECT_ROOT/docker-compose.yml" ] || [ -f "$PROJECT_ROOT/docker-compose.yml" ]; then
-    cp "$PROJECT_ROOT/docker-compose.yml" "$APP_BUNDLE/Contents/Resources/"
-fi
-
 if [ -d "$PROJECT_ROOT/apache" ]; then
     cp -r "$PROJECT_ROOT/apache" "$APP_BUNDLE/Contents/Resources/"
 fi